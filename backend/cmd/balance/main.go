@@ -0,0 +1,124 @@
+// Command balance plays thousands of simulated dungeons through the real
+// dungeon-graph RGD CEL and reports win rate, turns-to-victory, damage and
+// loot per (heroClass, difficulty, modifier, runCount) combination.
+//
+//	go run ./cmd/balance -games 2000 -difficulties easy,normal,hard
+//	go run ./cmd/balance -json report.json            # write a baseline
+//	go run ./cmd/balance -baseline report.json        # exit 1 on drift
+//
+// Run it from backend/ (or pass -rgd) after any change to dungeon-graph.yaml.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pnz1990/krombat/backend/internal/autoplay"
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/sim"
+)
+
+func main() {
+	rgd := flag.String("rgd", "../manifests/rgds/dungeon-graph.yaml", "path to dungeon-graph.yaml")
+	classes := flag.String("classes", strings.Join(catalog.ClassIDs(), ","), "comma-separated hero classes")
+	difficulties := flag.String("difficulties", "easy,normal,hard", "comma-separated difficulties")
+	modifiers := flag.String("modifiers", "any", "comma-separated modifiers (any, none, curse-fortitude, curse-fury, curse-darkness, blessing-strength, blessing-resilience, blessing-fortune)")
+	runCounts := flag.String("runcounts", "0", "comma-separated New Game+ run counts")
+	monsters := flag.Int("monsters", 3, "monsters per room")
+	rooms := flag.Int("rooms", 2, "rooms per dungeon (2-5)")
+	games := flag.Int("games", 1000, "games per combination")
+	strategy := flag.String("strategy", "heal-at-threshold", "player strategy ("+strings.Join(autoplay.Names(), ", ")+")")
+	maxTurns := flag.Int("max-turns", 400, "turn cap before a game counts as stalled")
+	workers := flag.Int("workers", 0, "parallel games (0 = GOMAXPROCS)")
+	jsonOut := flag.String("json", "", "write the JSON report to this file (- for stdout)")
+	baseline := flag.String("baseline", "", "compare against this JSON report and exit 1 on drift")
+	tolWin := flag.Float64("tol-winrate", 0.05, "allowed absolute win-rate drift")
+	tolTurns := flag.Float64("tol-turns", 0.15, "allowed relative drift of mean turns-to-victory")
+	tolLoot := flag.Float64("tol-loot", 0.5, "allowed absolute drift of loot per game")
+	flag.Parse()
+
+	rc, err := parseInts(*runCounts)
+	if err != nil {
+		fatal("-runcounts: %v", err)
+	}
+	engine, err := sim.LoadEngine(*rgd)
+	if err != nil {
+		fatal("load %s: %v", *rgd, err)
+	}
+	report, err := engine.Run(sim.Matrix{
+		HeroClasses:  split(*classes),
+		Difficulties: split(*difficulties),
+		Modifiers:    split(*modifiers),
+		RunCounts:    rc,
+		Monsters:     *monsters,
+		Rooms:        *rooms,
+		Games:        *games,
+		Strategy:     *strategy,
+		MaxTurns:     *maxTurns,
+		Workers:      *workers,
+	})
+	if err != nil {
+		fatal("%v", err)
+	}
+
+	if *jsonOut != "-" {
+		report.WriteTable(os.Stdout)
+	}
+	if *jsonOut != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		data = append(data, '\n')
+		if *jsonOut == "-" {
+			os.Stdout.Write(data)
+		} else if err := os.WriteFile(*jsonOut, data, 0o644); err != nil {
+			fatal("write %s: %v", *jsonOut, err)
+		}
+	}
+	if *baseline != "" {
+		data, err := os.ReadFile(*baseline)
+		if err != nil {
+			fatal("read baseline: %v", err)
+		}
+		var base sim.Report
+		if err := json.Unmarshal(data, &base); err != nil {
+			fatal("parse baseline: %v", err)
+		}
+		drift := sim.Compare(base, report, sim.Tolerance{WinRate: *tolWin, Turns: *tolTurns, Loot: *tolLoot})
+		for _, d := range drift {
+			fmt.Fprintln(os.Stderr, "DRIFT", d)
+		}
+		if len(drift) > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+func split(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, p := range split(s) {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "balance: "+format+"\n", args...)
+	os.Exit(2)
+}
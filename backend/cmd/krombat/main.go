@@ -0,0 +1,113 @@
+// Command krombat plays Krombat dungeons from the terminal. It is built on
+// pkg/client and doubles as a reference consumer of the API.
+//
+//	krombat login                 # paste a personal API token; it is saved for later runs
+//	krombat                       # list, create and play dungeons
+//	krombat -url http://localhost:8080 -session <cookie>
+//
+// Mint a token with the read and play scopes from a signed-in browser session
+// (POST /api/v1/auth/tokens {"name":"cli","scopes":["read","play"]}).
+// KROMBAT_URL and KROMBAT_TOKEN override the saved settings.
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/pnz1990/krombat/backend/pkg/client"
+	"golang.org/x/term"
+)
+
+func main() {
+	baseURL := flag.String("url", cmp.Or(os.Getenv("KROMBAT_URL"), "https://learn-kro.eks.aws.dev"), "backend base URL")
+	token := flag.String("token", os.Getenv("KROMBAT_TOKEN"), "personal API token (default: the one saved by krombat login)")
+	session := flag.String("session", "", "browser session cookie value, instead of a token")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if flag.Arg(0) == "login" {
+		if err := login(ctx, *baseURL, *token); err != nil {
+			fatal("login: %v", err)
+		}
+		return
+	}
+
+	opts := []client.Option{}
+	switch {
+	case *session != "":
+		opts = append(opts, client.WithSessionCookie(*session))
+	case *token != "":
+		opts = append(opts, client.WithToken(*token))
+	default:
+		saved, err := os.ReadFile(tokenPath())
+		if err != nil {
+			fatal("not logged in — run: krombat login")
+		}
+		opts = append(opts, client.WithToken(strings.TrimSpace(string(saved))))
+	}
+	c := client.New(*baseURL, opts...)
+	me, err := c.Me(ctx)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if err := runTUI(ctx, c, me.Login); err != nil {
+		fatal("%v", err)
+	}
+}
+
+// login checks a token against /api/v1/auth/me and saves it to tokenPath.
+func login(ctx context.Context, baseURL, token string) error {
+	if token == "" {
+		fmt.Printf("Mint a token while signed in to %s:\n  POST /api/v1/auth/tokens {\"name\":\"cli\",\"scopes\":[\"read\",\"play\"]}\nToken: ", baseURL)
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			b, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			if err != nil {
+				return err
+			}
+			token = string(b)
+		} else {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return err
+			}
+			token = line
+		}
+		token = strings.TrimSpace(token)
+	}
+	me, err := client.New(baseURL, client.WithToken(token)).Me(ctx)
+	if err != nil {
+		return err
+	}
+	path := tokenPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Logged in as %s (token saved to %s)\n", me.Login, path)
+	return nil
+}
+
+func tokenPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "krombat", "token")
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "krombat: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pnz1990/krombat/backend/pkg/client"
+)
+
+const barWidth = 24
+
+// ANSI styling. The whole UI is redrawn each frame, so styles never leak
+// across lines.
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	dim    = "\x1b[2m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	cyan   = "\x1b[36m"
+)
+
+// hpBar draws "[██████······]  60/100". Current HP above max (later rooms and
+// curse modifiers scale HP past the base maxima in status) fills the bar.
+func hpBar(hp, max int64) string {
+	if max < hp {
+		max = hp
+	}
+	filled := 0
+	if max > 0 && hp > 0 {
+		filled = int((hp*barWidth + max - 1) / max)
+	}
+	color := green
+	switch {
+	case hp <= 0:
+		color = dim
+	case hp*4 <= max:
+		color = red
+	case hp*2 <= max:
+		color = yellow
+	}
+	return fmt.Sprintf("%s[%s%s]%s %4d/%d", color,
+		strings.Repeat("█", filled), strings.Repeat("·", barWidth-filled), reset, hp, max)
+}
+
+func parseMax(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// renderList draws the dungeon picker.
+func renderList(login string, dungeons []client.DungeonSummary, cursor int) []string {
+	lines := []string{bold + "KROMBAT" + reset + dim + "  signed in as " + login + reset, ""}
+	if len(dungeons) == 0 {
+		lines = append(lines, dim+"No dungeons yet — press c to create one."+reset)
+	}
+	for i, d := range dungeons {
+		state := "reconciling"
+		if d.Victory != nil && *d.Victory {
+			state = green + "victory" + reset
+		} else if d.LivingMonsters != nil && d.BossState != nil {
+			state = fmt.Sprintf("%d monsters, boss %s", *d.LivingMonsters, *d.BossState)
+		}
+		line := fmt.Sprintf("  %-30s %-7s %s", d.Name, d.Difficulty, state)
+		if i == cursor {
+			line = cyan + "▶" + line[1:] + reset
+		}
+		lines = append(lines, line)
+	}
+	return append(lines, "", dim+"↑/↓ select · enter play · c create · x delete · r refresh · q quit"+reset)
+}
+
+// renderDungeon draws the fight screen for d, whose hero is of class. stab is
+// true while the next target key will backstab; p says what a digit key picks.
+func renderDungeon(d *client.Dungeon, class client.Class, stab bool, p pick) []string {
+	g := d.Status.Game
+	lines := []string{
+		fmt.Sprintf("%s%s%s  %s %s · room %d/%d · turn %d · %d gold · %s", bold, d.Name, reset,
+			d.Spec.HeroClass, d.Spec.Difficulty, max(g.CurrentRoom, 1), d.RoomCount(), d.TotalTurns(), g.Gold, g.Modifier),
+		"",
+	}
+	if !g.Initialized() {
+		return append(lines, dim+"Waiting for kro to initialise the dungeon…"+reset)
+	}
+
+	hero := fmt.Sprintf("  %-12s %s", "Hero", hpBar(g.HeroHP, d.Status.MaxHeroHPValue()))
+	if class.MaxMana > 0 {
+		hero += fmt.Sprintf("  mana %d", g.HeroMana)
+	}
+	var effects []string
+	for _, e := range []struct {
+		name  string
+		turns int64
+	}{{"poison", g.PoisonTurns}, {"burn", g.BurnTurns}, {"stun", g.StunTurns}, {"taunt", g.TauntActive}, {"backstab cd", g.BackstabCooldown}} {
+		if e.turns > 0 {
+			effects = append(effects, fmt.Sprintf("%s %d", e.name, e.turns))
+		}
+	}
+	if len(effects) > 0 {
+		hero += "  " + yellow + strings.Join(effects, ", ") + reset
+	}
+	lines = append(lines, hero, "")
+
+	maxMonster := parseMax(d.Status.MaxMonsterHP)
+	for i, hp := range g.MonsterHP {
+		kind := "monster"
+		if i < len(g.MonsterTypes) {
+			kind = g.MonsterTypes[i]
+		}
+		lines = append(lines, fmt.Sprintf("%d %-12s %s", (i+1)%10, kind, hpBar(hp, maxMonster)))
+	}
+	lines = append(lines, fmt.Sprintf("b %-12s %s", "Boss", hpBar(g.BossHP, parseMax(d.Status.MaxBossHP))), "")
+
+	for _, l := range []string{d.Spec.LastHeroAction, d.Spec.LastEnemyAction} {
+		if l != "" {
+			lines = append(lines, "  "+l)
+		}
+	}
+	if d.Status.Victory && d.InFinalRoom() {
+		lines = append(lines, "", green+bold+"  VICTORY"+reset)
+	} else if d.Status.Victory {
+		lines = append(lines, "", green+"  ROOM CLEARED"+reset)
+	} else if d.Status.Defeat || g.HeroHP <= 0 {
+		lines = append(lines, "", red+bold+"  DEFEAT"+reset)
+	}
+
+	items := g.Items()
+	lines = append(lines, "", "Inventory:")
+	if len(items) == 0 {
+		lines = append(lines, dim+"  (empty)"+reset)
+	}
+	for i, it := range items {
+		prefix := "  "
+		if p == pickItem || p == pickSell {
+			prefix = fmt.Sprintf("%d ", (i+1)%10)
+		}
+		lines = append(lines, prefix+it)
+	}
+	if g.MerchantOpen() {
+		lines = append(lines, "", "Merchant:")
+		stock := g.MerchantItems()
+		if len(stock) == 0 {
+			lines = append(lines, dim+"  (sold out)"+reset)
+		}
+		for i, it := range stock {
+			prefix := "  "
+			if p == pickBuy {
+				prefix = fmt.Sprintf("%d ", (i+1)%10)
+			}
+			lines = append(lines, prefix+it)
+		}
+	}
+
+	lines = append(lines, "")
+	switch {
+	case p == pickItem:
+		lines = append(lines, cyan+"Item: press its number to use/equip · esc cancel"+reset)
+	case p == pickBuy:
+		lines = append(lines, cyan+"Buy: press its number · esc cancel"+reset)
+	case p == pickSell:
+		lines = append(lines, cyan+"Sell: press its number for half its price · esc cancel"+reset)
+	case stab:
+		lines = append(lines, cyan+"Backstab: press a target (1-9, b) · esc cancel"+reset)
+	default:
+		keys := "1-9 attack · b boss · i items"
+		for _, ab := range abilityKeys {
+			if class.HasAbility(ab.ability) {
+				keys += " · " + ab.key + " " + ab.ability
+			}
+		}
+		if g.MerchantOpen() {
+			keys += " · m buy · v sell"
+		}
+		lines = append(lines, dim+keys+" · o open treasure · u unlock door · n next room · esc back"+reset)
+	}
+	return lines
+}
+
+// abilityKeys binds the class abilities to fight-screen keys.
+var abilityKeys = []struct{ ability, key string }{
+	{"heal", "h"},
+	{"taunt", "t"},
+	{"backstab", "s"},
+}
+
+// itemMove is the action for using or equipping an inventory item.
+func itemMove(item string) string {
+	if strings.HasPrefix(item, "hppotion-") || strings.HasPrefix(item, "manapotion-") {
+		return "use-" + item
+	}
+	return "equip-" + item
+}
+
+// createFields are the prompts of the create form, in order.
+// renderCreate lists the server's classes after the classField prompt.
+var createFields = []struct {
+	label, def string
+}{
+	{"Name", ""},
+	{"Monsters (1-10)", "3"},
+	{"Difficulty (easy/normal/hard)", "normal"},
+	{"Hero class", "warrior"},
+	{"Rooms (2-5)", "2"},
+}
+
+const classField = 3
+
+// renderCreate draws the create-dungeon form with field active, offering
+// classes for the hero class.
+func renderCreate(values []string, active int, classes []client.Class) []string {
+	lines := []string{bold + "New dungeon" + reset, ""}
+	for i, f := range createFields {
+		v := values[i]
+		if v == "" && i != active {
+			v = dim + f.def + reset
+		}
+		cursor := "  "
+		if i == active {
+			cursor = cyan + "▶ " + reset
+			v += "█"
+		}
+		label := f.label
+		if i == classField {
+			ids := make([]string, len(classes))
+			for j, c := range classes {
+				ids[j] = c.ID
+			}
+			label += " (" + strings.Join(ids, "/") + ")"
+		}
+		lines = append(lines, fmt.Sprintf("%s%-32s %s", cursor, label, v))
+	}
+	return append(lines, "", dim+"enter next/create · esc cancel"+reset)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"github.com/pnz1990/krombat/backend/pkg/client"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("1b\x1b[A\x1b[B\r\x7f\x1b\x03"))
+	want := []string{"1", "b", "up", "down", "enter", "backspace", "esc", "ctrl+c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeKeys = %q, want %q", got, want)
+	}
+}
+
+func TestHPBar(t *testing.T) {
+	tests := []struct {
+		hp, max    int64
+		filled     int
+		wantSuffix string
+	}{
+		{100, 100, barWidth, " 100/100"},
+		{50, 100, barWidth / 2, "  50/100"},
+		{1, 100, 1, "   1/100"},
+		{0, 100, 0, "   0/100"},
+		{120, 100, barWidth, " 120/120"}, // room 2 HP above the base max
+	}
+	for _, tt := range tests {
+		bar := hpBar(tt.hp, tt.max)
+		if n := strings.Count(bar, "█"); n != tt.filled {
+			t.Errorf("hpBar(%d, %d) fills %d cells, want %d", tt.hp, tt.max, n, tt.filled)
+		}
+		if !strings.HasSuffix(bar, tt.wantSuffix) {
+			t.Errorf("hpBar(%d, %d) = %q", tt.hp, tt.max, bar)
+		}
+	}
+}
+
+func TestRenderDungeon(t *testing.T) {
+	d := &client.Dungeon{}
+	d.Name = "lair"
+	d.Spec.HeroClass, d.Spec.Difficulty = "rogue", "easy"
+	d.Spec.LastHeroAction = "Rogue deals 24 damage to troll"
+	d.Status.MaxHeroHP, d.Status.MaxMonsterHP, d.Status.MaxBossHP = "120", "30", "200"
+	g := &d.Status.Game
+	g.InitProcessedSeq, g.CurrentRoom = 1, 1
+	g.HeroHP, g.MonsterHP, g.MonsterTypes, g.BossHP = 90, []int64{6, 30}, []string{"troll", "ghoul"}, 200
+	g.Inventory = model.ParseInventory(`["hppotion-common","weapon-rare"]`)
+	g.Gold, g.MerchantRoom, g.MerchantStock = 35, 1, `["shield-epic"]`
+
+	rogue := client.Class{ID: "rogue", Abilities: []string{"backstab"}}
+	screen := strings.Join(renderDungeon(d, rogue, false, pickItem), "\n")
+	for _, want := range []string{"troll", "ghoul", "Boss", "90/120", "6/30", "Rogue deals 24", "1 hppotion-common", "2 weapon-rare", "35 gold", "Merchant:"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+	if screen := strings.Join(renderDungeon(d, rogue, false, pickBuy), "\n"); !strings.Contains(screen, "1 shield-epic") {
+		t.Errorf("buy mode does not number the stock:\n%s", screen)
+	}
+	// Key hints and the mana readout follow the class, not its name.
+	screen = strings.Join(renderDungeon(d, rogue, false, pickTarget), "\n")
+	if !strings.Contains(screen, "s backstab") || strings.Contains(screen, "h heal") || strings.Contains(screen, "mana") {
+		t.Errorf("rogue screen shows the wrong abilities:\n%s", screen)
+	}
+	d.Spec.HeroClass = "druid"
+	druid := client.Class{ID: "druid", MaxMana: 6, Abilities: []string{"heal"}}
+	screen = strings.Join(renderDungeon(d, druid, false, pickTarget), "\n")
+	if !strings.Contains(screen, "h heal") || !strings.Contains(screen, "mana 0") {
+		t.Errorf("a class from the server is not drawn from its registry entry:\n%s", screen)
+	}
+	form := strings.Join(renderCreate(make([]string, len(createFields)), 0, []client.Class{{ID: "warrior"}, {ID: "druid"}}), "\n")
+	if !strings.Contains(form, "Hero class (warrior/druid)") {
+		t.Errorf("create form does not list the server's classes:\n%s", form)
+	}
+	if itemMove("hppotion-common") != "use-hppotion-common" || itemMove("weapon-rare") != "equip-weapon-rare" {
+		t.Error("itemMove picked the wrong verb")
+	}
+	if isAction("activate-taunt") || !isAction("equip-weapon-rare") || !isAction("enter-room-2") {
+		t.Error("isAction disagrees with the backend split")
+	}
+	if digitIndex("1") != 0 || digitIndex("0") != 9 || digitIndex("b") != -1 {
+		t.Error("digitIndex mapping wrong")
+	}
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pnz1990/krombat/backend/pkg/client"
+	"golang.org/x/term"
+)
+
+type screen int
+
+const (
+	screenList screen = iota
+	screenCreate
+	screenDungeon
+)
+
+// pick is what the next digit key on the fight screen selects.
+type pick int
+
+const (
+	pickTarget pick = iota // a monster to attack
+	pickItem               // an inventory item to use or equip
+	pickBuy                // a merchant item to buy
+	pickSell               // an inventory item to sell
+)
+
+// Messages delivered to the event loop by background work.
+type (
+	listMsg    []client.DungeonSummary
+	dungeonMsg struct{ d *client.Dungeon }
+	liveMsg    struct{ d *client.Dungeon } // from the event stream
+	createdMsg struct{ d *client.Dungeon }
+	errMsg     struct{ err error }
+	noticeMsg  string
+)
+
+// app is the TUI state. Everything except background HTTP calls runs on the
+// event-loop goroutine, so no locking is needed.
+type app struct {
+	ctx   context.Context
+	c     *client.Client
+	login   string
+	classes []client.Class
+	out     io.Writer
+	msgs    chan interface{}
+
+	screen   screen
+	status   string
+	busy     bool
+	dungeons []client.DungeonSummary
+	cursor   int
+	confirm  bool // delete confirmation pending
+
+	form       []string
+	formActive int
+
+	cur     *client.Dungeon
+	stab    bool
+	pick    pick
+	stopSub context.CancelFunc
+}
+
+// runTUI puts the terminal in raw mode and runs the event loop until quit.
+func runTUI(ctx context.Context, c *client.Client, login string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("stdin is not a terminal")
+	}
+	classes, err := c.Classes(ctx)
+	if err != nil {
+		return fmt.Errorf("load hero classes: %w", err)
+	}
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, old)
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	a := &app{ctx: ctx, c: c, login: login, classes: classes, out: os.Stdout, msgs: make(chan interface{}, 16)}
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+	a.refresh()
+	for {
+		a.draw()
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok || a.key(k) {
+				a.leaveDungeon()
+				return nil
+			}
+		case m := <-a.msgs:
+			a.update(m)
+		}
+	}
+}
+
+// readKeys decodes raw terminal input into key names: printable runes as
+// themselves, plus "up", "down", "enter", "esc", "backspace", "tab" and
+// "ctrl+c". It closes keys on EOF.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+func decodeKeys(b []byte) []string {
+	var out []string
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == 0x1b && i+2 < len(b) && b[i+1] == '[':
+			switch b[i+2] {
+			case 'A':
+				out = append(out, "up")
+			case 'B':
+				out = append(out, "down")
+			}
+			i += 2
+		case c == 0x1b:
+			out = append(out, "esc")
+		case c == 0x03:
+			out = append(out, "ctrl+c")
+		case c == '\r' || c == '\n':
+			out = append(out, "enter")
+		case c == '\t':
+			out = append(out, "tab")
+		case c == 0x7f || c == 0x08:
+			out = append(out, "backspace")
+		case c >= 0x20 && c < 0x7f:
+			out = append(out, string(c))
+		}
+	}
+	return out
+}
+
+func (a *app) draw() {
+	var lines []string
+	switch a.screen {
+	case screenList:
+		lines = renderList(a.login, a.dungeons, a.cursor)
+	case screenCreate:
+		lines = renderCreate(a.form, a.formActive, a.classes)
+	case screenDungeon:
+		lines = renderDungeon(a.cur, a.heroClass(), a.stab, a.pick)
+	}
+	status := a.status
+	if a.busy {
+		status = "… " + status
+	}
+	if status != "" {
+		lines = append(lines, "", status)
+	}
+	fmt.Fprint(a.out, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+}
+
+// async runs f off the event loop and delivers its result as a message.
+func (a *app) async(status string, f func() interface{}) {
+	a.busy, a.status = true, status
+	go func() { a.msgs <- f() }()
+}
+
+func (a *app) update(m interface{}) {
+	switch m := m.(type) {
+	case listMsg:
+		a.busy, a.status = false, ""
+		a.dungeons = m
+		a.cursor = min(a.cursor, max(len(m)-1, 0))
+	case dungeonMsg:
+		a.busy = false
+		a.show(m.d)
+	case liveMsg:
+		// A turn in flight stays busy until its own response lands.
+		a.show(m.d)
+	case noticeMsg:
+		a.status = string(m)
+	case createdMsg:
+		a.busy = false
+		a.enterDungeon(m.d.Namespace, m.d.Name)
+	case errMsg:
+		a.busy = false
+		a.status = red + m.err.Error() + reset
+	}
+}
+
+// heroClass returns the class of the open dungeon's hero.
+func (a *app) heroClass() client.Class {
+	id := cmp.Or(a.cur.Spec.HeroClass, "warrior")
+	for _, c := range a.classes {
+		if c.ID == id {
+			return c
+		}
+	}
+	return client.Class{ID: id}
+}
+
+// show replaces the open dungeon if d is a newer copy of it.
+func (a *app) show(d *client.Dungeon) {
+	if a.screen == screenDungeon && d.Name == a.cur.Name && d.Namespace == a.cur.Namespace {
+		a.cur = d
+	}
+}
+
+// key handles one key press and reports whether to quit.
+func (a *app) key(k string) bool {
+	if k == "ctrl+c" {
+		return true
+	}
+	switch a.screen {
+	case screenList:
+		return a.listKey(k)
+	case screenCreate:
+		a.createKey(k)
+	case screenDungeon:
+		a.dungeonKey(k)
+	}
+	return false
+}
+
+func (a *app) refresh() {
+	a.async("loading dungeons", func() interface{} {
+		list, err := a.c.ListDungeons(a.ctx)
+		if err != nil {
+			return errMsg{err}
+		}
+		return listMsg(list)
+	})
+}
+
+func (a *app) listKey(k string) bool {
+	if a.confirm {
+		a.confirm = false
+		a.status = ""
+		if k == "y" && a.cursor < len(a.dungeons) {
+			d := a.dungeons[a.cursor]
+			a.async("deleting "+d.Name, func() interface{} {
+				if err := a.c.DeleteDungeon(a.ctx, d.Namespace, d.Name); err != nil {
+					return errMsg{err}
+				}
+				list, err := a.c.ListDungeons(a.ctx)
+				if err != nil {
+					return errMsg{err}
+				}
+				return listMsg(list)
+			})
+		}
+		return false
+	}
+	switch k {
+	case "q":
+		return true
+	case "up", "k":
+		a.cursor = max(a.cursor-1, 0)
+	case "down", "j":
+		a.cursor = min(a.cursor+1, max(len(a.dungeons)-1, 0))
+	case "r":
+		a.refresh()
+	case "c":
+		a.screen, a.form, a.formActive, a.status = screenCreate, make([]string, len(createFields)), 0, ""
+	case "x":
+		if a.cursor < len(a.dungeons) {
+			a.confirm = true
+			a.status = "Delete " + a.dungeons[a.cursor].Name + "? (y/n)"
+		}
+	case "enter":
+		if a.cursor < len(a.dungeons) {
+			d := a.dungeons[a.cursor]
+			a.enterDungeon(d.Namespace, d.Name)
+		}
+	}
+	return false
+}
+
+func (a *app) createKey(k string) {
+	switch k {
+	case "esc":
+		a.screen, a.status = screenList, ""
+	case "backspace":
+		if v := a.form[a.formActive]; v != "" {
+			a.form[a.formActive] = v[:len(v)-1]
+		}
+	case "enter", "tab":
+		if a.formActive < len(a.form)-1 {
+			a.formActive++
+			return
+		}
+		a.submitCreate()
+	default:
+		if len(k) == 1 {
+			a.form[a.formActive] += k
+		}
+	}
+}
+
+func (a *app) submitCreate() {
+	v := func(i int) string { return cmp.Or(strings.TrimSpace(a.form[i]), createFields[i].def) }
+	monsters, err := strconv.ParseInt(v(1), 10, 64)
+	if err != nil {
+		a.status = red + "monsters must be a number" + reset
+		return
+	}
+	rooms, err := strconv.ParseInt(v(4), 10, 64)
+	if err != nil {
+		a.status = red + "rooms must be a number" + reset
+		return
+	}
+	req := client.CreateDungeonRequest{Name: v(0), Monsters: monsters, Difficulty: v(2), HeroClass: v(3), Rooms: rooms}
+	a.async("creating "+req.Name, func() interface{} {
+		d, err := a.c.CreateDungeon(a.ctx, req)
+		if err != nil {
+			return errMsg{err}
+		}
+		return createdMsg{d}
+	})
+	a.screen = screenList
+}
+
+func (a *app) enterDungeon(namespace, name string) {
+	a.leaveDungeon()
+	a.screen, a.stab, a.pick, a.status = screenDungeon, false, pickTarget, ""
+	a.cur = &client.Dungeon{}
+	a.cur.Namespace, a.cur.Name = namespace, name
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.stopSub = cancel
+	a.async("loading "+name, func() interface{} {
+		d, err := a.c.GetDungeon(ctx, namespace, name)
+		if err != nil {
+			return errMsg{err}
+		}
+		return dungeonMsg{d}
+	})
+	go a.stream(ctx, namespace, name)
+}
+
+// stream forwards DUNGEON_UPDATE events for one dungeon until ctx ends.
+func (a *app) stream(ctx context.Context, namespace, name string) {
+	sub, err := a.c.Subscribe(ctx, namespace, name)
+	if err != nil {
+		a.msgs <- noticeMsg(yellow + "live updates unavailable: " + err.Error() + reset)
+		return
+	}
+	defer sub.Close()
+	for {
+		ev, err := sub.Next()
+		if err != nil {
+			return
+		}
+		if d, err := client.DungeonFromEvent(ev); err == nil {
+			select {
+			case a.msgs <- liveMsg{d}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (a *app) leaveDungeon() {
+	if a.stopSub != nil {
+		a.stopSub()
+		a.stopSub = nil
+	}
+}
+
+func (a *app) dungeonKey(k string) {
+	d := a.cur
+	if k == "esc" || k == "q" {
+		if a.stab || a.pick != pickTarget {
+			a.stab, a.pick = false, pickTarget
+			return
+		}
+		a.leaveDungeon()
+		a.screen = screenList
+		a.refresh()
+		return
+	}
+	if a.busy {
+		return
+	}
+	if p := a.pick; p != pickTarget {
+		a.pick = pickTarget
+		items := d.Status.Game.Items()
+		if p == pickBuy {
+			items = d.Status.Game.MerchantItems()
+		}
+		i := digitIndex(k)
+		if i < 0 || i >= len(items) {
+			return
+		}
+		switch p {
+		case pickItem:
+			a.act(itemMove(items[i]))
+		case pickBuy:
+			a.act("buy-" + items[i])
+		case pickSell:
+			a.act("sell-" + items[i])
+		}
+		return
+	}
+	target := ""
+	switch {
+	case digitIndex(k) >= 0:
+		target = fmt.Sprintf("%s-monster-%d", d.Name, digitIndex(k))
+	case k == "b":
+		target = d.Name + "-boss"
+	}
+	if target != "" {
+		if a.stab {
+			a.stab = false
+			target += "-backstab"
+		}
+		a.act(target)
+		return
+	}
+	switch k {
+	case "s":
+		a.stab = a.heroClass().HasAbility("backstab")
+	case "i":
+		if len(d.Status.Game.Items()) > 0 {
+			a.pick = pickItem
+		}
+	case "m":
+		if g := d.Status.Game; g.MerchantOpen() && len(g.MerchantItems()) > 0 {
+			a.pick = pickBuy
+		}
+	case "v":
+		if g := d.Status.Game; g.MerchantOpen() && len(g.Items()) > 0 {
+			a.pick = pickSell
+		}
+	case "h":
+		a.act("hero")
+	case "t":
+		a.act("activate-taunt")
+	case "o":
+		a.act("open-treasure")
+	case "u":
+		a.act("unlock-door")
+	case "n":
+		a.act(fmt.Sprintf("enter-room-%d", max(d.Status.Game.CurrentRoom, 1)+1))
+	}
+}
+
+// digitIndex maps "1".."9","0" to 0..9, or -1.
+func digitIndex(k string) int {
+	if len(k) != 1 || k[0] < '0' || k[0] > '9' {
+		return -1
+	}
+	return (int(k[0]-'0') + 9) % 10
+}
+
+// isAction mirrors the backend's split between actions (actionSeq) and
+// combat moves (attackSeq).
+func isAction(move string) bool {
+	return strings.HasPrefix(move, "use-") || strings.HasPrefix(move, "equip-") ||
+		strings.HasPrefix(move, "buy-") || strings.HasPrefix(move, "sell-") ||
+		strings.HasPrefix(move, "craft-") || strings.HasPrefix(move, "drop-") ||
+		strings.HasPrefix(move, "unequip-") || strings.HasPrefix(move, "swap-") ||
+		strings.HasPrefix(move, "enter-room-") ||
+		move == "open-treasure" || move == "unlock-door"
+}
+
+// act submits a move; the SDK sends the matching sequence number and retries
+// on stale-seq conflicts.
+func (a *app) act(move string) {
+	ns, name := a.cur.Namespace, a.cur.Name
+	a.async(move, func() interface{} {
+		submit := a.c.Attack
+		if isAction(move) {
+			submit = a.c.Action
+		}
+		d, err := submit(a.ctx, ns, name, move)
+		if err != nil {
+			return errMsg{err}
+		}
+		return dungeonMsg{d}
+	})
+}
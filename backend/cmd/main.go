@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"github.com/pnz1990/krombat/backend/internal/handlers"
 	"github.com/pnz1990/krombat/backend/internal/k8s"
 	"github.com/pnz1990/krombat/backend/internal/ws"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -38,6 +40,15 @@ func main() {
 		}
 	}
 
+	// Certificates are signed with their own key and link to the public site,
+	// never to whatever Host a request claimed.
+	for _, v := range []string{"CERTIFICATE_SIGNING_KEY", "KROMBAT_PUBLIC_URL"} {
+		if os.Getenv(v) == "" {
+			slog.Error("required env var not set — cannot start", "var", v)
+			os.Exit(1)
+		}
+	}
+
 	client, err := k8s.NewClient()
 	if err != nil {
 		slog.Error("failed to create k8s client", "error", err)
@@ -49,33 +60,82 @@ func main() {
 	go k8s.StartWatchers(client, hub)
 	go k8s.StartReconcileDiffWatcher(client, hub)
 
+	dungeons := k8s.StartDungeonInformer(client)
+	handlers.RegisterGameMetrics(dungeons)
+
 	mux := http.NewServeMux()
-	h := handlers.New(client, hub)
+	// New loads the session and certificate keyrings; a bad rotation or key
+	// stops the rollout here instead of logging users out.
+	h, err := handlers.New(client, hub)
+	if err != nil {
+		slog.Error("failed to configure handlers — cannot start", "error", err)
+		os.Exit(1)
+	}
+	go h.ResumeAutoBattles(context.Background())
 
-	mux.HandleFunc("POST /api/v1/dungeons", h.CreateDungeon)
-	mux.HandleFunc("GET /api/v1/dungeons", h.ListDungeons)
-	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}", h.GetDungeon)
-	mux.HandleFunc("DELETE /api/v1/dungeons/{namespace}/{name}", h.DeleteDungeon)
-	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/attacks", h.AttackWithRateLimit())
-	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}/resources", h.GetDungeonResource)
-	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/cel-eval", h.CelEvalHandler)
+	// Routes are wrapped with the API-token scope they require; browser sessions
+	// and anonymous requests pass straight through RequireScope. Expensive or
+	// abusable routes also declare a token-bucket policy via h.RateLimit.
+	// Every route must also be described in internal/handlers/openapi.json,
+	// which ValidateRequest checks request bodies against. Routes are only
+	// registered under /api/v1; APIv2 serves each of them under /api/v2 too,
+	// with JSON error envelopes instead of plain-text errors.
+	read, play := handlers.ScopeRead, handlers.ScopePlay
+	mux.HandleFunc("POST /api/v1/dungeons", handlers.RequireScope(play, h.RateLimit("create", h.CreateDungeon)))
+	mux.HandleFunc("GET /api/v1/dungeons", handlers.RequireScope(read, h.ListDungeons))
+	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}", handlers.RequireScope(read, h.GetDungeon))
+	mux.HandleFunc("DELETE /api/v1/dungeons/{namespace}/{name}", handlers.RequireScope(play, h.DeleteDungeon))
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/attacks", handlers.RequireScope(play, h.RateLimit("attack", h.Idempotent(h.CreateAttack))))
+	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}/abilities", handlers.RequireScope(read, h.ListAbilities))
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/abilities", handlers.RequireScope(play, h.RateLimit("attack", h.Idempotent(h.UseAbility))))
+	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}/objectives", handlers.RequireScope(read, h.ListObjectives))
+	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}/auto-battle", handlers.RequireScope(read, h.GetAutoBattle))
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/auto-battle", handlers.RequireScope(play, h.StartAutoBattle))
+	mux.HandleFunc("DELETE /api/v1/dungeons/{namespace}/{name}/auto-battle", handlers.RequireScope(play, h.StopAutoBattle))
+	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}/resources", handlers.RequireScope(read, h.GetDungeonResource))
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/cel-eval", handlers.RequireScope(play, h.RateLimit("cel-eval", h.CelEvalHandler)))
 	mux.HandleFunc("GET /api/v1/run-card/{namespace}/{name}", h.RunCard)
-	mux.HandleFunc("GET /api/v1/run-narrative/{namespace}/{name}", h.RunNarrative)
-	mux.HandleFunc("GET /api/v1/leaderboard", h.GetLeaderboard)
-	mux.HandleFunc("GET /api/v1/profile", h.GetProfile)
-	mux.HandleFunc("POST /api/v1/profile/cert", h.AwardCert)
-	mux.HandleFunc("GET /api/v1/events", h.Events)
+	mux.HandleFunc("GET /api/v1/run-narrative/{namespace}/{name}", handlers.RequireScope(read, h.RunNarrative))
+	mux.HandleFunc("GET /api/v1/leaderboard", handlers.RequireScope(read, h.GetLeaderboard))
+	mux.HandleFunc("GET /api/v1/items", h.ListItems)
+	mux.HandleFunc("GET /api/v1/recipes", h.ListRecipes)
+	mux.HandleFunc("GET /api/v1/classes", h.ListClasses)
+	mux.HandleFunc("GET /api/v1/achievements", h.ListAchievements)
+	mux.HandleFunc("GET /api/v1/profile", handlers.RequireScope(read, h.GetProfile))
+	mux.HandleFunc("POST /api/v1/profile/cert", handlers.RequireScope(play, h.AwardCert))
+	mux.HandleFunc("GET /api/v1/events", handlers.RequireScope(read, h.Events))
+	// Certificates are public: whoever is shown one can verify it.
+	mux.HandleFunc("GET /api/v1/certificates/{id}/verify", h.RateLimit("certificates", h.VerifyCertificate))
+	mux.HandleFunc("GET /api/v1/certificates/{id}/svg", h.RateLimit("certificates", h.CertificateSVG))
+	mux.HandleFunc("GET /api/v1/certificates/{id}/pdf", h.RateLimit("certificates", h.CertificatePDF))
+	// Admin / moderation (allowlisted logins or orgs; every mutation audited)
+	admin := handlers.ScopeAdmin
+	mux.HandleFunc("GET /api/v1/admin/summary", handlers.RequireScope(admin, h.AdminGetSummary))
+	mux.HandleFunc("GET /api/v1/admin/audit", handlers.RequireScope(admin, h.AdminGetAudit))
+	mux.HandleFunc("GET /api/v1/admin/dungeons", handlers.RequireScope(admin, h.AdminListDungeons))
+	mux.HandleFunc("DELETE /api/v1/admin/dungeons/{namespace}/{name}", handlers.RequireScope(admin, h.AdminDeleteDungeon))
+	mux.HandleFunc("GET /api/v1/admin/leaderboard", handlers.RequireScope(admin, h.AdminListLeaderboard))
+	mux.HandleFunc("PUT /api/v1/admin/leaderboard/{key}", handlers.RequireScope(admin, h.AdminUpdateLeaderboardEntry))
+	mux.HandleFunc("DELETE /api/v1/admin/leaderboard/{key}", handlers.RequireScope(admin, h.AdminDeleteLeaderboardEntry))
+	mux.HandleFunc("GET /api/v1/admin/profiles/{login}", handlers.RequireScope(admin, h.AdminGetProfile))
+	mux.HandleFunc("PUT /api/v1/admin/profiles/{login}", handlers.RequireScope(admin, h.AdminUpdateProfile))
+	mux.HandleFunc("DELETE /api/v1/admin/profiles/{login}", handlers.RequireScope(admin, h.AdminResetProfile))
 	mux.HandleFunc("POST /api/v1/client-error", h.ClientErrorHandler)
 	mux.HandleFunc("POST /api/v1/vitals", h.VitalsHandler)
 	mux.HandleFunc("POST /api/v1/events-track", h.EventsTrackHandler)
 	// Auth routes
-	mux.HandleFunc("GET /api/v1/auth/login", handlers.LoginHandler)
-	mux.HandleFunc("GET /api/v1/auth/callback", handlers.CallbackHandler)
-	mux.HandleFunc("GET /api/v1/auth/me", handlers.MeHandler)
+	mux.HandleFunc("GET /api/v1/auth/login", h.RateLimit("auth", handlers.LoginHandler))
+	mux.HandleFunc("GET /api/v1/auth/callback", h.RateLimit("auth", h.CallbackHandler))
+	mux.HandleFunc("GET /api/v1/auth/me", handlers.RequireScope(read, handlers.MeHandler))
 	mux.HandleFunc("GET /api/v1/auth/logout", handlers.LogoutHandler)
+	// Personal API tokens (browser session only — tokens cannot manage tokens)
+	mux.HandleFunc("POST /api/v1/auth/tokens", h.RateLimit("tokens", h.CreateAPIToken))
+	mux.HandleFunc("GET /api/v1/auth/tokens", h.ListAPITokens)
+	mux.HandleFunc("DELETE /api/v1/auth/tokens/{id}", h.RateLimit("tokens", h.RevokeAPIToken))
 	// Test-only login: issues a real session cookie when KROMBAT_TEST_USER is set.
 	// Returns 404 when the krombat-test-auth secret is absent (i.e. in environments without the secret).
-	mux.HandleFunc("GET /api/v1/auth/test-login", handlers.TestLoginHandler)
+	mux.HandleFunc("GET /api/v1/auth/test-login", h.TestLoginHandler)
+	mux.HandleFunc("GET /api/v1/openapi.json", handlers.OpenAPIHandler)
 	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
 	// #560: return 200 on root to silence ALB health probe 404 noise (~19k/10h).
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
@@ -83,7 +143,9 @@ func main() {
 	// #416: serve Prometheus metrics on a separate internal-only port (9090).
 	// This port is NOT routed through the ALB ingress, preventing public exposure.
 	metricsMux := http.NewServeMux()
-	metricsMux.Handle("/metrics", promhttp.Handler())
+	// OpenMetrics negotiation is enabled so request-ID exemplars on the HTTP
+	// latency histogram reach scrapers that ask for them.
+	metricsMux.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
 	go func() {
 		metricsAddr := ":9090"
 		slog.Info("metrics server starting", "addr", metricsAddr)
@@ -97,7 +159,7 @@ func main() {
 		addr = ":" + p
 	}
 	slog.Info("backend starting", "addr", addr)
-	if err := http.ListenAndServe(addr, handlers.AccessLog(handlers.AuthMiddleware(mux))); err != nil {
+	if err := http.ListenAndServe(addr, handlers.AccessLog(handlers.APIv2(h.AuthMiddleware(handlers.ValidateRequest(mux))))); err != nil {
 		slog.Error("server failed", "error", err)
 		os.Exit(1)
 	}
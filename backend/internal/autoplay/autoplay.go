@@ -0,0 +1,341 @@
+// Package autoplay holds the player strategies shared by server-side
+// auto-battle (internal/handlers) and the balance simulator (internal/sim):
+// pure "what should the hero do next" functions.
+//
+// A strategy sees a read-only View of the dungeon and returns the next target
+// string in exactly the vocabulary the UI sends to CreateAttack
+// ("<dungeon>-monster-<i>", "<dungeon>-boss", "<target>-backstab", "hero",
+// "activate-taunt", "use-<item>", "equip-<item>", "buy-<item>",
+// "craft-<recipe>", "open-treasure", "unlock-door", "enter-room-<n>", ...).
+// Callers feed that string through the same path as a click, so every move is
+// validated and resolved by kro.
+//
+// Strategies never do game math — they only pick among legal moves.
+package autoplay
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+// View is the subset of dungeon state a strategy may look at.
+type View struct {
+	Dungeon          string
+	HeroClass        string
+	HeroHP           int64
+	MaxHeroHP        int64
+	HeroMana         int64
+	MonsterHP        []int64
+	MonsterTypes     []string
+	BossHP           int64
+	Inventory        []string
+	SlotBonus        map[string]int64 // equipment slot → current bonus (0 = empty)
+	BackstabCooldown int64
+	TauntActive      int64
+	TreasureOpened   int64
+	DoorUnlocked     int64
+	CurrentRoom      int64
+	Rooms            int64
+	Gold             int64
+	MerchantOpen     bool
+	MerchantStock    []string
+}
+
+// Options tunes strategies that have knobs.
+type Options struct {
+	HealThreshold int // percent of max HP below which healing strategies heal
+}
+
+// DefaultHealThreshold is the HealThreshold used when none is given.
+const DefaultHealThreshold = 40
+
+// Strategy picks the next move. "" means no legal move right now.
+type Strategy func(v View, opts Options) string
+
+// Strategies is the strategy registry — add an entry to plug in a new one.
+var Strategies = map[string]Strategy{
+	"aggressive":           strategyAggressive,
+	"heal-at-threshold":    strategyHealAtThreshold,
+	"backstab-on-cooldown": strategyBackstabOnCooldown,
+	"loot-first":           strategyLootFirst,
+	"shopper":              strategyShopper,
+	"crafter":              strategyCrafter,
+}
+
+// Names returns the registered strategy names, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Strategies))
+	for n := range Strategies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// equipSlots lists equipment slots in the order loot-first equips them.
+var equipSlots = []string{"weapon", "armor", "shield", "helmet", "amulet", "ring", "pants", "boots"}
+
+var rarityOrder = []string{"epic", "rare", "common"}
+
+// NewView builds a view from a dungeon (spec + status + status.game).
+func NewView(d *model.Dungeon) View {
+	game := d.Status.Game
+	v := View{
+		Dungeon:          d.Name,
+		HeroClass:        cmp.Or(d.Spec.HeroClass, "warrior"),
+		HeroHP:           game.HeroHP,
+		HeroMana:         game.HeroMana,
+		MaxHeroHP:        d.Status.MaxHeroHPValue(),
+		MonsterHP:        game.MonsterHP,
+		MonsterTypes:     game.MonsterTypes,
+		BossHP:           game.BossHP,
+		BackstabCooldown: game.BackstabCooldown,
+		TauntActive:      game.TauntActive,
+		TreasureOpened:   game.TreasureOpened,
+		DoorUnlocked:     game.DoorUnlocked,
+		CurrentRoom:      game.CurrentRoom,
+		Rooms:            d.RoomCount(),
+		Inventory:        game.Items(),
+		SlotBonus:        map[string]int64{},
+		Gold:             game.Gold,
+		MerchantOpen:     game.MerchantOpen(),
+		MerchantStock:    game.MerchantItems(),
+	}
+	for _, slot := range equipSlots {
+		v.SlotBonus[slot] = game.SlotBonus(slot)
+	}
+	// A weapon with no uses left is as good as an empty slot.
+	if game.WeaponUses <= 0 {
+		v.SlotBonus["weapon"] = 0
+	}
+	return v
+}
+
+func (v View) has(item string) bool {
+	for _, it := range v.Inventory {
+		if it == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (v View) roomCleared() bool {
+	return v.BossHP <= 0 && v.allMonstersDead()
+}
+
+func (v View) allMonstersDead() bool {
+	for _, hp := range v.MonsterHP {
+		if hp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (v View) belowThreshold(pct int) bool {
+	return v.MaxHeroHP > 0 && v.HeroHP*100 < v.MaxHeroHP*int64(pct)
+}
+
+// weakestTarget returns the living monster with the lowest HP, or the boss
+// once every monster is dead. Finishing kills first minimises incoming damage.
+func (v View) weakestTarget() string {
+	best := -1
+	for i, hp := range v.MonsterHP {
+		if hp > 0 && (best < 0 || hp < v.MonsterHP[best]) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return fmt.Sprintf("%s-monster-%d", v.Dungeon, best)
+	}
+	if v.BossHP > 0 {
+		return v.Dungeon + "-boss"
+	}
+	return ""
+}
+
+// strongestTarget returns the living enemy with the most HP (boss included),
+// the best use of a big burst like backstab.
+func (v View) strongestTarget() string {
+	best, bestHP := "", int64(0)
+	for i, hp := range v.MonsterHP {
+		if hp > bestHP {
+			best, bestHP = fmt.Sprintf("%s-monster-%d", v.Dungeon, i), hp
+		}
+	}
+	if v.BossHP > bestHP && v.allMonstersDead() {
+		best = v.Dungeon + "-boss"
+	}
+	return best
+}
+
+// progress walks the post-fight room sequence: treasure → door → next room.
+func (v View) progress() string {
+	if !v.roomCleared() {
+		return ""
+	}
+	switch {
+	case v.TreasureOpened != 1:
+		return "open-treasure"
+	case v.CurrentRoom < v.Rooms && v.DoorUnlocked != 1:
+		return "unlock-door"
+	case v.CurrentRoom < v.Rooms:
+		return fmt.Sprintf("enter-room-%d", v.CurrentRoom+1)
+	}
+	return ""
+}
+
+// equip returns an equip move for the first empty slot (of slots) holding an
+// item in the inventory, preferring the highest rarity.
+func (v View) equip(slots ...string) string {
+	for _, slot := range slots {
+		if v.SlotBonus[slot] > 0 {
+			continue
+		}
+		for _, rarity := range rarityOrder {
+			if item := slot + "-" + rarity; v.has(item) {
+				return "equip-" + item
+			}
+		}
+	}
+	return ""
+}
+
+// cheapest returns a use move for the lowest-rarity item of a type, or "".
+func (v View) cheapest(typ string) string {
+	for i := len(rarityOrder) - 1; i >= 0; i-- {
+		if item := typ + "-" + rarityOrder[i]; v.has(item) {
+			return "use-" + item
+		}
+	}
+	return ""
+}
+
+// heal returns a healing move when HP is below pct of max: the cheapest HP
+// potion, else a class heal (drinking a mana potion first if needed), else a
+// taunt to blunt the next counter-attack.
+func (v View) heal(pct int) string {
+	if !v.belowThreshold(pct) {
+		return ""
+	}
+	if mv := v.cheapest("hppotion"); mv != "" {
+		return mv
+	}
+	class, _ := catalog.LookupClass(v.HeroClass)
+	switch {
+	case class.HasAbility("heal"):
+		if heal, _, _ := catalog.LookupAbility(class.ID, "heal"); v.HeroMana >= heal.ManaCost {
+			return "hero"
+		}
+		return v.cheapest("manapotion")
+	case class.HasAbility("taunt"):
+		if v.TauntActive == 0 && !v.roomCleared() {
+			return "activate-taunt"
+		}
+	}
+	return ""
+}
+
+// shop buys the first affordable item in the merchant's stock the hero can
+// use, while the backpack has room.
+func (v View) shop() string {
+	if !v.MerchantOpen || len(v.Inventory) >= model.MaxInventory {
+		return ""
+	}
+	for _, id := range v.MerchantStock {
+		if it, ok := catalog.Default().Lookup(id); ok && it.Price <= v.Gold && it.UsableByClass(v.HeroClass) {
+			return "buy-" + id
+		}
+	}
+	return ""
+}
+
+// craft crafts the first recipe whose inputs are all in the backpack.
+func (v View) craft() string {
+	for _, r := range catalog.Recipes() {
+		if item, _, _ := r.Shortfall(v.Inventory); item == "" {
+			return "craft-" + r.ID
+		}
+	}
+	return ""
+}
+
+// firstMove returns the first non-empty move.
+func firstMove(moves ...func() string) string {
+	for _, m := range moves {
+		if mv := m(); mv != "" {
+			return mv
+		}
+	}
+	return ""
+}
+
+// strategyAggressive never heals: it straps on damage gear and hits the
+// weakest enemy every turn.
+func strategyAggressive(v View, _ Options) string {
+	return firstMove(
+		v.progress,
+		func() string { return v.equip("weapon", "amulet") },
+		v.weakestTarget,
+	)
+}
+
+// strategyHealAtThreshold attacks like aggressive but heals whenever HP drops
+// below the configured threshold.
+func strategyHealAtThreshold(v View, opts Options) string {
+	return firstMove(
+		v.progress,
+		func() string { return v.heal(opts.HealThreshold) },
+		func() string { return v.equip("weapon", "amulet") },
+		v.weakestTarget,
+	)
+}
+
+// strategyBackstabOnCooldown fires a rogue backstab at the strongest enemy
+// every time the cooldown expires. Other classes fall back to aggressive.
+func strategyBackstabOnCooldown(v View, opts Options) string {
+	if class, _ := catalog.LookupClass(v.HeroClass); !class.HasAbility("backstab") {
+		return strategyAggressive(v, opts)
+	}
+	return firstMove(
+		v.progress,
+		func() string {
+			if v.BackstabCooldown > 0 {
+				return ""
+			}
+			if t := v.strongestTarget(); t != "" {
+				return t + "-backstab"
+			}
+			return ""
+		},
+		v.weakestTarget,
+	)
+}
+
+// strategyLootFirst equips every item it can before swinging, and heals at the
+// threshold — the slow, safe way through.
+func strategyLootFirst(v View, opts Options) string {
+	return firstMove(
+		v.progress,
+		func() string { return v.equip(equipSlots...) },
+		func() string { return v.heal(opts.HealThreshold) },
+		v.weakestTarget,
+	)
+}
+
+// strategyShopper heals at the threshold and spends its gold at every
+// merchant before moving on.
+func strategyShopper(v View, opts Options) string {
+	return firstMove(v.shop, func() string { return strategyHealAtThreshold(v, opts) })
+}
+
+// strategyCrafter is a shopper that crafts whenever its backpack holds a
+// recipe's inputs.
+func strategyCrafter(v View, opts Options) string {
+	return firstMove(v.craft, func() string { return strategyShopper(v, opts) })
+}
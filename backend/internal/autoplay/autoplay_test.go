@@ -0,0 +1,84 @@
+package autoplay_test
+
+import (
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/autoplay"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+func TestAutoStrategies(t *testing.T) {
+	fight := model.GameState{HeroHP: 80, MonsterHP: []int64{0, 20, 5}, BossHP: 100, CurrentRoom: 1, WeaponUses: 3}
+	with := func(g model.GameState, edit func(*model.GameState)) model.GameState {
+		g.MonsterHP = append([]int64(nil), g.MonsterHP...)
+		edit(&g)
+		return g
+	}
+	tests := []struct {
+		name, strategy, class string
+		game                  model.GameState
+		want                  string
+	}{
+		{"weakest monster first", "aggressive", "warrior", fight, "lair-monster-2"},
+		{"boss once monsters are dead", "aggressive", "warrior", with(fight, func(g *model.GameState) { g.MonsterHP = []int64{0, 0} }), "lair-boss"},
+		{"treasure after the fight", "aggressive", "warrior", model.GameState{HeroHP: 80, CurrentRoom: 1}, "open-treasure"},
+		{"then the door", "aggressive", "warrior", model.GameState{HeroHP: 80, CurrentRoom: 1, TreasureOpened: 1}, "unlock-door"},
+		{"then the next room", "aggressive", "warrior", model.GameState{HeroHP: 80, CurrentRoom: 1, TreasureOpened: 1, DoorUnlocked: 1}, "enter-room-2"},
+		{"nothing left in the last room", "aggressive", "warrior", model.GameState{HeroHP: 80, CurrentRoom: 2, TreasureOpened: 1}, ""},
+		{"worn-out weapon replaced by the best one", "aggressive", "warrior", with(fight, func(g *model.GameState) {
+			g.WeaponBonus, g.WeaponUses, g.Inventory = 5, 0, model.ParseInventory(`["weapon-common","weapon-epic"]`)
+		}), "equip-weapon-epic"},
+		{"working weapon kept", "aggressive", "warrior", with(fight, func(g *model.GameState) { g.WeaponBonus, g.Inventory = 5, model.ParseInventory(`["weapon-epic"]`) }), "lair-monster-2"},
+		{"aggressive never heals", "aggressive", "warrior", with(fight, func(g *model.GameState) { g.HeroHP, g.Inventory = 10, model.ParseInventory(`["hppotion-common"]`) }), "lair-monster-2"},
+
+		{"cheapest potion below the threshold", "heal-at-threshold", "warrior", with(fight, func(g *model.GameState) {
+			g.HeroHP, g.Inventory = 30, model.ParseInventory(`["hppotion-rare","hppotion-common"]`)
+		}), "use-hppotion-common"},
+		{"no heal above the threshold", "heal-at-threshold", "warrior", with(fight, func(g *model.GameState) {
+			g.HeroHP, g.Inventory = 50, model.ParseInventory(`["hppotion-common"]`)
+		}), "lair-monster-2"},
+		{"class heal with mana", "heal-at-threshold", "mage", with(fight, func(g *model.GameState) { g.HeroHP, g.HeroMana = 30, 4 }), "hero"},
+		{"mana potion to afford the heal", "heal-at-threshold", "mage", with(fight, func(g *model.GameState) {
+			g.HeroHP, g.Inventory = 30, model.ParseInventory(`["manapotion-common"]`)
+		}), "use-manapotion-common"},
+		{"warrior taunts instead", "heal-at-threshold", "warrior", with(fight, func(g *model.GameState) { g.HeroHP = 30 }), "activate-taunt"},
+		{"taunt already up", "heal-at-threshold", "warrior", with(fight, func(g *model.GameState) { g.HeroHP, g.TauntActive = 30, 1 }), "lair-monster-2"},
+
+		{"backstab the strongest", "backstab-on-cooldown", "rogue", fight, "lair-monster-1-backstab"},
+		{"backstab the boss when it is alone", "backstab-on-cooldown", "rogue", with(fight, func(g *model.GameState) { g.MonsterHP = nil }), "lair-boss-backstab"},
+		{"weakest while on cooldown", "backstab-on-cooldown", "rogue", with(fight, func(g *model.GameState) { g.BackstabCooldown = 2 }), "lair-monster-2"},
+		{"other classes play aggressive", "backstab-on-cooldown", "warrior", fight, "lair-monster-2"},
+
+		{"loot-first equips every slot", "loot-first", "warrior", with(fight, func(g *model.GameState) {
+			g.HeroHP, g.Inventory = 30, model.ParseInventory(`["armor-rare","hppotion-common"]`)
+		}), "equip-armor-rare"},
+		{"loot-first heals once geared", "loot-first", "warrior", with(fight, func(g *model.GameState) {
+			g.HeroHP, g.ArmorBonus, g.Inventory = 30, 10, model.ParseInventory(`["hppotion-common"]`)
+		}), "use-hppotion-common"},
+
+		{"shopper buys what it can afford", "shopper", "warrior", with(fight, func(g *model.GameState) {
+			g.Gold, g.MerchantRoom, g.MerchantStock = 30, 1, `["weapon-rare","armor-common"]`
+		}), "buy-armor-common"},
+		{"shopper with nothing affordable", "shopper", "warrior", with(fight, func(g *model.GameState) {
+			g.Gold, g.MerchantRoom, g.MerchantStock = 10, 1, `["armor-common"]`
+		}), "lair-monster-2"},
+		{"merchant in another room", "shopper", "warrior", with(fight, func(g *model.GameState) {
+			g.Gold, g.MerchantRoom, g.MerchantStock = 30, 2, `["armor-common"]`
+		}), "lair-monster-2"},
+		{"crafter crafts first", "crafter", "warrior", with(fight, func(g *model.GameState) {
+			g.Inventory = model.ParseInventory(`["armor-common","armor-common","armor-common"]`)
+		}), "craft-armor-rare"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &model.Dungeon{
+				Spec:   model.DungeonSpec{HeroClass: tt.class, Rooms: 2},
+				Status: model.DungeonStatus{MaxHeroHP: "100", Game: tt.game},
+			}
+			d.Name = "lair"
+			if got := autoplay.Strategies[tt.strategy](autoplay.NewView(d), autoplay.Options{HealThreshold: 40}); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.strategy, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Targeting says what an ability is aimed at.
+type Targeting string
+
+const (
+	TargetSelf  Targeting = "self"  // resolved by abilityResolve; no counter-attack
+	TargetEnemy Targeting = "enemy" // an attack on a monster or the boss
+)
+
+// Trigger is the Dungeon spec field the backend writes to fire an ability,
+// and the value it writes.
+type Trigger struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// Ability is one class ability. ManaCost and Cooldown are descriptive: kro
+// deducts the mana and counts the cooldown down in CooldownField, which must
+// be 0 for the ability to be used.
+type Ability struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Action          string    `json:"action"`
+	Class           string    `json:"class"`
+	ManaCost        int64     `json:"manaCost,omitempty"`
+	Cooldown        int64     `json:"cooldown,omitempty"`
+	CooldownField   string    `json:"cooldownField,omitempty"`
+	CooldownMessage string    `json:"cooldownMessage,omitempty"`
+	Target          Targeting `json:"target"`
+	Trigger         Trigger   `json:"trigger"`
+	LegacyTarget    string    `json:"legacyTarget,omitempty"`
+	HeroAction      string    `json:"heroAction,omitempty"`
+	EnemyAction     string    `json:"enemyAction,omitempty"`
+	Description     string    `json:"description"`
+}
+
+//go:embed abilities.yaml
+var abilitiesYAML []byte
+
+var abilities = mustParseAbilities(abilitiesYAML)
+
+// Abilities returns every ability in registry order.
+func Abilities() []Ability { return abilities }
+
+// LookupAbility returns the ability a class uses for action ("heal"). ok is
+// false when the class has no such ability; known reports whether any class
+// has it, so callers can tell a wrong class from an unknown action.
+func LookupAbility(classID, action string) (a Ability, ok, known bool) {
+	for _, a := range abilities {
+		if a.Action != action {
+			continue
+		}
+		known = true
+		if a.Class == classID {
+			return a, true, true
+		}
+	}
+	return Ability{}, false, known
+}
+
+// AbilityForTarget maps a target from the attacks endpoint ("hero",
+// "activate-taunt", "<enemy>-backstab") to the action it stands for and the
+// enemy it is aimed at. ok is false for a plain attack.
+func AbilityForTarget(target string) (action, enemy string, ok bool) {
+	for _, a := range abilities {
+		prefix, suffix, templated := strings.Cut(a.LegacyTarget, "{target}")
+		switch {
+		case !templated && target == a.LegacyTarget:
+			return a.Action, "", true
+		case templated && len(target) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(target, prefix) && strings.HasSuffix(target, suffix):
+			return a.Action, target[len(prefix) : len(target)-len(suffix)], true
+		}
+	}
+	return "", "", false
+}
+
+// mustParseAbilities decodes the registry, checks it against the class
+// registry and fills in each class's Abilities.
+func mustParseAbilities(data []byte) []Ability {
+	var doc struct {
+		Abilities []Ability `json:"abilities"`
+	}
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		panic(fmt.Errorf("decode ability registry: %w", err))
+	}
+	seen := make(map[string]bool, len(doc.Abilities))
+	for i, a := range doc.Abilities {
+		if a.ID == "" || a.Action == "" || a.Trigger.Field == "" || a.Trigger.Value == nil {
+			panic(fmt.Errorf("ability %d (%q): id, action and trigger are required", i, a.ID))
+		}
+		if a.Target != TargetSelf && a.Target != TargetEnemy {
+			panic(fmt.Errorf("ability %s: target must be %q or %q", a.ID, TargetSelf, TargetEnemy))
+		}
+		if seen[a.ID] || seen[a.Class+"/"+a.Action] {
+			panic(fmt.Errorf("ability %s: duplicate id or %s %s", a.ID, a.Class, a.Action))
+		}
+		seen[a.ID], seen[a.Class+"/"+a.Action] = true, true
+		c, ok := classIndex[a.Class]
+		if !ok {
+			panic(fmt.Errorf("ability %s: unknown class %q", a.ID, a.Class))
+		}
+		classes[c].Abilities = append(classes[c].Abilities, a.Action)
+	}
+	return doc.Abilities
+}
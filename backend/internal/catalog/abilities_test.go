@@ -0,0 +1,92 @@
+package catalog_test
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+)
+
+// TestAbilitiesMatchRGD checks that every ability's trigger, mana cost,
+// cooldown counter and cooldown length are what the RGD acts on.
+func TestAbilitiesMatchRGD(t *testing.T) {
+	data, err := os.ReadFile(rgdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgd := string(data)
+	for _, a := range catalog.Abilities() {
+		trigger := "schema.spec." + a.Trigger.Field
+		if v, ok := a.Trigger.Value.(string); ok {
+			trigger = "'" + v + "'"
+		}
+		if !strings.Contains(rgd, trigger) {
+			t.Errorf("%s: RGD never reads trigger %s", a.ID, trigger)
+		}
+		if a.ManaCost > 0 {
+			m := regexp.MustCompile(regexp.QuoteMeta(trigger) + `[^?]*\?\s+mana - (\d+)`).FindStringSubmatch(rgd)
+			if m == nil || m[1] != strconv.FormatInt(a.ManaCost, 10) {
+				t.Errorf("%s: manaCost %d, RGD deducts %v", a.ID, a.ManaCost, m)
+			}
+		}
+		if a.CooldownField != "" && !strings.Contains(rgd, "kstate(schema.status.game, '"+a.CooldownField+"'") {
+			t.Errorf("%s: RGD has no %s counter", a.ID, a.CooldownField)
+		}
+		if a.Cooldown > 0 {
+			m := regexp.MustCompile(regexp.QuoteMeta(trigger) + `\s+\? (\d+)`).FindStringSubmatch(rgd)
+			if m == nil || m[1] != strconv.FormatInt(a.Cooldown, 10) {
+				t.Errorf("%s: cooldown %d, RGD sets %v", a.ID, a.Cooldown, m)
+			}
+		}
+	}
+}
+
+func TestLookupAbility(t *testing.T) {
+	tests := []struct {
+		class, action string
+		wantID        string
+		wantKnown     bool
+	}{
+		{"mage", "heal", "mage-heal", true},
+		{"paladin", "heal", "paladin-heal", true},
+		{"rogue", "heal", "", true},
+		{"ranger", "fireball", "", false},
+	}
+	for _, tt := range tests {
+		a, ok, known := catalog.LookupAbility(tt.class, tt.action)
+		if a.ID != tt.wantID || ok != (tt.wantID != "") || known != tt.wantKnown {
+			t.Errorf("LookupAbility(%s, %s) = %q, %v, %v", tt.class, tt.action, a.ID, ok, known)
+		}
+	}
+	for _, c := range catalog.Classes() {
+		for _, action := range c.Abilities {
+			if _, ok, _ := catalog.LookupAbility(c.ID, action); !ok {
+				t.Errorf("%s lists %s but has no such ability", c.ID, action)
+			}
+		}
+	}
+}
+
+func TestAbilityForTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string // "action enemy ok"
+	}{
+		{"hero", "heal  true"},
+		{"activate-taunt", "taunt  true"},
+		{"lair-monster-2-backstab", "backstab lair-monster-2 true"},
+		{"lair-boss-backstab", "backstab lair-boss true"},
+		{"lair-monster-0", "  false"},
+		{"-backstab", "  false"},
+	}
+	for _, tt := range tests {
+		action, enemy, ok := catalog.AbilityForTarget(tt.target)
+		if got := fmt.Sprintf("%s %s %v", action, enemy, ok); got != tt.want {
+			t.Errorf("AbilityForTarget(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
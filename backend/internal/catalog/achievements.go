@@ -0,0 +1,92 @@
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// AchievementKind says how often an achievement can be earned and where it
+// is stored on the profile.
+type AchievementKind string
+
+const (
+	Badge       AchievementKind = "badge"       // earnedBadges; counted every run it is earned
+	CareerBadge AchievementKind = "career"      // earnedBadges; earned once
+	Certificate AchievementKind = "certificate" // kroCertificates; earned once
+)
+
+// Achievement is a badge or kro certificate. Rule is a CEL expression over
+// the run and the profile; the backend compiles it with kro's CEL libraries
+// (this package only checks the structure). A certificate with no rule is
+// awarded by the frontend instead, for a dungeon of the player's own, and
+// Evidence, if set, is the expression that dungeon's run must satisfy when
+// it is claimed.
+type Achievement struct {
+	ID          string          `json:"id"`
+	Kind        AchievementKind `json:"kind"`
+	Tier        int             `json:"tier,omitempty"`
+	Title       string          `json:"title"`
+	Icon        string          `json:"icon,omitempty"`
+	Description string          `json:"description"`
+	Rule        string          `json:"rule,omitempty"`
+	Evidence    string          `json:"evidence,omitempty"`
+}
+
+// Achievements is an ordered, structurally valid set of achievements.
+type Achievements struct {
+	Achievements []Achievement `json:"achievements"`
+}
+
+//go:embed achievements.yaml
+var achievementsYAML []byte
+
+var defaultAchievements = mustParseAchievements(achievementsYAML)
+
+// DefaultAchievements returns the achievements built into the binary.
+func DefaultAchievements() *Achievements { return defaultAchievements }
+
+// ParseAchievements decodes and checks a file in the achievements.yaml
+// format. Every entry needs a unique ID, a known kind and a title; badges
+// need a rule, certificates a tier from 1 to 3, and only certificates without
+// a rule take evidence.
+func ParseAchievements(data []byte) (*Achievements, error) {
+	var a Achievements
+	if err := yaml.UnmarshalStrict(data, &a); err != nil {
+		return nil, fmt.Errorf("decode achievements: %w", err)
+	}
+	if len(a.Achievements) == 0 {
+		return nil, fmt.Errorf("achievements file is empty")
+	}
+	seen := make(map[string]bool, len(a.Achievements))
+	for i, ach := range a.Achievements {
+		switch {
+		case ach.ID == "":
+			return nil, fmt.Errorf("achievement %d: missing id", i)
+		case ach.Kind != Badge && ach.Kind != CareerBadge && ach.Kind != Certificate:
+			return nil, fmt.Errorf("achievement %s: kind must be %q, %q or %q", ach.ID, Badge, CareerBadge, Certificate)
+		case ach.Title == "":
+			return nil, fmt.Errorf("achievement %s: missing title", ach.ID)
+		case ach.Kind != Certificate && ach.Rule == "":
+			return nil, fmt.Errorf("achievement %s: badges need a rule", ach.ID)
+		case ach.Kind == Certificate && (ach.Tier < 1 || ach.Tier > 3):
+			return nil, fmt.Errorf("achievement %s: certificate tier must be 1, 2 or 3", ach.ID)
+		case ach.Evidence != "" && (ach.Kind != Certificate || ach.Rule != ""):
+			return nil, fmt.Errorf("achievement %s: only certificates without a rule take evidence", ach.ID)
+		}
+		if seen[ach.ID] {
+			return nil, fmt.Errorf("achievement %s: duplicate id", ach.ID)
+		}
+		seen[ach.ID] = true
+	}
+	return &a, nil
+}
+
+func mustParseAchievements(data []byte) *Achievements {
+	a, err := ParseAchievements(data)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
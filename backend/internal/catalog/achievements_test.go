@@ -0,0 +1,54 @@
+package catalog_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+)
+
+func TestParseAchievements(t *testing.T) {
+	tests := []struct {
+		name, yaml, wantErr string
+	}{
+		{"valid", `achievements: [{id: x, kind: badge, title: X, description: d, rule: run.victory}, {id: y, kind: certificate, tier: 2, title: Y, description: d, evidence: "run.turns > 0"}]`, ""},
+		{"empty", `achievements: []`, "empty"},
+		{"no id", `achievements: [{kind: badge, title: X, rule: run.victory}]`, "missing id"},
+		{"bad kind", `achievements: [{id: x, kind: trophy, title: X, rule: run.victory}]`, "kind must be"},
+		{"no title", `achievements: [{id: x, kind: badge, rule: run.victory}]`, "missing title"},
+		{"badge without rule", `achievements: [{id: x, kind: career, title: X}]`, "need a rule"},
+		{"bad tier", `achievements: [{id: x, kind: certificate, tier: 4, title: X}]`, "tier must be"},
+		{"evidence on a ruled certificate", `achievements: [{id: x, kind: certificate, tier: 1, title: X, rule: run.victory, evidence: "run.turns > 0"}]`, "take evidence"},
+		{"evidence on a badge", `achievements: [{id: x, kind: badge, title: X, rule: run.victory, evidence: "run.turns > 0"}]`, "take evidence"},
+		{"duplicate", `achievements: [{id: x, kind: badge, title: X, rule: "true"}, {id: x, kind: badge, title: X, rule: "true"}]`, "duplicate"},
+		{"unknown field", `achievements: [{id: x, kind: badge, title: X, rule: "true", points: 10}]`, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := catalog.ParseAchievements([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestClassWinBadges checks that every hero class has a "<class>-win" badge;
+// the multi-class career badge counts them.
+func TestClassWinBadges(t *testing.T) {
+	ids := map[string]bool{}
+	for _, a := range catalog.DefaultAchievements().Achievements {
+		ids[a.ID] = a.Kind == catalog.Badge
+	}
+	for _, c := range catalog.Classes() {
+		if !ids[c.ID+"-win"] {
+			t.Errorf("class %s has no %s-win badge", c.ID, c.ID)
+		}
+	}
+}
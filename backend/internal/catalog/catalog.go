@@ -0,0 +1,155 @@
+// Package catalog holds the game's static data: the items a hero can find,
+// use and equip (items.yaml), the recipes that craft them (recipes.yaml),
+// the hero classes (classes.yaml) and their abilities (abilities.yaml), all
+// embedded at build time. The backend may replace the item catalog at
+// runtime with a copy from a ConfigMap (see Parse and Compatible).
+//
+// The catalog is descriptive: kro's state nodes apply the effects, charge the
+// prices and craft the items. It exists so validation, log text and the
+// /api/v1/items, /api/v1/recipes and /api/v1/classes listings read the same
+// data instead of each keeping its own switch.
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Kind says which action an item answers to.
+type Kind string
+
+const (
+	Consumable Kind = "consumable" // use-<id>; removed from the inventory
+	Equipment  Kind = "equipment"  // equip-<id>; replaces the item in its slot
+)
+
+// Item is one catalog entry. IDs are "<type>-<rarity>", the same strings that
+// appear in a dungeon's inventory and in use-/equip- actions.
+type Item struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Rarity string `json:"rarity"`
+	Kind   Kind   `json:"kind"`
+	Slot   string `json:"slot,omitempty"`
+	// Stat is the status.game field the item sets and Value the amount. Full
+	// restores the stat to its maximum instead; Uses limits how many attacks
+	// an equipped item lasts (0 = until replaced).
+	Stat  string `json:"stat"`
+	Value int64  `json:"value,omitempty"`
+	Full  bool   `json:"full,omitempty"`
+	Uses  int64  `json:"uses,omitempty"`
+	// UsableBy lists the hero classes allowed to use or equip the item;
+	// empty means every class.
+	UsableBy []string `json:"usableBy,omitempty"`
+	// Price is what the merchant charges in gold; selling fetches half.
+	Price       int64  `json:"price,omitempty"`
+	Description string `json:"description"`
+}
+
+// SellPrice is the gold the merchant pays for the item.
+func (it Item) SellPrice() int64 { return it.Price / 2 }
+
+// UsableByClass reports whether heroClass may use or equip the item.
+func (it Item) UsableByClass(heroClass string) bool {
+	return len(it.UsableBy) == 0 || slices.Contains(it.UsableBy, heroClass)
+}
+
+// Catalog is an ordered, validated set of items.
+type Catalog struct {
+	Items []Item `json:"items"`
+
+	byID map[string]int
+}
+
+// Lookup returns the item with the given ID.
+func (c *Catalog) Lookup(id string) (Item, bool) {
+	i, ok := c.byID[id]
+	if !ok {
+		return Item{}, false
+	}
+	return c.Items[i], true
+}
+
+// Equipped returns the equipment item that sets slot's bonus to bonus. kro
+// records only the bonus, so this is how an equipped item is named again for
+// unequip-, swap- and the New Game+ carry-over.
+func (c *Catalog) Equipped(slot string, bonus int64) (Item, bool) {
+	for _, it := range c.Items {
+		if it.Kind == Equipment && it.Slot == slot && it.Value == bonus && bonus > 0 {
+			return it, true
+		}
+	}
+	return Item{}, false
+}
+
+//go:embed items.yaml
+var defaultYAML []byte
+
+var defaultCatalog = mustParse(defaultYAML)
+
+// Default returns the catalog built into the binary.
+func Default() *Catalog { return defaultCatalog }
+
+// Parse decodes and validates a catalog in the items.yaml format. Every item
+// needs an ID, a known kind and a stat; IDs must be unique and equipment
+// must name a slot.
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("decode item catalog: %w", err)
+	}
+	if len(c.Items) == 0 {
+		return nil, fmt.Errorf("item catalog is empty")
+	}
+	c.byID = make(map[string]int, len(c.Items))
+	for i, it := range c.Items {
+		switch {
+		case it.ID == "":
+			return nil, fmt.Errorf("item %d: missing id", i)
+		case it.Kind != Consumable && it.Kind != Equipment:
+			return nil, fmt.Errorf("item %s: kind must be %q or %q", it.ID, Consumable, Equipment)
+		case it.Kind == Equipment && it.Slot == "":
+			return nil, fmt.Errorf("item %s: equipment needs a slot", it.ID)
+		case it.Stat == "":
+			return nil, fmt.Errorf("item %s: missing stat", it.ID)
+		}
+		if _, dup := c.byID[it.ID]; dup {
+			return nil, fmt.Errorf("item %s: duplicate id", it.ID)
+		}
+		c.byID[it.ID] = i
+	}
+	return &c, nil
+}
+
+// Compatible reports whether c differs from base only in what the backend
+// alone reads: descriptions and class restrictions. Every other field is
+// mirrored in the RGD's CEL, so a catalog that changes one would describe and
+// validate items differently from how kro applies them.
+func (c *Catalog) Compatible(base *Catalog) error {
+	if len(c.Items) != len(base.Items) {
+		return fmt.Errorf("catalog has %d items, the RGD knows %d", len(c.Items), len(base.Items))
+	}
+	for _, want := range base.Items {
+		got, ok := c.Lookup(want.ID)
+		if !ok {
+			return fmt.Errorf("item %s: missing", want.ID)
+		}
+		got.UsableBy, got.Description = want.UsableBy, want.Description
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("item %s: only description and usableBy may differ from the built-in catalog", want.ID)
+		}
+	}
+	return nil
+}
+
+func mustParse(data []byte) *Catalog {
+	c, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
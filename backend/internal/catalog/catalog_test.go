@@ -0,0 +1,147 @@
+package catalog_test
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+)
+
+const rgdPath = "../../../manifests/rgds/dungeon-graph.yaml"
+
+// TestCatalogMatchesRGD checks every built-in item against the actionResolve
+// expressions that actually apply it, so the two cannot drift apart.
+func TestCatalogMatchesRGD(t *testing.T) {
+	rgd, err := os.ReadFile(rgdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range catalog.Default().Items {
+		verb := "use-"
+		if it.Kind == catalog.Equipment {
+			verb = "equip-"
+		}
+		action := verb + it.ID
+		// a == 'equip-weapon-rare' ? 10   /   a == 'use-hppotion-rare' ? (hp + 40 > ...
+		re := regexp.MustCompile(`a == '` + regexp.QuoteMeta(action) + `' \? \(?(?:[a-z]+ \+ )?(\w+)`)
+		m := re.FindSubmatch(rgd)
+		if m == nil {
+			t.Errorf("%s: no branch for %q in the RGD", it.ID, action)
+			continue
+		}
+		if it.Full {
+			continue // the maximum is class-dependent; presence is enough
+		}
+		if got, _ := strconv.ParseInt(string(m[1]), 10, 64); got != it.Value {
+			t.Errorf("%s: catalog value %d, RGD applies %s", it.ID, it.Value, m[1])
+		}
+		if !strings.Contains(string(rgd), "\n          "+it.Stat+": >-") {
+			t.Errorf("%s: RGD has no %s field", it.ID, it.Stat)
+		}
+	}
+}
+
+// TestPricesMatchRGD checks item prices against the rarity price table the
+// RGD's gold and merchant expressions use; every copy of the table must agree.
+func TestPricesMatchRGD(t *testing.T) {
+	rgd, err := os.ReadFile(rgdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := regexp.MustCompile(`\{'common': (\d+), 'rare': (\d+), 'epic': (\d+)\}`).FindAllSubmatch(rgd, -1)
+	if len(tables) == 0 {
+		t.Fatal("no price table in the RGD")
+	}
+	prices := map[string]int64{}
+	for i, rarity := range []string{"common", "rare", "epic"} {
+		prices[rarity], _ = strconv.ParseInt(string(tables[0][i+1]), 10, 64)
+	}
+	for _, tbl := range tables[1:] {
+		if string(tbl[0]) != string(tables[0][0]) {
+			t.Errorf("RGD price tables disagree: %s vs %s", tables[0][0], tbl[0])
+		}
+	}
+	for _, it := range catalog.Default().Items {
+		if it.Price != prices[it.Rarity] {
+			t.Errorf("%s: catalog price %d, RGD charges %d", it.ID, it.Price, prices[it.Rarity])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, yaml, wantErr string
+	}{
+		{"valid", `items: [{id: ring-common, kind: equipment, slot: ring, stat: ringBonus, value: 5}]`, ""},
+		{"empty", `items: []`, "empty"},
+		{"no id", `items: [{kind: consumable, stat: heroHP}]`, "missing id"},
+		{"bad kind", `items: [{id: x, kind: trinket, stat: heroHP}]`, "kind must be"},
+		{"no slot", `items: [{id: x, kind: equipment, stat: ringBonus}]`, "needs a slot"},
+		{"no stat", `items: [{id: x, kind: consumable}]`, "missing stat"},
+		{"duplicate", `items: [{id: x, kind: consumable, stat: heroHP}, {id: x, kind: consumable, stat: heroHP}]`, "duplicate"},
+		{"unknown field", `items: [{id: x, kind: consumable, stat: heroHP, power: 9000}]`, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := catalog.Parse([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := c.Lookup("ring-common"); !ok {
+					t.Error("Lookup(ring-common) failed")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUsableByClass(t *testing.T) {
+	mana, _ := catalog.Default().Lookup("manapotion-rare")
+	hp, _ := catalog.Default().Lookup("hppotion-rare")
+	if mana.UsableByClass("warrior") || !mana.UsableByClass("mage") {
+		t.Errorf("manapotion usableBy = %v", mana.UsableBy)
+	}
+	if !hp.UsableByClass("rogue") {
+		t.Error("hppotion should be usable by every class")
+	}
+}
+
+// TestEquippedMatchesRGD checks Equipped against the slot table actionResolve
+// uses to name an equipped item from its bonus ('weapon': {5: 'common', ...}).
+func TestEquippedMatchesRGD(t *testing.T) {
+	rgd, err := os.ReadFile(rgdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := regexp.MustCompile(`'([a-z]+)': \{(\d+): 'common', (\d+): 'rare', (\d+): 'epic'\}`).FindAllSubmatch(rgd, -1)
+	if len(entries) == 0 {
+		t.Fatal("no slot table in the RGD")
+	}
+	inRGD := map[string]bool{}
+	for _, m := range entries {
+		inRGD[string(m[1])] = true
+		for i, rarity := range []string{"common", "rare", "epic"} {
+			bonus, _ := strconv.ParseInt(string(m[i+2]), 10, 64)
+			it, ok := catalog.Default().Equipped(string(m[1]), bonus)
+			if want := string(m[1]) + "-" + rarity; !ok || it.ID != want {
+				t.Errorf("Equipped(%s, %d) = %q, RGD names it %s", m[1], bonus, it.ID, want)
+			}
+		}
+	}
+	for _, it := range catalog.Default().Items {
+		if it.Kind == catalog.Equipment && !inRGD[it.Slot] {
+			t.Errorf("RGD slot table has no %s", it.Slot)
+		}
+	}
+	if _, ok := catalog.Default().Equipped("weapon", 0); ok {
+		t.Error("an empty slot names an item")
+	}
+}
@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Class is one hero class. Percentages are whole numbers: DamagePct 130
+// means hero attacks deal 130% of the dice roll, DefensePct 25 that
+// counter-attacks deal 25% less, DodgePct 10 a 10% chance to avoid one.
+// Abilities holds the actions from the ability registry the class may use.
+// ManaAttacks marks a class whose attacks spend mana for their bonus and
+// whose monster kills restore it; AttackNote tags its attacks in the combat
+// log.
+type Class struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Icon        string   `json:"icon"`
+	MaxHP       int64    `json:"maxHP"`
+	MaxMana     int64    `json:"maxMana,omitempty"`
+	DamagePct   int64    `json:"damagePct"`
+	DefensePct  int64    `json:"defensePct,omitempty"`
+	DodgePct    int64    `json:"dodgePct,omitempty"`
+	Abilities   []string `json:"abilities,omitempty"`
+	Passives    []string `json:"passives,omitempty"`
+	ManaAttacks bool     `json:"manaAttacks,omitempty"`
+	AttackNote  string   `json:"attackNote,omitempty"`
+	Description string   `json:"description"`
+}
+
+// HasAbility reports whether the class may use the named ability.
+func (c Class) HasAbility(ability string) bool {
+	return slices.Contains(c.Abilities, ability)
+}
+
+//go:embed classes.yaml
+var classesYAML []byte
+
+var classes, classIndex = mustParseClasses(classesYAML)
+
+// Classes returns every hero class in display order.
+func Classes() []Class { return classes }
+
+// LookupClass returns the class with the given ID.
+func LookupClass(id string) (Class, bool) {
+	i, ok := classIndex[id]
+	if !ok {
+		return Class{}, false
+	}
+	return classes[i], true
+}
+
+// ClassIDs returns the IDs of every class, in display order.
+func ClassIDs() []string {
+	ids := make([]string, len(classes))
+	for i, c := range classes {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// ClassChoiceCEL renders the CEL chain the RGDs use to pick a per-class
+// number: subject == "<id>" ? <value> : ... : fallback. Classes whose value is
+// the fallback are left to it. quoted renders the values as strings, as the
+// hero-graph ConfigMap data does.
+func ClassChoiceCEL(subject, fallback string, value func(Class) int64, quoted bool) string {
+	var b strings.Builder
+	for _, c := range classes {
+		v := strconv.FormatInt(value(c), 10)
+		if quoted {
+			v = strconv.Quote(v)
+		}
+		if v != fallback {
+			fmt.Fprintf(&b, "%s == %q ? %s : ", subject, c.ID, v)
+		}
+	}
+	return b.String() + fallback
+}
+
+func mustParseClasses(data []byte) ([]Class, map[string]int) {
+	var doc struct {
+		Classes []Class `json:"classes"`
+	}
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		panic(fmt.Errorf("decode class registry: %w", err))
+	}
+	index := make(map[string]int, len(doc.Classes))
+	for i, c := range doc.Classes {
+		if c.ID == "" || c.MaxHP <= 0 || c.DamagePct <= 0 {
+			panic(fmt.Errorf("class %d (%q): id, maxHP and damagePct are required", i, c.ID))
+		}
+		if _, dup := index[c.ID]; dup {
+			panic(fmt.Errorf("class %s: duplicate id", c.ID))
+		}
+		index[c.ID] = i
+	}
+	return doc.Classes, index
+}
@@ -0,0 +1,104 @@
+package catalog_test
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+)
+
+const heroGraphPath = "../../../manifests/rgds/hero-graph.yaml"
+
+// TestClassesMatchRGD checks the registry against the CEL that implements each
+// class, so a class cannot be added to one side only.
+func TestClassesMatchRGD(t *testing.T) {
+	dungeon, err := os.ReadFile(rgdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hero, err := os.ReadFile(heroGraphPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, rgd := range map[string][]byte{rgdPath: dungeon, heroGraphPath: hero} {
+		m := regexp.MustCompile(`heroClass: string \| default="warrior" enum=([a-z,]+)`).FindSubmatch(rgd)
+		if m == nil {
+			t.Fatalf("%s: no heroClass enum", path)
+		}
+		if got := strings.Split(string(m[1]), ","); !slices.Equal(got, catalog.ClassIDs()) {
+			t.Errorf("%s: heroClass enum = %v, registry = %v", path, got, catalog.ClassIDs())
+		}
+	}
+
+	// The rendered chains are the RGDs' own, up to quote style.
+	maxHP := func(c catalog.Class) int64 { return c.MaxHP }
+	for _, tt := range []struct {
+		rgd []byte
+		cel string
+	}{
+		{hero, catalog.ClassChoiceCEL("schema.spec.heroClass", `"150"`, maxHP, true)},
+		{dungeon, catalog.ClassChoiceCEL("schema.spec.heroClass", "100", maxHP, false)},
+		{dungeon, catalog.ClassChoiceCEL("schema.spec.heroClass", "0", func(c catalog.Class) int64 { return c.MaxMana }, false)},
+	} {
+		if cel := strings.ReplaceAll(tt.cel, `"`, "'"); !strings.Contains(string(tt.rgd), cel) {
+			t.Errorf("RGD has no %s", cel)
+		}
+	}
+
+	rgd := string(dungeon)
+	// ratio finds `heroClass == '<id>' ? <operand> * N / D` and returns N*100/D.
+	ratio := func(id, operand string) (int64, bool) {
+		m := regexp.MustCompile(`heroClass == '` + id + `' \? ` + operand + ` \* (\d+) / (\d+)`).FindStringSubmatch(rgd)
+		if m == nil {
+			return 0, false
+		}
+		n, _ := strconv.ParseInt(m[1], 10, 64)
+		d, _ := strconv.ParseInt(m[2], 10, 64)
+		return n * 100 / d, true
+	}
+	for _, c := range catalog.Classes() {
+		if !strings.Contains(rgd, "heroClass == '"+c.ID+"' ? "+strconv.FormatInt(c.MaxHP, 10)) {
+			t.Errorf("%s: RGD has no max HP %d", c.ID, c.MaxHP)
+		}
+		if c.MaxMana > 0 && !strings.Contains(rgd, "heroClass == '"+c.ID+"' ? "+strconv.FormatInt(c.MaxMana, 10)) {
+			t.Errorf("%s: RGD has no max mana %d", c.ID, c.MaxMana)
+		}
+		// A mana-powered bonus has its own expression shape.
+		manaShape := strings.Contains(rgd, "heroClass == '"+c.ID+"' ? (kstate(schema.status.game, 'heroMana', 0) > 0 ? baseDmg * ")
+		if manaShape != c.ManaAttacks {
+			t.Errorf("%s: manaAttacks %v, RGD mana-powered damage %v", c.ID, c.ManaAttacks, manaShape)
+		}
+		if c.DamagePct != 100 && !c.ManaAttacks {
+			if got, ok := ratio(c.ID, "baseDmg"); got != c.DamagePct {
+				t.Errorf("%s: damagePct %d, RGD applies %d%% (found %v)", c.ID, c.DamagePct, got, ok)
+			}
+		}
+		if c.DefensePct > 0 {
+			if got, ok := ratio(c.ID, "shielded"); 100-got != c.DefensePct {
+				t.Errorf("%s: defensePct %d, RGD applies %d%% (found %v)", c.ID, c.DefensePct, 100-got, ok)
+			}
+		}
+		if c.DodgePct > 0 && !strings.Contains(rgd, "heroClass == '"+c.ID+"' && random.seededInt(0, 100, s + '-dodge-boss') < "+strconv.FormatInt(c.DodgePct, 10)+" ? 0") {
+			t.Errorf("%s: RGD has no %d%% dodge", c.ID, c.DodgePct)
+		}
+	}
+}
+
+func TestLookupClass(t *testing.T) {
+	for _, id := range catalog.ClassIDs() {
+		c, ok := catalog.LookupClass(id)
+		if !ok || c.Name == "" || c.Icon == "" {
+			t.Errorf("LookupClass(%q) = %+v, %v", id, c, ok)
+		}
+	}
+	if _, ok := catalog.LookupClass("bard"); ok {
+		t.Error("LookupClass(bard) succeeded")
+	}
+	if mage, _ := catalog.LookupClass("mage"); !mage.HasAbility("heal") || mage.HasAbility("taunt") {
+		t.Errorf("mage abilities = %v", mage.Abilities)
+	}
+}
@@ -0,0 +1,85 @@
+package catalog
+
+import (
+	_ "embed"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Recipe turns items from the backpack into another item. Inputs lists an
+// item once per copy consumed.
+type Recipe struct {
+	ID     string   `json:"id"`
+	Inputs []string `json:"inputs"`
+	Output string   `json:"output"`
+}
+
+// Shortfall returns the first input the backpack holds too few copies of,
+// with how many the recipe needs and how many there are. item is "" when the
+// recipe can be crafted.
+func (r Recipe) Shortfall(backpack []string) (item string, need, have int) {
+	for _, in := range r.Inputs {
+		need, have = 0, 0
+		for _, x := range r.Inputs {
+			if x == in {
+				need++
+			}
+		}
+		for _, x := range backpack {
+			if x == in {
+				have++
+			}
+		}
+		if have < need {
+			return in, need, have
+		}
+	}
+	return "", 0, 0
+}
+
+//go:embed recipes.yaml
+var recipesYAML []byte
+
+var recipes = mustParseRecipes(recipesYAML)
+
+// Recipes returns every crafting recipe in registry order.
+func Recipes() []Recipe { return recipes }
+
+// LookupRecipe returns the recipe with the given ID.
+func LookupRecipe(id string) (Recipe, bool) {
+	for _, r := range recipes {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Recipe{}, false
+}
+
+// mustParseRecipes decodes the registry and checks it against the built-in
+// item catalog.
+func mustParseRecipes(data []byte) []Recipe {
+	var doc struct {
+		Recipes []Recipe `json:"recipes"`
+	}
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		panic(fmt.Errorf("decode recipe registry: %w", err))
+	}
+	seen := make(map[string]bool, len(doc.Recipes))
+	for i, r := range doc.Recipes {
+		// Two or more inputs keep crafting from ever growing the backpack.
+		if r.ID == "" || r.Output == "" || len(r.Inputs) < 2 {
+			panic(fmt.Errorf("recipe %d (%q): id, output and at least two inputs are required", i, r.ID))
+		}
+		if seen[r.ID] {
+			panic(fmt.Errorf("recipe %s: duplicate id", r.ID))
+		}
+		seen[r.ID] = true
+		for _, it := range append([]string{r.Output}, r.Inputs...) {
+			if _, ok := defaultCatalog.Lookup(it); !ok {
+				panic(fmt.Errorf("recipe %s: unknown item %q", r.ID, it))
+			}
+		}
+	}
+	return doc.Recipes
+}
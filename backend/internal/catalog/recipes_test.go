@@ -0,0 +1,58 @@
+package catalog_test
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+)
+
+// TestRecipesMatchRGD checks the registry against the recipe table in
+// actionResolve, entry for entry.
+func TestRecipesMatchRGD(t *testing.T) {
+	rgd, err := os.ReadFile(rgdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := regexp.MustCompile(`'([a-z-]+)': \{'inputs': \[([^\]]*)\], 'output': \['([a-z-]+)'\]\}`).FindAllSubmatch(rgd, -1)
+	inRGD := map[string]string{}
+	for _, m := range entries {
+		inRGD[string(m[1])] = fmt.Sprintf("%s -> %s", strings.ReplaceAll(string(m[2]), "'", ""), m[3])
+	}
+	for _, r := range catalog.Recipes() {
+		want := fmt.Sprintf("%s -> %s", strings.Join(r.Inputs, ", "), r.Output)
+		if got, ok := inRGD[r.ID]; !ok || got != want {
+			t.Errorf("%s: registry has %s, RGD has %q", r.ID, want, got)
+		}
+		delete(inRGD, r.ID)
+	}
+	for id := range inRGD {
+		t.Errorf("RGD recipe %s is missing from the registry", id)
+	}
+}
+
+func TestShortfall(t *testing.T) {
+	r := catalog.Recipe{ID: "kit", Inputs: []string{"weapon-rare", "shield-common", "shield-common"}, Output: "weapon-epic"}
+	tests := []struct {
+		backpack []string
+		want     string // "item need have"
+	}{
+		{[]string{"shield-common", "weapon-rare", "shield-common"}, " 0 0"},
+		{[]string{"weapon-rare", "shield-common", "shield-common", "shield-common"}, " 0 0"},
+		{[]string{"weapon-rare", "shield-common"}, "shield-common 2 1"},
+		{[]string{"shield-common", "shield-common", "weapon-common"}, "weapon-rare 1 0"},
+		{nil, "weapon-rare 1 0"},
+	}
+	for _, tt := range tests {
+		item, need, have := r.Shortfall(tt.backpack)
+		if got := fmt.Sprintf("%s %d %d", item, need, have); got != tt.want {
+			t.Errorf("Shortfall(%v) = %q, want %q", tt.backpack, got, tt.want)
+		}
+	}
+	if _, ok := catalog.LookupRecipe("weapon-rare"); !ok {
+		t.Error("LookupRecipe(weapon-rare) not found")
+	}
+}
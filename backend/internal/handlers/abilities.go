@@ -0,0 +1,183 @@
+package handlers
+
+// Class abilities — see catalog/abilities.yaml for the registry.
+//
+// POST .../abilities and the legacy ability targets of POST .../attacks both
+// end up in processCombat, which validates the ability with checkAbility and
+// writes its trigger field. GET .../abilities runs the same check for every
+// ability in the registry without taking a turn, so clients can grey out
+// buttons with the reason a use would be rejected.
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+// UseAbilityReq is the body of POST .../abilities.
+type UseAbilityReq struct {
+	Ability string `json:"ability"` // registry action, e.g. "heal"
+	Target  string `json:"target"`  // enemy for enemy-targeted abilities
+	Seq     int64  `json:"seq"`
+}
+
+// abilityCheck is why the hero cannot use an ability right now; the zero
+// value means it can. Code is the error a use would be rejected with.
+type abilityCheck struct {
+	Code   ErrorCode `json:"code,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// checkAbility applies the registry's class, mana and cooldown rules.
+func checkAbility(a catalog.Ability, class catalog.Class, game model.GameState) abilityCheck {
+	switch {
+	case a.Class != class.ID:
+		return abilityCheck{CodeWrongClass, class.Name + " cannot " + a.Action}
+	case game.HeroMana < a.ManaCost:
+		return abilityCheck{CodeNotEnoughMana, "not enough mana"}
+	case a.CooldownField != "" && game.Counter(a.CooldownField) > 0:
+		return abilityCheck{CodeOnCooldown, cmp.Or(a.CooldownMessage, a.Action+" on cooldown")}
+	}
+	return abilityCheck{}
+}
+
+// useSelfAbility writes a self-targeted ability's trigger; kro's
+// abilityResolve applies the effect. The log text uses pre-turn values.
+func (h *Handler) useSelfAbility(ctx context.Context, ns, name string, dungeon *unstructured.Unstructured, a catalog.Ability, class catalog.Class, game model.GameState, newSeq int64, w http.ResponseWriter) error {
+	text := strings.NewReplacer(
+		"{class}", class.Name,
+		"{hp}", strconv.FormatInt(game.HeroHP, 10),
+		"{mana}", strconv.FormatInt(game.HeroMana, 10),
+	)
+	spec := map[string]interface{}{
+		"lastHeroAction":  text.Replace(a.HeroAction),
+		"lastEnemyAction": text.Replace(a.EnemyAction),
+		"lastLootDrop":    "",
+		"attackSeq":       newSeq,
+		// Clear cross-triggers
+		"lastAbility":      "",
+		"lastAttackTarget": "",
+		"lastAction":       "",
+	}
+	spec[a.Trigger.Field] = a.Trigger.Value
+	return h.patchTurnAndRespond(ctx, ns, name, dungeon, map[string]interface{}{"spec": spec}, nil, w)
+}
+
+// splitAbilityTarget maps a legacy attacks target ("hero", "activate-taunt",
+// "<enemy>-backstab") to the enemy and ability processCombat takes. A plain
+// attack has no ability.
+func splitAbilityTarget(target string) (enemy, ability string) {
+	if action, e, ok := catalog.AbilityForTarget(target); ok {
+		return e, action
+	}
+	return target, ""
+}
+
+// attackCRTarget is the target recorded on the Attack CR: the enemy, marked
+// with the ability used on it.
+func attackCRTarget(target string, a catalog.Ability) string {
+	if a.ID == "" {
+		return target
+	}
+	return target + "-" + a.Action
+}
+
+// UseAbility uses one of the hero's class abilities.
+// POST /api/v1/dungeons/{namespace}/{name}/abilities
+func (h *Handler) UseAbility(w http.ResponseWriter, r *http.Request) {
+	ns := r.PathValue("namespace")
+	name := r.PathValue("name")
+	if !validateNamespace(w, ns) {
+		return
+	}
+	if sess := sessionFromCtx(r.Context()); sess == nil {
+		writeError(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	var req UseAbilityReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+		return
+	}
+	if req.Ability == "" {
+		writeError(w, "ability required", http.StatusBadRequest)
+		return
+	}
+	// error already written
+	_ = h.processCombat(context.Background(), r, ns, name, req.Target, req.Ability, 0, req.Seq, w)
+}
+
+// abilityStatus is one entry of ListAbilities.
+type abilityStatus struct {
+	catalog.Ability
+	Usable bool `json:"usable"`
+	abilityCheck
+}
+
+// ListAbilities reports every ability in the registry and whether the hero
+// can use it right now.
+// GET /api/v1/dungeons/{namespace}/{name}/abilities
+func (h *Handler) ListAbilities(w http.ResponseWriter, r *http.Request) {
+	ns := r.PathValue("namespace")
+	name := r.PathValue("name")
+	if !validateNamespace(w, ns) {
+		return
+	}
+	obj, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(r.Context(), name, metav1.GetOptions{})
+	if err != nil {
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
+		return
+	}
+	if err := requireDungeonOwner(r, obj); err != nil {
+		writeOwnerError(w, err)
+		return
+	}
+	d, err := model.FromUnstructured(obj)
+	if err != nil {
+		slog.Error("failed to decode dungeon for abilities", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"heroClass": heroClassOrDefault(d.Spec.HeroClass).ID,
+		"abilities": abilityStatuses(d),
+	})
+}
+
+// abilityStatuses checks every ability against the dungeon in the order a
+// use would: class, then whether a turn can be taken at all, then mana and
+// cooldown.
+func abilityStatuses(d *model.Dungeon) []abilityStatus {
+	class := heroClassOrDefault(d.Spec.HeroClass)
+	game := d.Status.Game
+	var turn abilityCheck
+	switch {
+	case !d.Status.HeroReady():
+		turn = abilityCheck{CodeDungeonInitializing, "dungeon initializing"}
+	case game.HeroHP <= 0:
+		turn = abilityCheck{CodeGameOver, "hero is dead"}
+	case game.RoomCleared():
+		turn = abilityCheck{CodeGameOver, "room cleared"}
+	}
+	all := catalog.Abilities()
+	out := make([]abilityStatus, len(all))
+	for i, a := range all {
+		c := checkAbility(a, class, game)
+		if c.Code != CodeWrongClass && turn.Code != "" {
+			c = turn
+		}
+		out[i] = abilityStatus{Ability: a, Usable: c.Code == "", abilityCheck: c}
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+func abilityDungeon(name, class string, game map[string]interface{}) *unstructured.Unstructured {
+	game["heroHP"] = int64(100)
+	game["monsterHP"] = []interface{}{int64(30)}
+	game["bossHP"] = int64(200)
+	game["initProcessedSeq"] = int64(1)
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon",
+		"metadata": map[string]interface{}{
+			"name": name, "namespace": "default",
+			"labels": map[string]interface{}{"krombat.io/owner": "alice"},
+		},
+		"spec":   map[string]interface{}{"monsters": int64(1), "difficulty": "normal", "heroClass": class, "attackSeq": int64(0), "actionSeq": int64(0)},
+		"status": map[string]interface{}{"maxHeroHP": "180", "game": game},
+	}}
+}
+
+func TestAbilities(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		abilityDungeon("holy", "paladin", map[string]interface{}{"heroMana": int64(4)}),
+		abilityDungeon("drained", "paladin", map[string]interface{}{"heroMana": int64(1)}),
+		abilityDungeon("sneaky", "rogue", map[string]interface{}{"backstabCooldown": int64(2)}),
+		abilityDungeon("brute", "warrior", map[string]interface{}{"tauntActive": int64(1)}),
+	)
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/dungeons/{namespace}/{name}/abilities", h.ListAbilities)
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/abilities", h.UseAbility)
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/attacks", h.CreateAttack)
+	srv := h.AuthMiddleware(mux)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Test-User", "alice")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("list", func(t *testing.T) {
+		rec := do("GET", "/api/v1/dungeons/default/sneaky/abilities", "")
+		var got struct {
+			HeroClass string
+			Abilities []struct {
+				ID     string
+				Usable bool
+				Code   string
+			}
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.HeroClass != "rogue" {
+			t.Fatalf("GET abilities = %d %s (err %v)", rec.Code, rec.Body.String(), err)
+		}
+		codes := map[string]string{}
+		for _, a := range got.Abilities {
+			if a.Usable {
+				t.Errorf("%s usable, want blocked", a.ID)
+			}
+			codes[a.ID] = a.Code
+		}
+		if codes["rogue-backstab"] != "ON_COOLDOWN" || codes["mage-heal"] != "WRONG_CLASS" {
+			t.Errorf("codes = %v", codes)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+		wantSpec string // expected spec.lastAbility after the turn
+	}{
+		{"heal", "/holy/abilities", `{"ability":"heal","seq":0}`, http.StatusAccepted, "Paladin heals! HP: 100", "paladin-heal"},
+		{"no mana", "/drained/abilities", `{"ability":"heal","seq":0}`, http.StatusBadRequest, "not enough mana", ""},
+		{"wrong class", "/holy/abilities", `{"ability":"taunt","seq":-1}`, http.StatusBadRequest, "Paladin cannot taunt", ""},
+		{"unknown", "/holy/abilities", `{"ability":"fireball","seq":-1}`, http.StatusBadRequest, "unknown ability: fireball", ""},
+		{"cooldown", "/sneaky/abilities", `{"ability":"backstab","target":"sneaky-monster-0","seq":0}`, http.StatusBadRequest, "backstab on cooldown", ""},
+		{"legacy taunt", "/brute/attacks", `{"target":"activate-taunt","seq":0}`, http.StatusBadRequest, "taunt already active", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do("POST", "/api/v1/dungeons/default"+tt.path, tt.body)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("code = %d, body = %q; want %d containing %q", rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+			}
+			if tt.wantSpec == "" {
+				return
+			}
+			name := strings.Split(tt.path, "/")[1]
+			d, err := client.Resource(k8s.DungeonGVR).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _, _ := unstructured.NestedString(d.Object, "spec", "lastAbility"); got != tt.wantSpec {
+				t.Errorf("spec.lastAbility = %q, want %q", got, tt.wantSpec)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package handlers
+
+// Badges and kro certificates — see catalog.Achievement for the format.
+//
+// Each achievement's rule is a CEL expression compiled against kro's CEL
+// libraries plus two typed variables, run (RunStats) and profile
+// (ProfileStats), so a misspelt field or a non-bool rule fails at load time
+// rather than when a run ends. recordProfile evaluates badges, then career
+// badges, then certificates. A certificate the frontend awards may carry an
+// evidence expression instead, which AwardCert evaluates against the run of
+// the dungeon it is claimed for.
+//
+// Like the item catalog, the built-in set can be replaced without a rebuild
+// by storing a file of the same shape under the achievements.yaml key of the
+// krombat-achievements ConfigMap in rpg-system. It is re-read at most every
+// achievementsCacheTTL; if it is missing, fails to parse or any rule fails to
+// compile, the built-in set is used.
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	krocel "github.com/kubernetes-sigs/kro/pkg/cel"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+const (
+	achievementsCMName   = "krombat-achievements"
+	achievementsCMKey    = "achievements.yaml"
+	achievementsCacheTTL = 30 * time.Second
+)
+
+// RunStats is the run variable of an achievement rule.
+type RunStats struct {
+	Outcome       string `json:"outcome"` // victory, room-cleared, defeat or in-progress
+	Victory       bool   `json:"victory"`
+	HeroClass     string `json:"heroClass"`
+	Difficulty    string `json:"difficulty"`
+	Turns         int64  `json:"turns"`
+	HeroHP        int64  `json:"heroHP"`
+	MaxHeroHP     int64  `json:"maxHeroHP"` // the class default
+	CurrentRoom   int64  `json:"currentRoom"`
+	Rooms         int64  `json:"rooms"`
+	WeaponBonus   int64  `json:"weaponBonus"`
+	EquippedSlots int64  `json:"equippedSlots"`
+	// UsedPotion is whether any consumable was used this run.
+	UsedPotion   bool   `json:"usedPotion"`
+	RunCount     int64  `json:"runCount"`
+	Modifier     string `json:"modifier"`
+	BossMaxPhase int64  `json:"bossMaxPhase"` // highest phase a living boss reached, 0–3
+}
+
+// ProfileStats is the profile variable of an achievement rule: the profile
+// with this run already counted.
+type ProfileStats struct {
+	DungeonsPlayed int64    `json:"dungeonsPlayed"`
+	DungeonsWon    int64    `json:"dungeonsWon"`
+	DungeonsLost   int64    `json:"dungeonsLost"`
+	XP             int64    `json:"xp"`
+	Level          int64    `json:"level"`
+	Badges         []string `json:"badges"`
+	// ClassWins lists the classes with a "<class>-win" badge.
+	ClassWins []string `json:"classWins"`
+}
+
+func newRunStats(d *model.Dungeon, outcome string) RunStats {
+	game := d.Status.Game
+	return RunStats{
+		Outcome:       outcome,
+		Victory:       outcome == "victory",
+		HeroClass:     d.Spec.HeroClass,
+		Difficulty:    d.Spec.Difficulty,
+		Turns:         d.TotalTurns(),
+		HeroHP:        game.HeroHP,
+		MaxHeroHP:     classDefaultHP(d.Spec.HeroClass),
+		CurrentRoom:   game.CurrentRoom,
+		Rooms:         d.RoomCount(),
+		WeaponBonus:   game.WeaponBonus,
+		EquippedSlots: int64(game.EquippedSlots()),
+		UsedPotion:    d.Spec.ConsumablesUsed > 0,
+		RunCount:      d.Spec.RunCount,
+		Modifier:      game.Modifier,
+		BossMaxPhase:  d.Spec.BossMaxPhaseReached,
+	}
+}
+
+func newProfileStats(p UserProfile) ProfileStats {
+	s := ProfileStats{
+		DungeonsPlayed: int64(p.DungeonsPlayed),
+		DungeonsWon:    int64(p.DungeonsWon),
+		DungeonsLost:   int64(p.DungeonsLost),
+		XP:             int64(p.XP),
+		Level:          int64(p.Level),
+		Badges:         p.EarnedBadges,
+		ClassWins:      []string{},
+	}
+	for _, b := range p.EarnedBadges {
+		if class, ok := strings.CutSuffix(b, "-win"); ok {
+			if _, known := catalog.LookupClass(class); known {
+				s.ClassWins = append(s.ClassWins, class)
+			}
+		}
+	}
+	return s
+}
+
+var achievementEnv = mustAchievementEnv()
+
+func mustAchievementEnv() *cel.Env {
+	opts := krocel.BaseDeclarations()
+	opts = append(opts,
+		ext.NativeTypes(reflect.TypeOf(RunStats{}), reflect.TypeOf(ProfileStats{}), ext.ParseStructTag("json")),
+		cel.Variable("run", cel.ObjectType("handlers.RunStats")),
+		cel.Variable("profile", cel.ObjectType("handlers.ProfileStats")),
+	)
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build achievement CEL env: %v", err))
+	}
+	return env
+}
+
+// achievementRules is a set of achievements with their rules and evidence
+// compiled.
+type achievementRules struct {
+	catalog.Achievements
+	programs map[string]cel.Program
+	evidence map[string]cel.Program
+}
+
+// compileAchievements compiles every rule and evidence expression and
+// rejects the set if any does not compile or does not return a bool.
+func compileAchievements(a *catalog.Achievements) (*achievementRules, error) {
+	rules := &achievementRules{Achievements: *a, programs: map[string]cel.Program{}, evidence: map[string]cel.Program{}}
+	for _, ach := range a.Achievements {
+		for _, c := range []struct {
+			field, expr string
+			into        map[string]cel.Program
+		}{{"rule", ach.Rule, rules.programs}, {"evidence", ach.Evidence, rules.evidence}} {
+			if c.expr == "" {
+				continue
+			}
+			ast, iss := achievementEnv.Compile(c.expr)
+			if iss.Err() != nil {
+				return nil, fmt.Errorf("achievement %s: %w", ach.ID, iss.Err())
+			}
+			if ast.OutputType() != cel.BoolType {
+				return nil, fmt.Errorf("achievement %s: %s returns %s, want bool", ach.ID, c.field, ast.OutputType())
+			}
+			prg, err := achievementEnv.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("achievement %s: %w", ach.ID, err)
+			}
+			c.into[ach.ID] = prg
+		}
+	}
+	return rules, nil
+}
+
+var defaultAchievementRules = mustCompileAchievements(catalog.DefaultAchievements())
+
+func mustCompileAchievements(a *catalog.Achievements) *achievementRules {
+	rules, err := compileAchievements(a)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// earned returns the IDs of the achievements of kind whose rule holds. A
+// rule that fails to evaluate is logged and counts as not earned.
+func (r *achievementRules) earned(kind catalog.AchievementKind, run RunStats, profile ProfileStats) []string {
+	var ids []string
+	vars := map[string]any{"run": run, "profile": profile}
+	for _, ach := range r.Achievements.Achievements {
+		prg, ok := r.programs[ach.ID]
+		if ach.Kind != kind || !ok {
+			continue
+		}
+		if holds(prg, ach.ID, vars) {
+			ids = append(ids, ach.ID)
+		}
+	}
+	return ids
+}
+
+// holds evaluates a compiled rule. One that fails to evaluate is logged and
+// does not hold.
+func holds(prg cel.Program, id string, vars map[string]any) bool {
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		slog.Warn("achievements: rule failed", "component", "api", "achievement", id, "error", err)
+		return false
+	}
+	hit, _ := out.Value().(bool)
+	return hit
+}
+
+// lookup returns the achievement with the given ID.
+func (r *achievementRules) lookup(id string) (catalog.Achievement, bool) {
+	for _, ach := range r.Achievements.Achievements {
+		if ach.ID == id {
+			return ach, true
+		}
+	}
+	return catalog.Achievement{}, false
+}
+
+// clientAwarded reports whether id is a certificate the frontend awards
+// through POST /api/v1/profile/cert: one with no rule.
+func (r *achievementRules) clientAwarded(id string) bool {
+	ach, ok := r.lookup(id)
+	return ok && ach.Kind == catalog.Certificate && ach.Rule == ""
+}
+
+// evidenced reports whether the run a client-awarded certificate is claimed
+// for satisfies its evidence; one without evidence needs only the run.
+func (r *achievementRules) evidenced(id string, run RunStats, profile ProfileStats) bool {
+	prg, ok := r.evidence[id]
+	return !ok || holds(prg, id, map[string]any{"run": run, "profile": profile})
+}
+
+type achievementStore struct {
+	client *k8s.Client
+
+	mu        sync.Mutex
+	cached    *achievementRules
+	fetchedAt time.Time
+}
+
+func newAchievementStore(client *k8s.Client) *achievementStore {
+	return &achievementStore{client: client}
+}
+
+func (s *achievementStore) get(ctx context.Context) *achievementRules {
+	s.mu.Lock()
+	cached := s.cached
+	if cached != nil && time.Since(s.fetchedAt) < achievementsCacheTTL {
+		s.mu.Unlock()
+		return cached
+	}
+	// Other callers keep the cached copy while this one re-reads.
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	rules := s.fetch(ctx)
+	s.mu.Lock()
+	s.cached = rules
+	s.mu.Unlock()
+	return rules
+}
+
+// fetch reads the ConfigMap set, falling back to the built-in one.
+func (s *achievementStore) fetch(ctx context.Context) *achievementRules {
+	cm, err := s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Get(ctx, achievementsCMName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			slog.Warn("achievements: ConfigMap read failed, using built-in set", "component", "api", "error", err)
+		}
+		return defaultAchievementRules
+	}
+	data, _ := cm.Object["data"].(map[string]interface{})
+	raw, _ := data[achievementsCMKey].(string)
+	if raw == "" {
+		return defaultAchievementRules
+	}
+	a, err := catalog.ParseAchievements([]byte(raw))
+	if err == nil {
+		var rules *achievementRules
+		if rules, err = compileAchievements(a); err == nil {
+			return rules
+		}
+	}
+	slog.Error("achievements: invalid ConfigMap, using built-in set", "component", "api", "configmap", achievementsCMName, "error", err)
+	return defaultAchievementRules
+}
+
+// ListAchievements returns the badge and certificate definitions.
+// GET /api/v1/achievements
+func (h *Handler) ListAchievements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	writeJSON(w, h.achievements.get(r.Context()).Achievements)
+}
@@ -0,0 +1,256 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+func configMap(name, key, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": map[string]interface{}{"name": name, "namespace": "rpg-system"},
+		"data":     map[string]interface{}{key: value},
+	}}
+}
+
+// wonLair is alice's two-room dungeon, won as a warrior in 10 turns at full
+// HP after fighting the boss into phase 3.
+func wonLair() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon",
+		"metadata": map[string]interface{}{
+			"name": "lair", "namespace": "default",
+			"labels": map[string]interface{}{"krombat.io/owner": "alice"},
+		},
+		"spec": map[string]interface{}{
+			"monsters": int64(1), "difficulty": "normal", "heroClass": "warrior", "rooms": int64(2),
+			"attackSeq": int64(10), "actionSeq": int64(2), "bossMaxPhaseReached": int64(3),
+		},
+		"status": map[string]interface{}{
+			"victory": true, "maxHeroHP": "200",
+			"game": map[string]interface{}{
+				"heroHP": int64(200), "bossHP": int64(0), "monsterHP": []interface{}{int64(0)},
+				"currentRoom": int64(2), "initProcessedSeq": int64(1),
+			},
+		},
+	}}
+}
+
+// deleteLair deletes alice's dungeon, which records its run.
+func deleteLair(t *testing.T, h *handlers.Handler) {
+	t.Helper()
+	req := httptest.NewRequest("DELETE", "/api/v1/dungeons/default/lair", nil)
+	req.SetPathValue("namespace", "default")
+	req.SetPathValue("name", "lair")
+	req.Header.Set("X-Test-User", "alice")
+	rec := httptest.NewRecorder()
+	h.AuthMiddleware(http.HandlerFunc(h.DeleteDungeon)).ServeHTTP(rec, req)
+	if rec.Code >= 300 {
+		t.Fatalf("DELETE = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestAchievementRules plays the built-in rules against a won run: the
+// profile already holds two class wins, so the warrior win also earns
+// multi-class, and a boss fought into phase 3 earns boss-phase.
+func TestAchievementRules(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	profiles := configMap("krombat-profiles", "alice", `{"dungeonsPlayed":2,"dungeonsWon":2,"earnedBadges":["mage-win","rogue-win","speedrun"],"badgeCounts":{"mage-win":1,"rogue-win":1,"speedrun":1},"kroCertificates":["cel-state"]}`)
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), profiles, wonLair())
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	deleteLair(t, h)
+
+	var p struct {
+		EarnedBadges    []string
+		BadgeCounts     map[string]int
+		KroCertificates []string
+		CertificateIDs  map[string]string
+	}
+	cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		obj, err := client.Tracker().Get(cmGVR, "rpg-system", "krombat-profiles")
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _, _ := unstructured.NestedString(obj.(*unstructured.Unstructured).Object, "data", "alice")
+		if err := json.Unmarshal([]byte(raw), &p); err == nil && len(p.KroCertificates) > 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("profile was not updated: %s", raw)
+		}
+	}
+
+	wantBadges := []string{"mage-win", "rogue-win", "speedrun", "deathless", "pacifist", "warrior-win", "room2-winner", "no-damage", "no-potions", "multi-class"}
+	if !reflect.DeepEqual(p.EarnedBadges, wantBadges) {
+		t.Errorf("earnedBadges = %v, want %v", p.EarnedBadges, wantBadges)
+	}
+	if p.BadgeCounts["speedrun"] != 2 || p.BadgeCounts["multi-class"] != 1 {
+		t.Errorf("badgeCounts = %v, want speedrun 2 and multi-class 1", p.BadgeCounts)
+	}
+	if want := []string{"cel-state", "two-rooms", "boss-phase"}; !reflect.DeepEqual(p.KroCertificates, want) {
+		t.Errorf("kroCertificates = %v, want %v", p.KroCertificates, want)
+	}
+	if len(p.CertificateIDs) != 2 || p.CertificateIDs["two-rooms"] == "" || p.CertificateIDs["boss-phase"] == "" {
+		t.Errorf("certificateIds = %v, want documents for two-rooms and boss-phase", p.CertificateIDs)
+	}
+	for cert, id := range p.CertificateIDs {
+		if _, err := client.Tracker().Get(cmGVR, "rpg-system", "krombat-certificate-"+id); err != nil {
+			t.Errorf("%s document: %v", cert, err)
+		}
+	}
+}
+
+// TestCertificatesFollowProfile fails the profile write of a run that earns
+// certificates: their documents must not be stored for a profile that does
+// not name them.
+func TestCertificatesFollowProfile(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), configMap("krombat-profiles", "alice", `{"dungeonsPlayed":1}`), wonLair())
+	failed := make(chan struct{})
+	client.PrependReactor("patch", "configmaps", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		if a.(k8stesting.PatchAction).GetName() != "krombat-profiles" {
+			return false, nil, nil
+		}
+		close(failed)
+		return true, nil, apierrors.NewInternalError(errors.New("etcd unavailable"))
+	})
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	deleteLair(t, h)
+
+	select {
+	case <-failed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the profile was never written")
+	}
+	time.Sleep(100 * time.Millisecond)
+	list, err := client.Tracker().List(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "rpg-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cm := range list.(*unstructured.UnstructuredList).Items {
+		if strings.HasPrefix(cm.GetName(), "krombat-certificate-") {
+			t.Errorf("document %s stored although the profile write failed", cm.GetName())
+		}
+	}
+}
+
+// TestCertificateRules checks the certificates whose rules depend on the
+// dungeon's size or the profile's class wins.
+func TestCertificateRules(t *testing.T) {
+	won := func(room, rooms int64) handlers.RunStats {
+		return handlers.RunStats{Outcome: "victory", Victory: true, CurrentRoom: room, Rooms: rooms}
+	}
+	for _, tt := range []struct {
+		name    string
+		run     handlers.RunStats
+		profile handlers.ProfileStats
+		want    []string
+	}{
+		{"every room of two", won(2, 2), handlers.ProfileStats{DungeonsWon: 1}, []string{"cel-state", "two-rooms"}},
+		{"two rooms of four", won(2, 4), handlers.ProfileStats{DungeonsWon: 1}, []string{"cel-state"}},
+		{"every room of four", won(4, 4), handlers.ProfileStats{DungeonsWon: 1}, []string{"cel-state", "two-rooms"}},
+		{"five wins, one class", won(2, 2), handlers.ProfileStats{DungeonsWon: 5, ClassWins: []string{"warrior"}}, []string{"cel-state", "two-rooms"}},
+		{"five wins, two classes", won(2, 2), handlers.ProfileStats{DungeonsWon: 5, ClassWins: []string{"warrior", "mage"}}, []string{"cel-state", "two-rooms", "dungeon-master"}},
+	} {
+		if got := handlers.EarnedAchievements(catalog.Certificate, tt.run, tt.profile); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: certificates = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAchievementsConfigMap(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	tests := []struct {
+		name, yaml string
+		wantIDs    []string // nil: the built-in set
+	}{
+		{"valid", `achievements:
+  - {id: lucky, kind: badge, title: Lucky, description: "Win at 1 HP", rule: "run.victory && run.heroHP == 1"}
+  - {id: cel-state, kind: certificate, tier: 1, title: CEL State Machine, description: "Win", rule: run.victory}
+  - {id: kro-docs, kind: certificate, tier: 2, title: Reader, description: "Open the kro docs"}
+`, []string{"lucky", "cel-state", "kro-docs"}},
+		{"unknown field", `achievements: [{id: x, kind: badge, title: X, description: d, rule: "run.turnz > 1"}]`, nil},
+		{"not a bool", `achievements: [{id: x, kind: badge, title: X, description: d, rule: "run.turns + 1"}]`, nil},
+		{"syntax error", `achievements: [{id: x, kind: badge, title: X, description: d, rule: "run.victory &&"}]`, nil},
+		{"bad structure", `achievements: [{id: x, kind: trophy, title: X, rule: run.victory}]`, nil},
+	}
+	var builtIn []string
+	for _, a := range catalog.DefaultAchievements().Achievements {
+		builtIn = append(builtIn, a.ID)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lair := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon",
+				"metadata": map[string]interface{}{
+					"name": "lair", "namespace": "default",
+					"labels": map[string]interface{}{"krombat.io/owner": "alice"},
+				},
+				"spec": map[string]interface{}{"monsters": int64(1), "difficulty": "normal", "heroClass": "warrior"},
+			}}
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), configMap("krombat-achievements", "achievements.yaml", tt.yaml), lair)
+			h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /api/v1/achievements", h.ListAchievements)
+			mux.HandleFunc("POST /api/v1/profile/cert", h.AwardCert)
+			srv := h.AuthMiddleware(mux)
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/achievements", nil))
+			var got struct {
+				Achievements []struct{ ID string }
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("GET /achievements = %s: %v", rec.Body.String(), err)
+			}
+			var ids []string
+			for _, a := range got.Achievements {
+				ids = append(ids, a.ID)
+			}
+			want := tt.wantIDs
+			if want == nil {
+				want = builtIn
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Errorf("GET /achievements = %v, want %v", ids, want)
+			}
+
+			// Only certificates without a rule can be awarded by the client.
+			award := func(cert string) int {
+				req := httptest.NewRequest("POST", "/api/v1/profile/cert", strings.NewReader(`{"cert":"`+cert+`","namespace":"default","name":"lair"}`))
+				req.Header.Set("X-Test-User", "alice")
+				rec := httptest.NewRecorder()
+				srv.ServeHTTP(rec, req)
+				return rec.Code
+			}
+			clientCert := "log-explorer"
+			if tt.wantIDs != nil {
+				clientCert = "kro-docs"
+			}
+			if code := award(clientCert); code != http.StatusOK {
+				t.Errorf("award %s = %d, want 200", clientCert, code)
+			}
+			if code := award("cel-state"); code != http.StatusBadRequest {
+				t.Errorf("award cel-state = %d, want 400", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,638 @@
+package handlers
+
+// Admin / moderation API — operator actions without kubectl.
+//
+// Admins are configured by allowlist:
+//   KROMBAT_ADMIN_LOGINS — comma-separated GitHub logins
+//   KROMBAT_ADMIN_ORGS   — comma-separated GitHub orgs; members are admins.
+//                          Org membership is captured at sign-in, so removal
+//                          from an org takes effect within sessionTTL.
+//
+// API tokens act as admin only when they carry the "admin" scope AND the
+// token owner's login is on KROMBAT_ADMIN_LOGINS (tokens carry no org data).
+//
+// Every mutating admin action is audited twice: a structured "admin_action"
+// log line and an entry in the krombat-admin-audit ConfigMap (bounded, oldest
+// dropped first), readable via GET /api/v1/admin/audit.
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+const (
+	adminAuditCMName     = "krombat-admin-audit"
+	adminAuditMaxEntries = 200 // keeps the ConfigMap well under the 1MiB object limit
+)
+
+var adminActions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_rpg_admin_actions_total",
+	Help: "Admin moderation actions by action and result",
+}, []string{"action", "result"})
+
+// parseAllowlist splits a comma-separated env value into a lower-cased set.
+func parseAllowlist(v string) map[string]bool {
+	set := map[string]bool{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
+
+var (
+	adminLogins = parseAllowlist(os.Getenv("KROMBAT_ADMIN_LOGINS"))
+	adminOrgs   = parseAllowlist(os.Getenv("KROMBAT_ADMIN_ORGS"))
+)
+
+// isAdmin reports whether sess is on the admin allowlist.
+func isAdmin(sess *Session) bool {
+	if sess == nil {
+		return false
+	}
+	if sess.TokenID != "" {
+		return sess.HasScope(ScopeAdmin) && adminLogins[strings.ToLower(sess.Login)]
+	}
+	if adminLogins[strings.ToLower(sess.Login)] {
+		return true
+	}
+	for _, org := range sess.Orgs {
+		if adminOrgs[strings.ToLower(org)] {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchAdminOrgs returns the signed-in user's GitHub orgs that appear on the
+// admin org allowlist. Returns nil when no org allowlist is configured or the
+// lookup fails — login still succeeds, just without org-based admin.
+func fetchAdminOrgs(ctx context.Context, accessToken string) []string {
+	if len(adminOrgs) == 0 {
+		return nil
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user/orgs?per_page=100", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Warn("github org fetch failed", "component", "auth", "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		slog.Warn("github org fetch failed", "component", "auth", "status", resp.StatusCode)
+		return nil
+	}
+	var orgs []struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&orgs); err != nil {
+		slog.Warn("github org decode failed", "component", "auth", "error", err)
+		return nil
+	}
+	// Only keep allowlisted orgs — the cookie stays small and reveals nothing extra.
+	matched := []string{}
+	for _, o := range orgs {
+		if adminOrgs[strings.ToLower(o.Login)] {
+			matched = append(matched, o.Login)
+		}
+	}
+	return matched
+}
+
+// requireAdmin writes 401/403 and returns nil unless the caller is an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request) *Session {
+	sess := sessionFromCtx(r.Context())
+	if sess == nil {
+		writeError(w, "authentication required", http.StatusUnauthorized)
+		return nil
+	}
+	if !isAdmin(sess) {
+		slog.Warn("admin access denied", "component", "admin", "login", sess.Login, "path", r.URL.Path)
+		writeError(w, "forbidden: admin only", http.StatusForbidden)
+		return nil
+	}
+	return sess
+}
+
+// AdminAuditEntry is one recorded admin action.
+type AdminAuditEntry struct {
+	Admin     string      `json:"admin"`
+	Action    string      `json:"action"`
+	Target    string      `json:"target"`
+	Detail    interface{} `json:"detail,omitempty"`
+	Result    string      `json:"result"`
+	RequestID string      `json:"requestId,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// audit records an admin action. The ConfigMap write is best-effort — the log
+// line is always emitted so CloudWatch keeps a copy even if the write fails.
+func (h *Handler) audit(r *http.Request, admin *Session, action, target string, detail interface{}, actionErr error) {
+	result := "ok"
+	if actionErr != nil {
+		result = "error"
+	}
+	adminActions.WithLabelValues(action, result).Inc()
+	entry := AdminAuditEntry{
+		Admin:     admin.Login,
+		Action:    action,
+		Target:    target,
+		Detail:    detail,
+		Result:    result,
+		RequestID: requestIDFromCtx(r),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	slog.Info("admin_action",
+		"component", "admin",
+		"admin", entry.Admin,
+		"action", action,
+		"target", target,
+		"result", result,
+		"token_id", admin.TokenID,
+	)
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	key := time.Now().UTC().Format("20060102-150405.000000000") + "-" + action
+	ctx := context.Background()
+	cmClient := h.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace)
+	existing, err := cmClient.Get(ctx, adminAuditCMName, metav1.GetOptions{})
+	if err != nil {
+		newCM := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      adminAuditCMName,
+				"namespace": leaderboardNamespace,
+			},
+			"data": map[string]interface{}{key: string(entryJSON)},
+		}}
+		if _, createErr := cmClient.Create(ctx, newCM, metav1.CreateOptions{}); createErr != nil {
+			slog.Warn("admin audit: failed to create ConfigMap", "component", "admin", "error", createErr)
+		}
+		return
+	}
+	data, _ := existing.Object["data"].(map[string]interface{})
+	patchData := map[string]interface{}{key: string(entryJSON)}
+	// Enforce max entries: drop the oldest keys (timestamp-prefixed, so sortable).
+	if over := len(data) + 1 - adminAuditMaxEntries; over > 0 {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys[:over] {
+			patchData[k] = nil
+		}
+	}
+	patchJSON, _ := json.Marshal(map[string]interface{}{"data": patchData})
+	if _, patchErr := cmClient.Patch(ctx, adminAuditCMName, types.MergePatchType, patchJSON, metav1.PatchOptions{}); patchErr != nil {
+		slog.Warn("admin audit: failed to patch ConfigMap", "component", "admin", "error", patchErr)
+	}
+}
+
+// patchConfigMapKey sets (or with value == nil, deletes) a single data key.
+func (h *Handler) patchConfigMapKey(ctx context.Context, cmName, key string, value interface{}) error {
+	patchJSON, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{key: value}})
+	_, err := h.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Patch(
+		ctx, cmName, types.MergePatchType, patchJSON, metav1.PatchOptions{})
+	return err
+}
+
+// configMapData returns a ConfigMap's data, or an empty map if it is missing.
+func (h *Handler) configMapData(ctx context.Context, cmName string) map[string]interface{} {
+	cm, err := h.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Get(ctx, cmName, metav1.GetOptions{})
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	data, _ := cm.Object["data"].(map[string]interface{})
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// ---- dungeons ---------------------------------------------------------------
+
+// AdminDungeonSummary is one row of the cluster-wide dungeon list.
+type AdminDungeonSummary struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Owner      string `json:"owner"`
+	HeroClass  string `json:"heroClass"`
+	Difficulty string `json:"difficulty"`
+	Victory    bool   `json:"victory"`
+	Defeat     bool   `json:"defeat"`
+	Turns      int64  `json:"turns"`
+	Created    string `json:"created"`
+	Deleting   bool   `json:"deleting"`
+}
+
+// AdminListDungeons lists every dungeon in the cluster, optionally filtered by owner.
+// GET /api/v1/admin/dungeons?owner=<login>
+func (h *Handler) AdminListDungeons(w http.ResponseWriter, r *http.Request) {
+	if requireAdmin(w, r) == nil {
+		return
+	}
+	listOpts := metav1.ListOptions{}
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		if !validDNSLabel.MatchString(strings.ToLower(owner)) {
+			writeError(w, "invalid owner", http.StatusBadRequest)
+			return
+		}
+		listOpts.LabelSelector = "krombat.io/owner=" + owner
+	}
+	list, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace("").List(r.Context(), listOpts)
+	if err != nil {
+		slog.Error("admin: failed to list dungeons", "component", "admin", "error", err)
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	items := []AdminDungeonSummary{}
+	for i := range list.Items {
+		d, err := model.FromUnstructured(&list.Items[i])
+		if err != nil {
+			slog.Warn("admin: skipping undecodable dungeon", "component", "admin", "error", err)
+			continue
+		}
+		items = append(items, AdminDungeonSummary{
+			Name:       d.Name,
+			Namespace:  d.Namespace,
+			Owner:      d.Labels["krombat.io/owner"],
+			HeroClass:  d.Spec.HeroClass,
+			Difficulty: d.Spec.Difficulty,
+			Victory:    d.Status.Victory,
+			Defeat:     d.Status.Defeat,
+			Turns:      d.TotalTurns(),
+			Created:    d.CreationTimestamp.UTC().Format(time.RFC3339),
+			Deleting:   d.DeletionTimestamp != nil,
+		})
+	}
+	writeJSON(w, items)
+}
+
+// AdminDeleteDungeon force-deletes any user's dungeon. Unlike DeleteDungeon it
+// records nothing to the leaderboard or profile. With ?force=true, finalizers
+// are cleared first so a dungeon stuck in Terminating is removed.
+// DELETE /api/v1/admin/dungeons/{namespace}/{name}
+func (h *Handler) AdminDeleteDungeon(w http.ResponseWriter, r *http.Request) {
+	admin := requireAdmin(w, r)
+	if admin == nil {
+		return
+	}
+	ns, name := r.PathValue("namespace"), r.PathValue("name")
+	if !validDNSLabel.MatchString(ns) || !validDNSLabel.MatchString(name) {
+		writeError(w, "invalid namespace or name", http.StatusBadRequest)
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+	ctx := r.Context()
+	dungeons := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns)
+	target := ns + "/" + name
+	detail := map[string]interface{}{"force": force}
+	if d, err := dungeons.Get(ctx, name, metav1.GetOptions{}); err == nil {
+		detail["owner"] = d.GetLabels()["krombat.io/owner"]
+	}
+
+	var err error
+	if force {
+		patchJSON := []byte(`{"metadata":{"finalizers":null}}`)
+		if _, err = dungeons.Patch(ctx, name, types.MergePatchType, patchJSON, metav1.PatchOptions{}); err != nil && !isClientError(err) {
+			h.audit(r, admin, "dungeon.delete", target, detail, err)
+			writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+			return
+		}
+	}
+	err = retryK8s(3, func() error {
+		return dungeons.Delete(ctx, name, metav1.DeleteOptions{})
+	})
+	if apierrors.IsNotFound(err) && force {
+		err = nil // finalizer removal already let it go
+	}
+	h.audit(r, admin, "dungeon.delete", target, detail, err)
+	if apierrors.IsNotFound(err) {
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("admin: failed to delete dungeon", "component", "admin", "dungeon", target, "error", err)
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---- leaderboard ------------------------------------------------------------
+
+// AdminLeaderboardEntry pairs an entry with its ConfigMap key (needed to edit it).
+type AdminLeaderboardEntry struct {
+	Key string `json:"key"`
+	LeaderboardEntry
+}
+
+// AdminListLeaderboard returns every stored leaderboard entry (unfiltered), newest first.
+// GET /api/v1/admin/leaderboard
+func (h *Handler) AdminListLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if requireAdmin(w, r) == nil {
+		return
+	}
+	data := h.configMapData(r.Context(), leaderboardCMName)
+	entries := make([]AdminLeaderboardEntry, 0, len(data))
+	for k, v := range data {
+		raw, _ := v.(string)
+		var e LeaderboardEntry
+		if json.Unmarshal([]byte(raw), &e) != nil {
+			continue
+		}
+		entries = append(entries, AdminLeaderboardEntry{Key: k, LeaderboardEntry: e})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key > entries[j].Key })
+	writeJSON(w, entries)
+}
+
+// AdminUpdateLeaderboardEntry replaces a leaderboard entry.
+// PUT /api/v1/admin/leaderboard/{key}  Body: LeaderboardEntry
+func (h *Handler) AdminUpdateLeaderboardEntry(w http.ResponseWriter, r *http.Request) {
+	admin := requireAdmin(w, r)
+	if admin == nil {
+		return
+	}
+	key := r.PathValue("key")
+	ctx := r.Context()
+	before, ok := h.configMapData(ctx, leaderboardCMName)[key].(string)
+	if !ok {
+		writeError(w, "leaderboard entry not found", http.StatusNotFound)
+		return
+	}
+	var e LeaderboardEntry
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+		return
+	}
+	if e.DungeonName == "" || e.Outcome == "" || e.TotalTurns < 0 {
+		writeError(w, "dungeonName and outcome are required; totalTurns must be >= 0", http.StatusBadRequest)
+		return
+	}
+	entryJSON, _ := json.Marshal(e)
+	err := h.patchConfigMapKey(ctx, leaderboardCMName, key, string(entryJSON))
+	h.audit(r, admin, "leaderboard.update", key, map[string]interface{}{"before": json.RawMessage(before), "after": e}, err)
+	if err != nil {
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, AdminLeaderboardEntry{Key: key, LeaderboardEntry: e})
+}
+
+// AdminDeleteLeaderboardEntry removes a leaderboard entry (e.g. a cheated run).
+// DELETE /api/v1/admin/leaderboard/{key}
+func (h *Handler) AdminDeleteLeaderboardEntry(w http.ResponseWriter, r *http.Request) {
+	admin := requireAdmin(w, r)
+	if admin == nil {
+		return
+	}
+	key := r.PathValue("key")
+	ctx := r.Context()
+	before, ok := h.configMapData(ctx, leaderboardCMName)[key].(string)
+	if !ok {
+		writeError(w, "leaderboard entry not found", http.StatusNotFound)
+		return
+	}
+	err := h.patchConfigMapKey(ctx, leaderboardCMName, key, nil)
+	h.audit(r, admin, "leaderboard.delete", key, map[string]interface{}{"before": json.RawMessage(before)}, err)
+	if err != nil {
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---- profiles ---------------------------------------------------------------
+
+// AdminGetProfile returns any user's profile.
+// GET /api/v1/admin/profiles/{login}
+func (h *Handler) AdminGetProfile(w http.ResponseWriter, r *http.Request) {
+	if requireAdmin(w, r) == nil {
+		return
+	}
+	login := r.PathValue("login")
+	data := h.configMapData(r.Context(), profileCMName)
+	if _, ok := data[login]; !ok {
+		writeError(w, "profile not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, profileFromData(data, login))
+}
+
+// AdminUpdateProfile replaces a user's profile. Level is recomputed from XP so
+// the two can never disagree.
+// PUT /api/v1/admin/profiles/{login}  Body: UserProfile
+func (h *Handler) AdminUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	admin := requireAdmin(w, r)
+	if admin == nil {
+		return
+	}
+	login := r.PathValue("login")
+	ctx := r.Context()
+	before, ok := h.configMapData(ctx, profileCMName)[login].(string)
+	if !ok {
+		writeError(w, "profile not found", http.StatusNotFound)
+		return
+	}
+	p := emptyProfile()
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+		return
+	}
+	if p.XP < 0 || p.DungeonsPlayed < 0 || p.DungeonsWon < 0 || p.DungeonsLost < 0 {
+		writeError(w, "counters must be >= 0", http.StatusBadRequest)
+		return
+	}
+	if p.EarnedBadges == nil {
+		p.EarnedBadges = []string{}
+	}
+	if p.BadgeCounts == nil {
+		p.BadgeCounts = map[string]int{}
+	}
+	if p.KroCertificates == nil {
+		p.KroCertificates = []string{}
+	}
+	p.Level = computeLevel(p.XP)
+	profileJSON, _ := json.Marshal(p)
+	err := h.patchConfigMapKey(ctx, profileCMName, login, string(profileJSON))
+	h.audit(r, admin, "profile.update", login, map[string]interface{}{"before": json.RawMessage(before), "after": p}, err)
+	if err != nil {
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, p)
+}
+
+// AdminResetProfile deletes a user's profile; their next run starts fresh.
+// DELETE /api/v1/admin/profiles/{login}
+func (h *Handler) AdminResetProfile(w http.ResponseWriter, r *http.Request) {
+	admin := requireAdmin(w, r)
+	if admin == nil {
+		return
+	}
+	login := r.PathValue("login")
+	ctx := r.Context()
+	before, ok := h.configMapData(ctx, profileCMName)[login].(string)
+	if !ok {
+		writeError(w, "profile not found", http.StatusNotFound)
+		return
+	}
+	err := h.patchConfigMapKey(ctx, profileCMName, login, nil)
+	h.audit(r, admin, "profile.reset", login, map[string]interface{}{"before": json.RawMessage(before)}, err)
+	if err != nil {
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---- summary / audit --------------------------------------------------------
+
+// AdminSummary is the cluster-wide overview.
+type AdminSummary struct {
+	Dungeons           int            `json:"dungeons"`
+	DungeonsActive     int            `json:"dungeonsActive"`
+	DungeonsVictory    int            `json:"dungeonsVictory"`
+	DungeonsDefeat     int            `json:"dungeonsDefeat"`
+	DungeonsDeleting   int            `json:"dungeonsDeleting"`
+	ByDifficulty       map[string]int `json:"byDifficulty"`
+	ByHeroClass        map[string]int `json:"byHeroClass"`
+	Owners             int            `json:"owners"`
+	TopOwners          []OwnerCount   `json:"topOwners"`
+	LeaderboardEntries int            `json:"leaderboardEntries"`
+	Profiles           int            `json:"profiles"`
+	GeneratedAt        string         `json:"generatedAt"`
+}
+
+// OwnerCount is a login and how many dungeons it currently owns.
+type OwnerCount struct {
+	Login    string `json:"login"`
+	Dungeons int    `json:"dungeons"`
+}
+
+// AdminGetSummary returns a cluster-wide summary of dungeons and stored records.
+// GET /api/v1/admin/summary
+func (h *Handler) AdminGetSummary(w http.ResponseWriter, r *http.Request) {
+	if requireAdmin(w, r) == nil {
+		return
+	}
+	ctx := r.Context()
+	list, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Error("admin: failed to list dungeons", "component", "admin", "error", err)
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+	s := AdminSummary{
+		ByDifficulty: map[string]int{},
+		ByHeroClass:  map[string]int{},
+		TopOwners:    []OwnerCount{},
+	}
+	owners := map[string]int{}
+	for i := range list.Items {
+		s.Dungeons++
+		if list.Items[i].GetDeletionTimestamp() != nil {
+			s.DungeonsDeleting++
+			continue
+		}
+		d, err := model.FromUnstructured(&list.Items[i])
+		if err != nil {
+			slog.Warn("admin: skipping undecodable dungeon", "component", "admin", "error", err)
+			continue
+		}
+		switch {
+		case d.Status.Victory && d.InFinalRoom():
+			s.DungeonsVictory++
+		case d.Status.Defeat:
+			s.DungeonsDefeat++
+		default:
+			s.DungeonsActive++
+		}
+		s.ByDifficulty[cmp.Or(d.Spec.Difficulty, "unknown")]++
+		s.ByHeroClass[cmp.Or(d.Spec.HeroClass, "unknown")]++
+		owners[d.Labels["krombat.io/owner"]]++
+	}
+	s.Owners = len(owners)
+	for login, n := range owners {
+		s.TopOwners = append(s.TopOwners, OwnerCount{Login: login, Dungeons: n})
+	}
+	sort.Slice(s.TopOwners, func(i, j int) bool {
+		if s.TopOwners[i].Dungeons != s.TopOwners[j].Dungeons {
+			return s.TopOwners[i].Dungeons > s.TopOwners[j].Dungeons
+		}
+		return s.TopOwners[i].Login < s.TopOwners[j].Login
+	})
+	if len(s.TopOwners) > 10 {
+		s.TopOwners = s.TopOwners[:10]
+	}
+	s.LeaderboardEntries = len(h.configMapData(ctx, leaderboardCMName))
+	s.Profiles = len(h.configMapData(ctx, profileCMName))
+	s.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	writeJSON(w, s)
+}
+
+// AdminGetAudit returns the most recent admin actions, newest first.
+// GET /api/v1/admin/audit?limit=100
+func (h *Handler) AdminGetAudit(w http.ResponseWriter, r *http.Request) {
+	if requireAdmin(w, r) == nil {
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if _, err := fmt.Sscanf(v, "%d", &limit); err != nil || limit < 1 || limit > adminAuditMaxEntries {
+			writeError(w, fmt.Sprintf("limit must be 1-%d", adminAuditMaxEntries), http.StatusBadRequest)
+			return
+		}
+	}
+	data := h.configMapData(r.Context(), adminAuditCMName)
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	entries := make([]AdminAuditEntry, 0, len(keys))
+	for _, k := range keys {
+		raw, _ := data[k].(string)
+		var e AdminAuditEntry
+		if json.Unmarshal([]byte(raw), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	writeJSON(w, entries)
+}
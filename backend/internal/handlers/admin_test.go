@@ -0,0 +1,169 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+func newAdminServer(t *testing.T, objs ...runtime.Object) (http.Handler, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+	t.Cleanup(handlers.SetAdminAllowlist("alice", "Ops"))
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objs...)
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	mux := http.NewServeMux()
+	admin := handlers.ScopeAdmin
+	mux.HandleFunc("GET /api/v1/admin/audit", handlers.RequireScope(admin, h.AdminGetAudit))
+	mux.HandleFunc("DELETE /api/v1/admin/dungeons/{namespace}/{name}", handlers.RequireScope(admin, h.AdminDeleteDungeon))
+	mux.HandleFunc("PUT /api/v1/admin/profiles/{login}", handlers.RequireScope(admin, h.AdminUpdateProfile))
+	return h.AuthMiddleware(mux), client
+}
+
+func adminRequest(srv http.Handler, method, path, body, cookie, bearer string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "krombat_session", Value: cookie})
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminAllowlist(t *testing.T) {
+	tokens := configMap("krombat-api-tokens", "a1a1a1a1a1a1", tokenRecord("a1a1a1a1a1a1", "alice", "kpat_a1a1a1a1a1a1_x", "2099-01-01T00:00:00Z", "admin"))
+	data := tokens.Object["data"].(map[string]interface{})
+	data["a2a2a2a2a2a2"] = tokenRecord("a2a2a2a2a2a2", "alice", "kpat_a2a2a2a2a2a2_x", "2099-01-01T00:00:00Z", "play")
+	data["d1d1d1d1d1d1"] = tokenRecord("d1d1d1d1d1d1", "dave", "kpat_d1d1d1d1d1d1_x", "2099-01-01T00:00:00Z", "admin")
+	srv, _ := newAdminServer(t, tokens)
+
+	tests := []struct {
+		name, cookie, bearer string
+		want                 int
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized},
+		{"not listed", handlers.SessionCookie("bob", nil), "", http.StatusForbidden},
+		{"listed login", handlers.SessionCookie("Alice", nil), "", http.StatusOK},
+		{"listed org", handlers.SessionCookie("dave", []string{"ops"}), "", http.StatusOK},
+		{"other org", handlers.SessionCookie("dave", []string{"devs"}), "", http.StatusForbidden},
+		{"admin token, listed login", "", "kpat_a1a1a1a1a1a1_x", http.StatusOK},
+		{"play token, listed login", "", "kpat_a2a2a2a2a2a2_x", http.StatusForbidden},
+		// Tokens carry no org data, so org admins cannot act through one.
+		{"admin token, org member", "", "kpat_d1d1d1d1d1d1_x", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := adminRequest(srv, "GET", "/api/v1/admin/audit", "", tt.cookie, tt.bearer); rec.Code != tt.want {
+				t.Errorf("GET /admin/audit = %d %s, want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	profiles := configMap("krombat-profiles", "bob", `{"xp":10,"level":1}`)
+	audit := configMap("krombat-admin-audit", "00000000-000000.000000000-seed", `{"admin":"old","action":"seed"}`)
+	for i := 1; i < 200; i++ {
+		audit.Object["data"].(map[string]interface{})[fmt.Sprintf("00000000-000000.%09d-seed", i)] = `{"admin":"old","action":"seed"}`
+	}
+	srv, client := newAdminServer(t, profiles, audit)
+	client.PrependReactor("delete", "dungeons", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		if a.(k8stesting.DeleteAction).GetName() == "locked" {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "dungeons"}, "locked", fmt.Errorf("denied"))
+		}
+		return false, nil, nil
+	})
+	alice := handlers.SessionCookie("alice", nil)
+
+	if rec := adminRequest(srv, "PUT", "/api/v1/admin/profiles/bob", `{"xp":250}`, alice, ""); rec.Code != http.StatusOK {
+		t.Fatalf("PUT profile = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := adminRequest(srv, "DELETE", "/api/v1/admin/dungeons/default/missing", "", alice, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete missing dungeon = %d, want 404", rec.Code)
+	}
+	if rec := adminRequest(srv, "DELETE", "/api/v1/admin/dungeons/default/locked", "", alice, ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("delete with a non-404 error = %d, want 500", rec.Code)
+	}
+
+	rec := adminRequest(srv, "GET", "/api/v1/admin/audit?limit=5", "", alice, "")
+	var entries []struct {
+		Admin, Action, Target, Result string
+		Detail                        struct{ Before, After struct{ XP, Level int } }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("GET /admin/audit = %s: %v", rec.Body.String(), err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Action+" "+e.Target+" "+e.Result)
+	}
+	want := []string{"dungeon.delete default/locked error", "dungeon.delete default/missing error", "profile.update bob ok", "seed  ", "seed  "}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("audit = %q, want %q", got, want)
+	}
+	if d := entries[2].Detail; entries[2].Admin != "alice" || d.Before.XP != 10 || d.After.XP != 250 || d.After.Level < 2 {
+		t.Errorf("profile.update entry = %+v, want alice with before xp 10 and after xp 250 at a recomputed level", entries[2])
+	}
+
+	// The ConfigMap stays at its bound, oldest entries dropped first.
+	obj, err := client.Tracker().Get(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "rpg-system", "krombat-admin-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _, _ := unstructured.NestedStringMap(obj.(*unstructured.Unstructured).Object, "data")
+	if _, ok := kept["00000000-000000.000000000-seed"]; len(kept) != 200 || ok {
+		t.Errorf("audit ConfigMap holds %d entries (oldest kept: %v), want 200 with the oldest dropped", len(kept), ok)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestFetchAdminOrgs(t *testing.T) {
+	t.Cleanup(handlers.SetAdminAllowlist("", "ops,infra"))
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   []string
+	}{
+		{"members", http.StatusOK, `[{"login":"Ops"},{"login":"devs"},{"login":"infra"}]`, []string{"Ops", "infra"}},
+		{"none allowlisted", http.StatusOK, `[{"login":"devs"}]`, []string{}},
+		{"bad credentials", http.StatusUnauthorized, `{"message":"Bad credentials"}`, nil},
+		// An error status is refused even when its body would decode.
+		{"error status", http.StatusBadGateway, `[{"login":"ops"}]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := http.DefaultClient.Transport
+			t.Cleanup(func() { http.DefaultClient.Transport = prev })
+			http.DefaultClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				if r.Header.Get("Authorization") != "Bearer gho_test" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				return &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body)), Header: http.Header{}}, nil
+			})
+			if got := handlers.FetchAdminOrgs(t.Context(), "gho_test"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fetchAdminOrgs = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
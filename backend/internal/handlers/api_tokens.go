@@ -0,0 +1,450 @@
+package handlers
+
+// Personal API tokens — scoped bearer credentials for scripts, CI and bots.
+//
+// Token format (shown to the user exactly once, at creation):
+//
+//	kpat_<id>_<secret>
+//
+// Only the SHA-256 of the full token is stored, in the krombat-api-tokens
+// ConfigMap in rpg-system (one data key per token ID). The ID embedded in the
+// token makes lookup O(1) without ever storing the secret.
+//
+// Scopes:
+//   read  — GET endpoints (dungeons, leaderboard, profile, event stream)
+//   play  — read + create/delete dungeons, attacks, actions, CEL eval
+//   admin — play + operator endpoints (only effective for admin logins)
+//
+// Browser sessions carry no scopes and are treated as fully privileged for
+// their login. Token management itself requires a browser session — a token
+// can never mint or revoke tokens.
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopePlay  = "play"
+	ScopeAdmin = "admin"
+)
+
+const (
+	apiTokensCMName      = "krombat-api-tokens"
+	apiTokenPrefix       = "kpat_"
+	apiTokenMaxPerUser   = 10
+	apiTokenDefaultDays  = 90
+	apiTokenMaxDays      = 365
+	apiTokenMaxNameLen   = 64
+	apiTokenCacheTTL     = 15 * time.Second // bounds how long a revoked token stays valid on other replicas
+	apiTokenWriteRetries = 5
+	apiTokenAuthHeader   = "Authorization"
+	apiTokenBearerPrefix = "Bearer "
+)
+
+// scopeRank orders scopes so a higher scope implies every lower one.
+var scopeRank = map[string]int{ScopeRead: 1, ScopePlay: 2, ScopeAdmin: 3}
+
+// APIToken is the stored record for a personal access token. Hash is never
+// returned by the API.
+type APIToken struct {
+	ID        string   `json:"id"`
+	Login     string   `json:"login"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	Hash      string   `json:"hash,omitempty"`
+	CreatedAt string   `json:"createdAt"`
+	ExpiresAt string   `json:"expiresAt"`
+}
+
+// apiTokenStore reads and writes token records in the krombat-api-tokens
+// ConfigMap. Reads are served from a short-lived cache so bearer auth does not
+// cost a Kubernetes GET per request.
+type apiTokenStore struct {
+	client *k8s.Client
+
+	mu        sync.Mutex
+	cached    map[string]interface{}
+	fetchedAt time.Time
+	gen       uint64 // bumped by invalidate so an in-flight read is not cached
+}
+
+// errAPITokenLimit is returned by add when the login already holds
+// apiTokenMaxPerUser tokens.
+var errAPITokenLimit = errors.New("api token limit reached")
+
+func newAPITokenStore(client *k8s.Client) *apiTokenStore {
+	return &apiTokenStore{client: client}
+}
+
+// data returns the token records by ID. Any read error other than a missing
+// ConfigMap is returned and not cached, so one failed read does not reject
+// valid tokens for a whole cache period.
+func (s *apiTokenStore) data(ctx context.Context) (map[string]interface{}, error) {
+	s.mu.Lock()
+	if s.cached != nil && time.Since(s.fetchedAt) < apiTokenCacheTTL {
+		defer s.mu.Unlock()
+		return s.cached, nil
+	}
+	gen := s.gen
+	s.mu.Unlock()
+
+	// The GET runs unlocked so a slow API server does not stall every bearer
+	// request behind it.
+	cm, err := s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Get(ctx, apiTokensCMName, metav1.GetOptions{})
+	var data map[string]interface{}
+	switch {
+	case apierrors.IsNotFound(err):
+		// Missing ConfigMap simply means no tokens have been issued yet.
+		data = map[string]interface{}{}
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", apiTokensCMName, err)
+	default:
+		data, _ = cm.Object["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+	}
+	s.mu.Lock()
+	if s.gen == gen {
+		s.cached, s.fetchedAt = data, time.Now()
+	}
+	s.mu.Unlock()
+	return data, nil
+}
+
+func (s *apiTokenStore) invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.gen++
+	s.mu.Unlock()
+}
+
+func (s *apiTokenStore) get(ctx context.Context, id string) (APIToken, bool, error) {
+	data, err := s.data(ctx)
+	if err != nil {
+		return APIToken{}, false, err
+	}
+	raw, _ := data[id].(string)
+	var t APIToken
+	if raw == "" || json.Unmarshal([]byte(raw), &t) != nil {
+		return APIToken{}, false, nil
+	}
+	return t, true, nil
+}
+
+func (s *apiTokenStore) listFor(ctx context.Context, login string) ([]APIToken, error) {
+	data, err := s.data(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := []APIToken{}
+	for _, v := range data {
+		raw, _ := v.(string)
+		var t APIToken
+		if json.Unmarshal([]byte(raw), &t) != nil || t.Login != login {
+			continue
+		}
+		t.Hash = ""
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt < tokens[j].CreatedAt })
+	return tokens, nil
+}
+
+// add stores rec unless its login already holds limit tokens. The count and
+// the write happen against the same read: the Update carries that read's
+// resourceVersion, so a concurrent create on another replica forces a re-count.
+func (s *apiTokenStore) add(ctx context.Context, rec APIToken, limit int) error {
+	defer s.invalidate()
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	cmClient := s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace)
+	for range apiTokenWriteRetries {
+		cm, getErr := cmClient.Get(ctx, apiTokensCMName, metav1.GetOptions{})
+		if apierrors.IsNotFound(getErr) {
+			newCM := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      apiTokensCMName,
+					"namespace": leaderboardNamespace,
+				},
+				"data": map[string]interface{}{rec.ID: string(b)},
+			}}
+			_, err = cmClient.Create(ctx, newCM, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return err
+		}
+		if getErr != nil {
+			return getErr
+		}
+		data, _ := cm.Object["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		held := 0
+		for _, v := range data {
+			raw, _ := v.(string)
+			var t APIToken
+			if json.Unmarshal([]byte(raw), &t) == nil && t.Login == rec.Login {
+				held++
+			}
+		}
+		if held >= limit {
+			return errAPITokenLimit
+		}
+		data[rec.ID] = string(b)
+		cm.Object["data"] = data
+		_, err = cmClient.Update(ctx, cm, metav1.UpdateOptions{})
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// remove deletes a single data key. A JSON merge patch on one key never
+// clobbers concurrent writes to other tokens.
+func (s *apiTokenStore) remove(ctx context.Context, id string) error {
+	defer s.invalidate()
+	patchJSON, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{id: nil}})
+	_, err := s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Patch(ctx, apiTokensCMName, types.MergePatchType, patchJSON, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticate resolves a presented bearer token to its record. Returns false
+// for malformed, unknown, revoked or expired tokens, and an error when the
+// token store cannot be read.
+func (s *apiTokenStore) authenticate(ctx context.Context, token string) (APIToken, bool, error) {
+	rest, ok := strings.CutPrefix(token, apiTokenPrefix)
+	if !ok {
+		return APIToken{}, false, nil
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok || id == "" {
+		return APIToken{}, false, nil
+	}
+	rec, found, err := s.get(ctx, id)
+	if err != nil || !found {
+		return APIToken{}, false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIToken(token)), []byte(rec.Hash)) != 1 {
+		return APIToken{}, false, nil
+	}
+	if exp, err := time.Parse(time.RFC3339, rec.ExpiresAt); err == nil && time.Now().After(exp) {
+		return APIToken{}, false, nil
+	}
+	return rec, true, nil
+}
+
+// HasScope reports whether the session may call a route requiring scope.
+// Browser sessions (no token) are unrestricted.
+func (s *Session) HasScope(scope string) bool {
+	if s.TokenID == "" {
+		return true
+	}
+	for _, have := range s.Scopes {
+		if scopeRank[have] >= scopeRank[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope rejects token-authenticated requests whose token lacks scope.
+// Anonymous requests pass through — handlers enforce authentication themselves.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sess := sessionFromCtx(r.Context()); sess != nil && !sess.HasScope(scope) {
+			writeError(w, "API token lacks required scope: "+scope, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireBrowserSession writes 401/403 and returns nil unless the request is
+// authenticated with a session cookie (not an API token).
+func requireBrowserSession(w http.ResponseWriter, r *http.Request) *Session {
+	sess := sessionFromCtx(r.Context())
+	if sess == nil {
+		writeError(w, "authentication required", http.StatusUnauthorized)
+		return nil
+	}
+	if sess.TokenID != "" {
+		writeError(w, "API tokens cannot manage API tokens — sign in with a browser session", http.StatusForbidden)
+		return nil
+	}
+	return sess
+}
+
+type CreateAPITokenReq struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+// CreateAPIToken issues a new personal access token for the caller.
+// POST /api/v1/auth/tokens  Body: { "name": "ci", "scopes": ["play"], "expiresInDays": 30 }
+// The plaintext token is returned once in the "token" field and never again.
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	sess := requireBrowserSession(w, r)
+	if sess == nil {
+		return
+	}
+	var req CreateAPITokenReq
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > apiTokenMaxNameLen {
+		writeError(w, fmt.Sprintf("name is required (max %d chars)", apiTokenMaxNameLen), http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeError(w, "at least one scope is required (read, play, admin)", http.StatusBadRequest)
+		return
+	}
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, sc := range req.Scopes {
+		if scopeRank[sc] == 0 {
+			writeError(w, "unknown scope: "+sc, http.StatusBadRequest)
+			return
+		}
+		if !seen[sc] {
+			seen[sc] = true
+			scopes = append(scopes, sc)
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = apiTokenDefaultDays
+	}
+	if days < 1 || days > apiTokenMaxDays {
+		writeError(w, fmt.Sprintf("expiresInDays must be 1-%d", apiTokenMaxDays), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	id, err := randomHex(6)
+	if err != nil {
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	plaintext := apiTokenPrefix + id + "_" + secret
+	now := time.Now().UTC()
+	rec := APIToken{
+		ID:        id,
+		Login:     sess.Login,
+		Name:      req.Name,
+		Scopes:    scopes,
+		Hash:      hashAPIToken(plaintext),
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(time.Duration(days) * 24 * time.Hour).Format(time.RFC3339),
+	}
+	err = h.tokens.add(ctx, rec, apiTokenMaxPerUser)
+	if errors.Is(err, errAPITokenLimit) {
+		writeError(w, fmt.Sprintf("token limit reached: at most %d tokens per user — revoke one first", apiTokenMaxPerUser), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to store api token", "component", "auth", "login", sess.Login, "error", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("api_token_created", "component", "auth", "login", sess.Login, "token_id", id, "scopes", strings.Join(scopes, ","))
+
+	rec.Hash = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		APIToken
+		Token string `json:"token"`
+	}{rec, plaintext})
+}
+
+// ListAPITokens returns the caller's tokens (metadata only, never hashes).
+// GET /api/v1/auth/tokens
+func (h *Handler) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	sess := requireBrowserSession(w, r)
+	if sess == nil {
+		return
+	}
+	tokens, err := h.tokens.listFor(r.Context(), sess.Login)
+	if err != nil {
+		slog.Error("failed to read api tokens", "component", "auth", "login", sess.Login, "error", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeAPIToken deletes one of the caller's tokens.
+// DELETE /api/v1/auth/tokens/{id}
+func (h *Handler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	sess := requireBrowserSession(w, r)
+	if sess == nil {
+		return
+	}
+	id := r.PathValue("id")
+	ctx := r.Context()
+	h.tokens.invalidate()
+	rec, found, err := h.tokens.get(ctx, id)
+	if err != nil {
+		slog.Error("failed to read api tokens", "component", "auth", "login", sess.Login, "error", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !found || rec.Login != sess.Login {
+		writeError(w, "token not found", http.StatusNotFound)
+		return
+	}
+	if err := h.tokens.remove(ctx, id); err != nil {
+		slog.Error("failed to revoke api token", "component", "auth", "login", sess.Login, "token_id", id, "error", err)
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("api_token_revoked", "component", "auth", "login", sess.Login, "token_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,176 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+func tokenRecord(id, login, plaintext, expiresAt string, scopes ...string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	b, _ := json.Marshal(map[string]interface{}{
+		"id": id, "login": login, "name": "seeded", "scopes": scopes,
+		"hash": hex.EncodeToString(sum[:]), "createdAt": "2026-01-01T00:00:00Z", "expiresAt": expiresAt,
+	})
+	return string(b)
+}
+
+func TestAPITokens(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	seeded := configMap("krombat-api-tokens", "b0b0b0b0b0b0", tokenRecord("b0b0b0b0b0b0", "bob", "kpat_b0b0b0b0b0b0_x", "2099-01-01T00:00:00Z", "play"))
+	seeded.Object["data"].(map[string]interface{})["e0e0e0e0e0e0"] = tokenRecord("e0e0e0e0e0e0", "alice", "kpat_e0e0e0e0e0e0_x", "2020-01-01T00:00:00Z", "play")
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), seeded)
+	var failing atomic.Bool
+	client.PrependReactor("get", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failing.Load() {
+			return true, nil, errors.New("etcdserver: request timed out")
+		}
+		return false, nil, nil
+	})
+	newServer := func() http.Handler {
+		h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /api/v1/auth/tokens", h.CreateAPIToken)
+		mux.HandleFunc("GET /api/v1/auth/tokens", h.ListAPITokens)
+		mux.HandleFunc("DELETE /api/v1/auth/tokens/{id}", h.RevokeAPIToken)
+		mux.HandleFunc("GET /api/v1/auth/me", handlers.RequireScope(handlers.ScopeRead, handlers.MeHandler))
+		mux.HandleFunc("POST /api/v1/play", handlers.RequireScope(handlers.ScopePlay, func(w http.ResponseWriter, r *http.Request) {}))
+		return h.AuthMiddleware(mux)
+	}
+	srv := newServer()
+	do := func(srv http.Handler, method, path, body, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		} else {
+			req.Header.Set("X-Test-User", "alice")
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(srv, "POST", "/api/v1/auth/tokens", `{"name":"ci","scopes":["read","read"],"expiresInDays":30}`, "")
+	var created struct {
+		ID, Token, Hash string
+		Scopes          []string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(created.Token, "kpat_"+created.ID+"_") || created.Hash != "" || len(created.Scopes) != 1 {
+		t.Errorf("create = %s, want a kpat_ token, one scope and no hash", rec.Body.String())
+	}
+	for _, body := range []string{`{"name":"","scopes":["read"]}`, `{"name":"x","scopes":[]}`, `{"name":"x","scopes":["root"]}`, `{"name":"x","scopes":["read"],"expiresInDays":400}`} {
+		if rec := do(srv, "POST", "/api/v1/auth/tokens", body, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("create %s = %d, want 400", body, rec.Code)
+		}
+	}
+
+	tests := []struct {
+		name, method, path, bearer string
+		want                       int
+	}{
+		{"read scope", "GET", "/api/v1/auth/me", created.Token, http.StatusOK},
+		{"missing scope", "POST", "/api/v1/play", created.Token, http.StatusForbidden},
+		{"tokens cannot mint tokens", "POST", "/api/v1/auth/tokens", created.Token, http.StatusForbidden},
+		{"wrong secret", "GET", "/api/v1/auth/me", created.Token + "0", http.StatusUnauthorized},
+		{"malformed", "GET", "/api/v1/auth/me", "not-a-token", http.StatusUnauthorized},
+		{"expired", "GET", "/api/v1/auth/me", "kpat_e0e0e0e0e0e0_x", http.StatusUnauthorized},
+		{"seeded play token", "POST", "/api/v1/play", "kpat_b0b0b0b0b0b0_x", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := do(srv, tt.method, tt.path, `{"name":"x","scopes":["read"]}`, tt.bearer); rec.Code != tt.want {
+			t.Errorf("%s: %s %s = %d %s, want %d", tt.name, tt.method, tt.path, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+
+	rec = do(srv, "GET", "/api/v1/auth/tokens", "", "")
+	if strings.Contains(rec.Body.String(), "hash") || !strings.Contains(rec.Body.String(), created.ID) || strings.Contains(rec.Body.String(), "b0b0b0b0b0b0") {
+		t.Errorf("list = %s, want alice's tokens without hashes", rec.Body.String())
+	}
+
+	// A failed store read is a 503 and is not cached.
+	cold := newServer()
+	failing.Store(true)
+	if rec := do(cold, "GET", "/api/v1/auth/me", "", created.Token); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("me while the store fails = %d, want 503", rec.Code)
+	}
+	if rec := do(cold, "GET", "/api/v1/auth/tokens", "", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("list while the store fails = %d, want 500", rec.Code)
+	}
+	failing.Store(false)
+	if rec := do(cold, "GET", "/api/v1/auth/me", "", created.Token); rec.Code != http.StatusOK {
+		t.Errorf("me after the store recovers = %d, want 200", rec.Code)
+	}
+
+	if rec := do(srv, "DELETE", "/api/v1/auth/tokens/b0b0b0b0b0b0", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("revoke bob's token = %d, want 404", rec.Code)
+	}
+	if rec := do(srv, "DELETE", "/api/v1/auth/tokens/"+created.ID, "", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(srv, "GET", "/api/v1/auth/me", "", created.Token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("me with a revoked token = %d, want 401", rec.Code)
+	}
+}
+
+// TestAPITokenLimitRace checks that a token created on another replica between
+// the limit check and the write is counted: the conflicting write is retried
+// against a fresh read and rejected at the limit.
+func TestAPITokenLimitRace(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	seeded := configMap("krombat-api-tokens", "a00000000000", tokenRecord("a00000000000", "alice", "kpat_a00000000000_x", "2099-01-01T00:00:00Z", "read"))
+	data := seeded.Object["data"].(map[string]interface{})
+	for i := 1; i < 9; i++ {
+		id := fmt.Sprintf("a0000000000%d", i)
+		data[id] = tokenRecord(id, "alice", "kpat_"+id+"_x", "2099-01-01T00:00:00Z", "read")
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), seeded)
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	var raced atomic.Bool
+	client.PrependReactor("update", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if raced.Swap(true) {
+			return false, nil, nil
+		}
+		obj, err := client.Tracker().Get(gvr, "rpg-system", "krombat-api-tokens")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cm := obj.(*unstructured.Unstructured)
+		cm.Object["data"].(map[string]interface{})["a00000000009"] = tokenRecord("a00000000009", "alice", "kpat_a00000000009_x", "2099-01-01T00:00:00Z", "read")
+		if err := client.Tracker().Update(gvr, cm, "rpg-system"); err != nil {
+			t.Fatal(err)
+		}
+		return true, nil, apierrors.NewConflict(gvr.GroupResource(), "krombat-api-tokens", errors.New("resourceVersion changed"))
+	})
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	req := httptest.NewRequest("POST", "/api/v1/auth/tokens", strings.NewReader(`{"name":"ci","scopes":["read"]}`))
+	req.Header.Set("X-Test-User", "alice")
+	rec := httptest.NewRecorder()
+	h.AuthMiddleware(http.HandlerFunc(h.CreateAPIToken)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("create at the limit after a racing create = %d %s, want 409", rec.Code, rec.Body.String())
+	}
+	obj, _ := client.Tracker().Get(gvr, "rpg-system", "krombat-api-tokens")
+	if n := len(obj.(*unstructured.Unstructured).Object["data"].(map[string]interface{})); n != 10 {
+		t.Errorf("stored tokens = %d, want 10", n)
+	}
+}
@@ -10,7 +10,8 @@ package handlers
 //      across all pods — no shared in-memory store needed).
 //   3. Backend exchanges code for token, fetches user identity, sets a signed
 //      session cookie "krombat_session" containing login+avatarUrl+expiry,
-//      HMAC-signed with SESSION_SECRET.  Any pod can verify it independently.
+//      HMAC-signed with the current session key (SESSION_SECRET, identified by
+//      SESSION_KEY_ID).  Any pod can verify it independently.
 //   4. Frontend calls GET /api/v1/auth/me  → decodes cookie, returns identity or 401
 //   5. GET /api/v1/auth/logout             → clears cookie
 //
@@ -21,13 +22,13 @@ package handlers
 //   GITHUB_CLIENT_ID      — from krombat-github-oauth Secret
 //   GITHUB_CLIENT_SECRET  — from krombat-github-oauth Secret
 //   SESSION_SECRET        — random ≥32-byte string for HMAC signing
+//   SESSION_KEY_ID        — optional kid for SESSION_SECRET (default "k1")
+//   SESSION_PREVIOUS_KEYS — optional "kid=secret,..." still accepted for verification
 //   GITHUB_CALLBACK_URL   — e.g. https://learn-kro.eks.aws.dev/api/v1/auth/callback
 
 import (
 	"context"
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -49,53 +50,32 @@ const (
 // #429: Jti (JWT ID) is a per-session nonce for future revocation support.
 // When a revocation store is added, Jti values can be blocklisted at logout.
 type sessionPayload struct {
-	Login     string `json:"l"`
-	AvatarURL string `json:"a"`
-	ExpiresAt int64  `json:"e"` // unix seconds
-	Jti       string `json:"j"` // per-session nonce for revocation
-}
+	Login     string   `json:"l"`
+	AvatarURL string   `json:"a"`
+	ExpiresAt int64    `json:"e"`           // unix seconds
+	Jti       string   `json:"j"`           // per-session nonce for revocation
+	Orgs      []string `json:"o,omitempty"` // GitHub orgs — only fetched when KROMBAT_ADMIN_ORGS is set
 
-// sessionSecret returns the HMAC key from SESSION_SECRET env var.
-// main.go validates this is non-empty at startup — this function will only
-// be called after that check passes. The random fallback is intentionally
-// removed: running without a stable secret breaks multi-replica sessions.
-var sessionSecret = func() []byte {
-	s := os.Getenv("SESSION_SECRET")
-	if s == "" {
-		// This should never happen — main.go exits if SESSION_SECRET is absent.
-		// Panic here to make any misconfiguration immediately obvious in tests.
-		panic("auth: SESSION_SECRET is not set — main.go should have exited")
-	}
-	return []byte(s)
-}()
+	kid string // key that verified this token (not serialized)
+}
 
-// signToken encodes payload as JSON, appends an HMAC-SHA256 signature, and
-// returns "<hex-json>.<hex-sig>" — safe for use as a cookie value.
-func signToken(p sessionPayload) (string, error) {
+// signToken encodes payload as JSON and signs it with the current session key,
+// returning "<kid>.<hex-json>.<hex-sig>" — safe for use as a cookie value.
+// See session_keys.go for the key rotation scheme.
+func (kr *sessionKeyring) signToken(p sessionPayload) (string, error) {
 	data, err := json.Marshal(p)
 	if err != nil {
 		return "", err
 	}
-	encoded := hex.EncodeToString(data)
-	mac := hmac.New(sha256.New, sessionSecret)
-	mac.Write([]byte(encoded))
-	sig := hex.EncodeToString(mac.Sum(nil))
-	return encoded + "." + sig, nil
+	return kr.sign(hex.EncodeToString(data)), nil
 }
 
-// verifyToken parses and verifies a token produced by signToken.
-// Returns nil if the token is invalid or expired.
-func verifyToken(token string) *sessionPayload {
-	parts := strings.SplitN(token, ".", 2)
-	if len(parts) != 2 {
-		return nil
-	}
-	encoded, sig := parts[0], parts[1]
-	// Verify HMAC
-	mac := hmac.New(sha256.New, sessionSecret)
-	mac.Write([]byte(encoded))
-	expected := hex.EncodeToString(mac.Sum(nil))
-	if !hmac.Equal([]byte(sig), []byte(expected)) {
+// verifyToken parses and verifies a token produced by signToken (or a
+// pre-rotation token without a kid). Returns nil if the token is invalid or
+// expired. The returned payload records which key verified it.
+func (kr *sessionKeyring) verifyToken(token string) *sessionPayload {
+	encoded, kid, ok := kr.verify(token)
+	if !ok {
 		return nil
 	}
 	// Decode payload
@@ -111,6 +91,7 @@ func verifyToken(token string) *sessionPayload {
 	if time.Now().Unix() > p.ExpiresAt {
 		return nil
 	}
+	p.kid = kid
 	return &p
 }
 
@@ -127,6 +108,9 @@ func randomHex(n int) (string, error) {
 type Session struct {
 	Login     string
 	AvatarURL string
+	Orgs      []string // GitHub org logins captured at sign-in (admin allowlist)
+	TokenID   string   // set when authenticated with a personal API token
+	Scopes    []string // token scopes; empty for browser sessions
 }
 
 // contextKey is used to attach session data to request contexts.
@@ -143,13 +127,15 @@ func sessionFromCtx(ctx context.Context) *Session {
 	return v.(*Session)
 }
 
-// AuthMiddleware decodes the session cookie and injects the Session into the
-// request context.  Always calls next — endpoints that require auth check
-// sessionFromCtx themselves.
+// AuthMiddleware decodes the session cookie (or an "Authorization: Bearer"
+// personal API token) and injects the Session into the request context.
+// Always calls next for cookie/anonymous requests — endpoints that require
+// auth check sessionFromCtx themselves. A presented bearer token that does not
+// verify is rejected with 401 rather than silently treated as anonymous.
 //
 // Test bypass: if KROMBAT_TEST_USER env var is set and the request carries
 // X-Test-User header matching the env value, a synthetic session is injected.
-func AuthMiddleware(next http.Handler) http.Handler {
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	testUser := os.Getenv("KROMBAT_TEST_USER")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Test bypass (only active when KROMBAT_TEST_USER is configured)
@@ -159,10 +145,30 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		// Personal API token
+		if authz := r.Header.Get(apiTokenAuthHeader); strings.HasPrefix(authz, apiTokenBearerPrefix) {
+			tok, ok, err := h.tokens.authenticate(r.Context(), strings.TrimPrefix(authz, apiTokenBearerPrefix))
+			if err != nil {
+				slog.Error("api token lookup failed", "component", "auth", "error", err)
+				writeError(w, "API token store unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if !ok {
+				writeError(w, "invalid or expired API token", http.StatusUnauthorized)
+				return
+			}
+			sess := &Session{Login: tok.Login, TokenID: tok.ID, Scopes: tok.Scopes}
+			r = r.WithContext(context.WithValue(r.Context(), sessionContextKey, sess))
+			next.ServeHTTP(w, r)
+			return
+		}
 		// Normal cookie-based session
 		if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
-			if p := verifyToken(cookie.Value); p != nil {
-				sess := &Session{Login: p.Login, AvatarURL: p.AvatarURL}
+			if p := h.sessions.verifyToken(cookie.Value); p != nil {
+				if p.kid != h.sessions.signingKID {
+					h.reissueSession(w, *p)
+				}
+				sess := &Session{Login: p.Login, AvatarURL: p.AvatarURL, Orgs: p.Orgs}
 				r = r.WithContext(context.WithValue(r.Context(), sessionContextKey, sess))
 			}
 		}
@@ -170,6 +176,31 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// reissueSession re-signs a session that was verified with a retired key so the
+// browser moves onto the current signing key without logging in again. The
+// original expiry is preserved — rotation never extends a session.
+func (h *Handler) reissueSession(w http.ResponseWriter, p sessionPayload) {
+	sessionOldKeyVerifications.WithLabelValues(p.kid).Inc()
+	token, err := h.sessions.signToken(p)
+	if err != nil {
+		return
+	}
+	remaining := p.ExpiresAt - time.Now().Unix()
+	if remaining <= 0 {
+		return
+	}
+	slog.Info("session re-signed with current key", "component", "auth", "login", p.Login, "old_kid", p.kid)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(remaining),
+	})
+}
+
 // LoginHandler sets a short-lived state cookie and redirects to GitHub OAuth.
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	clientID := os.Getenv("GITHUB_CLIENT_ID")
@@ -196,16 +227,21 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	callbackURL := os.Getenv("GITHUB_CALLBACK_URL")
 	// #428: main.go validates GITHUB_CALLBACK_URL is non-empty at startup.
 	// No fallback here — a missing callback URL is a misconfiguration.
+	// read:org is only requested when an org-based admin allowlist is configured.
+	scope := "read:user"
+	if len(adminOrgs) > 0 {
+		scope = "read:user%20read:org"
+	}
 	redirectURL := fmt.Sprintf(
-		"https://github.com/login/oauth/authorize?client_id=%s&redirect_uri=%s&scope=read:user&state=%s",
-		clientID, callbackURL, state,
+		"https://github.com/login/oauth/authorize?client_id=%s&redirect_uri=%s&scope=%s&state=%s",
+		clientID, callbackURL, scope, state,
 	)
 	http.Redirect(w, r, redirectURL, http.StatusFound)
 }
 
 // CallbackHandler verifies the OAuth state cookie, exchanges the code for a
 // token, fetches the GitHub user, and sets a signed session cookie.
-func CallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	stateParam := r.URL.Query().Get("state")
 	code := r.URL.Query().Get("code")
 
@@ -304,8 +340,9 @@ func CallbackHandler(w http.ResponseWriter, r *http.Request) {
 		AvatarURL: ghUser.AvatarURL,
 		ExpiresAt: time.Now().Add(sessionTTL).Unix(),
 		Jti:       jti,
+		Orgs:      fetchAdminOrgs(r.Context(), tokenResp.AccessToken),
 	}
-	token, err := signToken(payload)
+	token, err := h.sessions.signToken(payload)
 	if err != nil {
 		http.Error(w, "session create failed", http.StatusInternalServerError)
 		return
@@ -337,9 +374,10 @@ func MeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"login":     sess.Login,
 		"avatarUrl": sess.AvatarURL,
+		"admin":     isAdmin(sess),
 	})
 }
 
@@ -364,7 +402,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 // going through the full GitHub OAuth flow.
 //
 // Returns 404 when KROMBAT_TEST_USER is not set (disabled in production without the secret).
-func TestLoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) TestLoginHandler(w http.ResponseWriter, r *http.Request) {
 	testUser := os.Getenv("KROMBAT_TEST_USER")
 	if testUser == "" {
 		http.NotFound(w, r)
@@ -381,7 +419,7 @@ func TestLoginHandler(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt: time.Now().Add(sessionTTL).Unix(),
 		Jti:       "test", // test sessions use a fixed jti — not revocable
 	}
-	signed, err := signToken(payload)
+	signed, err := h.sessions.signToken(payload)
 	if err != nil {
 		http.Error(w, "session create failed", http.StatusInternalServerError)
 		return
@@ -0,0 +1,600 @@
+package handlers
+
+// Server-side auto-battle — the backend plays a dungeon for its owner.
+//
+// State lives on the Dungeon CR as annotations so every replica agrees:
+//
+//	krombat.io/auto-battle         "<strategy>:<runID>" while a run is active
+//	krombat.io/auto-battle-stopped why the last run ended (victory, defeat,
+//	                               user-intervention, stopped, error: ...)
+//
+// The goroutine driving a run lives in the pod that accepted the POST. Each
+// turn it re-reads the dungeon, asks the strategy for a move and submits it
+// through processCombat/processAction with the observed seq, so a concurrent
+// manual click either pauses the run (see pauseAutoBattleOnIntervention) or
+// wins the seq race and the runner's turn is rejected with 409 — both stop
+// the run. The settings a run was started with are kept alongside it in
+//
+//	krombat.io/auto-battle-config  {"strategy", "healThreshold", "turnDelayMs"}
+//	krombat.io/auto-battle-lease   "<pod>@<unix seconds>", renewed by the driver
+//
+// so when a driver dies another pod takes the run over once its lease lapses
+// (see ResumeAutoBattles) instead of leaving annotations that nothing drives.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/pnz1990/krombat/backend/internal/autoplay"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"github.com/pnz1990/krombat/backend/internal/ws"
+)
+
+const (
+	autoBattleAnnotation        = "krombat.io/auto-battle"
+	autoBattleStoppedAnnotation = "krombat.io/auto-battle-stopped"
+	autoBattleConfigAnnotation  = "krombat.io/auto-battle-config"
+	autoBattleLeaseAnnotation   = "krombat.io/auto-battle-lease"
+
+	autoBattleDefaultDelay = 1500 * time.Millisecond
+	autoBattleMinDelay     = 500 * time.Millisecond
+	autoBattleMaxDelay     = 10 * time.Second
+	autoBattleMaxTurns     = 300              // hard stop so a stuck run can't loop forever
+	autoBattleMaxIdle      = 20               // consecutive ticks with no legal move or no hero yet (waiting on kro)
+	autoBattleLeaseTTL     = 30 * time.Second // a lease not renewed for this long is up for takeover
+)
+
+var autoBattleTurns = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_rpg_auto_battle_turns_total",
+	Help: "Turns submitted by the auto-battle runner",
+}, []string{"strategy"})
+
+// autoBattleCtxKey marks requests issued by the runner itself so they are not
+// mistaken for user intervention.
+const autoBattleCtxKey contextKey = 2
+
+// autoBattleRuns tracks runs driven by this pod, keyed "ns/name". holder
+// names this pod in the leases it writes.
+type autoBattleRuns struct {
+	holder string
+	mu     sync.Mutex
+	runs   map[string]autoBattleRun
+}
+
+type autoBattleRun struct {
+	value  string // annotation value identifying the run
+	cancel context.CancelFunc
+}
+
+func newAutoBattleRuns() *autoBattleRuns {
+	holder, _ := os.Hostname()
+	return &autoBattleRuns{holder: holder, runs: map[string]autoBattleRun{}}
+}
+
+// lease is the lease annotation value for a run this pod drives, renewed now.
+func (a *autoBattleRuns) lease() string {
+	return a.holder + "@" + strconv.FormatInt(time.Now().Unix(), 10)
+}
+
+// driving reports whether this pod has a live runner for key.
+func (a *autoBattleRuns) driving(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.runs[key]
+	return ok
+}
+
+// parseAutoBattleLease splits a lease annotation. A missing or malformed
+// lease reads as renewed at the zero time, i.e. long expired.
+func parseAutoBattleLease(v string) (holder string, renewed time.Time) {
+	i := strings.LastIndex(v, "@")
+	if i < 0 {
+		return "", time.Time{}
+	}
+	sec, err := strconv.ParseInt(v[i+1:], 10, 64)
+	if err != nil {
+		return "", time.Time{}
+	}
+	return v[:i], time.Unix(sec, 0)
+}
+
+// start registers a run, cancelling any previous run for the same dungeon on this pod.
+func (a *autoBattleRuns) start(key, value string) context.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if prev, ok := a.runs[key]; ok {
+		prev.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.runs[key] = autoBattleRun{value: value, cancel: cancel}
+	return ctx
+}
+
+// stop cancels whichever run this pod is driving for key.
+func (a *autoBattleRuns) stop(key string) {
+	a.release(key, "")
+}
+
+// release cancels the run for key only if it is still the run identified by
+// value ("" matches any) — a finished runner must never cancel its successor.
+func (a *autoBattleRuns) release(key, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if run, ok := a.runs[key]; ok && (value == "" || run.value == value) {
+		run.cancel()
+		delete(a.runs, key)
+	}
+}
+
+// StartAutoBattleReq configures an auto-battle run.
+type StartAutoBattleReq struct {
+	Strategy      string `json:"strategy"`
+	HealThreshold int    `json:"healThreshold"` // percent of max HP; default 40
+	TurnDelayMs   int    `json:"turnDelayMs"`   // pause between turns; default 1500
+}
+
+// AutoBattleStatus is returned by the auto-battle endpoints.
+type AutoBattleStatus struct {
+	Active        bool   `json:"active"`
+	Strategy      string `json:"strategy,omitempty"`
+	StoppedReason string `json:"stoppedReason,omitempty"`
+}
+
+// settings validates req, filling in defaults, and resolves its strategy.
+// The error is a message for the client.
+func (req *StartAutoBattleReq) settings() (autoplay.Strategy, autoplay.Options, time.Duration, error) {
+	strategy, ok := autoplay.Strategies[req.Strategy]
+	if !ok {
+		return nil, autoplay.Options{}, 0, fmt.Errorf("unknown strategy — one of: %s", strings.Join(autoplay.Names(), ", "))
+	}
+	if req.HealThreshold == 0 {
+		req.HealThreshold = autoplay.DefaultHealThreshold
+	}
+	if req.HealThreshold < 1 || req.HealThreshold > 99 {
+		return nil, autoplay.Options{}, 0, fmt.Errorf("healThreshold must be 1-99")
+	}
+	if req.TurnDelayMs == 0 {
+		req.TurnDelayMs = int(autoBattleDefaultDelay.Milliseconds())
+	}
+	delay := time.Duration(req.TurnDelayMs) * time.Millisecond
+	if delay < autoBattleMinDelay || delay > autoBattleMaxDelay {
+		return nil, autoplay.Options{}, 0, fmt.Errorf("turnDelayMs must be %d-%d", autoBattleMinDelay.Milliseconds(), autoBattleMaxDelay.Milliseconds())
+	}
+	return strategy, autoplay.Options{HealThreshold: req.HealThreshold}, delay, nil
+}
+
+func autoBattleStatusOf(obj *unstructured.Unstructured) AutoBattleStatus {
+	ann := obj.GetAnnotations()
+	st := AutoBattleStatus{StoppedReason: ann[autoBattleStoppedAnnotation]}
+	if v := ann[autoBattleAnnotation]; v != "" {
+		st.Active = true
+		st.Strategy, _, _ = strings.Cut(v, ":")
+	}
+	return st
+}
+
+// getOwnedDungeon loads a dungeon and enforces ownership, writing the error response.
+func (h *Handler) getOwnedDungeon(w http.ResponseWriter, r *http.Request, ns, name string) *unstructured.Unstructured {
+	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(r.Context(), name, metav1.GetOptions{})
+	if err != nil {
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
+		return nil
+	}
+	if err := requireDungeonOwner(r, dungeon); err != nil {
+		writeOwnerError(w, err)
+		return nil
+	}
+	return dungeon
+}
+
+// setAutoBattleAnnotations merge-patches the auto-battle annotations. A nil
+// value removes the annotation.
+func (h *Handler) setAutoBattleAnnotations(ctx context.Context, ns, name string, run, stopped, config, lease interface{}) error {
+	patch := map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{
+		autoBattleAnnotation:        run,
+		autoBattleStoppedAnnotation: stopped,
+		autoBattleConfigAnnotation:  config,
+		autoBattleLeaseAnnotation:   lease,
+	}}}
+	return h.patchDungeon(ctx, ns, name, patch)
+}
+
+// GetAutoBattle reports whether auto-battle is running for a dungeon.
+// GET /api/v1/dungeons/{namespace}/{name}/auto-battle
+func (h *Handler) GetAutoBattle(w http.ResponseWriter, r *http.Request) {
+	ns, name := r.PathValue("namespace"), r.PathValue("name")
+	if !validateNamespace(w, ns) {
+		return
+	}
+	dungeon := h.getOwnedDungeon(w, r, ns, name)
+	if dungeon == nil {
+		return
+	}
+	writeJSON(w, autoBattleStatusOf(dungeon))
+}
+
+// StartAutoBattle turns auto-battle on (or switches strategy).
+// POST /api/v1/dungeons/{namespace}/{name}/auto-battle  Body: { "strategy": "heal-at-threshold", "healThreshold": 40 }
+func (h *Handler) StartAutoBattle(w http.ResponseWriter, r *http.Request) {
+	ns, name := r.PathValue("namespace"), r.PathValue("name")
+	if !validateNamespace(w, ns) {
+		return
+	}
+	var req StartAutoBattleReq
+	r.Body = http.MaxBytesReader(w, r.Body, 1024)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+		return
+	}
+	strategy, opts, delay, err := req.settings()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dungeon := h.getOwnedDungeon(w, r, ns, name)
+	if dungeon == nil {
+		return
+	}
+	d, err := model.FromUnstructured(dungeon)
+	if err != nil {
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return
+	}
+	if d.Status.Victory && d.InFinalRoom() {
+		writeCodedError(w, "dungeon already won", http.StatusConflict, CodeGameOver)
+		return
+	}
+	if d.Status.Defeat {
+		writeCodedError(w, "dungeon already lost", http.StatusConflict, CodeGameOver)
+		return
+	}
+
+	runID, err := randomHex(4)
+	if err != nil {
+		writeError(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	runValue := req.Strategy + ":" + runID
+	config, _ := json.Marshal(req)
+	if err := h.setAutoBattleAnnotations(r.Context(), ns, name, runValue, nil, string(config), h.autoBattle.lease()); err != nil {
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return
+	}
+
+	owner := sessionFromCtx(r.Context())
+	runCtx := h.autoBattle.start(ns+"/"+name, runValue)
+	go h.runAutoBattle(runCtx, owner, ns, name, runValue, req.Strategy, strategy, opts, delay)
+
+	slog.Info("auto_battle_started",
+		"component", "game",
+		"dungeon", name,
+		"strategy", req.Strategy,
+		"heal_threshold", req.HealThreshold,
+		"turn_delay_ms", delay.Milliseconds(),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(AutoBattleStatus{Active: true, Strategy: req.Strategy})
+}
+
+// StopAutoBattle turns auto-battle off. Idempotent.
+// DELETE /api/v1/dungeons/{namespace}/{name}/auto-battle
+func (h *Handler) StopAutoBattle(w http.ResponseWriter, r *http.Request) {
+	ns, name := r.PathValue("namespace"), r.PathValue("name")
+	if !validateNamespace(w, ns) {
+		return
+	}
+	dungeon := h.getOwnedDungeon(w, r, ns, name)
+	if dungeon == nil {
+		return
+	}
+	h.autoBattle.stop(ns + "/" + name)
+	if autoBattleStatusOf(dungeon).Active {
+		if err := h.setAutoBattleAnnotations(r.Context(), ns, name, nil, "stopped", nil, nil); err != nil {
+			writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pauseAutoBattleOnIntervention stops an active auto-battle when its owner
+// submits a move by hand. Called from processCombat/processAction with the
+// dungeon they already loaded, so it costs nothing when no run is active.
+func (h *Handler) pauseAutoBattleOnIntervention(ctx context.Context, r *http.Request, dungeon *unstructured.Unstructured) {
+	if r.Context().Value(autoBattleCtxKey) != nil || dungeon.GetAnnotations()[autoBattleAnnotation] == "" {
+		return
+	}
+	ns, name := dungeon.GetNamespace(), dungeon.GetName()
+	h.autoBattle.stop(ns + "/" + name)
+	if err := h.setAutoBattleAnnotations(ctx, ns, name, nil, "user-intervention", nil, nil); err != nil {
+		slog.Warn("auto-battle: failed to clear annotation on intervention", "component", "game", "dungeon", name, "error", err)
+		return
+	}
+	slog.Info("auto_battle_stopped", "component", "game", "dungeon", name, "reason", "user-intervention")
+}
+
+// autoBattleWriter captures a processCombat/processAction response in memory.
+type autoBattleWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *autoBattleWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+func (w *autoBattleWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+func (w *autoBattleWriter) WriteHeader(code int) { w.code = code }
+
+// runAutoBattle drives one run until victory, defeat, intervention or error.
+func (h *Handler) runAutoBattle(ctx context.Context, owner *Session, ns, name, runValue, strategyName string,
+	strategy autoplay.Strategy, opts autoplay.Options, delay time.Duration) {
+	key := ns + "/" + name
+	reason := "stopped"
+	turns, idle := 0, 0
+	renewed := time.Now() // the start or takeover patch wrote the first lease
+	defer func() {
+		h.broadcastAutoBattle(ns, name, "stopped", map[string]interface{}{"strategy": strategyName, "reason": reason, "turns": turns})
+		slog.Info("auto_battle_stopped", "component", "game", "dungeon", name, "strategy", strategyName, "reason", reason, "turns", turns)
+	}()
+	// Requests issued on the owner's behalf — ownership checks still apply.
+	reqCtx := context.WithValue(context.WithValue(context.Background(), sessionContextKey, owner), autoBattleCtxKey, true)
+
+	for turns < autoBattleMaxTurns {
+		select {
+		case <-ctx.Done():
+			return // superseded by a new run or stopped via DELETE on this pod
+		case <-time.After(delay):
+		}
+		dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			reason = "dungeon gone"
+			h.autoBattle.release(key, runValue)
+			return
+		}
+		current := dungeon.GetAnnotations()[autoBattleAnnotation]
+		if current != runValue {
+			// Stopped elsewhere (DELETE, intervention, or a newer run on another pod).
+			reason = "superseded"
+			if current == "" {
+				reason = dungeon.GetAnnotations()[autoBattleStoppedAnnotation]
+			}
+			h.autoBattle.release(key, runValue)
+			return
+		}
+		if time.Since(renewed) >= autoBattleLeaseTTL/3 {
+			if err := h.renewAutoBattleLease(ctx, ns, name, runValue); err != nil {
+				if isClientError(err) {
+					continue // the run changed under us; the next read sees how
+				}
+				slog.Warn("auto-battle: failed to renew lease", "component", "game", "dungeon", name, "error", err)
+			} else {
+				renewed = time.Now()
+			}
+		}
+		d, err := model.FromUnstructured(dungeon)
+		if err != nil {
+			reason = "error: " + err.Error()
+			h.finishAutoBattle(key, ns, name, runValue, reason)
+			return
+		}
+		// status.victory only means this room's boss is down; earlier rooms
+		// carry on through treasure → door → next room.
+		if d.Status.Victory && d.InFinalRoom() {
+			reason = "victory"
+			h.finishAutoBattle(key, ns, name, runValue, reason)
+			return
+		}
+		if d.Status.Defeat || (d.Status.Game.HeroHP <= 0 && d.Status.HeroReady()) {
+			reason = "defeat"
+			h.finishAutoBattle(key, ns, name, runValue, reason)
+			return
+		}
+		// Waiting on kro to finish initialising and finding no legal move
+		// both count against the idle limit.
+		move := ""
+		if d.Status.HeroReady() {
+			move = strategy(autoplay.NewView(d), opts)
+		}
+		if move == "" {
+			if idle++; idle >= autoBattleMaxIdle {
+				reason = "no legal move"
+				if !d.Status.HeroReady() {
+					reason = "hero not ready"
+				}
+				h.finishAutoBattle(key, ns, name, runValue, reason)
+				return
+			}
+			continue
+		}
+		idle = 0
+
+		rec := &autoBattleWriter{}
+		req, _ := http.NewRequestWithContext(reqCtx, http.MethodPost, "/", nil)
+		if isActionTarget(move) {
+			_ = h.processAction(ctx, req, ns, name, move, d.Spec.ActionSeq, rec)
+		} else {
+			target, ability := splitAbilityTarget(move)
+			_ = h.processCombat(ctx, req, ns, name, target, ability, 0, d.Spec.AttackSeq, rec)
+		}
+		if rec.code >= 400 {
+			reason = fmt.Sprintf("error: %s", strings.TrimSpace(rec.body.String()))
+			if rec.code == http.StatusConflict {
+				reason = "user-intervention"
+			}
+			if rec.code == http.StatusServiceUnavailable {
+				continue // dungeon initializing — try again next tick
+			}
+			h.finishAutoBattle(key, ns, name, runValue, reason)
+			return
+		}
+		turns++
+		autoBattleTurns.WithLabelValues(strategyName).Inc()
+		h.broadcastAutoBattle(ns, name, "turn", map[string]interface{}{"strategy": strategyName, "move": move, "turn": turns})
+	}
+	reason = "turn limit"
+	h.finishAutoBattle(key, ns, name, runValue, reason)
+}
+
+// renewAutoBattleLease stamps this pod's lease on the run, provided the run
+// annotation still names runValue.
+func (h *Handler) renewAutoBattleLease(ctx context.Context, ns, name, runValue string) error {
+	ops, _ := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": annotationPath(autoBattleAnnotation), "value": runValue},
+		{"op": "add", "path": annotationPath(autoBattleLeaseAnnotation), "value": h.autoBattle.lease()},
+	})
+	_, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Patch(ctx, name, types.JSONPatchType, ops, metav1.PatchOptions{})
+	return err
+}
+
+// finishAutoBattle clears the run annotation and its lease (recording why)
+// and forgets the run. The JSON Patch "test" op makes the clear conditional
+// on the annotation still naming this run, so a newer run started meanwhile
+// is left alone.
+func (h *Handler) finishAutoBattle(key, ns, name, runValue, reason string) {
+	h.autoBattle.release(key, runValue)
+	ops, _ := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": annotationPath(autoBattleAnnotation), "value": runValue},
+		{"op": "remove", "path": annotationPath(autoBattleAnnotation)},
+		// add-then-remove drops the lease whether or not one was written.
+		{"op": "add", "path": annotationPath(autoBattleLeaseAnnotation), "value": ""},
+		{"op": "remove", "path": annotationPath(autoBattleLeaseAnnotation)},
+		{"op": "add", "path": annotationPath(autoBattleStoppedAnnotation), "value": reason},
+	})
+	_, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Patch(
+		context.Background(), name, types.JSONPatchType, ops, metav1.PatchOptions{})
+	if err != nil && !isClientError(err) {
+		slog.Warn("auto-battle: failed to clear annotation", "component", "game", "dungeon", name, "error", err)
+	}
+}
+
+// annotationPath is the JSON Pointer to an annotation key.
+func annotationPath(key string) string {
+	return "/metadata/annotations/" + strings.ReplaceAll(key, "/", "~1")
+}
+
+// ResumeAutoBattles takes over auto-battles whose driver has gone. The
+// goroutine driving a run dies with its pod, and without a new driver the
+// annotation would show a run that never moves. It scans once at startup and
+// then every autoBattleLeaseTTL until ctx is done.
+func (h *Handler) ResumeAutoBattles(ctx context.Context) {
+	for {
+		h.resumeAutoBattles(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(autoBattleLeaseTTL):
+		}
+	}
+}
+
+// resumeAutoBattles takes over every run whose lease has lapsed, or that this
+// pod's lease names but no local runner drives (the container restarted).
+// Runs leased to a live driver are left alone. Taking over swaps in a new run
+// ID under a "test" of the old one, so two pods racing for a run cannot both
+// win it. Runs whose settings or owner can no longer be recovered are stopped
+// instead.
+func (h *Handler) resumeAutoBattles(ctx context.Context) {
+	list, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.Warn("auto-battle: failed to list dungeons to resume", "component", "game", "error", err)
+		return
+	}
+	for i := range list.Items {
+		dungeon := &list.Items[i]
+		ns, name := dungeon.GetNamespace(), dungeon.GetName()
+		key := ns + "/" + name
+		ann := dungeon.GetAnnotations()
+		prev := ann[autoBattleAnnotation]
+		if prev == "" {
+			continue
+		}
+		holder, renewed := parseAutoBattleLease(ann[autoBattleLeaseAnnotation])
+		if holder == h.autoBattle.holder {
+			if h.autoBattle.driving(key) {
+				continue
+			}
+		} else if time.Since(renewed) < autoBattleLeaseTTL {
+			continue
+		}
+		// A missing or unreadable config leaves Strategy empty, which
+		// settings rejects.
+		var req StartAutoBattleReq
+		if json.Unmarshal([]byte(ann[autoBattleConfigAnnotation]), &req) != nil {
+			req = StartAutoBattleReq{}
+		}
+		strategy, opts, delay, err := req.settings()
+		owner := dungeon.GetLabels()["krombat.io/owner"]
+		if err != nil || owner == "" {
+			h.finishAutoBattle(key, ns, name, prev, "restart")
+			continue
+		}
+		runID, err := randomHex(4)
+		if err != nil {
+			continue
+		}
+		runValue := req.Strategy + ":" + runID
+		ops, _ := json.Marshal([]map[string]interface{}{
+			{"op": "test", "path": annotationPath(autoBattleAnnotation), "value": prev},
+			{"op": "replace", "path": annotationPath(autoBattleAnnotation), "value": runValue},
+			{"op": "add", "path": annotationPath(autoBattleLeaseAnnotation), "value": h.autoBattle.lease()},
+		})
+		if _, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Patch(
+			ctx, name, types.JSONPatchType, ops, metav1.PatchOptions{}); err != nil {
+			// A client error means the run changed since the list; whoever
+			// changed it owns it now.
+			if !isClientError(err) {
+				slog.Warn("auto-battle: failed to resume run", "component", "game", "dungeon", name, "error", err)
+			}
+			continue
+		}
+		runCtx := h.autoBattle.start(key, runValue)
+		go h.runAutoBattle(runCtx, &Session{Login: owner}, ns, name, runValue, req.Strategy, strategy, opts, delay)
+		slog.Info("auto_battle_resumed", "component", "game", "dungeon", name, "strategy", req.Strategy, "previous_holder", holder)
+	}
+}
+
+func (h *Handler) broadcastAutoBattle(ns, name, action string, payload map[string]interface{}) {
+	data, err := json.Marshal(ws.Event{Type: "AUTO_BATTLE", Action: action, Name: name, Namespace: ns, Payload: payload})
+	if err != nil {
+		return
+	}
+	h.hub.Broadcast(data, ns, name)
+}
+
+// isActionTarget reports whether a CreateAttack target is a non-combat action
+// (routed to processAction) rather than an attack or ability.
+func isActionTarget(target string) bool {
+	return strings.HasPrefix(target, "use-") || strings.HasPrefix(target, "equip-") ||
+		strings.HasPrefix(target, "buy-") || strings.HasPrefix(target, "sell-") ||
+		strings.HasPrefix(target, "craft-") || strings.HasPrefix(target, "drop-") ||
+		strings.HasPrefix(target, "unequip-") || strings.HasPrefix(target, "swap-") ||
+		strings.HasPrefix(target, "enter-room-") ||
+		target == "open-treasure" || target == "unlock-door"
+}
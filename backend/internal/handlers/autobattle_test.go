@@ -0,0 +1,95 @@
+package handlers_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/ws"
+)
+
+// TestResumeAutoBattles checks that a backend takes over runs whose lease has
+// lapsed, leaves runs leased to a live pod alone, and stops the runs it
+// cannot drive.
+func TestResumeAutoBattles(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+	dungeon := func(name, owner string, annotations map[string]interface{}) *unstructured.Unstructured {
+		meta := map[string]interface{}{"name": name, "namespace": "default", "annotations": annotations}
+		if owner != "" {
+			meta["labels"] = map[string]interface{}{"krombat.io/owner": owner}
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon", "metadata": meta,
+		}}
+	}
+	config := `{"strategy":"aggressive","healThreshold":40,"turnDelayMs":10000}`
+	fresh := fmt.Sprintf("other-pod@%d", time.Now().Unix())
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "DungeonList"},
+		dungeon("lair", "alice", map[string]interface{}{
+			"krombat.io/auto-battle":        "loot-first:0000",
+			"krombat.io/auto-battle-config": `{"strategy":"loot-first","healThreshold":60,"turnDelayMs":10000}`,
+		}),
+		dungeon("lapsed", "alice", map[string]interface{}{
+			"krombat.io/auto-battle":        "aggressive:0000",
+			"krombat.io/auto-battle-config": config,
+			"krombat.io/auto-battle-lease":  "other-pod@1",
+		}),
+		dungeon("leased", "alice", map[string]interface{}{
+			"krombat.io/auto-battle":        "aggressive:0000",
+			"krombat.io/auto-battle-config": config,
+			"krombat.io/auto-battle-lease":  fresh,
+		}),
+		dungeon("no-config", "alice", map[string]interface{}{"krombat.io/auto-battle": "aggressive:0000"}),
+		dungeon("orphan", "", map[string]interface{}{"krombat.io/auto-battle": "aggressive:0000", "krombat.io/auto-battle-config": config}),
+		dungeon("retired", "alice", map[string]interface{}{
+			"krombat.io/auto-battle":        "berserk:0000",
+			"krombat.io/auto-battle-config": `{"strategy":"berserk"}`,
+		}),
+		dungeon("idle", "alice", map[string]interface{}{"krombat.io/auto-battle-stopped": "victory"}),
+	)
+	h := newHandler(t, &k8s.Client{Dynamic: client}, ws.NewHub())
+	h.ResumeAutoBattlesOnce(t.Context())
+	for _, name := range []string{"lair", "lapsed"} {
+		t.Cleanup(func() { h.StopAutoBattleRun("default", name) })
+	}
+
+	tests := []struct {
+		name, run, stopped string
+	}{
+		{"lair", "loot-first:", ""},
+		{"lapsed", "aggressive:", ""},
+		{"leased", "aggressive:0000", ""},
+		{"no-config", "", "restart"},
+		{"orphan", "", "restart"},
+		{"retired", "", "restart"},
+		{"idle", "", "victory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := client.Tracker().Get(gvr, "default", tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ann := obj.(*unstructured.Unstructured).GetAnnotations()
+			run := ann["krombat.io/auto-battle"]
+			taken := strings.HasSuffix(tt.run, ":")
+			if !strings.HasPrefix(run, tt.run) || (tt.run == "") != (run == "") || (taken && strings.HasSuffix(run, ":0000")) {
+				t.Errorf("auto-battle = %q, want run %q", run, tt.run)
+			}
+			if lease := ann["krombat.io/auto-battle-lease"]; taken && (lease == "" || strings.HasPrefix(lease, "other-pod@")) {
+				t.Errorf("auto-battle-lease = %q, want this pod's lease", lease)
+			}
+			if ann["krombat.io/auto-battle-stopped"] != tt.stopped {
+				t.Errorf("auto-battle-stopped = %q, want %q", ann["krombat.io/auto-battle-stopped"], tt.stopped)
+			}
+		})
+	}
+}
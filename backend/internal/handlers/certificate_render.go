@@ -0,0 +1,166 @@
+package handlers
+
+// SVG and PDF renderings of a signed certificate. Both print the verify URL,
+// document ID and key ID so a printed copy can be checked against
+// GET /api/v1/certificates/{id}/verify.
+//
+// The PDF is written by hand: one A4 landscape page using the built-in
+// Courier fonts (fixed width, so text can be centred without font metrics)
+// and a link annotation on the verify URL.
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+var certificateTierLabels = map[int]string{1: "Tier 1 - Observer", 2: "Tier 2 - Practitioner", 3: "Tier 3 - Architect"}
+
+// certificateLines is the text both renderings print, top to bottom.
+type certificateLines struct {
+	tier, title, recipient, description, issued, evidence, ids, signature string
+}
+
+func newCertificateLines(c SignedCertificate) certificateLines {
+	issued := c.IssuedAt
+	if t, err := time.Parse(time.RFC3339, c.IssuedAt); err == nil {
+		issued = t.Format("2 January 2006")
+	}
+	evidence := "Awarded in the krombat UI"
+	if ev := c.Evidence; ev.Source == "run" && ev.Run != nil {
+		evidence = fmt.Sprintf("Earned in dungeon %s: %s, %s, %d turns, room %d",
+			ev.Dungeon, ev.Run.HeroClass, ev.Run.Difficulty, ev.Run.Turns, ev.Run.CurrentRoom)
+	}
+	sig := c.Signature
+	if len(sig) > 43 {
+		sig = sig[:43] + "..."
+	}
+	return certificateLines{
+		tier:        certificateTierLabels[c.Tier],
+		title:       c.Title,
+		recipient:   "@" + c.Recipient,
+		description: c.Description,
+		issued:      "Issued " + issued,
+		evidence:    evidence,
+		ids:         fmt.Sprintf("Certificate %s - Ed25519 key %s", c.ID, c.KeyID),
+		signature:   "Signature " + sig,
+	}
+}
+
+func renderCertificateSVG(c SignedCertificate, verifyURL string) string {
+	l := newCertificateLines(c)
+	e := html.EscapeString
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="800" height="560" viewBox="0 0 800 560">
+  <defs>
+    <style>
+      @import url('https://fonts.googleapis.com/css2?family=Press+Start+2P&amp;display=swap');
+      text { font-family: 'Press Start 2P', 'Courier New', monospace; }
+    </style>
+    <linearGradient id="bg" x1="0" y1="0" x2="1" y2="1">
+      <stop offset="0%%" stop-color="#0d0f14"/>
+      <stop offset="100%%" stop-color="#1a1d2e"/>
+    </linearGradient>
+  </defs>
+
+  <rect width="800" height="560" fill="url(#bg)" rx="8"/>
+  <rect x="16" y="16" width="768" height="528" fill="none" stroke="#00d4ff" stroke-width="2" rx="6"/>
+  <rect x="24" y="24" width="752" height="512" fill="none" stroke="#2a2d3e" stroke-width="1" rx="4"/>
+
+  <rect x="50" y="44" width="76" height="18" fill="#1e2235" rx="3"/>
+  <text x="88" y="57" text-anchor="middle" font-size="8" fill="#5b8cf5">kro / k8s</text>
+
+  <text x="400" y="100" text-anchor="middle" font-size="16" fill="#e8eaf6" letter-spacing="2">CERTIFICATE OF ACHIEVEMENT</text>
+  <text x="400" y="128" text-anchor="middle" font-size="9" fill="#00d4ff">%s</text>
+
+  <text x="400" y="190" text-anchor="middle" font-size="20" fill="#f0c060">%s</text>
+  <text x="400" y="232" text-anchor="middle" font-size="9" fill="#9ca3af">awarded to</text>
+  <text x="400" y="266" text-anchor="middle" font-size="16" fill="#e8eaf6">%s</text>
+  <text x="400" y="306" text-anchor="middle" font-size="8" fill="#9ca3af">%s</text>
+
+  <line x1="80" y1="336" x2="720" y2="336" stroke="#2a2d3e" stroke-width="1"/>
+
+  <text x="400" y="366" text-anchor="middle" font-size="8" fill="#e8eaf6">%s</text>
+  <text x="400" y="388" text-anchor="middle" font-size="7" fill="#9ca3af">%s</text>
+
+  <text x="400" y="450" text-anchor="middle" font-size="6" fill="#4e5568">%s</text>
+  <text x="400" y="468" text-anchor="middle" font-size="6" fill="#4e5568">%s</text>
+  <text x="400" y="492" text-anchor="middle" font-size="6" fill="#5b8cf5">Verify at %s</text>
+  <text x="400" y="516" text-anchor="middle" font-size="6" fill="#4e5568">Powered by kro on Kubernetes - learn-kro.eks.aws.dev</text>
+</svg>`,
+		e(l.tier), e(l.title), e(l.recipient), e(l.description),
+		e(l.issued), e(l.evidence), e(l.ids), e(l.signature), e(verifyURL))
+}
+
+func renderCertificatePDF(c SignedCertificate, verifyURL string) []byte {
+	const pageW, pageH = 842.0, 595.0
+	l := newCertificateLines(c)
+
+	var content bytes.Buffer
+	// Double border.
+	content.WriteString("0 0.83 1 RG 2 w 24 24 794 547 re S\n")
+	content.WriteString("0.7 0.7 0.75 RG 0.5 w 32 32 778 531 re S\n")
+	centred := func(font string, size, y float64, rgb, s string) {
+		s = pdfText(s)
+		x := max((pageW-float64(len(s))*0.6*size)/2, 40)
+		fmt.Fprintf(&content, "BT %s rg /%s %g Tf %.1f %g Td (%s) Tj ET\n", rgb, font, size, x, y, pdfEscape(s))
+	}
+	dark, dim, accent, gold := "0.1 0.1 0.15", "0.4 0.4 0.45", "0 0.5 0.7", "0.7 0.5 0.1"
+	centred("F2", 26, 500, dark, "CERTIFICATE OF ACHIEVEMENT")
+	centred("F1", 13, 472, accent, l.tier)
+	centred("F2", 30, 400, gold, l.title)
+	centred("F1", 13, 360, dim, "awarded to")
+	centred("F2", 24, 325, dark, l.recipient)
+	centred("F1", 12, 285, dim, l.description)
+	content.WriteString("0.7 0.7 0.75 RG 0.5 w 120 260 m 722 260 l S\n")
+	centred("F1", 13, 232, dark, l.issued)
+	centred("F1", 10, 212, dim, l.evidence)
+	centred("F1", 9, 130, dim, l.ids)
+	centred("F1", 9, 115, dim, l.signature)
+	centred("F1", 9, 90, accent, "Verify at "+verifyURL)
+	centred("F1", 8, 55, dim, "Powered by kro on Kubernetes - learn-kro.eks.aws.dev")
+
+	linkW := float64(len(pdfText("Verify at "+verifyURL))) * 0.6 * 9
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R /Annots [7 0 R] >>", pageW, pageH),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%.1f 85 %.1f 100] /Border [0 0 0] /A << /S /URI /URI (%s) >> >>",
+			(pageW-linkW)/2, (pageW+linkW)/2, pdfEscape(pdfText(verifyURL))),
+		fmt.Sprintf("<< /Title (%s) /Producer (krombat) >>", pdfEscape(pdfText(c.Title+" - "+l.recipient))),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, len(objects), xref)
+	return out.Bytes()
+}
+
+// pdfText keeps s to printable ASCII, which the standard fonts can draw.
+func pdfText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// pdfEscape escapes s for a PDF literal string.
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
@@ -0,0 +1,354 @@
+package handlers
+
+// Signed kro certificates.
+//
+// Every certificate issued to a signed-in player is also written as a signed
+// document to a ConfigMap of its own in rpg-system, krombat-certificate-<id>,
+// labelled with its recipient, and the profile's certificateIds maps the
+// certificate to its document. The document names the recipient, the
+// certificate, when it was issued and the evidence: the run and rule that
+// earned it. Only certificates the backend saw earned are signed; the Tier 2
+// certificates the UI awards are recorded on the profile without one. The
+// documents are stored only once the profile naming them has been written,
+// so a failed profile write leaves no certificate behind.
+//
+// Documents are signed with Ed25519 so anyone holding the public key can
+// check them offline:
+//
+//	signature = base64url(ed25519.Sign(key, "krombat-certificate-v1\n" + json(document without signature)))
+//
+// The key is the 32-byte seed in CERTIFICATE_SIGNING_KEY (base64), which is
+// required. List the old public key in CERTIFICATE_PREVIOUS_KEYS
+// (comma-separated base64) before rotating it, or older documents stop
+// verifying. keyId is the first 8 bytes of SHA-256(public key), in hex.
+//
+// GET /api/v1/certificates/{id}/verify, /svg and /pdf are public so a
+// certificate can be checked by whoever it is shown to. The renderings print
+// the verify link under KROMBAT_PUBLIC_URL, never under the request's Host.
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+const (
+	certificateCMPrefix      = "krombat-certificate-"
+	certificateRecipientKey  = "krombat.io/certificate-recipient"
+	certificateDataKey       = "certificate.json"
+	certificateSigningDomain = "krombat-certificate-v1\n"
+)
+
+// validCertificateID matches document IDs as issueCertificate mints them.
+var validCertificateID = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// SignedCertificate is an issued certificate document.
+type SignedCertificate struct {
+	ID          string              `json:"id"`
+	Cert        string              `json:"cert"` // achievement ID
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Tier        int                 `json:"tier"`
+	Recipient   string              `json:"recipient"` // GitHub login
+	IssuedAt    string              `json:"issuedAt"`  // RFC 3339, UTC
+	Evidence    CertificateEvidence `json:"evidence"`
+	KeyID       string              `json:"keyId"`
+	Signature   string              `json:"signature,omitempty"`
+}
+
+// CertificateEvidence records why a certificate was issued.
+type CertificateEvidence struct {
+	Source      string    `json:"source"` // "run": Rule held when Dungeon's run was recorded
+	Rule        string    `json:"rule,omitempty"`
+	Dungeon     string    `json:"dungeon,omitempty"`
+	Run         *RunStats `json:"run,omitempty"`
+	DungeonsWon int64     `json:"dungeonsWon,omitempty"`
+	Level       int64     `json:"level,omitempty"`
+}
+
+// signedBytes is what the signature covers.
+func (c SignedCertificate) signedBytes() []byte {
+	c.Signature = ""
+	b, _ := json.Marshal(c)
+	return append([]byte(certificateSigningDomain), b...)
+}
+
+// certificateKeyring holds the signing key and every public key accepted for
+// verification, by key ID.
+type certificateKeyring struct {
+	signingKID string
+	private    ed25519.PrivateKey
+	public     map[string]ed25519.PublicKey
+}
+
+func certificateKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadCertificateKeyring builds the keyring from CERTIFICATE_SIGNING_KEY and
+// CERTIFICATE_PREVIOUS_KEYS.
+func loadCertificateKeyring(seed, previous string) (*certificateKeyring, error) {
+	if seed == "" {
+		return nil, errors.New("CERTIFICATE_SIGNING_KEY is not set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("CERTIFICATE_SIGNING_KEY must be %d base64-encoded bytes", ed25519.SeedSize)
+	}
+	priv := ed25519.NewKeyFromSeed(raw)
+	pub := priv.Public().(ed25519.PublicKey)
+	kr := &certificateKeyring{
+		signingKID: certificateKeyID(pub),
+		private:    priv,
+		public:     map[string]ed25519.PublicKey{certificateKeyID(pub): pub},
+	}
+	for _, k := range strings.Split(previous, ",") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(k)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("CERTIFICATE_PREVIOUS_KEYS entry %q is not a base64 Ed25519 public key", k)
+		}
+		kr.public[certificateKeyID(b)] = b
+	}
+	return kr, nil
+}
+
+// certificateStore signs certificate documents and keeps them in rpg-system.
+type certificateStore struct {
+	client    *k8s.Client
+	keys      *certificateKeyring
+	publicURL string // KROMBAT_PUBLIC_URL without a trailing slash
+}
+
+// newCertificateStore loads the keyring and public URL from the environment.
+// A missing or malformed key is returned to New so the misconfiguration shows
+// at rollout.
+func newCertificateStore(client *k8s.Client) (*certificateStore, error) {
+	kr, err := loadCertificateKeyring(os.Getenv("CERTIFICATE_SIGNING_KEY"), os.Getenv("CERTIFICATE_PREVIOUS_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	return &certificateStore{client: client, keys: kr, publicURL: strings.TrimRight(os.Getenv("KROMBAT_PUBLIC_URL"), "/")}, nil
+}
+
+// verifyURL is the absolute verify link printed on a certificate.
+func (s *certificateStore) verifyURL(id string) string {
+	return s.publicURL + "/api/v1/certificates/" + id + "/verify"
+}
+
+// put writes c as a new ConfigMap; document IDs are never reused.
+func (s *certificateStore) put(ctx context.Context, c SignedCertificate) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      certificateCMPrefix + c.ID,
+			"namespace": leaderboardNamespace,
+			"labels":    map[string]interface{}{certificateRecipientKey: c.Recipient},
+		},
+		"data": map[string]interface{}{certificateDataKey: string(b)},
+	}}
+	_, err = s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Create(ctx, cm, metav1.CreateOptions{})
+	return err
+}
+
+// get reads document id from its ConfigMap.
+func (s *certificateStore) get(ctx context.Context, id string) (SignedCertificate, error) {
+	var c SignedCertificate
+	if !validCertificateID.MatchString(id) {
+		return c, errCertificateNotFound
+	}
+	cm, err := s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Get(ctx, certificateCMPrefix+id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return c, errCertificateNotFound
+	}
+	if err != nil {
+		return c, err
+	}
+	raw, _, _ := unstructured.NestedString(cm.Object, "data", certificateDataKey)
+	if raw == "" {
+		return c, errCertificateNotFound
+	}
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		return c, fmt.Errorf("decode certificate %s: %w", id, err)
+	}
+	return c, nil
+}
+
+func (kr *certificateKeyring) sign(c *SignedCertificate) {
+	c.KeyID = kr.signingKID
+	c.Signature = base64.RawURLEncoding.EncodeToString(ed25519.Sign(kr.private, c.signedBytes()))
+}
+
+// verify checks c's signature and returns the key that made it; reason says
+// why it failed otherwise.
+func (kr *certificateKeyring) verify(c SignedCertificate) (pub ed25519.PublicKey, reason string) {
+	pub, ok := kr.public[c.KeyID]
+	if !ok {
+		return nil, "signed by an unknown key"
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(c.Signature)
+	if err != nil || !ed25519.Verify(pub, c.signedBytes(), sig) {
+		return nil, "signature does not match the document"
+	}
+	return pub, ""
+}
+
+// newCertificate builds and signs a certificate document for login.
+func (h *Handler) newCertificate(login string, ach catalog.Achievement, evidence CertificateEvidence) (SignedCertificate, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return SignedCertificate{}, err
+	}
+	c := SignedCertificate{
+		ID:          id,
+		Cert:        ach.ID,
+		Title:       ach.Title,
+		Description: ach.Description,
+		Tier:        ach.Tier,
+		Recipient:   login,
+		IssuedAt:    time.Now().UTC().Format(time.RFC3339),
+		Evidence:    evidence,
+	}
+	h.certificates.keys.sign(&c)
+	return c, nil
+}
+
+// signCertificates signs a document for each of certs and records it in
+// profile.CertificateIDs; storeCertificates writes them once the profile is
+// saved. Anonymous players get the certificate IDs only: a document must
+// name who earned it.
+func (h *Handler) signCertificates(login string, rules *achievementRules, certs []string, evidence CertificateEvidence, profile *UserProfile) []SignedCertificate {
+	if login == "" || login == "anonymous" {
+		return nil
+	}
+	var docs []SignedCertificate
+	for _, cert := range certs {
+		ach, ok := rules.lookup(cert)
+		if !ok {
+			continue
+		}
+		ev := evidence
+		ev.Rule = ach.Rule
+		c, err := h.newCertificate(login, ach, ev)
+		if err != nil {
+			slog.Warn("certificates: failed to sign", "component", "api", "user", login, "cert", cert, "error", err)
+			continue
+		}
+		if profile.CertificateIDs == nil {
+			profile.CertificateIDs = map[string]string{}
+		}
+		profile.CertificateIDs[cert] = c.ID
+		docs = append(docs, c)
+	}
+	return docs
+}
+
+// storeCertificates writes the documents signCertificates made, retrying
+// transient failures.
+func (h *Handler) storeCertificates(ctx context.Context, docs []SignedCertificate) {
+	for _, c := range docs {
+		if err := retryK8s(3, func() error { return h.certificates.put(ctx, c) }); err != nil {
+			slog.Warn("certificates: failed to store", "component", "api", "user", c.Recipient, "cert", c.Cert, "id", c.ID, "error", err)
+		}
+	}
+}
+
+var errCertificateNotFound = errors.New("certificate not found")
+
+// readCertificate loads the document named by the {id} path value, writing
+// the error response itself when there is none.
+func (h *Handler) readCertificate(w http.ResponseWriter, r *http.Request) (SignedCertificate, bool) {
+	c, err := h.certificates.get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, errCertificateNotFound) {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return c, false
+	}
+	if err != nil {
+		slog.Error("certificates: read failed", "component", "api", "error", err)
+		writeError(w, "failed to read certificate", http.StatusInternalServerError)
+		return c, false
+	}
+	return c, true
+}
+
+// verifiedCertificate is readCertificate for documents about to be rendered:
+// one that fails verification is refused with 422.
+func (h *Handler) verifiedCertificate(w http.ResponseWriter, r *http.Request) (SignedCertificate, bool) {
+	c, ok := h.readCertificate(w, r)
+	if !ok {
+		return c, false
+	}
+	if _, reason := h.certificates.keys.verify(c); reason != "" {
+		writeError(w, "certificate is not genuine: "+reason, http.StatusUnprocessableEntity)
+		return c, false
+	}
+	return c, true
+}
+
+// VerifyCertificate reports whether a certificate document is genuine. A
+// tampered document is still returned, with valid false and the reason.
+// GET /api/v1/certificates/{id}/verify
+func (h *Handler) VerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCertificate(w, r)
+	if !ok {
+		return
+	}
+	resp := map[string]interface{}{"valid": true, "certificate": c}
+	if pub, reason := h.certificates.keys.verify(c); reason != "" {
+		resp["valid"], resp["reason"] = false, reason
+	} else {
+		resp["publicKey"] = base64.StdEncoding.EncodeToString(pub)
+	}
+	writeJSON(w, resp)
+}
+
+// CertificateSVG renders a genuine certificate as an SVG image.
+// GET /api/v1/certificates/{id}/svg
+func (h *Handler) CertificateSVG(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.verifiedCertificate(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write([]byte(renderCertificateSVG(c, h.certificates.verifyURL(c.ID))))
+}
+
+// CertificatePDF renders a genuine certificate as a one-page PDF.
+// GET /api/v1/certificates/{id}/pdf
+func (h *Handler) CertificatePDF(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.verifiedCertificate(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="krombat-%s-%s.pdf"`, c.Cert, c.ID))
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(renderCertificatePDF(c, h.certificates.verifyURL(c.ID)))
+}
@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+// TestSignedCertificates issues a certificate document, then checks it
+// verifies and renders, and that an edited copy does neither.
+func TestSignedCertificates(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/certificates/{id}/verify", h.VerifyCertificate)
+	mux.HandleFunc("GET /api/v1/certificates/{id}/svg", h.CertificateSVG)
+	mux.HandleFunc("GET /api/v1/certificates/{id}/pdf", h.CertificatePDF)
+	srv := h.AuthMiddleware(mux)
+	get := func(path string) *httptest.ResponseRecorder {
+		// The verify link must not follow what the request claims.
+		req := httptest.NewRequest("GET", path, nil)
+		req.Host = "phish.example"
+		req.Header.Set("X-Forwarded-Proto", "http")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	id, err := h.IssueCertificate(context.Background(), "alice", "two-rooms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var verify struct {
+		Valid       bool
+		Reason      string
+		PublicKey   string
+		Certificate struct {
+			ID, Cert, Recipient, KeyID, Signature string
+			Tier                                  int
+			Evidence                              struct{ Source string }
+		}
+	}
+	rec := get("/api/v1/certificates/" + id + "/verify")
+	if err := json.Unmarshal(rec.Body.Bytes(), &verify); err != nil {
+		t.Fatalf("verify = %d %s: %v", rec.Code, rec.Body.String(), err)
+	}
+	c := verify.Certificate
+	if !verify.Valid || verify.PublicKey == "" || c.ID != id || c.Cert != "two-rooms" || c.Recipient != "alice" ||
+		c.Tier != 1 || c.Evidence.Source != "run" || c.KeyID == "" || c.Signature == "" {
+		t.Errorf("verify = %s, want a valid two-rooms document for alice", rec.Body.String())
+	}
+	verifyURL := testPublicURL + "/api/v1/certificates/" + id + "/verify"
+	if rec := get("/api/v1/certificates/" + id + "/svg"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "@alice") || !strings.Contains(rec.Body.String(), verifyURL) {
+		t.Errorf("svg = %d, want the recipient and %s", rec.Code, verifyURL)
+	}
+	if rec := get("/api/v1/certificates/" + id + "/pdf"); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "%PDF-") || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("pdf = %d %q, want a PDF", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// Each document has a ConfigMap of its own, labelled with its recipient.
+	obj, err := client.Tracker().Get(cmGVR, "rpg-system", "krombat-certificate-"+id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	own := obj.(*unstructured.Unstructured)
+	if got := own.GetLabels()["krombat.io/certificate-recipient"]; got != "alice" {
+		t.Errorf("document recipient label = %q, want alice", got)
+	}
+	raw, _, _ := unstructured.NestedString(own.Object, "data", "certificate.json")
+
+	// Change the recipient without re-signing.
+	unstructured.SetNestedField(own.Object, strings.Replace(raw, `"recipient":"alice"`, `"recipient":"mallory"`, 1), "data", "certificate.json")
+	if err := client.Tracker().Update(cmGVR, own, "rpg-system"); err != nil {
+		t.Fatal(err)
+	}
+	verify.Valid = true
+	rec = get("/api/v1/certificates/" + id + "/verify")
+	json.Unmarshal(rec.Body.Bytes(), &verify)
+	if rec.Code != http.StatusOK || verify.Valid || verify.Reason == "" {
+		t.Errorf("verify tampered = %d %s, want valid false with a reason", rec.Code, rec.Body.String())
+	}
+	for _, format := range []string{"svg", "pdf"} {
+		if rec := get("/api/v1/certificates/" + id + "/" + format); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s tampered = %d, want 422", format, rec.Code)
+		}
+	}
+
+	for _, bad := range []string{"0123456789abcdef", "not-an-id"} {
+		if rec := get("/api/v1/certificates/" + bad + "/verify"); rec.Code != http.StatusNotFound {
+			t.Errorf("verify %s = %d, want 404", bad, rec.Code)
+		}
+	}
+}
+
+// TestAwardCert claims client-awarded certificates: only a signed-in owner
+// of a dungeon whose run backs the claim gets one, and never a document.
+func TestAwardCert(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	lair := func(name, owner string, turns int64) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon",
+			"metadata": map[string]interface{}{
+				"name": name, "namespace": "default",
+				"labels": map[string]interface{}{"krombat.io/owner": owner},
+			},
+			"spec": map[string]interface{}{"monsters": int64(1), "difficulty": "normal", "heroClass": "warrior", "attackSeq": turns},
+		}}
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		configMap("krombat-profiles", "alice", `{"dungeonsPlayed":1}`),
+		lair("fresh", "alice", 1), lair("seasoned", "alice", 3), lair("theirs", "bob", 3))
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/profile/cert", h.AwardCert)
+	srv := h.AuthMiddleware(mux)
+
+	for _, tt := range []struct {
+		name, user, body string
+		want             int
+	}{
+		{"anonymous", "", `{"cert":"glossary","namespace":"default","name":"fresh"}`, http.StatusUnauthorized},
+		{"rule-based cert", "alice", `{"cert":"two-rooms","namespace":"default","name":"fresh"}`, http.StatusBadRequest},
+		{"no dungeon named", "alice", `{"cert":"glossary"}`, http.StatusBadRequest},
+		{"missing dungeon", "alice", `{"cert":"glossary","namespace":"default","name":"gone"}`, http.StatusNotFound},
+		{"another player's dungeon", "alice", `{"cert":"glossary","namespace":"default","name":"theirs"}`, http.StatusForbidden},
+		{"no evidence", "alice", `{"cert":"kro-reconcile","namespace":"default","name":"fresh"}`, http.StatusForbidden},
+		{"evidence", "alice", `{"cert":"kro-reconcile","namespace":"default","name":"seasoned"}`, http.StatusOK},
+		{"no evidence needed", "alice", `{"cert":"glossary","namespace":"default","name":"fresh"}`, http.StatusOK},
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/profile/cert", strings.NewReader(tt.body))
+		if tt.user != "" {
+			req.Header.Set("X-Test-User", tt.user)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: code = %d (%s), want %d", tt.name, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+
+	obj, err := client.Tracker().Get(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "rpg-system", "krombat-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedStringMap(obj.(*unstructured.Unstructured).Object, "data")
+	var p struct {
+		KroCertificates []string
+		CertificateIDs  map[string]string
+	}
+	json.Unmarshal([]byte(data["alice"]), &p)
+	if strings.Join(p.KroCertificates, ",") != "kro-reconcile,glossary" || len(p.CertificateIDs) != 0 {
+		t.Errorf("alice's profile = %+v, want kro-reconcile and glossary without documents", p)
+	}
+	if _, ok := data["anonymous"]; ok {
+		t.Error("anonymous award wrote a profile")
+	}
+}
+
+func TestCertificateKeyringRequiresKey(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	for _, tt := range []struct {
+		name, seed, previous string
+		wantErr              bool
+	}{
+		{"no signing key", "", "", true},
+		{"short key", base64.StdEncoding.EncodeToString(make([]byte, 16)), "", true},
+		{"bad previous key", seed, "not-a-key", true},
+		{"signing key", seed, "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := handlers.LoadCertificateKeyring(tt.seed, tt.previous); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+func TestCraftActions(t *testing.T) {
+	tests := []struct {
+		name      string
+		inventory string
+		action    string
+		wantCode  int
+		wantMsg   string
+	}{
+		{"craft", `["weapon-common","hppotion-common","weapon-common","weapon-common"]`, "craft-weapon-rare", http.StatusAccepted, ""},
+		{"one short", `["weapon-common","weapon-common"]`, "craft-weapon-rare", http.StatusBadRequest, "weapon-rare needs 3 weapon-common, you have 2"},
+		{"wrong rarity", `["weapon-common","weapon-common","weapon-common"]`, "craft-weapon-epic", http.StatusBadRequest, "needs 3 weapon-rare, you have 0"},
+		{"unknown recipe", `["weapon-common"]`, "craft-excalibur", http.StatusBadRequest, "unknown recipe: excalibur"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDungeonAPI(t, 0)
+			f.client.PrependReactor("patch", "actions", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, &unstructured.Unstructured{}, nil
+			})
+			gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+			obj, err := f.client.Tracker().Get(gvr, "default", "lair")
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := obj.(*unstructured.Unstructured)
+			unstructured.SetNestedField(d.Object, model.ParseInventory(tt.inventory).Unstructured(), "status", "game", "inventory")
+			if err := f.client.Tracker().Update(gvr, d, "default"); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := postAttack(attackServer(t, f), tt.action, 0)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Errorf("code = %d (%s), want %d mentioning %q", rec.Code, strings.TrimSpace(rec.Body.String()), tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestListRecipes(t *testing.T) {
+	h := newHandler(t, &k8s.Client{}, nil)
+	rec := httptest.NewRecorder()
+	h.ListRecipes(rec, httptest.NewRequest("GET", "/api/v1/recipes", nil))
+	var got struct {
+		Recipes []struct {
+			ID     string
+			Inputs []string
+			Output string
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got.Recipes) == 0 {
+		t.Fatalf("GET /recipes = %s (err %v)", rec.Body.String(), err)
+	}
+	for _, r := range got.Recipes {
+		if r.ID == "weapon-rare" && (r.Output != "weapon-rare" || len(r.Inputs) != 3) {
+			t.Errorf("weapon-rare recipe = %+v", r)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+func TestEquipmentActions(t *testing.T) {
+	worn := map[string]interface{}{
+		"weaponBonus": int64(10), "weaponUses": int64(2),
+		"inventory": model.ParseInventory(`["weapon-common","hppotion-common"]`).Unstructured(),
+	}
+	tests := []struct {
+		name     string
+		game     map[string]interface{}
+		action   string
+		wantCode int
+		wantMsg  string
+		wantLog  string // lastHeroAction written on success, if checked
+	}{
+		{"drop", worn, "drop-hppotion-common", http.StatusAccepted, "", ""},
+		{"drop what you lack", worn, "drop-ring-epic", http.StatusBadRequest, "not in inventory", ""},
+		{"unequip", worn, "unequip-weapon", http.StatusAccepted, "", ""},
+		{"unequip empty slot", worn, "unequip-ring", http.StatusBadRequest, "nothing equipped in the ring slot", ""},
+		{"unequip into full backpack", map[string]interface{}{"weaponBonus": int64(10), "inventory": model.ParseInventory(`["a","b","c","d","e","f","g","h"]`).Unstructured()}, "unequip-weapon", http.StatusBadRequest, "inventory full", ""},
+		{"unknown slot", worn, "unequip-cape", http.StatusBadRequest, "unknown slot: cape", ""},
+		{"swap", worn, "swap-weapon", http.StatusAccepted, "", "Swapped weapon-rare for weapon-common! +5 damage for 3 attacks"},
+		{"swap into empty slot", map[string]interface{}{"inventory": model.ParseInventory(`["ring-rare"]`).Unstructured()}, "swap-ring", http.StatusAccepted, "", "Equipped ring-rare! +8 HP regen per round"},
+		{"swap with nothing to swap in", worn, "swap-armor", http.StatusBadRequest, "no armor in the backpack", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDungeonAPI(t, 0)
+			f.client.PrependReactor("patch", "actions", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, &unstructured.Unstructured{}, nil
+			})
+			gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+			obj, err := f.client.Tracker().Get(gvr, "default", "lair")
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := obj.(*unstructured.Unstructured)
+			for k, v := range tt.game {
+				unstructured.SetNestedField(d.Object, v, "status", "game", k)
+			}
+			if err := f.client.Tracker().Update(gvr, d, "default"); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := postAttack(attackServer(t, f), tt.action, 0)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Errorf("code = %d (%s), want %d mentioning %q", rec.Code, strings.TrimSpace(rec.Body.String()), tt.wantCode, tt.wantMsg)
+			}
+			if tt.wantLog != "" {
+				obj, _ := f.client.Tracker().Get(gvr, "default", "lair")
+				if got, _, _ := unstructured.NestedString(obj.(*unstructured.Unstructured).Object, "spec", "lastHeroAction"); got != tt.wantLog {
+					t.Errorf("lastHeroAction = %q, want %q", got, tt.wantLog)
+				}
+			}
+		})
+	}
+}
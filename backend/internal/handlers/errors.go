@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorCode is the stable, machine-readable code carried by /api/v2 error
+// responses. Messages may be reworded; codes may not.
+type ErrorCode string
+
+const (
+	CodeBadRequest           ErrorCode = "BAD_REQUEST"
+	CodeInvalidBody          ErrorCode = "INVALID_BODY"
+	CodeBodyTooLarge         ErrorCode = "BODY_TOO_LARGE"
+	CodeUnauthenticated      ErrorCode = "UNAUTHENTICATED"
+	CodeForbidden            ErrorCode = "FORBIDDEN"
+	CodeNotOwner             ErrorCode = "NOT_OWNER"
+	CodeNotFound             ErrorCode = "NOT_FOUND"
+	CodeDungeonNotFound      ErrorCode = "DUNGEON_NOT_FOUND"
+	CodeConflict             ErrorCode = "CONFLICT"
+	CodeStaleSeq             ErrorCode = "STALE_SEQ"
+	CodeDungeonLimit         ErrorCode = "DUNGEON_LIMIT"
+	CodeGameOver             ErrorCode = "GAME_OVER"
+	CodeDungeonInitializing  ErrorCode = "DUNGEON_INITIALIZING"
+	CodeWrongClass           ErrorCode = "WRONG_CLASS"
+	CodeNotEnoughMana        ErrorCode = "NOT_ENOUGH_MANA"
+	CodeOnCooldown           ErrorCode = "ON_COOLDOWN"
+	CodeInvalidTarget        ErrorCode = "INVALID_TARGET"
+	CodeItemNotInInventory   ErrorCode = "ITEM_NOT_IN_INVENTORY"
+	CodeUnknownItem          ErrorCode = "UNKNOWN_ITEM"
+	CodeUnknownRecipe        ErrorCode = "UNKNOWN_RECIPE"
+	CodeUnknownAction        ErrorCode = "UNKNOWN_ACTION"
+	CodeActionOutOfOrder     ErrorCode = "ACTION_OUT_OF_ORDER"
+	CodeNotEnoughGold        ErrorCode = "NOT_ENOUGH_GOLD"
+	CodeInventoryFull        ErrorCode = "INVENTORY_FULL"
+	CodeSlotEmpty            ErrorCode = "SLOT_EMPTY"
+	CodeRateLimited          ErrorCode = "RATE_LIMITED"
+	CodeRequestInProgress    ErrorCode = "REQUEST_IN_PROGRESS"
+	CodeIdempotencyKeyReused ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	CodeInternal             ErrorCode = "INTERNAL"
+	CodeUnavailable          ErrorCode = "UNAVAILABLE"
+)
+
+// pendingRetryAfter is the retry hint for DUNGEON_INITIALIZING and
+// REQUEST_IN_PROGRESS; kro usually finishes a reconcile within a second.
+const pendingRetryAfter = time.Second
+
+// codeForStatus is the generic code for errors written without a specific one.
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeBodyTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
+
+// ErrorEnvelope is the /api/v2 error body.
+type ErrorEnvelope struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes one failed request. Retryable means the same request
+// may succeed later unchanged — or, for STALE_SEQ, after re-reading the
+// dungeon for the current seq. RetryAfterMs is a lower bound when known.
+type ErrorBody struct {
+	Code         ErrorCode `json:"code"`
+	Message      string    `json:"message"`
+	RequestID    string    `json:"requestId,omitempty"`
+	Retryable    bool      `json:"retryable"`
+	RetryAfterMs int64     `json:"retryAfterMs,omitempty"`
+}
+
+// writeCodedError is writeError with an explicit code. v1 responses stay
+// plain text; requests routed through APIv2 get an ErrorEnvelope.
+func writeCodedError(w http.ResponseWriter, msg string, status int, code ErrorCode) {
+	// Client errors are routine (stale seqs, rate limits, bad input) and would
+	// drown the log; only server-side failures warrant a warning.
+	level := slog.LevelDebug
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "request error", "component", "api", "status", status, "code", code, "error", msg)
+	v2, ok := asV2Writer(w)
+	if !ok {
+		http.Error(w, msg, status)
+		return
+	}
+	body := ErrorBody{Code: code, Message: msg, RequestID: v2.requestID}
+	switch code {
+	case CodeStaleSeq, CodeUnavailable, CodeInternal:
+		body.Retryable = true
+	case CodeDungeonInitializing, CodeRequestInProgress:
+		body.Retryable, body.RetryAfterMs = true, pendingRetryAfter.Milliseconds()
+	case CodeRateLimited:
+		body.Retryable = true
+		if s, err := strconv.Atoi(w.Header().Get("Retry-After")); err == nil {
+			body.RetryAfterMs = int64(s) * 1000
+		}
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorEnvelope{Error: body})
+}
+
+var (
+	errAuthRequired   = errors.New("authentication required")
+	errNoOwnerLabel   = errors.New("forbidden: dungeon has no owner label")
+	errOtherUsersGame = errors.New("forbidden: dungeon belongs to another user")
+)
+
+// writeOwnerError reports a requireDungeonOwner failure. The status stays 403
+// for v1 compatibility even when the caller is unauthenticated.
+func writeOwnerError(w http.ResponseWriter, err error) {
+	code := CodeNotOwner
+	if errors.Is(err, errAuthRequired) {
+		code = CodeUnauthenticated
+	}
+	writeCodedError(w, err.Error(), http.StatusForbidden, code)
+}
+
+// asV2Writer finds the v2Writer under any wrappers that expose Unwrap, as
+// recordingWriter does.
+func asV2Writer(w http.ResponseWriter) (*v2Writer, bool) {
+	for {
+		switch t := w.(type) {
+		case *v2Writer:
+			return t, true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// v2Writer marks a response as belonging to /api/v2 so error writers emit the
+// JSON envelope. It carries the request ID AccessLog assigned.
+type v2Writer struct {
+	http.ResponseWriter
+	requestID string
+}
+
+// Hijack passes WebSocket upgrades on /api/v2/events through.
+func (w *v2Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// APIv2 serves /api/v2 as an alias of /api/v1 that differs only in its error
+// bodies. It rewrites the path before next — auth, validation and the mux —
+// sees the request, so v2 needs no routes of its own. Other paths pass through.
+func APIv2(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/v2/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/api/v1/" + rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(&v2Writer{ResponseWriter: w, requestID: requestIDFromCtx(r)}, r2)
+	})
+}
@@ -0,0 +1,49 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+)
+
+func TestAPIv2ErrorEnvelope(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/dungeons", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	srv := handlers.AccessLog(handlers.APIv2(handlers.ValidateRequest(mux)))
+	bad := `{"name":"lair","monsters":11,"difficulty":"easy"}`
+
+	// v1 keeps its plain-text errors.
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/dungeons", strings.NewReader(bad)))
+	if rec.Code != http.StatusBadRequest || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("v1: code = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// v2 routes to the same handler and wraps errors in the envelope.
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v2/dungeons", strings.NewReader(bad))
+	req.Header.Set("X-Request-Id", "req-123")
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("v2: code = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var env handlers.ErrorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("v2 body %q: %v", rec.Body.String(), err)
+	}
+	if env.Error.Code != handlers.CodeInvalidBody || env.Error.RequestID != "req-123" || env.Error.Retryable || !strings.Contains(env.Error.Message, "monsters") {
+		t.Errorf("v2 envelope = %+v", env.Error)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v2/dungeons", strings.NewReader(`{"name":"lair","monsters":3,"difficulty":"easy"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("v2 valid create: code = %d (%s)", rec.Code, rec.Body.String())
+	}
+}
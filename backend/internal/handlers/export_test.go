@@ -0,0 +1,95 @@
+package handlers
+
+// Exports for the handlers_test package, which covers unexported helpers.
+
+import (
+	"context"
+	"time"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+)
+
+var LoadSessionKeyring = loadSessionKeyring
+
+func (kr *sessionKeyring) Sign(encoded string) string { return kr.sign(encoded) }
+
+func (kr *sessionKeyring) Verify(token string) (encoded, kid string, ok bool) {
+	return kr.verify(token)
+}
+
+var FetchAdminOrgs = fetchAdminOrgs
+
+var LoadCertificateKeyring = loadCertificateKeyring
+
+// SetAdminAllowlist replaces KROMBAT_ADMIN_LOGINS and KROMBAT_ADMIN_ORGS
+// until the returned func is called.
+func SetAdminAllowlist(logins, orgs string) (restore func()) {
+	prevLogins, prevOrgs := adminLogins, adminOrgs
+	adminLogins, adminOrgs = parseAllowlist(logins), parseAllowlist(orgs)
+	return func() { adminLogins, adminOrgs = prevLogins, prevOrgs }
+}
+
+// SessionCookie signs a session cookie value for login with the key a Handler
+// built from the same environment signs with.
+func SessionCookie(login string, orgs []string) string {
+	kr, err := sessionKeyringFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	token, err := kr.signToken(sessionPayload{Login: login, Orgs: orgs, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// StopAutoBattleRun cancels the run h drives for a dungeon, if any.
+func (h *Handler) StopAutoBattleRun(ns, name string) { h.autoBattle.stop(ns + "/" + name) }
+
+// ResumeAutoBattlesOnce runs a single ResumeAutoBattles scan.
+func (h *Handler) ResumeAutoBattlesOnce(ctx context.Context) { h.resumeAutoBattles(ctx) }
+
+// EarnedAchievements evaluates the built-in rules of one kind.
+func EarnedAchievements(kind catalog.AchievementKind, run RunStats, profile ProfileStats) []string {
+	return mustCompileAchievements(catalog.DefaultAchievements()).earned(kind, run, profile)
+}
+
+// IssueCertificate signs and stores a document for cert as recording a run
+// that earned it would, and returns the document's ID.
+func (h *Handler) IssueCertificate(ctx context.Context, login, cert string) (string, error) {
+	ach, _ := h.achievements.get(ctx).lookup(cert)
+	c, err := h.newCertificate(login, ach, CertificateEvidence{Source: "run", Rule: ach.Rule})
+	if err != nil {
+		return "", err
+	}
+	return c.ID, h.certificates.put(ctx, c)
+}
+
+var ClientIP = clientIP
+
+// SetTrustedProxies replaces KROMBAT_TRUSTED_PROXIES until the returned func
+// is called.
+func SetTrustedProxies(cidrs string) (restore func()) {
+	prev := trustedProxies
+	trustedProxies = parseTrustedProxies(cidrs)
+	return func() { trustedProxies = prev }
+}
+
+// RateDecision is what one take from a token bucket reports.
+type RateDecision struct {
+	Allowed           bool
+	Remaining         int
+	Reset, RetryAfter time.Duration
+}
+
+// TokenBucket returns a take func for a rate limiter that reads its clock
+// from now.
+func TokenBucket(rate float64, burst int, now func() time.Time) func(key string) RateDecision {
+	rl := &rateLimiter{policy: rateLimitPolicy{Name: "test", Rate: rate, Burst: burst}, now: now, buckets: map[string]*tokenBucket{}}
+	return func(key string) RateDecision {
+		d := rl.take(key)
+		return RateDecision{Allowed: d.allowed, Remaining: d.remaining, Reset: d.reset, RetryAfter: d.retryAfter}
+	}
+}
+
+const IdempotencyMaxEntries = idempotencyMaxEntries
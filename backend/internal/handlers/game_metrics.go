@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"cmp"
+	"log/slog"
+	"time"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// gameCollector computes the game gauges from the Dungeon informer cache at
+// scrape time, so they are never older than the watch stream and cost no API
+// calls. Label values are clamped to known sets to keep cardinality bounded.
+type gameCollector struct {
+	dungeons *k8s.DungeonInformer
+}
+
+// RegisterGameMetrics registers the scrape-time game collector, hooks the
+// dungeonInit latency histogram onto informer updates, and starts the
+// active_dungeons log line CloudWatch turns into a metric (#475).
+func RegisterGameMetrics(dungeons *k8s.DungeonInformer) {
+	prometheus.MustRegister(&gameCollector{dungeons: dungeons})
+	dungeons.OnUpdate(observeDungeonInit)
+	go logActiveDungeons(dungeons)
+}
+
+func (c *gameCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		activeDungeonsDesc, monstersAliveDesc, monstersDeadDesc,
+		bossesPendingDesc, bossesReadyDesc, bossesDefeatedDesc,
+		gameVictoriesDesc, gameDefeatsDesc, dungeonsDesc,
+	} {
+		ch <- d
+	}
+}
+
+type dungeonSeriesKey struct{ heroClass, difficulty, outcome string }
+
+func (c *gameCollector) Collect(ch chan<- prometheus.Metric) {
+	// Until the first List lands every gauge would read 0 — report nothing
+	// rather than a misleading dip after each backend restart.
+	if !c.dungeons.HasSynced() {
+		return
+	}
+	list := c.dungeons.List()
+	var alive, dead, bPend, bReady, bDef, wins, losses float64
+	byKey := map[dungeonSeriesKey]float64{}
+	for _, u := range list {
+		d, err := model.FromUnstructured(u)
+		if err != nil {
+			continue
+		}
+		living := d.Status.Game.LivingMonsters()
+		alive += float64(living)
+		dead += float64(len(d.Status.Game.MonsterHP) - living)
+		switch d.Status.BossState {
+		case "ready":
+			bReady++
+		case "defeated":
+			bDef++
+		default:
+			bPend++
+		}
+		outcome := "in-progress"
+		if d.Status.Victory && d.InFinalRoom() {
+			wins++
+			outcome = "victory"
+		}
+		if d.Status.Defeat {
+			losses++
+			outcome = "defeat"
+		}
+		byKey[dungeonSeriesKey{
+			heroClass:  boundedLabel(cmp.Or(d.Spec.HeroClass, "warrior"), catalog.ClassIDs()...),
+			difficulty: boundedLabel(cmp.Or(d.Spec.Difficulty, "normal"), "easy", "normal", "hard"),
+			outcome:    outcome,
+		}]++
+	}
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+	gauge(activeDungeonsDesc, float64(len(list)))
+	gauge(monstersAliveDesc, alive)
+	gauge(monstersDeadDesc, dead)
+	gauge(bossesPendingDesc, bPend)
+	gauge(bossesReadyDesc, bReady)
+	gauge(bossesDefeatedDesc, bDef)
+	gauge(gameVictoriesDesc, wins)
+	gauge(gameDefeatsDesc, losses)
+	for k, v := range byKey {
+		gauge(dungeonsDesc, v, k.heroClass, k.difficulty, k.outcome)
+	}
+}
+
+// boundedLabel returns v if it is one of allowed, otherwise "other".
+func boundedLabel(v string, allowed ...string) string {
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	return "other"
+}
+
+// observeDungeonInit records how long kro took to run dungeonInit: from the
+// CR's creation to the first status.game carrying initProcessedSeq.
+func observeDungeonInit(old, cur *unstructured.Unstructured) {
+	before, err1 := model.FromUnstructured(old)
+	after, err2 := model.FromUnstructured(cur)
+	if err1 != nil || err2 != nil || before.Status.Game.Initialized() || !after.Status.Game.Initialized() {
+		return
+	}
+	created := after.CreationTimestamp.Time
+	if created.IsZero() {
+		return
+	}
+	kroStateNodeLatency.WithLabelValues("dungeonInit").Observe(time.Since(created).Seconds())
+}
+
+// logActiveDungeons emits the active_dungeons log line every 30s from the
+// informer cache (#475: CloudWatch metric filter reads $.count).
+func logActiveDungeons(dungeons *k8s.DungeonInformer) {
+	for {
+		time.Sleep(30 * time.Second)
+		if dungeons.HasSynced() {
+			slog.Info("active_dungeons", "component", "game", "count", len(dungeons.List()))
+		}
+	}
+}
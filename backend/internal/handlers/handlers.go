@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pnz1990/krombat/backend/internal/catalog"
 	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
 	"github.com/pnz1990/krombat/backend/internal/ws"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -23,73 +31,52 @@ import (
 type Handler struct {
 	client         *k8s.Client
 	hub            *ws.Hub
-	attackLimit    *rateLimiter
-	telemetryLimit *rateLimiter // #419: rate-limit telemetry endpoints (per IP)
+	limits         map[string]*rateLimiter // per-route token buckets, see rateLimitPolicies
+	telemetryLimit *rateLimiter            // #419: rate-limit telemetry endpoints (per client)
+	tokens         *apiTokenStore
+	autoBattle     *autoBattleRuns
+	idempotency    *idempotencyStore
+	items          *itemCatalogStore
+	achievements   *achievementStore
+	certificates   *certificateStore
+	sessions       *sessionKeyring
+	done           chan struct{} // closed by Close to stop background loops
+	closeOnce      sync.Once
 }
 
-func New(client *k8s.Client, hub *ws.Hub) *Handler {
-	h := &Handler{
-		client:         client,
-		hub:            hub,
-		attackLimit:    newRateLimiter(300 * time.Millisecond),
-		telemetryLimit: newRateLimiter(2 * time.Second), // max 1 telemetry event per 2s per remote addr
+func New(client *k8s.Client, hub *ws.Hub) (*Handler, error) {
+	sessions, err := sessionKeyringFromEnv()
+	if err != nil {
+		return nil, err
 	}
-	go h.pollGameMetrics()
-	return h
-}
-
-func (h *Handler) pollGameMetrics() {
-	for {
-		list, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace("").List(
-			context.Background(), metav1.ListOptions{})
-		if err == nil {
-			var alive, dead, bPend, bReady, bDef, wins, losses float64
-			activeDungeons.Set(float64(len(list.Items)))
-			// #475: emit structured log for CloudWatch active_dungeons metric filter
-			slog.Info("active_dungeons", "component", "game", "count", len(list.Items))
-			for _, d := range list.Items {
-				game := getGameState(d.Object)
-				status, _ := d.Object["status"].(map[string]interface{})
-				if hps, ok := game["monsterHP"].([]interface{}); ok {
-					for _, hp := range hps {
-						if sliceInt(hp) > 0 {
-							alive++
-						} else {
-							dead++
-						}
-					}
-				}
-				switch bs, _ := status["bossState"].(string); bs {
-				case "ready":
-					bReady++
-				case "defeated":
-					bDef++
-				default:
-					bPend++
-				}
-				if v, _ := status["victory"].(bool); v {
-					wins++
-				}
-				if v, _ := status["defeat"].(bool); v {
-					losses++
-				}
-			}
-			monstersAlive.Set(alive)
-			monstersDead.Set(dead)
-			bossesPending.Set(bPend)
-			bossesReady.Set(bReady)
-			bossesDefeated.Set(bDef)
-			gameVictories.Set(wins)
-			gameDefeats.Set(losses)
-		}
-		time.Sleep(30 * time.Second)
+	certificates, err := newCertificateStore(client)
+	if err != nil {
+		return nil, err
 	}
+	h := &Handler{
+		client:       client,
+		hub:          hub,
+		limits:       make(map[string]*rateLimiter, len(rateLimitPolicies)),
+		tokens:       newAPITokenStore(client),
+		autoBattle:   newAutoBattleRuns(),
+		idempotency:  newIdempotencyStore(client),
+		items:        newItemCatalogStore(client),
+		achievements: newAchievementStore(client),
+		certificates: certificates,
+		sessions:     sessions,
+		done:         make(chan struct{}),
+	}
+	for name, p := range rateLimitPolicies {
+		h.limits[name] = newRateLimiter(p, h.done)
+	}
+	h.telemetryLimit = h.limits["telemetry"]
+	return h, nil
 }
 
-func (h *Handler) AttackWithRateLimit() http.HandlerFunc {
-	return h.attackLimit.Wrap(h.CreateAttack, func(r *http.Request) string {
-		return r.PathValue("namespace") + "/" + r.PathValue("name")
-	})
+// Close stops the Handler's background loops. The server never calls it —
+// the loops end with the process — but every other Handler must be closed.
+func (h *Handler) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
 }
 
 // validDNSLabel matches valid Kubernetes namespace names (RFC 1123 DNS label).
@@ -117,6 +104,8 @@ type CreateDungeonReq struct {
 	Difficulty string `json:"difficulty"`
 	HeroClass  string `json:"heroClass"`
 	Namespace  string `json:"namespace"`
+	// Rooms is how many rooms the hero fights through (2-5, 0 = 2).
+	Rooms int64 `json:"rooms"`
 	// New Game+ carry-over fields (optional, 0 = fresh start)
 	// NOTE: *Bonus fields are intentionally ignored — gear is carried in
 	// inventory only and the player re-equips each run to avoid double-dipping.
@@ -135,7 +124,7 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 	// #421: cap body to prevent multi-megabyte JSON DoS
 	r.Body = http.MaxBytesReader(w, r.Body, 4096) // 4 KB is well above any valid dungeon creation payload
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "invalid request body", http.StatusBadRequest)
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
 		return
 	}
 	if req.Name == "" || req.Monsters < 1 || req.Monsters > 10 {
@@ -150,6 +139,13 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 		writeError(w, "difficulty must be easy, normal, or hard", http.StatusBadRequest)
 		return
 	}
+	if req.Rooms == 0 {
+		req.Rooms = model.DefaultRooms
+	}
+	if req.Rooms < model.DefaultRooms || req.Rooms > model.MaxRooms {
+		writeError(w, fmt.Sprintf("rooms must be %d-%d", model.DefaultRooms, model.MaxRooms), http.StatusBadRequest)
+		return
+	}
 	if req.Namespace == "" {
 		req.Namespace = "default"
 	}
@@ -170,7 +166,7 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 			LabelSelector: "krombat.io/owner=" + sess.Login,
 		})
 	if listErr == nil && len(existing.Items) >= maxDungeonsPerUser {
-		writeError(w, fmt.Sprintf("dungeon limit reached: you may have at most %d active dungeons — delete one first", maxDungeonsPerUser), http.StatusConflict)
+		writeCodedError(w, fmt.Sprintf("dungeon limit reached: you may have at most %d active dungeons — delete one first", maxDungeonsPerUser), http.StatusConflict, CodeDungeonLimit)
 		return
 	}
 
@@ -178,8 +174,8 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 	if heroClass == "" {
 		heroClass = "warrior"
 	}
-	if heroClass != "warrior" && heroClass != "mage" && heroClass != "rogue" {
-		writeError(w, "heroClass must be warrior, mage, or rogue", http.StatusBadRequest)
+	if _, ok := catalog.LookupClass(heroClass); !ok {
+		writeCodedError(w, "heroClass must be one of "+strings.Join(catalog.ClassIDs(), ", "), http.StatusBadRequest, CodeBadRequest)
 		return
 	}
 
@@ -197,14 +193,14 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 	// Equipment bonuses are NOT carried over — items are in inventory and the
 	// player re-equips each run, preventing gear from being both equipped and
 	// in the backpack simultaneously (#555).
-	var profileInv string
+	var profileInv model.Inventory
 	if sess != nil {
 		ctx0 := context.Background()
 		cmClient0 := h.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace)
 		if profCM, profErr := cmClient0.Get(ctx0, profileCMName, metav1.GetOptions{}); profErr == nil {
 			if d, ok := profCM.Object["data"].(map[string]interface{}); ok {
 				p := profileFromData(d, sess.Login)
-				if p.HeroHP > 0 || p.Inventory != "" {
+				if p.HeroHP > 0 || p.Inventory.Len() > 0 {
 					profileInv = p.Inventory
 				}
 			}
@@ -219,12 +215,15 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 		"difficulty": req.Difficulty,
 		"heroClass":  heroClass,
 		"runCount":   runCount,
+		"rooms":      req.Rooms,
+		// Seeded by name and run so a dungeon's goals are reproducible.
+		"objectives": model.RollObjectives(fmt.Sprintf("%s-%d", req.Name, runCount), req.Monsters, req.Rooms).Unstructured(),
 	}
 	// Carry persistent inventory only — no *Bonus fields (#555).
 	// Items start in the backpack; the player equips them manually each run.
 	// kro actionResolve will set the bonus fields when the player equips.
-	if profileInv != "" {
-		dungeonSpec["inventory"] = profileInv
+	if profileInv.Len() > 0 {
+		dungeonSpec["inventory"] = profileInv.Unstructured()
 	}
 
 	dungeon := &unstructured.Unstructured{Object: map[string]interface{}{
@@ -259,6 +258,7 @@ func (h *Handler) CreateDungeon(w http.ResponseWriter, r *http.Request) {
 		"hero_class", heroClass,
 		"difficulty", req.Difficulty,
 		"monsters", req.Monsters,
+		"rooms", req.Rooms,
 		"run_count", runCount,
 	)
 	w.Header().Set("Content-Type", "application/json")
@@ -286,33 +286,44 @@ func (h *Handler) ListDungeons(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type summary struct {
-		Name           string      `json:"name"`
-		Namespace      string      `json:"namespace"`
-		Difficulty     interface{} `json:"difficulty"`
-		LivingMonsters interface{} `json:"livingMonsters"`
-		BossState      interface{} `json:"bossState"`
-		Victory        interface{} `json:"victory"`
-		Modifier       interface{} `json:"modifier"`
-		RunCount       interface{} `json:"runCount"`
+		Name           string  `json:"name"`
+		Namespace      string  `json:"namespace"`
+		Difficulty     string  `json:"difficulty"`
+		LivingMonsters *int64  `json:"livingMonsters"` // null until kro reconciles
+		BossState      *string `json:"bossState"`
+		Victory        *bool   `json:"victory"`
+		Modifier       string  `json:"modifier"`
+		RunCount       int64   `json:"runCount"`
+		Rooms          int64   `json:"rooms"`
+		CurrentRoom    int64   `json:"currentRoom"`
 	}
 	items := []summary{}
-	for _, d := range list.Items {
-		if d.GetDeletionTimestamp() != nil {
+	for i := range list.Items {
+		if list.Items[i].GetDeletionTimestamp() != nil {
 			continue
 		}
-		spec, _ := d.Object["spec"].(map[string]interface{})
-		status, _ := d.Object["status"].(map[string]interface{})
-		game := getGameState(d.Object)
-		items = append(items, summary{
-			Name:           d.GetName(),
-			Namespace:      d.GetNamespace(),
-			Difficulty:     spec["difficulty"],
-			LivingMonsters: status["livingMonsters"],
-			BossState:      status["bossState"],
-			Victory:        status["victory"],
-			Modifier:       game["modifier"],
-			RunCount:       spec["runCount"],
-		})
+		d, err := model.FromUnstructured(&list.Items[i])
+		if err != nil {
+			slog.Warn("skipping undecodable dungeon", "component", "api", "error", err)
+			continue
+		}
+		item := summary{
+			Name:        d.Name,
+			Namespace:   d.Namespace,
+			Difficulty:  d.Spec.Difficulty,
+			Modifier:    d.Status.Game.Modifier,
+			RunCount:    d.Spec.RunCount,
+			Rooms:       d.RoomCount(),
+			CurrentRoom: max(d.Status.Game.CurrentRoom, 1),
+		}
+		if d.HasStatus() {
+			item.LivingMonsters = &d.Status.LivingMonsters
+			item.BossState = &d.Status.BossState
+			// status.victory is per room; the run is won in the final room.
+			victory := d.Status.Victory && d.InFinalRoom()
+			item.Victory = &victory
+		}
+		items = append(items, item)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(items)
@@ -329,13 +340,13 @@ func (h *Handler) GetDungeon(w http.ResponseWriter, r *http.Request) {
 		context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		slog.Error("failed to get dungeon", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return
 	}
 
 	// Ownership check: only the owning user can get their dungeon.
 	if err := requireDungeonOwner(r, dungeon); err != nil {
-		writeError(w, err.Error(), http.StatusForbidden)
+		writeOwnerError(w, err)
 		return
 	}
 
@@ -355,28 +366,18 @@ func (h *Handler) DeleteDungeon(w http.ResponseWriter, r *http.Request) {
 	if dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{}); err == nil {
 		// Ownership check: only the owning user can delete their dungeon.
 		if ownerErr := requireDungeonOwner(r, dungeon); ownerErr != nil {
-			writeError(w, ownerErr.Error(), http.StatusForbidden)
+			writeOwnerError(w, ownerErr)
 			return
 		}
-		spec, _ := dungeon.Object["spec"].(map[string]interface{})
-		kroStatus, _ := dungeon.Object["status"].(map[string]interface{})
-		if spec != nil {
+		if d, decodeErr := model.FromUnstructured(dungeon); decodeErr == nil {
 			login := ""
 			if sess2 := sessionFromCtx(r.Context()); sess2 != nil {
 				login = sess2.Login
 			}
-			// Merge spec + game state for recordLeaderboard/recordProfile
-			// (game state lives in status.game after migration)
-			merged := make(map[string]interface{}, len(spec))
-			for k, v := range spec {
-				merged[k] = v
-			}
-			game := getGameState(dungeon.Object)
-			for k, v := range game {
-				merged[k] = v
-			}
-			go h.recordLeaderboard(merged, kroStatus, name, login)
-			go h.recordProfile(login, merged, kroStatus)
+			go h.recordLeaderboard(d, login)
+			go h.recordProfile(login, d)
+		} else {
+			slog.Warn("skipping run stats for undecodable dungeon", "component", "api", "error", decodeErr)
 		}
 	}
 
@@ -385,7 +386,7 @@ func (h *Handler) DeleteDungeon(w http.ResponseWriter, r *http.Request) {
 			context.Background(), name, metav1.DeleteOptions{})
 	}); err != nil {
 		slog.Error("failed to delete dungeon", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return
 	}
 	slog.Info("dungeon deleted", "component", "api", "dungeon", name, "namespace", ns)
@@ -400,7 +401,8 @@ type LeaderboardEntry struct {
 	Difficulty  string `json:"difficulty"`
 	Outcome     string `json:"outcome"`
 	TotalTurns  int64  `json:"totalTurns"`
-	CurrentRoom int64  `json:"currentRoom"`
+	CurrentRoom int64  `json:"currentRoom"` // deepest room reached
+	Rooms       int64  `json:"rooms,omitempty"`
 	Timestamp   string `json:"timestamp"`
 }
 
@@ -412,54 +414,44 @@ const profileCMName = "krombat-profiles"
 
 var leaderboardGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
 
-// recordLeaderboard writes a run completion entry to the krombat-leaderboard ConfigMap.
-// Called asynchronously before dungeon deletion. Silently skips on any error.
-// kroStatus is the kro-derived dungeon status (may be nil if kro hasn't reconciled yet).
-func (h *Handler) recordLeaderboard(spec map[string]interface{}, kroStatus map[string]interface{}, dungeonName string, githubLogin string) {
-	heroClass, _ := spec["heroClass"].(string)
-	difficulty, _ := spec["difficulty"].(string)
-	currentRoom := getInt(spec, "currentRoom")
-	attackSeq := getInt(spec, "attackSeq")
-	actionSeq := getInt(spec, "actionSeq")
-
-	// Use kro-derived victory/defeat status where available — it is the authoritative
-	// source computed by dungeon-graph CEL from boss and hero entity states.
-	// Fall back to spec-based derivation only if kro status is absent.
-	outcome := "in-progress"
-	if kroStatus != nil {
-		isVictory, _ := kroStatus["victory"].(bool)
-		isDefeat, _ := kroStatus["defeat"].(bool)
-		if isVictory {
-			outcome = "victory"
-		} else if isDefeat {
-			outcome = "defeat"
-		} else {
-			// kro says neither victory nor defeat — check for room1-cleared
-			// (boss dead, all monsters dead, still in room 1)
-			heroHP := getInt(spec, "heroHP")
-			bossHP := getInt(spec, "bossHP")
-			if heroHP > 0 {
-				monsterHPRaw, _ := spec["monsterHP"].([]interface{})
-				allDead := true
-				for _, v := range monsterHPRaw {
-					if sliceInt(v) > 0 {
-						allDead = false
-						break
-					}
-				}
-				if bossHP <= 0 && allDead {
-					outcome = "room1-cleared"
-				}
-			}
-		}
-	} else {
+// runOutcome classifies a run for the leaderboard and profile: "victory"
+// (boss of the final room defeated), "defeat", "room-cleared" (an earlier
+// room cleared, hero alive) or "in-progress".
+//
+// kro's status.victory only says the current room's boss is defeated, so it
+// is a full victory only in the final room.
+func runOutcome(d *model.Dungeon) string {
+	if !d.HasStatus() {
 		// #402: kro status unavailable — do not fall back to raw-HP derivation.
-		// Outcome stays "in-progress"; only victories are persisted, so this is a no-op.
-		slog.Debug("recordLeaderboard: kro status unavailable, skipping raw-HP fallback (#402)", "dungeon", dungeonName)
+		return "in-progress"
 	}
+	game := d.Status.Game
+	switch {
+	case d.Status.Victory && d.InFinalRoom():
+		return "victory"
+	case d.Status.Defeat:
+		return "defeat"
+	case d.Status.Victory || (game.HeroHP > 0 && game.RoomCleared()):
+		return "room-cleared"
+	}
+	return "in-progress"
+}
 
-	totalTurns := attackSeq + actionSeq
-	runCount := getInt(spec, "runCount")
+// recordLeaderboard writes a run completion entry to the krombat-leaderboard ConfigMap.
+// Called asynchronously before dungeon deletion. Silently skips on any error.
+// The kro-derived status may be absent if kro hasn't reconciled yet.
+func (h *Handler) recordLeaderboard(d *model.Dungeon, githubLogin string) {
+	dungeonName := d.Name
+	heroClass := d.Spec.HeroClass
+	difficulty := d.Spec.Difficulty
+	currentRoom := d.Status.Game.CurrentRoom
+
+	// Use kro-derived victory/defeat status — it is the authoritative source
+	// computed by dungeon-graph CEL from boss and hero entity states.
+	outcome := runOutcome(d)
+
+	totalTurns := d.TotalTurns()
+	runCount := d.Spec.RunCount
 	// Business metric: dungeon lifecycle end event (Issue #358)
 	slog.Info("dungeon_ended",
 		"component", "game",
@@ -469,9 +461,10 @@ func (h *Handler) recordLeaderboard(spec map[string]interface{}, kroStatus map[s
 		"outcome", outcome,
 		"total_turns", totalTurns,
 		"current_room", currentRoom,
+		"rooms", d.RoomCount(),
 		"run_count", runCount,
 	)
-	// Only persist victories to the leaderboard — defeats, room1-cleared and
+	// Only persist victories to the leaderboard — defeats, room-cleared and
 	// in-progress deletions are noise that would clutter the top-runs list.
 	if outcome != "victory" {
 		return
@@ -484,6 +477,7 @@ func (h *Handler) recordLeaderboard(spec map[string]interface{}, kroStatus map[s
 		Outcome:     outcome,
 		TotalTurns:  totalTurns,
 		CurrentRoom: currentRoom,
+		Rooms:       d.RoomCount(),
 		Timestamp:   time.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -553,36 +547,49 @@ func (h *Handler) recordLeaderboard(spec map[string]interface{}, kroStatus map[s
 
 // UserProfile holds a player's persistent cross-dungeon stats, badges, and inventory.
 type UserProfile struct {
-	DungeonsPlayed    int            `json:"dungeonsPlayed"`
-	DungeonsWon       int            `json:"dungeonsWon"`
-	DungeonsLost      int            `json:"dungeonsLost"`
-	DungeonsAbandoned int            `json:"dungeonsAbandoned"`
-	TotalTurns        int            `json:"totalTurns"`
-	TotalKills        int            `json:"totalKills"`
-	TotalBossKills    int            `json:"totalBossKills"`
-	FavouriteClass    string         `json:"favouriteClass"`
-	FavouriteDiff     string         `json:"favouriteDifficulty"`
-	Inventory         string         `json:"inventory"` // JSON array string, same format as spec.inventory
-	WeaponBonus       int64          `json:"weaponBonus"`
-	WeaponUses        int64          `json:"weaponUses"`
-	ArmorBonus        int64          `json:"armorBonus"`
-	ShieldBonus       int64          `json:"shieldBonus"`
-	HelmetBonus       int64          `json:"helmetBonus"`
-	PantsBonus        int64          `json:"pantsBonus"`
-	BootsBonus        int64          `json:"bootsBonus"`
-	RingBonus         int64          `json:"ringBonus"`
-	AmuletBonus       int64          `json:"amuletBonus"`
-	HeroHP            int64          `json:"heroHP"`
-	HeroMana          int64          `json:"heroMana"`
-	EarnedBadges      []string       `json:"earnedBadges"`
-	BadgeCounts       map[string]int `json:"badgeCounts"`
-	XP                int            `json:"xp"`
-	Level             int            `json:"level"`
-	KroCertificates   []string       `json:"kroCertificates"`
-	FirstPlayed       string         `json:"firstPlayed"`
-	LastPlayed        string         `json:"lastPlayed"`
+	DungeonsPlayed    int             `json:"dungeonsPlayed"`
+	DungeonsWon       int             `json:"dungeonsWon"`
+	DungeonsLost      int             `json:"dungeonsLost"`
+	DungeonsAbandoned int             `json:"dungeonsAbandoned"`
+	TotalTurns        int             `json:"totalTurns"`
+	TotalKills        int             `json:"totalKills"`
+	TotalBossKills    int             `json:"totalBossKills"`
+	FavouriteClass    string          `json:"favouriteClass"`
+	FavouriteDiff     string          `json:"favouriteDifficulty"`
+	Inventory         model.Inventory `json:"inventory"`
+	WeaponBonus       int64           `json:"weaponBonus"`
+	WeaponUses        int64           `json:"weaponUses"`
+	ArmorBonus        int64           `json:"armorBonus"`
+	ShieldBonus       int64           `json:"shieldBonus"`
+	HelmetBonus       int64           `json:"helmetBonus"`
+	PantsBonus        int64           `json:"pantsBonus"`
+	BootsBonus        int64           `json:"bootsBonus"`
+	RingBonus         int64           `json:"ringBonus"`
+	AmuletBonus       int64           `json:"amuletBonus"`
+	HeroHP            int64           `json:"heroHP"`
+	HeroMana          int64           `json:"heroMana"`
+	EarnedBadges      []string        `json:"earnedBadges"`
+	BadgeCounts       map[string]int  `json:"badgeCounts"`
+	XP                int             `json:"xp"`
+	Level             int             `json:"level"`
+	KroCertificates   []string        `json:"kroCertificates"`
+	FirstPlayed       string          `json:"firstPlayed"`
+	LastPlayed        string          `json:"lastPlayed"`
+
+	// CertificateIDs maps a certificate to its signed document (see
+	// certificates.go); certificates earned before signing have none.
+	CertificateIDs map[string]string `json:"certificateIds,omitempty"`
+
+	// ObjectivesPaid lists the most recent dungeons, by UID, whose
+	// objectives XP is already in XP. A won run is recorded at its victory
+	// and again when it is deleted; the second record must not pay twice.
+	ObjectivesPaid []string `json:"objectivesPaid,omitempty"`
 }
 
+// objectivesPaidKept bounds UserProfile.ObjectivesPaid. Only the victory
+// record and the delete that follows it need to see each other.
+const objectivesPaidKept = 20
+
 func emptyProfile() UserProfile {
 	return UserProfile{
 		EarnedBadges:    []string{},
@@ -612,24 +619,23 @@ func profileFromData(data map[string]interface{}, key string) UserProfile {
 	return p
 }
 
-// classDefaultHP returns the default max HP for a hero class.
+// classDefaultHP returns the default max HP for a hero class; unknown classes
+// get the warrior's.
 func classDefaultHP(heroClass string) int64 {
-	switch heroClass {
-	case "mage":
-		return 120
-	case "rogue":
-		return 150
-	default:
-		return 200
-	}
+	return heroClassOrDefault(heroClass).MaxHP
 }
 
-// classDefaultMana returns the default max mana for a hero class (only mage has mana).
+// classDefaultMana returns the default max mana for a hero class.
 func classDefaultMana(heroClass string) int64 {
-	if heroClass == "mage" {
-		return 8
+	return heroClassOrDefault(heroClass).MaxMana
+}
+
+func heroClassOrDefault(heroClass string) catalog.Class {
+	if c, ok := catalog.LookupClass(heroClass); ok {
+		return c
 	}
-	return 0
+	c, _ := catalog.LookupClass("warrior")
+	return c
 }
 
 // xpThresholds and levelTitles define the career XP level-up table for issue #360.
@@ -659,175 +665,31 @@ func levelTitle(level int) string {
 	return levelTitles[level-1]
 }
 
-// computeProfileBadges returns badge IDs earned in this dungeon run that should be
-// persisted to the profile. Career badges (multi-class, reaper, legend) are evaluated
-// after profile stats are updated.
-func computeProfileBadges(spec map[string]interface{}, outcome string) []string {
-	if outcome != "victory" && outcome != "room1-cleared" {
-		return nil
-	}
-	heroClass, _ := spec["heroClass"].(string)
-	difficulty, _ := spec["difficulty"].(string)
-	attackSeq := getInt(spec, "attackSeq")
-	actionSeq := getInt(spec, "actionSeq")
-	totalTurns := attackSeq + actionSeq
-	heroHP := getInt(spec, "heroHP")
-	currentRoom := getInt(spec, "currentRoom")
-
-	maxHeroHP := classDefaultHP(heroClass)
-
-	weaponBonus := getInt(spec, "weaponBonus")
-	equip := []interface{}{
-		spec["weaponBonus"], spec["armorBonus"], spec["shieldBonus"],
-		spec["helmetBonus"], spec["pantsBonus"], spec["bootsBonus"],
-		spec["ringBonus"], spec["amuletBonus"],
-	}
-	equippedCount := 0
-	for _, v := range equip {
-		if getInt(map[string]interface{}{"v": v}, "v") > 0 {
-			equippedCount++
-		}
-	}
-
-	// Check no-potion: if inventory still contains potions and hero never used one
-	// — approximated by checking whether any potion type appears in lastHeroAction
-	lastAction, _ := spec["lastHeroAction"].(string)
-	usedPotion := strings.Contains(lastAction, "potion")
-
-	var badges []string
-	addIf := func(id string, cond bool) {
-		if cond {
-			badges = append(badges, id)
-		}
-	}
-
-	addIf("speedrun", totalTurns <= 30 && outcome == "victory")
-	addIf("deathless", heroHP >= maxHeroHP*8/10 && outcome == "victory")
-	addIf("pacifist", weaponBonus == 0 && outcome == "victory")
-	addIf("warrior-win", heroClass == "warrior" && outcome == "victory")
-	addIf("mage-win", heroClass == "mage" && outcome == "victory")
-	addIf("rogue-win", heroClass == "rogue" && outcome == "victory")
-	addIf("hard-win", difficulty == "hard" && outcome == "victory")
-	addIf("collector", equippedCount >= 5 && outcome == "victory")
-	addIf("room2-winner", currentRoom >= 2 && outcome == "victory")
-	addIf("no-damage", heroHP >= maxHeroHP && outcome == "victory")
-	addIf("no-potions", !usedPotion && outcome == "victory")
-	addIf("full-kit", equippedCount >= 8 && outcome == "victory")
-	return badges
-}
-
-// tier2Certs is the set of certificate IDs that can be awarded via the
-// POST /api/v1/profile/cert endpoint (frontend-triggered on K8s log tab interaction).
-var tier2Certs = map[string]bool{
-	"log-explorer":  true,
-	"cel-trace":     true,
-	"insight-card":  true,
-	"glossary":      true,
-	"graph-panel":   true,
-	"kro-reconcile": true,
-}
-
-// computeCertificates derives Tier 1 and Tier 3 certificate IDs from spec + profile state.
-// Returns only certs not already in profile.KroCertificates.
-func computeCertificates(spec map[string]interface{}, profile UserProfile, outcome string) []string {
-	existing := map[string]bool{}
-	for _, c := range profile.KroCertificates {
-		existing[c] = true
-	}
+// computeCertificates returns the certificates whose rules hold for this run
+// and profile, leaving out those already in profile.KroCertificates.
+func computeCertificates(rules *achievementRules, run RunStats, profile UserProfile) []string {
 	var certs []string
-	add := func(id string) {
-		if !existing[id] {
-			certs = append(certs, id)
-		}
-	}
-
-	// Tier 1 — Observer
-	if profile.DungeonsPlayed == 1 {
-		add("first-dungeon")
-	}
-	if outcome == "victory" {
-		add("cel-state")
-	}
-	if outcome == "victory" && getInt(spec, "currentRoom") >= 2 {
-		add("two-rooms")
-	}
-	// loot-system: 3+ distinct equipped item types
-	equippedTypes := 0
-	for _, field := range []string{"weaponBonus", "armorBonus", "shieldBonus", "helmetBonus", "pantsBonus", "bootsBonus", "ringBonus", "amuletBonus"} {
-		if getInt(spec, field) > 0 {
-			equippedTypes++
+	for _, c := range rules.earned(catalog.Certificate, run, newProfileStats(profile)) {
+		if !slices.Contains(profile.KroCertificates, c) {
+			certs = append(certs, c)
 		}
 	}
-	if equippedTypes >= 3 {
-		add("loot-system")
-	}
-
-	// Tier 3 — Architect
-	modifier, _ := spec["modifier"].(string)
-	difficulty, _ := spec["difficulty"].(string)
-	if outcome == "victory" && strings.HasPrefix(modifier, "curse-") && difficulty == "hard" {
-		add("modifier-master")
-	}
-	if outcome == "victory" && getInt(spec, "runCount") >= 1 {
-		add("new-game-plus-cert")
-	}
-	if profile.DungeonsWon >= 5 {
-		add("dungeon-master")
-	}
-	if profile.Level >= 5 {
-		add("cel-scholar")
-	}
-	// boss-phase: check if bossPhase ever reached phase3 (we track via spec.bossMaxPhase if present;
-	// fallback: phase3 means boss HP was ≤25% of room's max — we can't reconstruct that here without
-	// tracking, so we gate on outcome==victory as an approximation for now)
-	// TODO: add spec.bossMaxPhaseReached field in future
 	return certs
 }
 
 // recordProfile writes/updates the player's profile ConfigMap in rpg-system.
 // Called asynchronously before dungeon deletion. Silently skips on any error.
-func (h *Handler) recordProfile(login string, spec map[string]interface{}, kroStatus map[string]interface{}) {
+func (h *Handler) recordProfile(login string, d *model.Dungeon) {
 	if login == "" {
 		login = "anonymous"
 	}
 
-	heroClass, _ := spec["heroClass"].(string)
-	difficulty, _ := spec["difficulty"].(string)
-	attackSeq := getInt(spec, "attackSeq")
-	actionSeq := getInt(spec, "actionSeq")
-	totalTurns := attackSeq + actionSeq
-
-	// Derive outcome same way as recordLeaderboard.
-	outcome := "in-progress"
-	if kroStatus != nil {
-		isVictory, _ := kroStatus["victory"].(bool)
-		isDefeat, _ := kroStatus["defeat"].(bool)
-		if isVictory {
-			outcome = "victory"
-		} else if isDefeat {
-			outcome = "defeat"
-		} else {
-			heroHP := getInt(spec, "heroHP")
-			bossHP := getInt(spec, "bossHP")
-			if heroHP > 0 {
-				monsterHPRaw, _ := spec["monsterHP"].([]interface{})
-				allDead := true
-				for _, v := range monsterHPRaw {
-					if sliceInt(v) > 0 {
-						allDead = false
-						break
-					}
-				}
-				if bossHP <= 0 && allDead {
-					outcome = "room1-cleared"
-				}
-			}
-		}
-	} else {
-		// #402: kro status unavailable — do not fall back to raw-HP derivation.
-		// Outcome stays "in-progress"; profile update is best-effort, not critical.
-		slog.Debug("updateUserProfile: kro status unavailable, skipping raw-HP fallback (#402)")
-	}
+	heroClass := d.Spec.HeroClass
+	difficulty := d.Spec.Difficulty
+	game := d.Status.Game
+	totalTurns := d.TotalTurns()
+
+	outcome := runOutcome(d)
 
 	// Load existing profiles CM or start fresh.
 	ctx := context.Background()
@@ -856,33 +718,28 @@ func (h *Handler) recordProfile(login string, spec map[string]interface{}, kroSt
 	profile.TotalTurns += int(totalTurns)
 
 	// Count monster kills this run.
-	monsterHPRaw, _ := spec["monsterHP"].([]interface{})
-	for _, v := range monsterHPRaw {
-		if sliceInt(v) <= 0 {
-			profile.TotalKills++
-		}
-	}
+	profile.TotalKills += len(game.MonsterHP) - game.LivingMonsters()
 
 	// Count boss kill.
-	if getInt(spec, "bossHP") <= 0 {
+	if game.BossHP <= 0 {
 		profile.TotalBossKills++
 	}
 
 	// Update favourite class (most victories per class).
 	if outcome == "victory" {
 		profile.DungeonsWon++
-		// Carry inventory and equipment forward only on victory.
-		rawInv, _ := spec["inventory"].(string)
-		profile.Inventory = rawInv
-		profile.WeaponBonus = getInt(spec, "weaponBonus")
-		profile.WeaponUses = getInt(spec, "weaponUses")
-		profile.ArmorBonus = getInt(spec, "armorBonus")
-		profile.ShieldBonus = getInt(spec, "shieldBonus")
-		profile.HelmetBonus = getInt(spec, "helmetBonus")
-		profile.PantsBonus = getInt(spec, "pantsBonus")
-		profile.BootsBonus = getInt(spec, "bootsBonus")
-		profile.RingBonus = getInt(spec, "ringBonus")
-		profile.AmuletBonus = getInt(spec, "amuletBonus")
+		// Carry inventory and equipment forward only on victory. Worn gear
+		// travels in the backpack; the bonus fields only record it (#555).
+		profile.Inventory = carryOverInventory(h.items.get(ctx), game)
+		profile.WeaponBonus = game.WeaponBonus
+		profile.WeaponUses = game.WeaponUses
+		profile.ArmorBonus = game.ArmorBonus
+		profile.ShieldBonus = game.ShieldBonus
+		profile.HelmetBonus = game.HelmetBonus
+		profile.PantsBonus = game.PantsBonus
+		profile.BootsBonus = game.BootsBonus
+		profile.RingBonus = game.RingBonus
+		profile.AmuletBonus = game.AmuletBonus
 		// Reset HP/mana to class defaults on victory.
 		profile.HeroHP = classDefaultHP(heroClass)
 		profile.HeroMana = classDefaultMana(heroClass)
@@ -891,23 +748,25 @@ func (h *Handler) recordProfile(login string, spec map[string]interface{}, kroSt
 	} else if outcome == "defeat" {
 		profile.DungeonsLost++
 		// Persist hero's wounded state — next dungeon inherits these HP values.
-		profile.HeroHP = getInt(spec, "heroHP")
-		profile.HeroMana = getInt(spec, "heroMana")
+		profile.HeroHP = game.HeroHP
+		profile.HeroMana = game.HeroMana
 	} else {
 		profile.DungeonsAbandoned++
 	}
 
 	// XP accumulation — add session XP earned during combat plus end-of-run bonuses (#360).
 	// Kill/clear XP is always added (even on defeat) because it was earned.
-	sessionXP := int(getInt(spec, "xpEarned"))
+	sessionXP := int(d.Spec.XPEarned)
 	// Victory bonuses (only on full dungeon win)
 	if outcome == "victory" {
 		sessionXP += 150 // base victory bonus
 		if difficulty == "hard" {
 			sessionXP += 50 // hard difficulty bonus
 		}
+		// Depth: 50 per room beyond the default two
+		sessionXP += 50 * int(d.RoomCount()-model.DefaultRooms)
 		// Flawless: hero HP equals class default max
-		if getInt(spec, "heroHP") >= classDefaultHP(heroClass) {
+		if game.HeroHP >= classDefaultHP(heroClass) {
 			sessionXP += 25
 		}
 		// Speedrun: ≤30 total turns
@@ -915,60 +774,57 @@ func (h *Handler) recordProfile(login string, spec map[string]interface{}, kroSt
 			sessionXP += 25
 		}
 		// New Game+: runCount ≥ 1
-		if getInt(spec, "runCount") >= 1 {
+		if d.Spec.RunCount >= 1 {
 			sessionXP += 50
 		}
 	}
+	// Objectives pay out whatever the outcome, once per dungeon; a finished
+	// run settles the ones still open first.
+	objectives := d.Spec.Objectives
+	if outcome == "victory" || outcome == "defeat" {
+		objectives.Finish(outcome == "victory")
+	}
+	if paidKey := cmp.Or(string(d.UID), d.Namespace+"/"+d.Name); len(objectives) > 0 && !slices.Contains(profile.ObjectivesPaid, paidKey) {
+		sessionXP += int(objectives.XP())
+		profile.ObjectivesPaid = append(profile.ObjectivesPaid, paidKey)
+		if n := len(profile.ObjectivesPaid); n > objectivesPaidKept {
+			profile.ObjectivesPaid = profile.ObjectivesPaid[n-objectivesPaidKept:]
+		}
+	}
 	newTotalXP := profile.XP + sessionXP
 	profile.XP = newTotalXP
 	profile.Level = computeLevel(newTotalXP)
 
-	// Append earned badges and increment counts.
-	newBadges := computeProfileBadges(spec, outcome)
-	existing_set := map[string]bool{}
+	// Badges and certificates come from the achievement rules (see
+	// achievements.go). Run badges are counted every time they are earned;
+	// career badges and certificates only once. Each stage sees the profile
+	// as the stages before it left it.
+	rules := h.achievements.get(ctx)
+	run := newRunStats(d, outcome)
+	earnedBadges := map[string]bool{}
 	for _, b := range profile.EarnedBadges {
-		existing_set[b] = true
+		earnedBadges[b] = true
 	}
-	for _, b := range newBadges {
-		if !existing_set[b] {
+	for _, b := range rules.earned(catalog.Badge, run, newProfileStats(profile)) {
+		if !earnedBadges[b] {
+			earnedBadges[b] = true
 			profile.EarnedBadges = append(profile.EarnedBadges, b)
 		}
 		profile.BadgeCounts[b]++
 	}
-
-	// Career badges evaluated after stats update.
-	wonClasses := map[string]bool{}
-	// Infer from badges.
-	for _, b := range profile.EarnedBadges {
-		switch b {
-		case "warrior-win":
-			wonClasses["warrior"] = true
-		case "mage-win":
-			wonClasses["mage"] = true
-		case "rogue-win":
-			wonClasses["rogue"] = true
+	for _, b := range rules.earned(catalog.CareerBadge, run, newProfileStats(profile)) {
+		if !earnedBadges[b] {
+			earnedBadges[b] = true
+			profile.EarnedBadges = append(profile.EarnedBadges, b)
+			profile.BadgeCounts[b]++
 		}
 	}
-	if len(wonClasses) >= 3 && !existing_set["multi-class"] {
-		profile.EarnedBadges = append(profile.EarnedBadges, "multi-class")
-		profile.BadgeCounts["multi-class"]++
-	}
-	if profile.DungeonsWon >= 10 && !existing_set["reaper"] {
-		profile.EarnedBadges = append(profile.EarnedBadges, "reaper")
-		profile.BadgeCounts["reaper"]++
-	}
-	if profile.DungeonsWon >= 25 && !existing_set["legend"] {
-		profile.EarnedBadges = append(profile.EarnedBadges, "legend")
-		profile.BadgeCounts["legend"]++
-	}
-	if getInt(spec, "runCount") >= 1 && outcome == "victory" && !existing_set["new-game-plus"] {
-		profile.EarnedBadges = append(profile.EarnedBadges, "new-game-plus")
-		profile.BadgeCounts["new-game-plus"]++
-	}
-
-	// Compute and append new Tier 1 + Tier 3 certificates (#361).
-	newCerts := computeCertificates(spec, profile, outcome)
+	newCerts := computeCertificates(rules, run, profile)
 	profile.KroCertificates = append(profile.KroCertificates, newCerts...)
+	stats := newProfileStats(profile)
+	docs := h.signCertificates(login, rules, newCerts, CertificateEvidence{
+		Source: "run", Dungeon: d.Name, Run: &run, DungeonsWon: stats.DungeonsWon, Level: stats.Level,
+	}, &profile)
 
 	profileJSON, err := json.Marshal(profile)
 	if err != nil {
@@ -989,7 +845,9 @@ func (h *Handler) recordProfile(login string, spec map[string]interface{}, kroSt
 		}}
 		if _, createErr := cmClient.Create(ctx, newCM, metav1.CreateOptions{}); createErr != nil {
 			slog.Warn("profile: failed to create ConfigMap", "user", login, "error", createErr)
+			return
 		}
+		h.storeCertificates(ctx, docs)
 		return
 	}
 
@@ -997,7 +855,10 @@ func (h *Handler) recordProfile(login string, spec map[string]interface{}, kroSt
 	patchJSON, _ := json.Marshal(patch)
 	if _, patchErr := cmClient.Patch(ctx, profileCMName, types.MergePatchType, patchJSON, metav1.PatchOptions{}); patchErr != nil {
 		slog.Warn("profile: failed to patch ConfigMap", "user", login, "error", patchErr)
+		return
 	}
+	// Only now that the profile names them, so a failed write orphans none.
+	h.storeCertificates(ctx, docs)
 }
 
 // GetProfile returns the authenticated user's persistent profile.
@@ -1027,24 +888,54 @@ func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(profile)
 }
 
-// AwardCert awards a Tier 2 certificate to the authenticated user.
-// POST /api/v1/profile/cert  Body: { "cert": "<id>" }
-// Only accepts cert IDs in the tier2Certs allow-list. No-op if already earned.
+// AwardCert awards a Tier 2 certificate to the authenticated user for one of
+// their dungeons.
+// POST /api/v1/profile/cert  Body: { "cert": "<id>", "namespace": "<ns>", "name": "<dungeon>" }
+// Only accepts certificates without a rule (see achievementRules.clientAwarded),
+// and only if the dungeon's run satisfies the certificate's evidence. The
+// claim is the client's own, so no signed document is issued for it.
+// No-op if already earned.
 // Returns the updated kroCertificates array.
 func (h *Handler) AwardCert(w http.ResponseWriter, r *http.Request) {
 	sess := sessionFromCtx(r.Context())
-	login := "anonymous"
-	if sess != nil {
-		login = sess.Login
+	if sess == nil {
+		writeError(w, "authentication required", http.StatusUnauthorized)
+		return
 	}
+	login := sess.Login
 
 	var req struct {
-		Cert string `json:"cert"`
+		Cert      string `json:"cert"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !tier2Certs[req.Cert] {
+	rules := h.achievements.get(r.Context())
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !rules.clientAwarded(req.Cert) {
 		writeError(w, "invalid or missing cert id", http.StatusBadRequest)
 		return
 	}
+	if req.Name == "" {
+		writeError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if !validateNamespace(w, req.Namespace) {
+		return
+	}
+	obj, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(req.Namespace).Get(r.Context(), req.Name, metav1.GetOptions{})
+	if err != nil {
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
+		return
+	}
+	if err := requireDungeonOwner(r, obj); err != nil {
+		writeOwnerError(w, err)
+		return
+	}
+	d, err := model.FromUnstructured(obj)
+	if err != nil {
+		slog.Error("failed to decode dungeon for cert", "component", "api", "dungeon", req.Name, "namespace", req.Namespace, "error", err)
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return
+	}
 
 	ctx := context.Background()
 	cmClient := h.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace)
@@ -1071,6 +962,10 @@ func (h *Handler) AwardCert(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if !rules.evidenced(req.Cert, newRunStats(d, runOutcome(d)), newProfileStats(profile)) {
+		writeError(w, "this dungeon has not earned "+req.Cert, http.StatusForbidden)
+		return
+	}
 	profile.KroCertificates = append(profile.KroCertificates, req.Cert)
 
 	profileJSON, err := json.Marshal(profile)
@@ -1144,15 +1039,17 @@ func (h *Handler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
 		entries = append(entries, e)
 	}
 
-	// Sort by fewest turns (ascending), then by timestamp descending for ties
-	for i := 1; i < len(entries); i++ {
-		for j := i; j > 0; j-- {
-			a, b := entries[j-1], entries[j]
-			if a.TotalTurns > b.TotalTurns || (a.TotalTurns == b.TotalTurns && a.Timestamp < b.Timestamp) {
-				entries[j-1], entries[j] = entries[j], entries[j-1]
-			}
+	// Sort by deepest room reached, then fewest turns, then newest first
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.CurrentRoom != b.CurrentRoom {
+			return a.CurrentRoom > b.CurrentRoom
 		}
-	}
+		if a.TotalTurns != b.TotalTurns {
+			return a.TotalTurns < b.TotalTurns
+		}
+		return a.Timestamp > b.Timestamp
+	})
 
 	// Cap at top 20
 	if len(entries) > 20 {
@@ -1194,7 +1091,7 @@ func (h *Handler) CreateAttack(w http.ResponseWriter, r *http.Request) {
 
 	var req CreateAttackReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, "invalid request body", http.StatusBadRequest)
+		writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
 		return
 	}
 	if req.Target == "" {
@@ -1202,8 +1099,7 @@ func (h *Handler) CreateAttack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAction := strings.HasPrefix(req.Target, "use-") || strings.HasPrefix(req.Target, "equip-") ||
-		req.Target == "open-treasure" || req.Target == "unlock-door" || req.Target == "enter-room-2"
+	isAction := isActionTarget(req.Target)
 
 	ctx := context.Background()
 
@@ -1213,7 +1109,9 @@ func (h *Handler) CreateAttack(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		if err := h.processCombat(ctx, r, ns, name, req.Target, req.Damage, req.Seq, w); err != nil {
+		// Ability targets take the same path as POST .../abilities.
+		target, ability := splitAbilityTarget(req.Target)
+		if err := h.processCombat(ctx, r, ns, name, target, ability, req.Damage, req.Seq, w); err != nil {
 			// error already written
 			return
 		}
@@ -1226,7 +1124,9 @@ func (h *Handler) CreateAttack(w http.ResponseWriter, r *http.Request) {
 // 3. Upsert fixed-name Attack CR (SSA) with new seq = attackSeq+1 and targetRoom
 // 4. kro re-reconciles dungeon-graph, combatResolve state node writes results to status.game
 // 5. Backend polls until status.game.combatProcessedSeq reaches attackSeq, reads post-state
-func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name, target string, clientDamage int64, clientSeq int64, w http.ResponseWriter) error {
+// ability is an action from the ability registry ("heal", "backstab") or ""
+// for a plain attack; target is the enemy, or "" for a self-targeted ability.
+func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name, target, ability string, clientDamage int64, clientSeq int64, w http.ResponseWriter) error {
 	start := time.Now()
 	// These vars are captured by the defer to emit rich log + metrics at end.
 	var heroClass, difficulty, combatOutcome string
@@ -1256,49 +1156,45 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		slog.Error("failed to get dungeon for combat", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return err
 	}
 	// #409: verify the caller owns this dungeon.
 	if ownerErr := requireDungeonOwner(r, dungeon); ownerErr != nil {
-		writeError(w, ownerErr.Error(), http.StatusForbidden)
+		writeOwnerError(w, ownerErr)
 		return ownerErr
 	}
-	spec := getMap(dungeon.Object, "spec")
-	dungeonStatus := getMap(dungeon.Object, "status")
-	game := getGameState(dungeon.Object)
+	h.pauseAutoBattleOnIntervention(ctx, r, dungeon)
+	pre, err := model.FromUnstructured(dungeon)
+	if err != nil {
+		slog.Error("failed to decode dungeon for combat", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return err
+	}
+	game := pre.Status.Game
 
-	heroHP := getInt(game, "heroHP")
-	heroClass = getString(spec, "heroClass", "warrior")
-	difficulty = getString(spec, "difficulty", "normal")
+	heroHP := game.HeroHP
+	heroClass = pre.Spec.HeroClass
+	difficulty = pre.Spec.Difficulty
 	// #399: reject if kro has not yet provided maxHeroHP (hero-graph not reconciled yet)
-	if maxHeroHPStr := getString(dungeonStatus, "maxHeroHP", ""); maxHeroHPStr == "" {
-		writeError(w, "dungeon initializing — hero max HP not yet computed by kro, please retry", http.StatusServiceUnavailable)
+	if !pre.Status.HeroReady() {
+		writeCodedError(w, "dungeon initializing — hero max HP not yet computed by kro, please retry", http.StatusServiceUnavailable, CodeDungeonInitializing)
 		return fmt.Errorf("hero maxHeroHP not yet available from kro")
 	}
-	heroMana := getInt(game, "heroMana")
-	attackSeq := getInt(spec, "attackSeq")
-	bossHP := getInt(game, "bossHP")
-	monsterHPRaw, _ := game["monsterHP"].([]interface{})
-	currentRoom := getInt(game, "currentRoom")
-	stunTurns := getInt(game, "stunTurns")
+	attackSeq := pre.Spec.AttackSeq
+	bossHP := game.BossHP
+	currentRoom := game.CurrentRoom
+	stunTurns := game.StunTurns
 
 	// Conflict guard: reject stale requests
 	if clientSeq >= 0 && clientSeq != attackSeq {
 		slog.Warn("stale attack rejected", "component", "api", "dungeon", name, "clientSeq", clientSeq, "serverSeq", attackSeq)
-		writeError(w, "stale request — dungeon state has changed, please retry", http.StatusConflict)
+		writeStaleSeq(w)
 		return fmt.Errorf("stale attack: clientSeq=%d serverSeq=%d", clientSeq, attackSeq)
 	}
 
 	// Guard: reject if dungeon is over
-	allMonstersDead := true
-	for _, hp := range monsterHPRaw {
-		if sliceInt(hp) > 0 {
-			allMonstersDead = false
-			break
-		}
-	}
-	if heroHP <= 0 || (bossHP <= 0 && allMonstersDead) {
+	if heroHP <= 0 || game.RoomCleared() {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted)
 		json.NewEncoder(w).Encode(dungeon.Object)
@@ -1307,100 +1203,44 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 
 	newSeq := attackSeq + 1
 
-	// Mage heal ability
-	if target == "hero" {
-		if heroClass != "mage" {
-			writeError(w, "only mage can heal", http.StatusBadRequest)
-			return fmt.Errorf("only mage can heal")
-		}
-		if heroMana < 2 {
-			writeError(w, "not enough mana", http.StatusBadRequest)
-			return fmt.Errorf("not enough mana")
-		}
-		// #400: log text uses pre-state HP; kro abilityResolve is authoritative for actual HP mutation
-		heroAction := fmt.Sprintf("Mage heals! HP: %d -> (healing...) (Mana: %d -> (spending...))", heroHP, heroMana)
-		patch := map[string]interface{}{
-			"spec": map[string]interface{}{
-				"lastAbility":     "mage-heal",
-				"lastHeroAction":  heroAction,
-				"lastEnemyAction": "No counter-attack during heal",
-				"lastLootDrop":    "",
-				"attackSeq":       newSeq,
-				// Clear cross-triggers
-				"lastAttackTarget": "",
-				"lastAction":       "",
-			},
+	class := heroClassOrDefault(heroClass)
+
+	// Class abilities come from the ability registry. Self-targeted ones are a
+	// turn of their own; enemy-targeted ones modify the attack below.
+	var ab catalog.Ability
+	if ability != "" {
+		a, ok, known := catalog.LookupAbility(class.ID, ability)
+		if !known {
+			writeCodedError(w, "unknown ability: "+ability, http.StatusBadRequest, CodeUnknownAction)
+			return fmt.Errorf("unknown ability %q", ability)
 		}
-		if err := h.patchDungeon(ctx, ns, name, patch); err != nil {
-			slog.Error("failed to patch dungeon after heal", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-			writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
-			return err
+		if !ok {
+			writeCodedError(w, class.Name+" cannot "+ability, http.StatusBadRequest, CodeWrongClass)
+			return fmt.Errorf("%s cannot %s", heroClass, ability)
 		}
-		// Business metric: ability used (Issue #358)
-		slog.Info("ability_used",
-			"component", "game",
-			"dungeon", name,
-			"hero_class", heroClass,
-			"ability", "heal",
-			"turn", newSeq,
-		)
-		return h.respondDungeon(ctx, ns, name, w)
-	}
-
-	// Warrior taunt activation
-	tauntActive := getInt(game, "tauntActive")
-	if target == "activate-taunt" {
-		if heroClass != "warrior" {
-			writeError(w, "only warrior can taunt", http.StatusBadRequest)
-			return fmt.Errorf("only warrior can taunt")
-		}
-		if tauntActive > 0 {
-			writeError(w, "taunt already active", http.StatusBadRequest)
-			return fmt.Errorf("taunt already active")
-		}
-		patch := map[string]interface{}{
-			"spec": map[string]interface{}{
-				"lastAbility":     "warrior-taunt",
-				"lastHeroAction":  "Warrior activates Taunt! Next attack has 60% counter-attack reduction.",
-				"lastEnemyAction": "",
-				"lastLootDrop":    "",
-				"attackSeq":       newSeq,
-				// Clear cross-triggers
-				"lastAttackTarget": "",
-				"lastAction":       "",
-			},
+		if c := checkAbility(a, class, game); c.Code != "" {
+			writeCodedError(w, c.Reason, http.StatusBadRequest, c.Code)
+			return errors.New(c.Reason)
+		}
+		if a.Target == catalog.TargetEnemy && target == "" {
+			writeCodedError(w, ability+" needs a target", http.StatusBadRequest, CodeInvalidTarget)
+			return fmt.Errorf("%s without a target", ability)
 		}
 		// Business metric: ability used (Issue #358)
 		slog.Info("ability_used",
 			"component", "game",
 			"dungeon", name,
 			"hero_class", heroClass,
-			"ability", "taunt",
+			"ability", ability,
 			"turn", newSeq,
 		)
-		return h.patchAndRespond(ctx, ns, name, patch, w)
+		if a.Target == catalog.TargetSelf {
+			return h.useSelfAbility(ctx, ns, name, dungeon, a, class, game, newSeq, w)
+		}
+		ab = a
 	}
-
-	// Determine real target (strip -backstab suffix)
-	isBackstab := false
 	realTarget := target
-	backstabCD := getInt(game, "backstabCooldown")
-	if strings.HasSuffix(target, "-backstab") {
-		isBackstab = true
-		realTarget = strings.TrimSuffix(target, "-backstab")
-		if backstabCD > 0 {
-			writeError(w, "backstab on cooldown", http.StatusBadRequest)
-			return fmt.Errorf("backstab on cooldown")
-		}
-		// Business metric: backstab ability used (Issue #358)
-		slog.Info("ability_used",
-			"component", "game",
-			"dungeon", name,
-			"hero_class", heroClass,
-			"ability", "backstab",
-			"turn", newSeq,
-		)
-	}
+	isBackstab := ab.Trigger.Field == "lastAttackIsBackstab"
 
 	isBossTarget := strings.HasSuffix(realTarget, "-boss")
 
@@ -1416,8 +1256,8 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 		}
 		idxParsed, _ := strconv.ParseInt(idxStr, 10, strconv.IntSize)
 		idxInt = int(idxParsed)
-		if idxInt < 0 || idxInt >= len(monsterHPRaw) {
-			writeError(w, "invalid monster index", http.StatusBadRequest)
+		if idxInt < 0 || idxInt >= len(game.MonsterHP) {
+			writeCodedError(w, "invalid monster index", http.StatusBadRequest, CodeInvalidTarget)
 			return fmt.Errorf("invalid monster index")
 		}
 	}
@@ -1425,46 +1265,17 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 	// Early-exit: target already dead
 	if isBossTarget && bossHP <= 0 {
 		patch := map[string]interface{}{"spec": map[string]interface{}{"lastLootDrop": "", "lastHeroAction": "Boss already defeated", "lastEnemyAction": "", "attackSeq": newSeq}}
-		return h.patchAndRespond(ctx, ns, name, patch, w)
+		return h.patchTurnAndRespond(ctx, ns, name, dungeon, patch, nil, w)
 	}
-	if !isBossTarget && idxInt >= 0 && sliceInt(monsterHPRaw[idxInt]) <= 0 {
+	if !isBossTarget && !game.MonsterAlive(idxInt) {
 		patch := map[string]interface{}{"spec": map[string]interface{}{"lastLootDrop": "", "lastHeroAction": "Monster already dead", "lastEnemyAction": "", "attackSeq": newSeq}}
-		return h.patchAndRespond(ctx, ns, name, patch, w)
+		return h.patchTurnAndRespond(ctx, ns, name, dungeon, patch, nil, w)
 	}
 
-	// Step 2: Upsert Attack CR (trigger for kro)
-	attackCRName := name + "-latest-attack"
-	attackObj := &unstructured.Unstructured{Object: map[string]interface{}{
-		"apiVersion": "game.k8s.example/v1alpha1",
-		"kind":       "Attack",
-		"metadata": map[string]interface{}{
-			"name":      attackCRName,
-			"namespace": "default",
-		},
-		"spec": map[string]interface{}{
-			"dungeonName":      name,
-			"dungeonNamespace": ns,
-			"target":           target,
-			"damage":           clientDamage,
-			"seq":              newSeq,
-			"targetRoom":       currentRoom,
-		},
-	}}
-	attackData, _ := json.Marshal(attackObj.Object)
-	_, err = h.client.Dynamic.Resource(k8s.AttackGVR).Namespace("default").Patch(
-		ctx, attackCRName, types.ApplyPatchType, attackData,
-		metav1.PatchOptions{FieldManager: "rpg-backend", Force: boolPtr(true)})
-	if err != nil {
-		slog.Error("failed to upsert attack CR", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
-		return err
-	}
-	attacksSubmitted.WithLabelValues(name).Inc()
-
 	// Per-turn seed (unique per dungeon+turn, ensures real dice variance)
 	turnSeed := name + "-seq-" + strconv.FormatInt(newSeq, 10)
 
-	// Step 3: Write trigger fields only — kro's combatResolve state node computes
+	// Step 2: Write trigger fields only — kro's combatResolve state node computes
 	// all actual game state (HP, mana, DoT, loot, inventory) and writes to status.game.
 	patchSpec := map[string]interface{}{
 		"attackSeq":            newSeq,
@@ -1472,7 +1283,7 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 		"lastAttackSeed":       turnSeed,
 		"lastAttackIndex":      int64(idxInt),
 		"lastAttackIsBoss":     isBossTarget,
-		"lastAttackIsBackstab": isBackstab,
+		"lastAttackIsBackstab": false,
 		// Clear cross-triggers so other state nodes don't misfire
 		"lastAbility": "",
 		"lastAction":  "",
@@ -1480,47 +1291,72 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 		"lastHeroAction":  "",
 		"lastEnemyAction": "",
 	}
-	if err := h.patchDungeon(ctx, ns, name, map[string]interface{}{"spec": patchSpec}); err != nil {
+	if ab.ID != "" {
+		patchSpec[ab.Trigger.Field] = ab.Trigger.Value
+	}
+	if err := h.patchTurn(ctx, ns, name, dungeon, map[string]interface{}{"spec": patchSpec}, nil); err != nil {
 		slog.Error("failed to patch trigger fields", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		writeTurnPatchError(w, err)
 		return err
 	}
 
+	// Step 3: Upsert the Attack CR recording this turn. Only the request whose
+	// trigger patch won gets here, so a turn rejected as stale never
+	// overwrites it. The turn has already landed, so a failure is only logged.
+	attackCRName := name + "-latest-attack"
+	attackObj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1",
+		"kind":       "Attack",
+		"metadata": map[string]interface{}{
+			"name":      attackCRName,
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"dungeonName":      name,
+			"dungeonNamespace": ns,
+			"target":           attackCRTarget(target, ab),
+			"damage":           clientDamage,
+			"seq":              newSeq,
+			"targetRoom":       currentRoom,
+		},
+	}}
+	attackData, _ := json.Marshal(attackObj.Object)
+	if _, err := h.client.Dynamic.Resource(k8s.AttackGVR).Namespace("default").Patch(
+		ctx, attackCRName, types.ApplyPatchType, attackData,
+		metav1.PatchOptions{FieldManager: "rpg-backend", Force: boolPtr(true)}); err != nil {
+		slog.Error("failed to upsert attack CR", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+	}
+	attacksSubmitted.WithLabelValues(heroClass, difficulty).Inc()
+
 	// Step 4: Poll until kro's combatResolve has fired (combatProcessedSeq == newSeq).
+	triggeredAt := time.Now()
 	postDungeon, err := h.pollUntilCombatProcessed(ctx, ns, name, newSeq)
 	if err != nil {
 		// Timed out or error — return current state so frontend doesn't hang
 		slog.Warn("combat poll timed out or failed", "component", "api", "dungeon", name, "seq", newSeq, "error", err)
 		return h.respondDungeon(ctx, ns, name, w)
 	}
-	postSpec := getMap(postDungeon.Object, "spec")
-	postStatus := getMap(postDungeon.Object, "status")
-	postGame := getGameState(postDungeon.Object)
+	kroStateNodeLatency.WithLabelValues("combatResolve").Observe(time.Since(triggeredAt).Seconds())
+	post, err := model.FromUnstructured(postDungeon)
+	if err != nil {
+		slog.Warn("failed to decode post-combat dungeon", "component", "api", "dungeon", name, "seq", newSeq, "error", err)
+		return h.respondDungeon(ctx, ns, name, w)
+	}
+	postGame := post.Status.Game
 
 	// Populate telemetry vars from post-combat state.
-	postHeroHP = getInt(postGame, "heroHP")
+	postHeroHP = postGame.HeroHP
 	if isBossTarget {
-		damageDealt = getInt(game, "bossHP") - getInt(postGame, "bossHP")
-	} else if idxInt >= 0 {
-		preMonHPs, _ := game["monsterHP"].([]interface{})
-		postMonHPs, _ := postGame["monsterHP"].([]interface{})
-		if idxInt < len(preMonHPs) && idxInt < len(postMonHPs) {
-			damageDealt = sliceInt(preMonHPs[idxInt]) - sliceInt(postMonHPs[idxInt])
-		}
+		damageDealt = game.BossHP - postGame.BossHP
+	} else if idxInt >= 0 && idxInt < len(game.MonsterHP) && idxInt < len(postGame.MonsterHP) {
+		damageDealt = game.MonsterHP[idxInt] - postGame.MonsterHP[idxInt]
 	}
 	if damageDealt < 0 {
 		damageDealt = 0
 	}
 	// Determine combat outcome for metrics.
-	postBossHP := getInt(postGame, "bossHP")
-	postMonsterHPRaw, _ := postGame["monsterHP"].([]interface{})
-	postAllMonstersDead := true
-	for _, v := range postMonsterHPRaw {
-		if sliceInt(v) > 0 {
-			postAllMonstersDead = false
-			break
-		}
-	}
+	postBossHP := postGame.BossHP
+	postAllMonstersDead := postGame.LivingMonsters() == 0
 	switch {
 	case postHeroHP <= 0:
 		combatOutcome = "defeat"
@@ -1528,29 +1364,33 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 		combatOutcome = "victory"
 	case isBossTarget && postBossHP <= 0:
 		combatOutcome = "boss_kill"
-	case !isBossTarget && idxInt >= 0 && len(postMonsterHPRaw) > idxInt && sliceInt(postMonsterHPRaw[idxInt]) == 0:
+	case !isBossTarget && idxInt >= 0 && len(postGame.MonsterHP) > idxInt && postGame.MonsterHP[idxInt] == 0:
 		combatOutcome = "kill"
 	default:
 		combatOutcome = "hit"
 	}
+	hitTarget := "monster"
+	if isBossTarget {
+		hitTarget = "boss"
+	}
+	damagePerHit.WithLabelValues(heroClass, difficulty, hitTarget).Observe(float64(damageDealt))
+	if combatOutcome == "victory" && post.InFinalRoom() {
+		turnsToVictory.WithLabelValues(heroClass, difficulty).Observe(float64(newSeq + pre.Spec.ActionSeq))
+	}
 
 	// Emit status-effect metrics from log derivation state.
-	prePoisonTurns := getInt(game, "poisonTurns")
-	preBurnTurns := getInt(game, "burnTurns")
-	preStunTurns := getInt(game, "stunTurns")
-	if getInt(postGame, "poisonTurns") > prePoisonTurns {
+	if postGame.PoisonTurns > game.PoisonTurns {
 		statusEffectsInflicted.With(map[string]string{"effect": "poison"}).Inc()
 	}
-	if getInt(postGame, "burnTurns") > preBurnTurns {
+	if postGame.BurnTurns > game.BurnTurns {
 		statusEffectsInflicted.With(map[string]string{"effect": "burn"}).Inc()
 	}
-	if getInt(postGame, "stunTurns") > preStunTurns {
+	if postGame.StunTurns > game.StunTurns {
 		statusEffectsInflicted.With(map[string]string{"effect": "stun"}).Inc()
 	}
 
 	// Business metrics: emit kill events (Issue #358)
 	if combatOutcome == "kill" || combatOutcome == "boss_kill" || combatOutcome == "victory" {
-		monstersTypeRaw, _ := game["monsterTypes"].([]interface{})
 		targetType := realTarget
 		if isBossTarget {
 			slog.Info("boss_killed",
@@ -1573,10 +1413,8 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 				)
 			}
 		} else if idxInt >= 0 {
-			if idxInt < len(monstersTypeRaw) {
-				if t, ok := monstersTypeRaw[idxInt].(string); ok && t != "" {
-					targetType = t
-				}
+			if idxInt < len(game.MonsterTypes) && game.MonsterTypes[idxInt] != "" {
+				targetType = game.MonsterTypes[idxInt]
 			}
 			slog.Info("monster_killed",
 				"component", "game",
@@ -1591,7 +1429,7 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 	}
 
 	// Emit loot drop metric + business event if a new item was dropped.
-	postLoot := getString(postGame, "lastLootDrop", "")
+	postLoot := postGame.LastLootDrop
 	if postLoot != "" {
 		parts := strings.SplitN(postLoot, "-", 2)
 		if len(parts) == 2 {
@@ -1614,11 +1452,11 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 	}
 
 	// Step 5: Derive log text from pre→post game state diff (no math — kro is authoritative).
-	diceFormula := getString(postStatus, "diceFormula", getString(dungeonStatus, "diceFormula", ""))
-	bossPhaseStr := getString(postStatus, "bossPhase", getString(dungeonStatus, "bossPhase", "phase1"))
+	diceFormula := cmp.Or(post.Status.DiceFormula, pre.Status.DiceFormula)
+	bossPhaseStr := cmp.Or(post.Status.BossPhase, pre.Status.BossPhase, "phase1")
 	// bossDamageMultiplier is stored ×10 as a string in dungeon status (from boss-graph CEL).
 	// e.g. '10'=1.0×, '13'=1.3×, '16'=1.6×. Default '10' when not yet set.
-	bossDmgMultStr := getString(postStatus, "bossDamageMultiplier", getString(dungeonStatus, "bossDamageMultiplier", "10"))
+	bossDmgMultStr := cmp.Or(post.Status.BossDamageMultiplier, pre.Status.BossDamageMultiplier, "10")
 	heroAction, enemyAction := deriveCombatLog(
 		game, postGame, realTarget, isBossTarget, idxInt, isBackstab, stunTurns > 0,
 		heroClass, diceFormula, bossPhaseStr, bossDmgMultStr,
@@ -1631,18 +1469,10 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 	case "kill":
 		xpDelta = 10
 	case "boss_kill":
-		if currentRoom == 2 {
-			xpDelta = 100
-		} else {
-			xpDelta = 50
-		}
+		// boss kill XP grows with depth: 50 in room 1, 100 in room 2, ...
+		xpDelta = 50 * max(currentRoom, 1)
 	case "victory":
-		// boss kill XP (always room 2 for a full victory in room 2, or room 1 for room1-cleared)
-		if currentRoom == 2 {
-			xpDelta = 100
-		} else {
-			xpDelta = 50
-		}
+		xpDelta = 50 * max(currentRoom, 1)
 		// room-clear bonus (all monsters + boss dead)
 		xpDelta += 25
 	case "defeat":
@@ -1654,40 +1484,69 @@ func (h *Handler) processCombat(ctx context.Context, r *http.Request, ns, name,
 		xpDelta += 25
 	}
 
-	newXPEarned := getInt(postSpec, "xpEarned") + xpDelta
-
-	logPatch := map[string]interface{}{
-		"spec": map[string]interface{}{
+	// xpEarned, objectives and bossMaxPhaseReached accumulate in spec, and
+	// an action or the next turn may write them after the poll above. They
+	// are built from the dungeon the log write lands on, and rebuilt from a
+	// fresh read whenever that write loses the race.
+	winner, err := h.patchRebased(ctx, ns, name, postDungeon, func(cur *model.Dungeon) map[string]interface{} {
+		cur.Spec.XPEarned += xpDelta
+		logSpec := map[string]interface{}{
 			"lastHeroAction":  heroAction,
 			"lastEnemyAction": enemyAction,
-			"xpEarned":        newXPEarned,
-		},
+			"xpEarned":        cur.Spec.XPEarned,
+		}
+
+		// Objectives advance on the same pre/post diff; the run's end settles them.
+		objectives := cur.Spec.Objectives
+		changed := objectives.TrackCombat(game, postGame, bossPhaseStr, newSeq)
+		switch {
+		case combatOutcome == "defeat":
+			changed = objectives.Finish(false) || changed
+		case combatOutcome == "victory" && post.InFinalRoom():
+			changed = objectives.Finish(true) || changed
+		}
+		if changed {
+			logSpec["objectives"] = objectives.Unstructured()
+		}
+
+		// Track the deepest boss phase fought for the boss-phase certificate. A
+		// dead boss reads phase3, so only a living boss's phase counts.
+		maxPhase := cur.Spec.BossMaxPhaseReached
+		if game.BossHP > 0 {
+			maxPhase = max(maxPhase, bossPhaseNumber(pre.Status.BossPhase))
+		}
+		if postGame.BossHP > 0 {
+			maxPhase = max(maxPhase, bossPhaseNumber(post.Status.BossPhase))
+		}
+		if maxPhase > cur.Spec.BossMaxPhaseReached {
+			cur.Spec.BossMaxPhaseReached = maxPhase
+			logSpec["bossMaxPhaseReached"] = maxPhase
+		}
+		return map[string]interface{}{"spec": logSpec}
+	})
+	if err != nil {
+		slog.Error("failed to patch dungeon", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+		writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+		return err
 	}
 
-	// Record leaderboard + profile immediately on victory so the run appears
-	// in the leaderboard without requiring the player to delete the dungeon.
-	// recordLeaderboard uses dungeonName as the ConfigMap key, so a second
-	// write at delete-time is a harmless overwrite with identical data.
-	if combatOutcome == "victory" {
+	// Record leaderboard + profile immediately on a final-room victory so the
+	// run appears in the leaderboard without requiring the player to delete
+	// the dungeon. recordLeaderboard uses dungeonName as the ConfigMap key, so
+	// a second write at delete-time is a harmless overwrite with identical data,
+	// and recordProfile pays a dungeon's objectives only once.
+	if combatOutcome == "victory" && post.InFinalRoom() {
 		victoryLogin := "anonymous"
 		if sess := sessionFromCtx(r.Context()); sess != nil {
 			victoryLogin = sess.Login
 		}
-		// Build a merged map that includes spec + game state + final xpEarned value
-		// so the leaderboard/profile entries reflect the full run state.
-		victorySpec := make(map[string]interface{}, len(postSpec)+len(postGame)+1)
-		for k, v := range postSpec {
-			victorySpec[k] = v
-		}
-		for k, v := range postGame {
-			victorySpec[k] = v
-		}
-		victorySpec["xpEarned"] = newXPEarned
-		go h.recordLeaderboard(victorySpec, postStatus, name, victoryLogin)
-		go h.recordProfile(victoryLogin, victorySpec, postStatus)
+		// winner carries the final xpEarned and objectives, so the
+		// leaderboard/profile entries reflect the full run state.
+		go h.recordLeaderboard(winner, victoryLogin)
+		go h.recordProfile(victoryLogin, winner)
 	}
 
-	return h.patchAndRespond(ctx, ns, name, logPatch, w)
+	return h.respondDungeon(ctx, ns, name, w)
 }
 
 // pollUntilCombatProcessed polls the Dungeon CR until status.game.combatProcessedSeq >= targetSeq,
@@ -1698,8 +1557,7 @@ func (h *Handler) pollUntilCombatProcessed(ctx context.Context, ns, name string,
 	for time.Now().Before(deadline) {
 		d, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
 		if err == nil {
-			game := getGameState(d.Object)
-			if getInt(game, "combatProcessedSeq") >= targetSeq {
+			if got, decodeErr := model.FromUnstructured(d); decodeErr == nil && got.Status.Game.CombatProcessedSeq >= targetSeq {
 				return d, nil
 			}
 		}
@@ -1713,36 +1571,33 @@ func (h *Handler) pollUntilCombatProcessed(ctx context.Context, ns, name string,
 	return d, fmt.Errorf("combatProcessedSeq did not reach %d", targetSeq)
 }
 
+// bossPhaseNumber turns status.bossPhase ("phase1".."phase3") into 1–3; 0
+// when it is unset.
+func bossPhaseNumber(phase string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimPrefix(phase, "phase"), 10, 64)
+	return n
+}
+
 // deriveCombatLog generates heroAction and enemyAction log strings from a pre→post game state diff.
 // No RNG or math — all values are read directly from kro's computed post-state in status.game.
 func deriveCombatLog(
-	pre, post map[string]interface{},
+	pre, post model.GameState,
 	realTarget string, isBossTarget bool, idxInt int,
 	isBackstab bool, wasStunned bool,
 	heroClass, diceFormula, bossPhaseStr, bossDmgMultStr string,
 ) (heroAction, enemyAction string) {
-	preHeroHP := getInt(pre, "heroHP")
-	postHeroHP := getInt(post, "heroHP")
-	preHeroMana := getInt(pre, "heroMana")
-	postHeroMana := getInt(post, "heroMana")
-	preBossHP := getInt(pre, "bossHP")
-	postBossHP := getInt(post, "bossHP")
-	preMonsterHP, _ := pre["monsterHP"].([]interface{})
-	postMonsterHP, _ := post["monsterHP"].([]interface{})
-	preInventory := getString(pre, "inventory", "")
-	postInventory := getString(post, "inventory", "")
-	prePoisonTurns := getInt(pre, "poisonTurns")
-	postPoisonTurns := getInt(post, "poisonTurns")
-	preBurnTurns := getInt(pre, "burnTurns")
-	postBurnTurns := getInt(post, "burnTurns")
-	preStunTurns := getInt(pre, "stunTurns")
-	postStunTurns := getInt(post, "stunTurns")
-	postWeaponBonus := getInt(post, "weaponBonus")
-	postWeaponUses := getInt(post, "weaponUses")
-	preWeaponBonus := getInt(pre, "weaponBonus")
-	preWeaponUses := getInt(pre, "weaponUses")
-	postAmuletBonus := getInt(post, "amuletBonus")
-	postLastLootDrop := getString(post, "lastLootDrop", "")
+	preHeroHP, postHeroHP := pre.HeroHP, post.HeroHP
+	preHeroMana, postHeroMana := pre.HeroMana, post.HeroMana
+	preBossHP, postBossHP := pre.BossHP, post.BossHP
+	preMonsterHP, postMonsterHP := pre.MonsterHP, post.MonsterHP
+	preItems, postItems := pre.Inventory.Len(), post.Inventory.Len()
+	prePoisonTurns, postPoisonTurns := pre.PoisonTurns, post.PoisonTurns
+	preBurnTurns, postBurnTurns := pre.BurnTurns, post.BurnTurns
+	preStunTurns, postStunTurns := pre.StunTurns, post.StunTurns
+	preWeaponBonus, postWeaponBonus := pre.WeaponBonus, post.WeaponBonus
+	preWeaponUses, postWeaponUses := pre.WeaponUses, post.WeaponUses
+	postAmuletBonus := post.AmuletBonus
+	postLastLootDrop := post.LastLootDrop
 
 	// --- Hero action ---
 	var notes []string
@@ -1768,8 +1623,8 @@ func deriveCombatLog(
 			effectiveDamage = 0
 		}
 	} else if idxInt >= 0 && idxInt < len(preMonsterHP) && idxInt < len(postMonsterHP) {
-		oldHP = sliceInt(preMonsterHP[idxInt])
-		newHP = sliceInt(postMonsterHP[idxInt])
+		oldHP = preMonsterHP[idxInt]
+		newHP = postMonsterHP[idxInt]
 		effectiveDamage = oldHP - newHP
 		if effectiveDamage < 0 {
 			effectiveDamage = 0
@@ -1777,20 +1632,20 @@ func deriveCombatLog(
 	}
 
 	// Class notes from damage diff
-	if isBackstab {
+	class, _ := catalog.LookupClass(heroClass)
+	switch {
+	case isBackstab:
 		notes = append(notes, "Backstab 3x!")
-	} else if heroClass == "mage" {
-		if preHeroMana > 0 && postHeroMana < preHeroMana {
-			notes = append(notes, "Mage power!")
-		} else if preHeroMana == 0 {
-			notes = append(notes, "No mana!")
-		}
-	} else if heroClass == "rogue" {
-		notes = append(notes, "Rogue strike!")
+	case class.ManaAttacks && preHeroMana > 0 && postHeroMana < preHeroMana:
+		notes = append(notes, class.AttackNote)
+	case class.ManaAttacks && preHeroMana == 0:
+		notes = append(notes, "No mana!")
+	case !class.ManaAttacks && class.AttackNote != "":
+		notes = append(notes, class.AttackNote)
 	}
 
-	// Mana regen on monster kill (mage)
-	if !isBossTarget && heroClass == "mage" && oldHP > 0 && newHP == 0 {
+	// Mana regen on monster kill
+	if !isBossTarget && class.ManaAttacks && oldHP > 0 && newHP == 0 {
 		if postHeroMana > preHeroMana {
 			notes = append(notes, "+1 mana!")
 		}
@@ -1808,9 +1663,14 @@ func deriveCombatLog(
 		notes = append(notes, fmt.Sprintf("+%d%% amulet", postAmuletBonus))
 	}
 
-	// Inventory full (loot dropped but inventory unchanged)
-	if postLastLootDrop != "" && inventoryCount(postInventory) == inventoryCount(preInventory) && inventoryCount(postInventory) >= 8 {
-		notes = append(notes, "inventory full")
+	// Inventory full (loot dropped but inventory unchanged): kro sold it
+	if postLastLootDrop != "" && postItems == preItems && postItems >= model.MaxInventory {
+		notes = append(notes, "inventory full, loot sold")
+	}
+
+	// Gold from kills and sold loot
+	if gold := post.Gold - pre.Gold; gold > 0 {
+		notes = append(notes, fmt.Sprintf("+%d gold", gold))
 	}
 
 	noteStr := ""
@@ -1839,8 +1699,8 @@ func deriveCombatLog(
 		if i >= len(preMonsterHP) {
 			break
 		}
-		postHP := sliceInt(postMonsterHP[i])
-		preHP := sliceInt(preMonsterHP[i])
+		postHP := postMonsterHP[i]
+		preHP := preMonsterHP[i]
 		if postHP > preHP {
 			healNotes = append(healNotes, fmt.Sprintf("Shaman heals m%d for %d HP!", i, postHP-preHP))
 		}
@@ -1902,13 +1762,7 @@ func deriveCombatLog(
 		if newHP == 0 && heroDmgTaken == 0 {
 			enemyAction = "Monster slain! No remaining counter-attack." + effectStr
 		} else if heroDmgTaken > 0 {
-			// Count alive monsters in post-state
-			aliveCount := int64(0)
-			for _, v := range postMonsterHP {
-				if sliceInt(v) > 0 {
-					aliveCount++
-				}
-			}
+			aliveCount := post.LivingMonsters()
 			enemyAction = fmt.Sprintf("%d monster(s) counter-attack for %d total damage! (Hero HP: %d)%s",
 				aliveCount, heroDmgTaken, postHeroHP, effectStr)
 		} else {
@@ -1944,41 +1798,44 @@ func (h *Handler) processAction(ctx context.Context, r *http.Request, ns, name,
 	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		slog.Error("failed to get dungeon for action", "component", "api", "dungeon", name, "namespace", ns, "error", err)
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return err
 	}
 	// #409: verify the caller owns this dungeon.
 	if ownerErr := requireDungeonOwner(r, dungeon); ownerErr != nil {
-		writeError(w, ownerErr.Error(), http.StatusForbidden)
+		writeOwnerError(w, ownerErr)
 		return ownerErr
 	}
-	spec := getMap(dungeon.Object, "spec")
-	dungeonStatusAction := getMap(dungeon.Object, "status")
-	gameAction := getGameState(dungeon.Object)
+	h.pauseAutoBattleOnIntervention(ctx, r, dungeon)
+	d, err := model.FromUnstructured(dungeon)
+	if err != nil {
+		slog.Error("failed to decode dungeon for action", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return err
+	}
+	gameAction := d.Status.Game
 
-	heroHP := getInt(gameAction, "heroHP")
-	heroMana := getInt(gameAction, "heroMana")
-	heroClass := getString(spec, "heroClass", "warrior")
+	heroHP := gameAction.HeroHP
+	heroMana := gameAction.HeroMana
+	heroClass := d.Spec.HeroClass
 	heroClassAction = heroClass
-	difficultyAction = getString(spec, "difficulty", "normal")
+	difficultyAction = d.Spec.Difficulty
 	// #399: reject if kro has not yet provided maxHeroHP (hero-graph not reconciled yet)
-	if maxHeroHPStr := getString(dungeonStatusAction, "maxHeroHP", ""); maxHeroHPStr == "" {
-		writeError(w, "dungeon initializing — hero max HP not yet computed by kro, please retry", http.StatusServiceUnavailable)
+	if !d.Status.HeroReady() {
+		writeCodedError(w, "dungeon initializing — hero max HP not yet computed by kro, please retry", http.StatusServiceUnavailable, CodeDungeonInitializing)
 		return fmt.Errorf("hero maxHeroHP not yet available from kro")
 	}
-	inventory := getString(gameAction, "inventory", "")
-	bossHP := getInt(gameAction, "bossHP")
-	actionSeq := getInt(spec, "actionSeq")
+	backpack := gameAction.Inventory
+	actionSeq := d.Spec.ActionSeq
 
 	// Conflict guard: reject stale requests where the client's observed
 	// actionSeq no longer matches the server. clientSeq < 0 means the client
 	// did not send a sequence (old clients) — those are passed through.
 	if clientSeq >= 0 && clientSeq != actionSeq {
 		slog.Warn("stale action rejected", "component", "api", "dungeon", name, "clientSeq", clientSeq, "serverSeq", actionSeq)
-		writeError(w, "stale request — dungeon state has changed, please retry", http.StatusConflict)
+		writeStaleSeq(w)
 		return fmt.Errorf("stale action: clientSeq=%d serverSeq=%d", clientSeq, actionSeq)
 	}
-	monsterHPRaw, _ := gameAction["monsterHP"].([]interface{})
 
 	// NOTE: backstabCooldown decrement is now handled by kro state node (tickCooldown)
 	// in dungeon-graph.yaml, gated on attackSeq + actionSeq advancement.
@@ -2019,6 +1876,8 @@ func (h *Handler) processAction(ctx context.Context, r *http.Request, ns, name,
 		"lastAttackTarget": "",
 		"lastAbility":      "",
 	}
+	// totals adds the running-total fields; see patchTurn.
+	var totals func(*model.Dungeon, map[string]interface{})
 
 	// State mutations (inventory, heroHP, heroMana, equipment bonuses,
 	// treasureOpened, doorUnlocked, room transition fields) are computed by
@@ -2026,199 +1885,288 @@ func (h *Handler) processAction(ctx context.Context, r *http.Request, ns, name,
 	// Validation stays here (returns 400 errors before setting trigger).
 
 	switch {
-	case strings.HasPrefix(action, "use-"):
-		item := strings.TrimPrefix(action, "use-")
-		if !inventoryContains(inventory, item) {
-			writeError(w, "item not in inventory: "+item, http.StatusBadRequest)
+	case strings.HasPrefix(action, "use-"), strings.HasPrefix(action, "equip-"):
+		verb, item, _ := strings.Cut(action, "-")
+		if !backpack.Contains(item) {
+			writeCodedError(w, "item not in inventory: "+item, http.StatusBadRequest, CodeItemNotInInventory)
 			return fmt.Errorf("item not in inventory")
 		}
-
-		switch item {
-		case "hppotion-common":
-			// #400: no hardcoded heal amounts — log text uses pre-state HP; kro actionResolve is authoritative for actual HP mutation
-			patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! HP: %d -> (healing...)", item, heroHP)
-			patchSpec["lastEnemyAction"] = "Item used"
-		case "hppotion-rare":
-			patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! HP: %d -> (healing...)", item, heroHP)
-			patchSpec["lastEnemyAction"] = "Item used"
-		case "hppotion-epic":
-			patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! HP: %d -> (healing...)", item, heroHP)
-			patchSpec["lastEnemyAction"] = "Item used"
-		case "manapotion-common":
-			if heroClass != "mage" {
-				writeError(w, "mana potions can only be used by Mage", http.StatusBadRequest)
-				return fmt.Errorf("mana potion non-mage")
-			}
-			patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! Mana: %d -> (restoring...)", item, heroMana)
-			patchSpec["lastEnemyAction"] = "Item used"
-		case "manapotion-rare":
-			if heroClass != "mage" {
-				writeError(w, "mana potions can only be used by Mage", http.StatusBadRequest)
-				return fmt.Errorf("mana potion non-mage")
-			}
-			patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! Mana: %d -> (restoring...)", item, heroMana)
-			patchSpec["lastEnemyAction"] = "Item used"
-		case "manapotion-epic":
-			if heroClass != "mage" {
-				writeError(w, "mana potions can only be used by Mage", http.StatusBadRequest)
-				return fmt.Errorf("mana potion non-mage")
-			}
-			patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! Mana: %d -> (restoring...)", item, heroMana)
-			patchSpec["lastEnemyAction"] = "Item used"
-		default:
-			writeError(w, "unknown item: "+item, http.StatusBadRequest)
+		def, ok := h.items.get(ctx).Lookup(item)
+		if verb == "use" && (!ok || def.Kind != catalog.Consumable) {
+			writeCodedError(w, "unknown item: "+item, http.StatusBadRequest, CodeUnknownItem)
 			return fmt.Errorf("unknown item")
 		}
-		// Business metric: item used (consume) (Issue #358)
-		{
-			parts := strings.SplitN(item, "-", 2)
-			rarity := ""
-			if len(parts) == 2 {
-				rarity = parts[1]
+		if verb == "equip" && (!ok || def.Kind != catalog.Equipment) {
+			writeCodedError(w, "cannot equip: "+item, http.StatusBadRequest, CodeUnknownItem)
+			return fmt.Errorf("cannot equip item")
+		}
+		if !def.UsableByClass(heroClass) {
+			writeCodedError(w, fmt.Sprintf("%s can only be used by %s", item, strings.Join(def.UsableBy, ", ")), http.StatusBadRequest, CodeWrongClass)
+			return fmt.Errorf("item not usable by %s", heroClass)
+		}
+
+		// #400: no hardcoded amounts — log text uses pre-state values; kro
+		// actionResolve is authoritative for the actual mutation.
+		metricAction := "equip"
+		if verb == "use" {
+			metricAction = "consume"
+			switch def.Stat {
+			case "heroHP":
+				patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! HP: %d -> (healing...)", item, heroHP)
+			case "heroMana":
+				patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! Mana: %d -> (restoring...)", item, heroMana)
+			default:
+				patchSpec["lastHeroAction"] = fmt.Sprintf("Used %s! %s", item, def.Description)
 			}
-			slog.Info("item_used",
-				"component", "game",
-				"dungeon", name,
-				"hero_class", heroClass,
-				"item_type", parts[0],
-				"item_rarity", rarity,
-				"action", "consume",
-			)
+			patchSpec["lastEnemyAction"] = "Item used"
+			totals = func(cur *model.Dungeon, spec map[string]interface{}) {
+				spec["consumablesUsed"] = cur.Spec.ConsumablesUsed + 1
+				if cur.Spec.Objectives.UsedConsumable() {
+					spec["objectives"] = cur.Spec.Objectives.Unstructured()
+				}
+			}
+		} else {
+			patchSpec["lastHeroAction"] = fmt.Sprintf("Equipped %s! %s%s", item, def.Description, usesLeft(backpack, item))
+			patchSpec["lastEnemyAction"] = "Item equipped"
 		}
+		// Business metric: item used (Issue #358)
+		slog.Info("item_used",
+			"component", "game",
+			"dungeon", name,
+			"hero_class", heroClass,
+			"item_type", def.Type,
+			"item_rarity", def.Rarity,
+			"action", metricAction,
+		)
 
-	case strings.HasPrefix(action, "equip-"):
-		item := strings.TrimPrefix(action, "equip-")
-		if !inventoryContains(inventory, item) {
-			writeError(w, "item not in inventory: "+item, http.StatusBadRequest)
+	case strings.HasPrefix(action, "drop-"):
+		item := strings.TrimPrefix(action, "drop-")
+		if !backpack.Contains(item) {
+			writeCodedError(w, "item not in inventory: "+item, http.StatusBadRequest, CodeItemNotInInventory)
 			return fmt.Errorf("item not in inventory")
 		}
+		// kro actionResolve removes one copy.
+		patchSpec["lastHeroAction"] = fmt.Sprintf("Dropped %s.", item)
+		patchSpec["lastEnemyAction"] = "Item dropped"
+		slog.Info("item_dropped",
+			"component", "game",
+			"dungeon", name,
+			"hero_class", heroClass,
+			"item", item,
+		)
 
-		switch item {
-		case "weapon-common":
-			patchSpec["lastHeroAction"] = "Equipped weapon-common! +5 damage for 3 attacks"
-		case "weapon-rare":
-			patchSpec["lastHeroAction"] = "Equipped weapon-rare! +10 damage for 3 attacks"
-		case "weapon-epic":
-			patchSpec["lastHeroAction"] = "Equipped weapon-epic! +20 damage for 3 attacks"
-		case "armor-common":
-			patchSpec["lastHeroAction"] = "Equipped armor-common! +10% defense"
-		case "armor-rare":
-			patchSpec["lastHeroAction"] = "Equipped armor-rare! +20% defense"
-		case "armor-epic":
-			patchSpec["lastHeroAction"] = "Equipped armor-epic! +30% defense"
-		case "shield-common":
-			patchSpec["lastHeroAction"] = "Equipped shield-common! +10% block chance"
-		case "shield-rare":
-			patchSpec["lastHeroAction"] = "Equipped shield-rare! +15% block chance"
-		case "shield-epic":
-			patchSpec["lastHeroAction"] = "Equipped shield-epic! +25% block chance"
-		case "helmet-common":
-			patchSpec["lastHeroAction"] = "Equipped helmet-common! +5% crit chance"
-		case "helmet-rare":
-			patchSpec["lastHeroAction"] = "Equipped helmet-rare! +10% crit chance"
-		case "helmet-epic":
-			patchSpec["lastHeroAction"] = "Equipped helmet-epic! +15% crit chance"
-		case "pants-common":
-			patchSpec["lastHeroAction"] = "Equipped pants-common! +5% dodge chance"
-		case "pants-rare":
-			patchSpec["lastHeroAction"] = "Equipped pants-rare! +10% dodge chance"
-		case "pants-epic":
-			patchSpec["lastHeroAction"] = "Equipped pants-epic! +15% dodge chance"
-		case "boots-common":
-			patchSpec["lastHeroAction"] = "Equipped boots-common! +20% status resist"
-		case "boots-rare":
-			patchSpec["lastHeroAction"] = "Equipped boots-rare! +40% status resist"
-		case "boots-epic":
-			patchSpec["lastHeroAction"] = "Equipped boots-epic! +60% status resist"
-		case "ring-common":
-			patchSpec["lastHeroAction"] = "Equipped ring-common! +5 HP regen per round"
-		case "ring-rare":
-			patchSpec["lastHeroAction"] = "Equipped ring-rare! +8 HP regen per round"
-		case "ring-epic":
-			patchSpec["lastHeroAction"] = "Equipped ring-epic! +12 HP regen per round"
-		case "amulet-common":
-			patchSpec["lastHeroAction"] = "Equipped amulet-common! +10% damage boost"
-		case "amulet-rare":
-			patchSpec["lastHeroAction"] = "Equipped amulet-rare! +20% damage boost"
-		case "amulet-epic":
-			patchSpec["lastHeroAction"] = "Equipped amulet-epic! +30% damage boost"
-		default:
-			writeError(w, "cannot equip: "+item, http.StatusBadRequest)
-			return fmt.Errorf("cannot equip item")
-		}
-		patchSpec["lastEnemyAction"] = "Item equipped"
-		// Business metric: item used (equip) (Issue #358)
-		{
-			parts := strings.SplitN(item, "-", 2)
-			rarity := ""
-			if len(parts) == 2 {
-				rarity = parts[1]
+	case strings.HasPrefix(action, "unequip-"), strings.HasPrefix(action, "swap-"):
+		verb, slot, _ := strings.Cut(action, "-")
+		if !slices.Contains(model.EquipmentSlots, slot) {
+			writeCodedError(w, "unknown slot: "+slot, http.StatusBadRequest, CodeUnknownAction)
+			return fmt.Errorf("unknown slot")
+		}
+		// kro records only the bonus; the catalog names the worn item.
+		worn, wearing := h.items.get(ctx).Equipped(slot, gameAction.SlotBonus(slot))
+		if verb == "unequip" {
+			if !wearing {
+				writeCodedError(w, fmt.Sprintf("nothing equipped in the %s slot", slot), http.StatusBadRequest, CodeSlotEmpty)
+				return fmt.Errorf("slot empty")
+			}
+			if backpack.Len() >= model.MaxInventory {
+				writeCodedError(w, "inventory full: drop something first", http.StatusBadRequest, CodeInventoryFull)
+				return fmt.Errorf("inventory full")
 			}
-			slog.Info("item_used",
+			patchSpec["lastHeroAction"] = fmt.Sprintf("Unequipped %s! Back in the backpack.", worn.ID)
+			patchSpec["lastEnemyAction"] = "Item unequipped"
+			slog.Info("item_unequipped",
 				"component", "game",
 				"dungeon", name,
 				"hero_class", heroClass,
-				"item_type", parts[0],
-				"item_rarity", rarity,
-				"action", "equip",
+				"item", worn.ID,
 			)
+			break
+		}
+		items := gameAction.Items()
+		i := slices.IndexFunc(items, func(it string) bool { return strings.HasPrefix(it, slot+"-") })
+		if i < 0 {
+			writeCodedError(w, fmt.Sprintf("no %s in the backpack", slot), http.StatusBadRequest, CodeItemNotInInventory)
+			return fmt.Errorf("nothing to swap in")
+		}
+		def, ok := h.items.get(ctx).Lookup(items[i])
+		if !ok || def.Kind != catalog.Equipment {
+			writeCodedError(w, "cannot equip: "+items[i], http.StatusBadRequest, CodeUnknownItem)
+			return fmt.Errorf("cannot equip item")
+		}
+		if !def.UsableByClass(heroClass) {
+			writeCodedError(w, fmt.Sprintf("%s can only be used by %s", def.ID, strings.Join(def.UsableBy, ", ")), http.StatusBadRequest, CodeWrongClass)
+			return fmt.Errorf("item not usable by %s", heroClass)
+		}
+		// kro actionResolve equips it and puts the worn item in its place.
+		if wearing {
+			patchSpec["lastHeroAction"] = fmt.Sprintf("Swapped %s for %s! %s%s", worn.ID, def.ID, def.Description, usesLeft(backpack, def.ID))
+		} else {
+			patchSpec["lastHeroAction"] = fmt.Sprintf("Equipped %s! %s%s", def.ID, def.Description, usesLeft(backpack, def.ID))
 		}
+		patchSpec["lastEnemyAction"] = "Item swapped"
+		slog.Info("item_swapped",
+			"component", "game",
+			"dungeon", name,
+			"hero_class", heroClass,
+			"item", def.ID,
+			"replaced", worn.ID,
+		)
 
-	case action == "open-treasure":
-		allDead := true
-		for _, v := range monsterHPRaw {
-			if sliceInt(v) > 0 {
-				allDead = false
-				break
-			}
+	case strings.HasPrefix(action, "craft-"):
+		id := strings.TrimPrefix(action, "craft-")
+		recipe, ok := catalog.LookupRecipe(id)
+		if !ok {
+			writeCodedError(w, "unknown recipe: "+id, http.StatusBadRequest, CodeUnknownRecipe)
+			return fmt.Errorf("unknown recipe")
+		}
+		if item, need, have := recipe.Shortfall(backpack.IDs()); item != "" {
+			writeCodedError(w, fmt.Sprintf("%s needs %d %s, you have %d", id, need, item, have), http.StatusBadRequest, CodeItemNotInInventory)
+			return fmt.Errorf("missing recipe input %s", item)
+		}
+		def, ok := h.items.get(ctx).Lookup(recipe.Output)
+		if !ok {
+			writeCodedError(w, "unknown item: "+recipe.Output, http.StatusBadRequest, CodeUnknownItem)
+			return fmt.Errorf("unknown recipe output")
+		}
+		// kro actionResolve swaps the inputs for the output.
+		patchSpec["lastHeroAction"] = fmt.Sprintf("Crafted %s from %s! %s", recipe.Output, recipeInputs(recipe), def.Description)
+		patchSpec["lastEnemyAction"] = "Item crafted"
+		slog.Info("item_crafted",
+			"component", "game",
+			"dungeon", name,
+			"hero_class", heroClass,
+			"recipe", recipe.ID,
+			"item_type", def.Type,
+			"item_rarity", def.Rarity,
+		)
+
+	case strings.HasPrefix(action, "buy-"):
+		item := strings.TrimPrefix(action, "buy-")
+		if !gameAction.MerchantOpen() {
+			writeCodedError(w, "no merchant here: clear the room first", http.StatusBadRequest, CodeActionOutOfOrder)
+			return fmt.Errorf("merchant not open")
+		}
+		def, ok := h.items.get(ctx).Lookup(item)
+		if !ok || !slices.Contains(gameAction.MerchantItems(), item) {
+			writeCodedError(w, "the merchant does not sell "+item, http.StatusBadRequest, CodeUnknownItem)
+			return fmt.Errorf("item not in merchant stock")
+		}
+		if !def.UsableByClass(heroClass) {
+			writeCodedError(w, fmt.Sprintf("%s can only be used by %s", item, strings.Join(def.UsableBy, ", ")), http.StatusBadRequest, CodeWrongClass)
+			return fmt.Errorf("item not usable by %s", heroClass)
+		}
+		if gameAction.Gold < def.Price {
+			writeCodedError(w, fmt.Sprintf("%s costs %d gold, you have %d", item, def.Price, gameAction.Gold), http.StatusBadRequest, CodeNotEnoughGold)
+			return fmt.Errorf("not enough gold")
+		}
+		if backpack.Len() >= model.MaxInventory {
+			writeCodedError(w, "inventory full: sell something first", http.StatusBadRequest, CodeInventoryFull)
+			return fmt.Errorf("inventory full")
+		}
+		// kro actionResolve moves the item and the gold.
+		patchSpec["lastHeroAction"] = fmt.Sprintf("Bought %s for %d gold! %s", item, def.Price, def.Description)
+		patchSpec["lastEnemyAction"] = "The merchant bows"
+		slog.Info("item_bought",
+			"component", "game",
+			"dungeon", name,
+			"hero_class", heroClass,
+			"item_type", def.Type,
+			"item_rarity", def.Rarity,
+			"gold", def.Price,
+		)
+
+	case strings.HasPrefix(action, "sell-"):
+		item := strings.TrimPrefix(action, "sell-")
+		if !gameAction.MerchantOpen() {
+			writeCodedError(w, "no merchant here: clear the room first", http.StatusBadRequest, CodeActionOutOfOrder)
+			return fmt.Errorf("merchant not open")
 		}
-		if bossHP > 0 || !allDead {
-			writeError(w, "cannot open treasure: boss not defeated", http.StatusBadRequest)
+		if !backpack.Contains(item) {
+			writeCodedError(w, "item not in inventory: "+item, http.StatusBadRequest, CodeItemNotInInventory)
+			return fmt.Errorf("item not in inventory")
+		}
+		def, ok := h.items.get(ctx).Lookup(item)
+		if !ok {
+			writeCodedError(w, "unknown item: "+item, http.StatusBadRequest, CodeUnknownItem)
+			return fmt.Errorf("unknown item")
+		}
+		patchSpec["lastHeroAction"] = fmt.Sprintf("Sold %s for %d gold!", item, def.SellPrice())
+		patchSpec["lastEnemyAction"] = "The merchant bows"
+		slog.Info("item_sold",
+			"component", "game",
+			"dungeon", name,
+			"hero_class", heroClass,
+			"item_type", def.Type,
+			"item_rarity", def.Rarity,
+			"gold", def.SellPrice(),
+		)
+
+	case action == "open-treasure":
+		if !gameAction.RoomCleared() {
+			writeCodedError(w, "cannot open treasure: boss not defeated", http.StatusBadRequest, CodeActionOutOfOrder)
 			return fmt.Errorf("cannot open treasure")
 		}
 		patchSpec["lastHeroAction"] = "Opened the treasure chest!"
 		patchSpec["lastEnemyAction"] = ""
 
 	case action == "unlock-door":
-		treasureOpened := getInt(gameAction, "treasureOpened")
-		if treasureOpened != 1 {
-			writeError(w, "open the treasure first", http.StatusBadRequest)
+		if gameAction.TreasureOpened != 1 {
+			writeCodedError(w, "open the treasure first", http.StatusBadRequest, CodeActionOutOfOrder)
 			return fmt.Errorf("open treasure first")
 		}
+		if d.InFinalRoom() {
+			writeCodedError(w, "the final room has no door", http.StatusBadRequest, CodeActionOutOfOrder)
+			return fmt.Errorf("no door in final room")
+		}
 		patchSpec["lastHeroAction"] = "Door unlocked! A new room awaits..."
 		patchSpec["lastEnemyAction"] = ""
 
-	case action == "enter-room-2":
-		doorUnlocked := getInt(gameAction, "doorUnlocked")
-		if doorUnlocked != 1 {
-			writeError(w, "unlock the door first", http.StatusBadRequest)
+	case strings.HasPrefix(action, "enter-room-"):
+		room, err := strconv.ParseInt(strings.TrimPrefix(action, "enter-room-"), 10, 64)
+		if err != nil {
+			writeCodedError(w, "unknown action: "+action, http.StatusBadRequest, CodeUnknownAction)
+			return fmt.Errorf("unknown action")
+		}
+		// Rooms are entered in order; kro's actionResolve trusts the number.
+		if next := max(gameAction.CurrentRoom, 1) + 1; room != next || room > d.RoomCount() {
+			msg := fmt.Sprintf("cannot enter room %d: the next room is %d", room, next)
+			if next > d.RoomCount() {
+				msg = fmt.Sprintf("cannot enter room %d: this dungeon has %d rooms", room, d.RoomCount())
+			}
+			writeCodedError(w, msg, http.StatusBadRequest, CodeActionOutOfOrder)
+			return fmt.Errorf("room %d out of order", room)
+		}
+		if gameAction.DoorUnlocked != 1 {
+			writeCodedError(w, "unlock the door first", http.StatusBadRequest, CodeActionOutOfOrder)
 			return fmt.Errorf("unlock door first")
 		}
-		patchSpec["lastHeroAction"] = "Entered Room 2! Stronger enemies await..."
+		patchSpec["lastHeroAction"] = fmt.Sprintf("Entered Room %d! Stronger enemies await...", room)
 		patchSpec["lastEnemyAction"] = ""
-		// Award XP for entering room 2 (#360)
-		patchSpec["xpEarned"] = getInt(spec, "xpEarned") + int64(10)
-		// Delete stale Room 1 Attack CR so it cannot be re-processed in Room 2 (#AGENTS rule)
+		// Award XP for entering a new room (#360)
+		totals = func(cur *model.Dungeon, spec map[string]interface{}) {
+			spec["xpEarned"] = cur.Spec.XPEarned + int64(10)
+		}
+		// Delete the previous room's stale Attack CR so it cannot be re-processed (#AGENTS rule)
 		attackCRName := name + "-latest-attack"
 		_ = h.client.Dynamic.Resource(k8s.AttackGVR).Namespace("default").Delete(
 			ctx, attackCRName, metav1.DeleteOptions{})
-		// Business metric: room 2 entered (Issue #358)
-		attackSeqAction := getInt(spec, "attackSeq")
-		slog.Info("room2_entered",
+		// Business metric: room entered (Issue #358)
+		slog.Info("room_entered",
 			"component", "game",
 			"dungeon", name,
 			"hero_class", heroClass,
 			"difficulty", difficultyAction,
-			"turns_used", attackSeqAction+actionSeq,
+			"room", room,
+			"turns_used", d.TotalTurns(),
 		)
 
 	default:
-		writeError(w, "unknown action: "+action, http.StatusBadRequest)
+		writeCodedError(w, "unknown action: "+action, http.StatusBadRequest, CodeUnknownAction)
 		return fmt.Errorf("unknown action")
 	}
 
 	patch := map[string]interface{}{"spec": patchSpec}
-	return h.patchAndRespond(ctx, ns, name, patch, w)
+	return h.patchTurnAndRespond(ctx, ns, name, dungeon, patch, totals, w)
 }
 
 // ---- helpers ----------------------------------------------------------------
@@ -2230,16 +2178,16 @@ func (h *Handler) processAction(ctx context.Context, r *http.Request, ns, name,
 func requireDungeonOwner(r *http.Request, dungeon interface{ GetLabels() map[string]string }) error {
 	sess := sessionFromCtx(r.Context())
 	if sess == nil {
-		return fmt.Errorf("authentication required")
+		return errAuthRequired
 	}
 	labels := dungeon.GetLabels()
 	owner, hasLabel := labels["krombat.io/owner"]
 	if !hasLabel {
 		// #422: deny access to unlabelled dungeons — the label is mandatory.
-		return fmt.Errorf("forbidden: dungeon has no owner label")
+		return errNoOwnerLabel
 	}
 	if owner != sess.Login {
-		return fmt.Errorf("forbidden: dungeon belongs to another user")
+		return errOtherUsersGame
 	}
 	return nil
 }
@@ -2265,6 +2213,9 @@ func retryK8s(attempts int, fn func() error) error {
 // isClientError reports whether err is a Kubernetes 4xx client error that
 // should not be retried (as opposed to a transient 5xx / network failure).
 func isClientError(err error) bool {
+	if apierrors.IsConflict(err) {
+		return true // a resourceVersion precondition fails the same way every time
+	}
 	errStr := strings.ToLower(err.Error())
 	return strings.Contains(errStr, "not found") ||
 		strings.Contains(errStr, "already exists") ||
@@ -2293,6 +2244,109 @@ func (h *Handler) patchAndRespond(ctx context.Context, ns, name string, patch ma
 	return h.respondDungeon(ctx, ns, name, w)
 }
 
+// turnPatchAttempts bounds how often patchTurn and patchRebased re-base a
+// patch on a newer resourceVersion.
+const turnPatchAttempts = 5
+
+// errStaleTurn means another turn landed between a request's seq check and
+// its trigger write.
+var errStaleTurn = errors.New("stale turn: dungeon seq advanced")
+
+// patchTurn writes a turn's trigger fields only if no other turn has landed
+// since pre was read. The merge patch carries pre's resourceVersion, so the
+// API server rejects it if anything wrote the dungeon in between. When that
+// write was not a turn — kro updating status, an annotation, the previous
+// attack's log — attackSeq and actionSeq are unchanged and the patch is
+// retried against the new version; otherwise it fails with errStaleTurn.
+//
+// totals, if non-nil, adds the spec fields that build on running totals
+// (xpEarned, consumablesUsed, objectives) to the patch's spec. It is called
+// with every version the patch is based on, so a retry never writes back a
+// total computed from an older read.
+func (h *Handler) patchTurn(ctx context.Context, ns, name string, pre *unstructured.Unstructured, patch map[string]interface{}, totals func(*model.Dungeon, map[string]interface{})) error {
+	attackSeq, _, _ := unstructured.NestedInt64(pre.Object, "spec", "attackSeq")
+	actionSeq, _, _ := unstructured.NestedInt64(pre.Object, "spec", "actionSeq")
+	base := pre
+	for range turnPatchAttempts {
+		if totals != nil {
+			d, err := model.FromUnstructured(base)
+			if err != nil {
+				return err
+			}
+			spec, _ := patch["spec"].(map[string]interface{})
+			totals(d, spec)
+		}
+		patch["metadata"] = map[string]interface{}{"resourceVersion": base.GetResourceVersion()}
+		err := h.patchDungeon(ctx, ns, name, patch)
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+		cur, getErr := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+		curAttack, _, _ := unstructured.NestedInt64(cur.Object, "spec", "attackSeq")
+		curAction, _, _ := unstructured.NestedInt64(cur.Object, "spec", "actionSeq")
+		if curAttack != attackSeq || curAction != actionSeq {
+			staleTurnsRejected.Inc()
+			return errStaleTurn
+		}
+		base = cur
+	}
+	staleTurnsRejected.Inc()
+	return errStaleTurn
+}
+
+// patchRebased writes the patch build makes from obj, on the condition that
+// nothing else wrote the dungeon since obj was read. After a conflict it reads
+// the dungeon again and rebuilds the patch from that, so fields the backend
+// accumulates are never written back from a stale copy. It returns the
+// dungeon the written patch was built from, as build left it.
+func (h *Handler) patchRebased(ctx context.Context, ns, name string, obj *unstructured.Unstructured, build func(*model.Dungeon) map[string]interface{}) (*model.Dungeon, error) {
+	for attempt := 1; ; attempt++ {
+		cur, err := model.FromUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		patch := build(cur)
+		patch["metadata"] = map[string]interface{}{"resourceVersion": obj.GetResourceVersion()}
+		err = h.patchDungeon(ctx, ns, name, patch)
+		if !apierrors.IsConflict(err) || attempt == turnPatchAttempts {
+			if err != nil {
+				return nil, err
+			}
+			return cur, nil
+		}
+		if obj, err = h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeStaleSeq reports a turn submitted against an outdated dungeon.
+func writeStaleSeq(w http.ResponseWriter) {
+	writeCodedError(w, "stale request — dungeon state has changed, please retry", http.StatusConflict, CodeStaleSeq)
+}
+
+// writeTurnPatchError reports a failed patchTurn.
+func writeTurnPatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errStaleTurn) {
+		writeStaleSeq(w)
+		return
+	}
+	writeError(w, sanitizeK8sError(err), http.StatusInternalServerError)
+}
+
+// patchTurnAndRespond is patchAndRespond for trigger writes; see patchTurn.
+func (h *Handler) patchTurnAndRespond(ctx context.Context, ns, name string, pre *unstructured.Unstructured, patch map[string]interface{}, totals func(*model.Dungeon, map[string]interface{}), w http.ResponseWriter) error {
+	if err := h.patchTurn(ctx, ns, name, pre, patch, totals); err != nil {
+		slog.Warn("turn patch rejected", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+		writeTurnPatchError(w, err)
+		return err
+	}
+	return h.respondDungeon(ctx, ns, name, w)
+}
+
 func (h *Handler) respondDungeon(ctx context.Context, ns, name string, w http.ResponseWriter) error {
 	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
@@ -2327,9 +2381,10 @@ func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// writeError reports an error with the generic code for its status; see
+// writeCodedError.
 func writeError(w http.ResponseWriter, msg string, code int) {
-	slog.Warn("request error", "component", "api", "status", code, "error", msg)
-	http.Error(w, msg, code)
+	writeCodedError(w, msg, code, codeForStatus(code))
 }
 
 // validGameEvents is the allowlist of event names accepted by EventsTrackHandler.
@@ -2365,8 +2420,7 @@ var allowedEventKeys = map[string]bool{
 // POST /api/v1/client-error
 func (h *Handler) ClientErrorHandler(w http.ResponseWriter, r *http.Request) {
 	// #419/#421: rate-limit + body size cap to prevent CloudWatch log flooding
-	if !h.telemetryLimit.Allow(r.RemoteAddr) {
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	if !h.telemetryLimit.allowRequest(w, r) {
 		return
 	}
 	r.Body = http.MaxBytesReader(w, r.Body, 8192) // 8 KB cap
@@ -2426,8 +2480,7 @@ func (h *Handler) VitalsHandler(w http.ResponseWriter, r *http.Request) {
 // POST /api/v1/events-track
 func (h *Handler) EventsTrackHandler(w http.ResponseWriter, r *http.Request) {
 	// #419/#421: rate-limit + body size cap + event allowlist
-	if !h.telemetryLimit.Allow(r.RemoteAddr) {
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	if !h.telemetryLimit.allowRequest(w, r) {
 		return
 	}
 	r.Body = http.MaxBytesReader(w, r.Body, 4096) // 4 KB cap
@@ -2481,32 +2534,54 @@ func sanitizeK8sError(err error) string {
 	}
 }
 
-func inventoryContains(inventory, item string) bool {
-	if inventory == "" {
-		return false
-	}
-	var items []string
-	if err := json.Unmarshal([]byte(inventory), &items); err != nil {
-		return false
+// carryOverInventory is the backpack a won run hands to the next one: the
+// equipped items, unequipped, then the backpack, up to model.MaxInventory. A
+// worn weapon keeps the uses it has left as its durability, and so do worn
+// weapons already in the backpack.
+func carryOverInventory(items *catalog.Catalog, game model.GameState) model.Inventory {
+	var inv model.Inventory
+	for _, slot := range model.EquipmentSlots {
+		if it, ok := items.Equipped(slot, game.SlotBonus(slot)); ok {
+			s := model.ItemStack{ID: it.ID, Quantity: 1}
+			if slot == "weapon" && game.WeaponUses < it.Uses {
+				s.Durability = game.WeaponUses
+			}
+			inv.Add(s)
+		}
 	}
-	for _, v := range items {
-		if v == item {
-			return true
+	for _, s := range game.Inventory {
+		for range s.Quantity {
+			inv.Add(model.ItemStack{ID: s.ID, Quantity: 1, Durability: s.Durability})
 		}
 	}
-	return false
+	return inv
+}
+
+// usesLeft notes a worn copy in the equip log: kro's actionResolve equips
+// the first stack of id and starts weaponUses from its durability.
+func usesLeft(backpack model.Inventory, id string) string {
+	if s, ok := backpack.First(id); ok && s.Durability > 0 {
+		return fmt.Sprintf(" (%d uses left)", s.Durability)
+	}
+	return ""
 }
 
-// inventoryCount returns the number of items in the inventory string.
-func inventoryCount(inventory string) int {
-	if inventory == "" {
-		return 0
+// recipeInputs words a recipe's inputs for the combat log: "3 weapon-common".
+func recipeInputs(r catalog.Recipe) string {
+	var parts []string
+	counts := map[string]int{}
+	for _, in := range r.Inputs {
+		if counts[in] == 0 {
+			parts = append(parts, in)
+		}
+		counts[in]++
 	}
-	var items []string
-	if err := json.Unmarshal([]byte(inventory), &items); err != nil {
-		return 0
+	for i, in := range parts {
+		if counts[in] > 1 {
+			parts[i] = fmt.Sprintf("%d %s", counts[in], in)
+		}
 	}
-	return len(items)
+	return strings.Join(parts, ", ")
 }
 
 func getMap(obj map[string]interface{}, key string) map[string]interface{} {
@@ -2514,40 +2589,6 @@ func getMap(obj map[string]interface{}, key string) map[string]interface{} {
 	return v
 }
 
-// getGameState returns the kro-computed game state map from status.game.
-// During rolling migration, it falls back to spec if status.game is empty
-// (old dungeons created before the state-node migration).
-// After the 4-hour dungeon reaper cleans all old dungeons, the fallback
-// can be removed and this function can simply return status.game.
-func getGameState(dungeonObj map[string]interface{}) map[string]interface{} {
-	status := getMap(dungeonObj, "status")
-	game := getMap(status, "game")
-	if len(game) > 0 {
-		return game
-	}
-	// Fallback: old dungeon with game state in spec (pre-migration).
-	return getMap(dungeonObj, "spec")
-}
-
-func getInt(m map[string]interface{}, key string) int64 {
-	switch v := m[key].(type) {
-	case int64:
-		return v
-	case float64:
-		return int64(v)
-	case int:
-		return int64(v)
-	}
-	return 0
-}
-
-func getString(m map[string]interface{}, key, def string) string {
-	if v, ok := m[key].(string); ok {
-		return v
-	}
-	return def
-}
-
 func min64(a, b int64) int64 {
 	if a < b {
 		return a
@@ -2564,20 +2605,6 @@ func max64(a, b int64) int64 {
 
 func boolPtr(b bool) *bool { return &b }
 
-// sliceInt converts an interface{} slice element to int64,
-// handling both float64 (JSON-decoded) and int64 (Go-native) values.
-func sliceInt(v interface{}) int64 {
-	switch x := v.(type) {
-	case float64:
-		return int64(x)
-	case int64:
-		return x
-	case int:
-		return int64(x)
-	}
-	return 0
-}
-
 // CelEvalHandler evaluates a CEL expression against the live dungeon state
 // using the real kro CEL environment (same libraries as kro reconcile).
 // POST /api/v1/dungeons/{namespace}/{name}/cel-eval
@@ -2614,12 +2641,12 @@ func (h *Handler) CelEvalHandler(w http.ResponseWriter, r *http.Request) {
 	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(
 		r.Context(), name, metav1.GetOptions{})
 	if err != nil {
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return
 	}
 	// #411: require ownership — callers may only eval expressions against their own dungeon.
 	if ownerErr := requireDungeonOwner(r, dungeon); ownerErr != nil {
-		writeError(w, ownerErr.Error(), http.StatusForbidden)
+		writeOwnerError(w, ownerErr)
 		return
 	}
 
@@ -2662,11 +2689,11 @@ func (h *Handler) GetDungeonResource(w http.ResponseWriter, r *http.Request) {
 	// Read the parent dungeon first (needed for ownership check).
 	dungeonObj, dungeonErr := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
 	if dungeonErr != nil {
-		writeError(w, sanitizeK8sError(dungeonErr), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(dungeonErr), http.StatusNotFound, CodeDungeonNotFound)
 		return
 	}
 	if ownerErr := requireDungeonOwner(r, dungeonObj); ownerErr != nil {
-		writeError(w, ownerErr.Error(), http.StatusForbidden)
+		writeOwnerError(w, ownerErr)
 		return
 	}
 
@@ -2765,7 +2792,7 @@ func (h *Handler) GetDungeonResource(w http.ResponseWriter, r *http.Request) {
 
 // RunCard generates a shareable SVG run card for a completed dungeon.
 // This endpoint is intentionally unauthenticated — the card contains only
-// public-facing display info (hero class, difficulty, turns, dungeon name).
+// public-facing display info (hero class, difficulty, turns, gold, dungeon name).
 // Query params:
 //   - concepts=N  — kro concepts unlocked during the run (optional, from frontend localStorage)
 func (h *Handler) RunCard(w http.ResponseWriter, r *http.Request) {
@@ -2778,30 +2805,25 @@ func (h *Handler) RunCard(w http.ResponseWriter, r *http.Request) {
 	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(
 		context.Background(), name, metav1.GetOptions{})
 	if err != nil {
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return
 	}
 
-	spec, _ := dungeon.Object["spec"].(map[string]interface{})
-	if spec == nil {
+	if _, ok := dungeon.Object["spec"].(map[string]interface{}); !ok {
 		writeError(w, "dungeon spec not found", http.StatusNotFound)
 		return
 	}
-	game := getGameState(dungeon.Object)
-
-	heroClass, _ := spec["heroClass"].(string)
-	if heroClass == "" {
-		heroClass = "warrior"
-	}
-	difficulty, _ := spec["difficulty"].(string)
-	if difficulty == "" {
-		difficulty = "normal"
-	}
-	attackSeq := getInt(spec, "attackSeq")
-	currentRoom := getInt(game, "currentRoom")
-	if currentRoom < 1 {
-		currentRoom = 1
+	d, err := model.FromUnstructured(dungeon)
+	if err != nil {
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return
 	}
+	game := d.Status.Game
+
+	heroClass := cmp.Or(d.Spec.HeroClass, "warrior")
+	difficulty := cmp.Or(d.Spec.Difficulty, "normal")
+	attackSeq := d.Spec.AttackSeq
+	currentRoom := max(game.CurrentRoom, 1)
 
 	// Optional kro concepts unlocked (passed from frontend)
 	conceptsStr := r.URL.Query().Get("concepts")
@@ -2820,14 +2842,7 @@ func (h *Handler) RunCard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hero class icon (Unicode block art)
-	heroIcon := map[string]string{
-		"warrior": "⚔",
-		"mage":    "✦",
-		"rogue":   "†",
-	}[heroClass]
-	if heroIcon == "" {
-		heroIcon = "⚔"
-	}
+	heroIcon := heroClassOrDefault(heroClass).Icon
 
 	// Difficulty colour
 	diffColour := map[string]string{
@@ -2840,8 +2855,8 @@ func (h *Handler) RunCard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Room label
-	roomLabel := "Room 1"
-	if currentRoom >= 2 {
+	roomLabel := fmt.Sprintf("Room %d of %d", currentRoom, d.RoomCount())
+	if d.InFinalRoom() {
 		roomLabel = "All Rooms"
 	}
 
@@ -2898,6 +2913,9 @@ func (h *Handler) RunCard(w http.ResponseWriter, r *http.Request) {
   <rect x="348" y="88" width="92" height="22" fill="#1e2235" rx="4"/>
   <text x="394" y="104" text-anchor="middle" font-size="7" fill="#4ec94e">%s</text>
 
+  <!-- Gold purse -->
+  <text x="394" y="126" text-anchor="middle" font-size="7" fill="#f0c060">%d gold</text>
+
   <!-- kro concepts section -->
   <text x="130" y="148" text-anchor="middle" font-size="7" fill="#9ca3af">kro concepts</text>
   <rect x="20" y="154" width="220" height="8" fill="#1e2235" rx="4"/>
@@ -2929,6 +2947,7 @@ func (h *Handler) RunCard(w http.ResponseWriter, r *http.Request) {
 		diffColour, difficulty,
 		attackSeq,
 		roomLabel,
+		game.Gold,
 		conceptBarWidth,
 		conceptsUnlocked, totalConcepts,
 	)
@@ -2957,43 +2976,34 @@ func (h *Handler) RunNarrative(w http.ResponseWriter, r *http.Request) {
 	dungeon, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(
 		context.Background(), name, metav1.GetOptions{})
 	if err != nil {
-		writeError(w, sanitizeK8sError(err), http.StatusNotFound)
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
 		return
 	}
 
 	if ownerErr := requireDungeonOwner(r, dungeon); ownerErr != nil {
-		writeError(w, ownerErr.Error(), http.StatusForbidden)
+		writeOwnerError(w, ownerErr)
 		return
 	}
 
-	spec, _ := dungeon.Object["spec"].(map[string]interface{})
-	if spec == nil {
+	if _, ok := dungeon.Object["spec"].(map[string]interface{}); !ok {
 		writeError(w, "dungeon spec not found", http.StatusNotFound)
 		return
 	}
-	game := getGameState(dungeon.Object)
-
-	heroClass, _ := spec["heroClass"].(string)
-	if heroClass == "" {
-		heroClass = "warrior"
-	}
-	difficulty, _ := spec["difficulty"].(string)
-	if difficulty == "" {
-		difficulty = "normal"
-	}
-	attackSeq := getInt(spec, "attackSeq")
-	currentRoom := getInt(game, "currentRoom")
-	if currentRoom < 1 {
-		currentRoom = 1
-	}
-	bossHP := getInt(game, "bossHP")
-	room2BossHP := getInt(game, "room2BossHP")
-	modifier, _ := game["modifier"].(string)
-	monstersRaw, _ := spec["monsters"].(int64)
-	monsters := int(monstersRaw)
-	if monsters == 0 {
-		monsters = 1
+	d, err := model.FromUnstructured(dungeon)
+	if err != nil {
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return
 	}
+	game := d.Status.Game
+
+	heroClass := cmp.Or(d.Spec.HeroClass, "warrior")
+	difficulty := cmp.Or(d.Spec.Difficulty, "normal")
+	attackSeq := d.Spec.AttackSeq
+	currentRoom := max(game.CurrentRoom, 1)
+	bossHP := game.BossHP
+	roomBossHP := game.RoomBossHP
+	modifier := game.Modifier
+	monsters := max(int(d.Spec.Monsters), 1)
 
 	// Parse unlocked concept IDs from query param
 	conceptsParam := r.URL.Query().Get("concepts")
@@ -3066,15 +3076,11 @@ func (h *Handler) RunNarrative(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// Event 2: Hero class stats via CEL (hero-graph)
-	classHP := map[string]int{"warrior": 200, "mage": 120, "rogue": 150}[heroClass]
-	if classHP == 0 {
-		classHP = 150
-	}
-	classMana := map[string]int{"warrior": 0, "mage": 8, "rogue": 4}[heroClass]
+	classHP, classMana := classDefaultHP(heroClass), classDefaultMana(heroClass)
 	events = append(events, kroEvent{
 		turn: 1,
 		desc: fmt.Sprintf("The `hero-graph` RGD computed the Hero's stats via CEL: max HP = %d for a %s, max mana = %d. The Hero ConfigMap was created with these values, written back by kro's CEL writeback feature.", classHP, heroClass, classMana),
-		cel:  fmt.Sprintf(`schema.spec.heroClass == "%s" ? %d : (schema.spec.heroClass == "warrior" ? 200 : 150)  // maxHP`, heroClass, classHP),
+		cel:  catalog.ClassChoiceCEL("schema.spec.heroClass", `"150"`, func(c catalog.Class) int64 { return c.MaxHP }, true) + "  // maxHP",
 		rgd:  "hero-graph",
 	})
 
@@ -3109,36 +3115,25 @@ func (h *Handler) RunNarrative(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	// Event 5: Room 2 transition (if player made it)
-	if currentRoom >= 2 && room2BossHP > 0 {
+	// Event 5: Room transition (if player made it past room 1)
+	if currentRoom >= 2 && roomBossHP > 0 {
 		events = append(events, kroEvent{
 			turn: attackSeq / 2,
-			desc: fmt.Sprintf("After clearing Room 1, the dungeon transitioned to Room 2. kro wrote `status.game.monsterHP` with 1.5x scaled values (trolls and ghouls replace goblins) and `status.game.bossHP` with 1.3x values. The entire room state was driven by a single state node in kro's `dungeon-graph` RGD — all 16 child resources reconciled automatically."),
-			cel:  `schema.status.game.currentRoom >= 2 ? int(schema.status.game.room2BossHP * 1.3) : schema.status.game.bossHP`,
+			desc: fmt.Sprintf("After clearing Room %d, the dungeon transitioned to Room %d. kro's `enterRoomResolve` state node wrote `status.game.monsterHP` scaled to the room (trolls and ghouls replace goblins) and a %d HP boss. The entire room state was driven by a single state node in kro's `dungeon-graph` RGD — all 16 child resources reconciled automatically.", currentRoom-1, currentRoom, roomBossHP),
+			cel:  `cel.bind(room, schema.status.game.currentRoom, r1b * (7 + 3 * room) / 10)`,
 			rgd:  "dungeon-graph",
 		})
 	}
 
 	// Event 6: Loot drop via loot-graph (if inventory non-empty)
-	inventory, _ := game["inventory"].(string)
-	if inventory != "" {
-		var invItems []string
-		json.Unmarshal([]byte(inventory), &invItems)
-		firstItem := ""
-		for _, it := range invItems {
-			if it != "" {
-				firstItem = it
-				break
-			}
-		}
-		if firstItem != "" {
-			events = append(events, kroEvent{
-				turn: attackSeq / 4,
-				desc: fmt.Sprintf("A monster kill triggered a loot drop. The `loot-graph` RGD computed the item type (`%s`), rarity, and description entirely in CEL — the result was written to a Kubernetes Secret managed by kro, then surfaced to the frontend via `status.game`.", firstItem),
-				cel:  `schema.spec.difficulty == "hard" ? "epic" : (random.seededInt(0, 3, schema.metadata.uid) == 0 ? "rare" : "common")`,
-				rgd:  "loot-graph",
-			})
-		}
+	inventory, _ := json.Marshal(game.Inventory) // JSON is valid flow-style YAML
+	if backpack := game.Inventory; len(backpack) > 0 {
+		events = append(events, kroEvent{
+			turn: attackSeq / 4,
+			desc: fmt.Sprintf("A monster kill triggered a loot drop. The `loot-graph` RGD computed the item type (`%s`), rarity, and description entirely in CEL — the result was written to a Kubernetes Secret managed by kro, then surfaced to the frontend via `status.game`.", backpack[0].ID),
+			cel:  `schema.spec.difficulty == "hard" ? "epic" : (random.seededInt(0, 3, schema.metadata.uid) == 0 ? "rare" : "common")`,
+			rgd:  "loot-graph",
+		})
 	}
 
 	// Build YAML snippet from key spec fields
@@ -3156,11 +3151,11 @@ spec:
   attackSeq: %d
   currentRoom: %d
   modifier: "%s"
-  inventory: "%s"`,
+  inventory: %s`,
 		name, ns,
 		heroClass, difficulty,
 		monsters,
-		getInt(game, "heroHP"),
+		game.HeroHP,
 		bossHP,
 		attackSeq, currentRoom,
 		modifier, inventory,
@@ -3178,10 +3173,10 @@ spec:
 	}
 	sb.WriteString(fmt.Sprintf("> **%s** | **%s** difficulty | **%d turns** | dungeon: `%s`\n\n", capitalize(heroClass), capitalize(difficulty), attackSeq, name))
 
-	if currentRoom >= 2 && bossHP <= 0 {
-		sb.WriteString("**Victory!** Both rooms cleared.\n\n")
+	if d.InFinalRoom() && bossHP <= 0 {
+		sb.WriteString(fmt.Sprintf("**Victory!** All %d rooms cleared.\n\n", d.RoomCount()))
 	} else if currentRoom >= 2 {
-		sb.WriteString("**Room 2 reached.** Final boss still standing.\n\n")
+		sb.WriteString(fmt.Sprintf("**Room %d of %d reached.** Boss still standing.\n\n", currentRoom, d.RoomCount()))
 	} else {
 		sb.WriteString("**Room 1 cleared.**\n\n")
 	}
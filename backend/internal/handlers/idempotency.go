@@ -0,0 +1,317 @@
+package handlers
+
+// Idempotency keys for turn submissions.
+//
+// A client that loses the response to POST .../attacks cannot tell whether its
+// turn ran, and a blind retry would bump attackSeq/actionSeq a second time —
+// another counter-attack, another potion gone. Sending the same
+// Idempotency-Key header on the retry replays the first response instead.
+//
+// Records live in a krombat-idempotency-<dungeon> ConfigMap next to each
+// Dungeon so every replica sees them, one data key per (login, key). Each
+// dungeon has its own ConfigMap so players never contend with each other's
+// turns; it is owned by the Dungeon and garbage-collected with it. A request
+// first claims its key with a pending record — an Update guarded by
+// resourceVersion, so exactly one of two racing requests wins — and replaces
+// it with the final response once the turn completes. Only 2xx responses are
+// kept; an error releases the claim so the key can be retried. Records expire
+// after idempotencyTTL and each ConfigMap holds at most idempotencyMaxEntries
+// completed ones.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+const (
+	idempotencyCMPrefix     = "krombat-idempotency-"
+	idempotencyHeader       = "Idempotency-Key"
+	idempotencyMaxKeyLen    = 255
+	idempotencyTTL          = 10 * time.Minute
+	idempotencyPendingTTL   = time.Minute // a claim whose request died is abandoned after this
+	idempotencyMaxEntries   = 100         // stored bodies are a few KiB; keeps the ConfigMap well under 1MiB
+	idempotencyClaimRetries = 5
+)
+
+// idempotencyRecord is one stored key. Status is 0 while the first request is
+// still running.
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	Body        string `json:"body,omitempty"`
+	ExpiresAt   int64  `json:"expiresAt"` // unix seconds
+}
+
+type idempotencyStore struct {
+	client *k8s.Client
+	now    func() time.Time
+}
+
+func newIdempotencyStore(client *k8s.Client) *idempotencyStore {
+	return &idempotencyStore{client: client, now: time.Now}
+}
+
+// update applies mutate to the data of dungeon ns/name's ConfigMap under
+// optimistic concurrency, retrying on conflicts. Expired records are dropped
+// and the oldest completed ones evicted to make room for key before mutate
+// sees the data.
+func (s *idempotencyStore) update(ctx context.Context, ns, name, key string, mutate func(data map[string]interface{})) error {
+	cmClient := s.client.Dynamic.Resource(leaderboardGVR).Namespace(ns)
+	cmName := idempotencyCMPrefix + name
+	var err error
+	for range idempotencyClaimRetries {
+		cm, getErr := cmClient.Get(ctx, cmName, metav1.GetOptions{})
+		if apierrors.IsNotFound(getErr) {
+			// The Dungeon owns its store, so deleting it deletes the keys.
+			dungeon, dErr := s.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			if dErr != nil {
+				return dErr
+			}
+			data := map[string]interface{}{}
+			mutate(data)
+			cm = &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      cmName,
+					"namespace": ns,
+					"ownerReferences": []interface{}{map[string]interface{}{
+						"apiVersion": dungeon.GetAPIVersion(),
+						"kind":       dungeon.GetKind(),
+						"name":       name,
+						"uid":        string(dungeon.GetUID()),
+					}},
+				},
+				"data": data,
+			}}
+			_, err = cmClient.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			return err
+		}
+		if getErr != nil {
+			return getErr
+		}
+		data, _ := cm.Object["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		s.prune(data, key)
+		mutate(data)
+		cm.Object["data"] = data
+		_, err = cmClient.Update(ctx, cm, metav1.UpdateOptions{})
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// prune drops expired records, then the oldest completed ones until key's
+// record fits under idempotencyMaxEntries. Pending records are never evicted:
+// their request is still running, and dropping the claim would let a retry
+// run the turn a second time. They expire after idempotencyPendingTTL.
+func (s *idempotencyStore) prune(data map[string]interface{}, key string) {
+	now := s.now().Unix()
+	expiry := make(map[string]int64, len(data))
+	var completed []string
+	for k, v := range data {
+		var rec idempotencyRecord
+		raw, _ := v.(string)
+		if json.Unmarshal([]byte(raw), &rec) != nil || rec.ExpiresAt <= now {
+			delete(data, k)
+			continue
+		}
+		if rec.Status != 0 {
+			expiry[k] = rec.ExpiresAt
+			completed = append(completed, k)
+		}
+	}
+	// Completed records all live idempotencyTTL, so the soonest to expire
+	// is the oldest.
+	over := len(data) + 1 - idempotencyMaxEntries
+	if _, ok := data[key]; ok {
+		over-- // key replaces its own record
+	}
+	over = min(over, len(completed))
+	if over > 0 {
+		sort.Slice(completed, func(i, j int) bool { return expiry[completed[i]] < expiry[completed[j]] })
+		for _, k := range completed[:over] {
+			delete(data, k)
+		}
+	}
+}
+
+// claim stores a pending record for key in dungeon ns/name's store unless a
+// live one exists, which it returns instead.
+func (s *idempotencyStore) claim(ctx context.Context, ns, name, key, fingerprint string) (*idempotencyRecord, error) {
+	var existing *idempotencyRecord
+	err := s.update(ctx, ns, name, key, func(data map[string]interface{}) {
+		existing = nil
+		if raw, ok := data[key].(string); ok {
+			var rec idempotencyRecord
+			if json.Unmarshal([]byte(raw), &rec) == nil {
+				existing = &rec
+				return
+			}
+		}
+		data[key] = s.encode(idempotencyRecord{Fingerprint: fingerprint, ExpiresAt: s.now().Add(idempotencyPendingTTL).Unix()})
+	})
+	return existing, err
+}
+
+// complete replaces key's pending record with the final response, or with
+// status == 0 releases it.
+func (s *idempotencyStore) complete(ctx context.Context, ns, name, key, fingerprint string, status int, body []byte) error {
+	return s.update(ctx, ns, name, key, func(data map[string]interface{}) {
+		if status == 0 {
+			delete(data, key)
+			return
+		}
+		data[key] = s.encode(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			Body:        string(body),
+			ExpiresAt:   s.now().Add(idempotencyTTL).Unix(),
+		})
+	})
+}
+
+func (s *idempotencyStore) encode(rec idempotencyRecord) string {
+	b, _ := json.Marshal(rec)
+	return string(b)
+}
+
+// idempotencyDataKey scopes a client key to one player within a dungeon's
+// store. Hashing keeps arbitrary header values within ConfigMap key syntax.
+func idempotencyDataKey(login, key string) string {
+	sum := sha256.Sum256([]byte(login + "\x00" + key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// stripManagedFields drops metadata.managedFields from a stored dungeon
+// response; it is most of the object's size and no client reads it.
+func stripManagedFields(body []byte) []byte {
+	var obj map[string]interface{}
+	if json.Unmarshal(body, &obj) != nil {
+		return body
+	}
+	meta, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return body
+	}
+	delete(meta, "managedFields")
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// recordingWriter tees the response so it can be stored once complete.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recordingWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+// Idempotent honours the Idempotency-Key header on a turn-submitting route:
+// a repeat of a completed request replays its response (marked with
+// Idempotent-Replayed: true) instead of calling next again. Requests without
+// the header or outside the dungeon namespaces, and any request when the
+// store is unreachable, go straight to next.
+func (h *Handler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" || !allowedNamespaces[r.PathValue("namespace")] {
+			next(w, r)
+			return
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			writeCodedError(w, "Idempotency-Key too long (max "+strconv.Itoa(idempotencyMaxKeyLen)+" chars)", http.StatusBadRequest, CodeBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		login := ""
+		if sess := sessionFromCtx(r.Context()); sess != nil {
+			login = sess.Login
+		}
+		ns, name := r.PathValue("namespace"), r.PathValue("name")
+		dataKey := idempotencyDataKey(login, key)
+		// Store writes outlive a client that hangs up mid-turn.
+		ctx := context.WithoutCancel(r.Context())
+
+		existing, err := h.idempotency.claim(ctx, ns, name, dataKey, fingerprint)
+		switch {
+		case err != nil:
+			slog.Warn("idempotency: claim failed, running request unguarded", "component", "api", "dungeon", name, "error", err)
+			next(w, r)
+			return
+		case existing == nil:
+		case existing.Fingerprint != fingerprint:
+			writeCodedError(w, "Idempotency-Key was already used with a different request body", http.StatusUnprocessableEntity, CodeIdempotencyKeyReused)
+			return
+		case existing.Status == 0:
+			writeCodedError(w, "a request with this Idempotency-Key is still in progress", http.StatusConflict, CodeRequestInProgress)
+			return
+		default:
+			idempotentReplays.Inc()
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(existing.Status)
+			io.WriteString(w, existing.Body)
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: w}
+		next(rec, r)
+		status, stored := rec.status, stripManagedFields(rec.body.Bytes())
+		if status < 200 || status > 299 {
+			status, stored = 0, nil // release the claim so the key can be retried
+		}
+		if err := h.idempotency.complete(ctx, ns, name, dataKey, fingerprint, status, stored); err != nil {
+			slog.Warn("idempotency: failed to store response", "component", "api", "dungeon", name, "error", err)
+		}
+	}
+}
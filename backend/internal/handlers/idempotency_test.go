@@ -0,0 +1,148 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+var idempotencyCMGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+// idempotentServer serves a counting turn handler behind h.Idempotent for a
+// seeded Dungeon "lair", with objs preloaded into the fake cluster.
+func idempotentServer(t *testing.T, objs ...runtime.Object) (mux *http.ServeMux, client *dynamicfake.FakeDynamicClient, turns *int, fail *bool) {
+	t.Helper()
+	lair := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1",
+		"kind":       "Dungeon",
+		"metadata":   map[string]interface{}{"name": "lair", "namespace": "default", "uid": "lair-uid"},
+	}}
+	client = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), append(objs, lair)...)
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	turns, fail = new(int), new(bool)
+	mux = http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/attacks", h.Idempotent(func(w http.ResponseWriter, r *http.Request) {
+		if *fail {
+			http.Error(w, "stale request", http.StatusConflict)
+			return
+		}
+		*turns++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"metadata":{"name":"lair","managedFields":[{}]},"spec":{"attackSeq":` + strconv.Itoa(*turns) + `}}`))
+	}))
+	return mux, client, turns, fail
+}
+
+func postTurn(mux http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1/dungeons/default/lair/attacks", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotent(t *testing.T) {
+	mux, client, turns, fail := idempotentServer(t)
+	post := func(key, body string) *httptest.ResponseRecorder { return postTurn(mux, key, body) }
+	body := `{"target":"lair-boss","seq":0}`
+
+	first := post("k1", body)
+	replay := post("k1", body)
+	if *turns != 1 {
+		t.Fatalf("turns = %d after a replay, want 1", *turns)
+	}
+	if replay.Code != http.StatusAccepted || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: code = %d, headers = %v", replay.Code, replay.Header())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" || !strings.Contains(replay.Body.String(), `"attackSeq":1`) || strings.Contains(replay.Body.String(), "managedFields") {
+		t.Errorf("replay body = %s", replay.Body.String())
+	}
+
+	if rec := post("k1", `{"target":"lair-monster-0","seq":0}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused with another body: code = %d, want 422", rec.Code)
+	}
+	if post("k2", body); *turns != 2 {
+		t.Errorf("a new key should run a new turn; turns = %d", *turns)
+	}
+	post("", body)
+	if post("", body); *turns != 4 {
+		t.Errorf("requests without a key should always run; turns = %d", *turns)
+	}
+
+	// Errors are not stored: the same key runs again once the cause is gone.
+	*fail = true
+	if rec := post("k3", body); rec.Code != http.StatusConflict {
+		t.Fatalf("failing turn: code = %d", rec.Code)
+	}
+	*fail = false
+	if rec := post("k3", body); rec.Code != http.StatusAccepted || *turns != 5 {
+		t.Errorf("retry after error: code = %d, turns = %d", rec.Code, *turns)
+	}
+
+	// Each dungeon has its own store, owned by the Dungeon.
+	obj, err := client.Tracker().Get(idempotencyCMGVR, "default", "krombat-idempotency-lair")
+	if err != nil {
+		t.Fatalf("per-dungeon store: %v", err)
+	}
+	owners := obj.(*unstructured.Unstructured).GetOwnerReferences()
+	if len(owners) != 1 || owners[0].Kind != "Dungeon" || owners[0].Name != "lair" || owners[0].UID != "lair-uid" {
+		t.Errorf("store ownerReferences = %+v, want the lair Dungeon", owners)
+	}
+}
+
+// TestIdempotencyPrune fills a store to its bound and checks that a new claim
+// evicts the oldest completed record and never a pending one.
+func TestIdempotencyPrune(t *testing.T) {
+	data := map[string]interface{}{}
+	record := func(status int, expiresIn time.Duration) string {
+		b, _ := json.Marshal(map[string]interface{}{"fingerprint": "f", "status": status, "expiresAt": time.Now().Add(expiresIn).Unix()})
+		return string(b)
+	}
+	for i := range handlers.IdempotencyMaxEntries - 2 {
+		data[fmt.Sprintf("pending-%d", i)] = record(0, time.Second) // soonest to expire
+	}
+	data["completed-old"] = record(http.StatusAccepted, 5*time.Minute)
+	data["completed-new"] = record(http.StatusAccepted, 9*time.Minute)
+	store := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": map[string]interface{}{"name": "krombat-idempotency-lair", "namespace": "default"},
+		"data":     data,
+	}}
+	mux, client, turns, _ := idempotentServer(t, store)
+
+	if rec := postTurn(mux, "fresh", `{"target":"lair-boss","seq":0}`); rec.Code != http.StatusAccepted || *turns != 1 {
+		t.Fatalf("claim in a full store: code = %d, turns = %d", rec.Code, *turns)
+	}
+	obj, err := client.Tracker().Get(idempotencyCMGVR, "default", "krombat-idempotency-lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _, _ := unstructured.NestedStringMap(obj.(*unstructured.Unstructured).Object, "data")
+	pending := 0
+	for k := range kept {
+		if strings.HasPrefix(k, "pending-") {
+			pending++
+		}
+	}
+	_, oldKept := kept["completed-old"]
+	_, newKept := kept["completed-new"]
+	if pending != handlers.IdempotencyMaxEntries-2 || oldKept || !newKept || len(kept) != handlers.IdempotencyMaxEntries {
+		t.Errorf("after prune: %d pending, completed-old kept %v, completed-new kept %v, %d records", pending, oldKept, newKept, len(kept))
+	}
+}
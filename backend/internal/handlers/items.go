@@ -0,0 +1,110 @@
+package handlers
+
+// Item catalog, recipes and hero class registry — see package catalog for the
+// format.
+//
+// The catalog built into the binary can be replaced without a rebuild by
+// storing a file of the same shape under the items.yaml key of the
+// krombat-item-catalog ConfigMap in rpg-system. kro applies item effects and
+// prices from the RGD, so the ConfigMap may only change descriptions and class
+// restrictions (see catalog.Compatible). It is re-read at most every
+// itemCatalogCacheTTL; if it is missing, fails to parse or changes anything
+// else, the built-in catalog is used. Recipes and classes are built in only:
+// both are also enumerated in the RGDs, so changing one needs a redeploy
+// anyway.
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+const (
+	itemCatalogCMName   = "krombat-item-catalog"
+	itemCatalogCMKey    = "items.yaml"
+	itemCatalogCacheTTL = 30 * time.Second
+)
+
+type itemCatalogStore struct {
+	client *k8s.Client
+
+	mu        sync.Mutex
+	cached    *catalog.Catalog
+	fetchedAt time.Time
+}
+
+func newItemCatalogStore(client *k8s.Client) *itemCatalogStore {
+	return &itemCatalogStore{client: client}
+}
+
+func (s *itemCatalogStore) get(ctx context.Context) *catalog.Catalog {
+	s.mu.Lock()
+	cached := s.cached
+	if cached != nil && time.Since(s.fetchedAt) < itemCatalogCacheTTL {
+		s.mu.Unlock()
+		return cached
+	}
+	// Other callers keep the cached copy while this one re-reads.
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	c := s.fetch(ctx)
+	s.mu.Lock()
+	s.cached = c
+	s.mu.Unlock()
+	return c
+}
+
+// fetch reads the ConfigMap catalog, falling back to the built-in one.
+func (s *itemCatalogStore) fetch(ctx context.Context) *catalog.Catalog {
+	cm, err := s.client.Dynamic.Resource(leaderboardGVR).Namespace(leaderboardNamespace).Get(ctx, itemCatalogCMName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			slog.Warn("item catalog: ConfigMap read failed, using built-in catalog", "component", "api", "error", err)
+		}
+		return catalog.Default()
+	}
+	data, _ := cm.Object["data"].(map[string]interface{})
+	raw, _ := data[itemCatalogCMKey].(string)
+	if raw == "" {
+		return catalog.Default()
+	}
+	c, err := catalog.Parse([]byte(raw))
+	if err == nil {
+		err = c.Compatible(catalog.Default())
+	}
+	if err != nil {
+		slog.Error("item catalog: invalid ConfigMap, using built-in catalog", "component", "api", "configmap", itemCatalogCMName, "error", err)
+		return catalog.Default()
+	}
+	return c
+}
+
+// ListItems returns the item catalog.
+// GET /api/v1/items
+func (h *Handler) ListItems(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	writeJSON(w, h.items.get(r.Context()))
+}
+
+// ListRecipes returns the crafting recipes.
+// GET /api/v1/recipes
+func (h *Handler) ListRecipes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, map[string]interface{}{"recipes": catalog.Recipes()})
+}
+
+// ListClasses returns the hero class registry.
+// GET /api/v1/classes
+func (h *Handler) ListClasses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, map[string]interface{}{"classes": catalog.Classes()})
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+// catalogConfigMap is the krombat-item-catalog ConfigMap holding the built-in
+// catalog as changed by edit.
+func catalogConfigMap(t *testing.T, edit func(items []catalog.Item) []catalog.Item) *unstructured.Unstructured {
+	t.Helper()
+	items := edit(append([]catalog.Item(nil), catalog.Default().Items...))
+	raw, err := json.Marshal(map[string]interface{}{"items": items})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": map[string]interface{}{"name": "krombat-item-catalog", "namespace": "rpg-system"},
+		"data":     map[string]interface{}{"items.yaml": string(raw)},
+	}}
+}
+
+func TestItemCatalog(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	override := catalogConfigMap(t, func(items []catalog.Item) []catalog.Item {
+		for i := range items {
+			switch items[i].ID {
+			case "hppotion-rare":
+				items[i].UsableBy, items[i].Description = []string{"rogue"}, "Rogues only"
+			case "ring-common":
+				items[i].Description = "A plain band"
+			}
+		}
+		return items
+	})
+	dungeon := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon",
+		"metadata": map[string]interface{}{
+			"name": "lair", "namespace": "default",
+			"labels": map[string]interface{}{"krombat.io/owner": "alice"},
+		},
+		"spec": map[string]interface{}{"monsters": int64(1), "difficulty": "normal", "heroClass": "warrior", "attackSeq": int64(0), "actionSeq": int64(0)},
+		"status": map[string]interface{}{
+			"maxHeroHP": "200",
+			"game": map[string]interface{}{
+				// A dungeon not yet migrated still stores the string form.
+				"heroHP": int64(150), "inventory": `["hppotion-rare","ring-common"]`, "initProcessedSeq": int64(1),
+			},
+		},
+	}}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), override, dungeon)
+	client.PrependReactor("patch", "actions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{}, nil
+	})
+	h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/items", h.ListItems)
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/attacks", h.CreateAttack)
+	srv := h.AuthMiddleware(mux)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/items", nil))
+	if !strings.Contains(rec.Body.String(), "A plain band") {
+		t.Fatalf("GET /items = %s, want the ConfigMap catalog", rec.Body.String())
+	}
+
+	// Items are validated against the catalog in effect, not a fixed list.
+	tests := []struct {
+		action   string
+		wantCode int
+		wantBody string
+	}{
+		{"use-hppotion-rare", http.StatusBadRequest, "hppotion-rare can only be used by rogue"},
+		{"use-ring-common", http.StatusBadRequest, "unknown item: ring-common"},
+		{"equip-ring-common", http.StatusAccepted, "Equipped ring-common! A plain band"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/dungeons/default/lair/attacks", strings.NewReader(`{"target":"`+tt.action+`","damage":0,"seq":0}`))
+		req.Header.Set("X-Test-User", "alice")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+			t.Errorf("%s: code = %d, body = %q; want %d containing %q", tt.action, rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+		}
+	}
+}
+
+// TestItemCatalogRejectsRuleChanges checks that a ConfigMap catalog changing
+// anything kro applies is ignored in favour of the built-in one.
+func TestItemCatalogRejectsRuleChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(items []catalog.Item) []catalog.Item
+	}{
+		{"value", func(items []catalog.Item) []catalog.Item { items[0].Value += 100; return items }},
+		{"price", func(items []catalog.Item) []catalog.Item { items[0].Price = 1; return items }},
+		{"new item", func(items []catalog.Item) []catalog.Item {
+			return append(items, catalog.Item{ID: "elixir-epic", Kind: catalog.Consumable, Stat: "heroHP", Full: true})
+		}},
+		{"missing item", func(items []catalog.Item) []catalog.Item { return items[1:] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), catalogConfigMap(t, tt.edit))
+			h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+			rec := httptest.NewRecorder()
+			h.ListItems(rec, httptest.NewRequest("GET", "/api/v1/items", nil))
+			var got catalog.Catalog
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Items, catalog.Default().Items) {
+				t.Errorf("GET /items served the changed catalog, want the built-in one")
+			}
+		})
+	}
+}
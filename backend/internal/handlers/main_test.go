@@ -0,0 +1,38 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/ws"
+)
+
+// testPublicURL is KROMBAT_PUBLIC_URL for the tests; certificates link to it.
+const testPublicURL = "https://krombat.example"
+
+// TestMain supplies the session and certificate settings main.go requires of
+// a deployment before any test builds a Handler.
+func TestMain(m *testing.M) {
+	if os.Getenv("SESSION_SECRET") == "" {
+		os.Setenv("SESSION_SECRET", "test-session-secret")
+	}
+	os.Setenv("CERTIFICATE_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	os.Setenv("KROMBAT_PUBLIC_URL", testPublicURL+"/")
+	os.Exit(m.Run())
+}
+
+// newHandler builds a Handler that is closed with the test, failing the test
+// on a configuration error.
+func newHandler(t testing.TB, client *k8s.Client, hub *ws.Hub) *handlers.Handler {
+	t.Helper()
+	h, err := handlers.New(client, hub)
+	if err != nil {
+		t.Fatalf("handlers.New: %v", err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+var lairGVR = schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+
+// seedGame sets the given status.game fields on the lair and lets Action CR
+// upserts succeed.
+func seedGame(t *testing.T, f *fakeDungeonAPI, game map[string]interface{}) {
+	t.Helper()
+	f.client.PrependReactor("patch", "actions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{}, nil
+	})
+	obj, err := f.client.Tracker().Get(lairGVR, "default", "lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := obj.(*unstructured.Unstructured)
+	for k, v := range game {
+		unstructured.SetNestedField(d.Object, v, "status", "game", k)
+	}
+	if err := f.client.Tracker().Update(lairGVR, d, "default"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wantTrigger checks that a successful action wrote its trigger fields:
+// lastAction names the action, actionSeq advanced past the seeded 0 and
+// lastHeroAction carries the log line.
+func wantTrigger(t *testing.T, f *fakeDungeonAPI, action, heroLog string) {
+	t.Helper()
+	obj, err := f.client.Tracker().Get(lairGVR, "default", "lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, _, _ := unstructured.NestedMap(obj.(*unstructured.Unstructured).Object, "spec")
+	if spec["lastAction"] != action || spec["actionSeq"] != int64(1) {
+		t.Errorf("spec lastAction = %v, actionSeq = %v; want %q, 1", spec["lastAction"], spec["actionSeq"], action)
+	}
+	if spec["lastHeroAction"] != heroLog {
+		t.Errorf("spec lastHeroAction = %v, want %q", spec["lastHeroAction"], heroLog)
+	}
+}
+
+func TestMerchantActions(t *testing.T) {
+	shop := map[string]interface{}{
+		"monsterHP": []interface{}{int64(0), int64(0)}, "bossHP": int64(0),
+		"gold": int64(60), "merchantRoom": int64(1),
+		"merchantStock": `["weapon-rare","manapotion-common","armor-epic"]`,
+		"inventory":     model.ParseInventory(`["hppotion-common","hppotion-common"]`).Unstructured(),
+	}
+	tests := []struct {
+		name     string
+		game     map[string]interface{}
+		action   string
+		wantCode int
+		wantMsg  string
+		wantLog  string // lastHeroAction written on success
+	}{
+		{"buy", nil, "buy-weapon-rare", http.StatusAccepted, "", "Bought weapon-rare for 50 gold! +10 damage for 3 attacks"},
+		{"sell", nil, "sell-hppotion-common", http.StatusAccepted, "", "Sold hppotion-common for 10 gold!"},
+		{"merchant closed", map[string]interface{}{"merchantRoom": int64(0)}, "buy-weapon-rare", http.StatusBadRequest, "no merchant", ""},
+		{"not in stock", nil, "buy-ring-epic", http.StatusBadRequest, "does not sell", ""},
+		{"wrong class", nil, "buy-manapotion-common", http.StatusBadRequest, "only be used by", ""},
+		{"too poor", nil, "buy-armor-epic", http.StatusBadRequest, "costs 120 gold, you have 60", ""},
+		{"backpack full", map[string]interface{}{"inventory": model.ParseInventory(`["a","b","c","d","e","f","g","h"]`).Unstructured()}, "buy-weapon-rare", http.StatusBadRequest, "inventory full", ""},
+		{"sell what you lack", nil, "sell-ring-common", http.StatusBadRequest, "not in inventory", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := maps.Clone(shop)
+			maps.Copy(game, tt.game)
+			f := newFakeDungeonAPI(t, 0)
+			seedGame(t, f, game)
+
+			rec := postAttack(attackServer(t, f), tt.action, 0)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Errorf("code = %d (%s), want %d mentioning %q", rec.Code, strings.TrimSpace(rec.Body.String()), tt.wantCode, tt.wantMsg)
+			}
+			if tt.wantCode == http.StatusAccepted {
+				wantTrigger(t, f, tt.action, tt.wantLog)
+			}
+		})
+	}
+}
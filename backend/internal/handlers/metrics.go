@@ -10,14 +10,28 @@ var (
 		Name: "k8s_rpg_dungeons_created_total",
 		Help: "Total dungeons created",
 	})
+	// attacksSubmitted is labelled by game dimensions, never by dungeon name —
+	// names are user-chosen and would make the series count unbounded.
 	attacksSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "k8s_rpg_attacks_submitted_total",
 		Help: "Total attacks submitted",
-	}, []string{"dungeon"})
+	}, []string{"hero_class", "difficulty"})
 	attacksRateLimited = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "k8s_rpg_attacks_rate_limited_total",
 		Help: "Total attacks rejected by rate limiter",
 	})
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_rpg_rate_limited_total",
+		Help: "Total requests rejected by a rate-limit policy",
+	}, []string{"policy"})
+	staleTurnsRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_rpg_stale_turns_rejected_total",
+		Help: "Total turns rejected because another turn landed between the seq check and the trigger write",
+	})
+	idempotentReplays = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "k8s_rpg_idempotent_replays_total",
+		Help: "Total turn submissions answered from a stored Idempotency-Key response",
+	})
 
 	// httpRequests is now incremented by AccessLog middleware for every request
 	// (success and error alike). Labels are sanitized to prevent cardinality explosion.
@@ -26,7 +40,8 @@ var (
 		Help: "Total HTTP requests",
 	}, []string{"method", "path", "status"})
 
-	// httpDuration tracks per-route latency in milliseconds.
+	// httpDuration tracks per-route latency in milliseconds. AccessLog attaches
+	// the request ID as an exemplar so a slow bucket links straight to its logs.
 	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "k8s_rpg_http_duration_ms",
 		Help:    "HTTP request latency in milliseconds",
@@ -53,12 +68,40 @@ var (
 		Help: "Status effects inflicted on hero",
 	}, []string{"effect"}) // effect = "poison" | "burn" | "stun"
 
-	activeDungeons = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_active_dungeons", Help: "Active dungeon count"})
-	monstersAlive  = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_monsters_alive", Help: "Alive monsters"})
-	monstersDead   = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_monsters_dead", Help: "Dead monsters"})
-	bossesPending  = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_bosses_pending", Help: "Bosses pending"})
-	bossesReady    = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_bosses_ready", Help: "Bosses ready"})
-	bossesDefeated = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_bosses_defeated", Help: "Bosses defeated"})
-	gameVictories  = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_victories", Help: "Victories"})
-	gameDefeats    = promauto.NewGauge(prometheus.GaugeOpts{Name: "k8s_rpg_defeats", Help: "Defeats"})
+	// turnsToVictory is observed once per full (final room) victory.
+	turnsToVictory = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_rpg_turns_to_victory",
+		Help:    "Turns (attackSeq + actionSeq) taken to clear every room",
+		Buckets: []float64{10, 20, 30, 40, 50, 60, 80, 100, 150, 200},
+	}, []string{"hero_class", "difficulty"})
+
+	// damagePerHit is the HP kro's combatResolve removed from the target.
+	damagePerHit = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_rpg_damage_per_hit",
+		Help:    "Damage dealt per hero attack",
+		Buckets: []float64{0, 5, 10, 15, 20, 30, 45, 60, 90, 120},
+	}, []string{"hero_class", "difficulty", "target"}) // target = "monster" | "boss"
+
+	// kroStateNodeLatency is the time from the backend writing a trigger to kro
+	// publishing the state node's result in status.game.
+	// node = "dungeonInit" | "combatResolve"
+	kroStateNodeLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_rpg_kro_state_node_seconds",
+		Help:    "Latency from trigger write to kro state-node result",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 30},
+	}, []string{"node"})
+)
+
+// Scrape-time game gauges, computed by gameCollector from the Dungeon informer.
+var (
+	activeDungeonsDesc = prometheus.NewDesc("k8s_rpg_active_dungeons", "Active dungeon count", nil, nil)
+	monstersAliveDesc  = prometheus.NewDesc("k8s_rpg_monsters_alive", "Alive monsters", nil, nil)
+	monstersDeadDesc   = prometheus.NewDesc("k8s_rpg_monsters_dead", "Dead monsters", nil, nil)
+	bossesPendingDesc  = prometheus.NewDesc("k8s_rpg_bosses_pending", "Bosses pending", nil, nil)
+	bossesReadyDesc    = prometheus.NewDesc("k8s_rpg_bosses_ready", "Bosses ready", nil, nil)
+	bossesDefeatedDesc = prometheus.NewDesc("k8s_rpg_bosses_defeated", "Bosses defeated", nil, nil)
+	gameVictoriesDesc  = prometheus.NewDesc("k8s_rpg_victories", "Victories", nil, nil)
+	gameDefeatsDesc    = prometheus.NewDesc("k8s_rpg_defeats", "Defeats", nil, nil)
+	dungeonsDesc       = prometheus.NewDesc("k8s_rpg_dungeons", "Dungeons by hero class, difficulty and outcome",
+		[]string{"hero_class", "difficulty", "outcome"}, nil) // outcome = in-progress | victory | defeat
 )
@@ -9,8 +9,10 @@ import (
 	"regexp"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type ctxKey string
@@ -66,8 +68,10 @@ func AccessLog(next http.Handler) http.Handler {
 		start := time.Now()
 
 		// Propagate or generate request ID
+		// Client-supplied IDs are capped so they stay valid exemplar labels
+		// (Prometheus rejects exemplars over 128 runes).
 		reqID := r.Header.Get("X-Request-Id")
-		if reqID == "" {
+		if reqID == "" || len(reqID) > 64 || !utf8.ValidString(reqID) {
 			reqID = uuid.NewString()
 		}
 		w.Header().Set("X-Request-Id", reqID)
@@ -93,12 +97,12 @@ func AccessLog(next http.Handler) http.Handler {
 			"status": statusStr,
 		}).Inc()
 
-		// Observe latency histogram
+		// Observe latency histogram, with the request ID as exemplar
 		httpDuration.With(map[string]string{
 			"method": r.Method,
 			"path":   sanitized,
 			"status": statusStr,
-		}).Observe(float64(durationMs))
+		}).(prometheus.ExemplarObserver).ObserveWithExemplar(float64(durationMs), prometheus.Labels{"request_id": reqID})
 
 		slog.Info("http_request",
 			"component", "api",
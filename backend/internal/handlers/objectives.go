@@ -0,0 +1,48 @@
+package handlers
+
+// Run objectives — see model.RollObjectives.
+//
+// CreateDungeon rolls two or three objectives into spec.objectives.
+// processCombat advances them from each turn's pre/post game state,
+// processAction fails no-potions when a consumable is used, and
+// recordProfile adds the XP of the completed ones to the profile. Dungeons
+// created before objectives existed have none.
+
+import (
+	"log/slog"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+// ListObjectives handles GET /api/v1/dungeons/{namespace}/{name}/objectives.
+func (h *Handler) ListObjectives(w http.ResponseWriter, r *http.Request) {
+	ns := r.PathValue("namespace")
+	name := r.PathValue("name")
+	if !validateNamespace(w, ns) {
+		return
+	}
+	obj, err := h.client.Dynamic.Resource(k8s.DungeonGVR).Namespace(ns).Get(r.Context(), name, metav1.GetOptions{})
+	if err != nil {
+		writeCodedError(w, sanitizeK8sError(err), http.StatusNotFound, CodeDungeonNotFound)
+		return
+	}
+	if err := requireDungeonOwner(r, obj); err != nil {
+		writeOwnerError(w, err)
+		return
+	}
+	d, err := model.FromUnstructured(obj)
+	if err != nil {
+		slog.Error("failed to decode dungeon for objectives", "component", "api", "dungeon", name, "namespace", ns, "error", err)
+		writeError(w, "dungeon state is malformed", http.StatusInternalServerError)
+		return
+	}
+	objectives := d.Spec.Objectives
+	writeJSON(w, map[string]interface{}{
+		"objectives": objectives,
+		"xp":         objectives.XP(),
+	})
+}
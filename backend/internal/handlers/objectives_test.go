@@ -0,0 +1,269 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+func TestObjectives(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	f := newFakeDungeonAPI(t, 0)
+	f.client.PrependReactor("patch", "actions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{}, nil
+	})
+	gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+	obj, err := f.client.Tracker().Get(gvr, "default", "lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := obj.(*unstructured.Unstructured)
+	objectives := model.Objectives{
+		{ID: "kill-goblin", Kind: model.ObjectiveKill, Monster: "goblin", Target: 1, Progress: 1, State: model.ObjectiveDone, XP: 15},
+		{ID: "no-potions", Kind: model.ObjectiveNoPotions, State: model.ObjectiveActive, XP: 75},
+	}
+	unstructured.SetNestedSlice(d.Object, objectives.Unstructured(), "spec", "objectives")
+	unstructured.SetNestedField(d.Object, model.ParseInventory(`["hppotion-common"]`).Unstructured(), "status", "game", "inventory")
+	if err := f.client.Tracker().Update(gvr, d, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newHandler(t, &k8s.Client{Dynamic: f.client}, nil)
+	list := func() (got struct {
+		Objectives []model.Objective
+		XP         int64
+	}) {
+		req := httptest.NewRequest("GET", "/api/v1/dungeons/default/lair/objectives", nil)
+		req.SetPathValue("namespace", "default")
+		req.SetPathValue("name", "lair")
+		req.Header.Set("X-Test-User", "alice")
+		rec := httptest.NewRecorder()
+		h.AuthMiddleware(http.HandlerFunc(h.ListObjectives)).ServeHTTP(rec, req)
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("GET objectives = %d %s", rec.Code, rec.Body.String())
+		}
+		return got
+	}
+
+	got := list()
+	if len(got.Objectives) != 2 || got.Objectives[1].State != model.ObjectiveActive || got.XP != 15 {
+		t.Fatalf("before the potion: %+v", got)
+	}
+	if rec := postAttack(attackServer(t, f), "use-hppotion-common", 0); rec.Code != http.StatusAccepted {
+		t.Fatalf("use-hppotion-common = %d %s", rec.Code, rec.Body.String())
+	}
+	obj, _ = f.client.Tracker().Get(gvr, "default", "lair")
+	if used, _, _ := unstructured.NestedInt64(obj.(*unstructured.Unstructured).Object, "spec", "consumablesUsed"); used != 1 {
+		t.Errorf("spec consumablesUsed = %d, want 1", used)
+	}
+	if got := list(); got.Objectives[1].State != model.ObjectiveFailed || got.XP != 15 {
+		t.Errorf("after the potion: %+v", got)
+	}
+}
+
+// TestObjectivesSurviveRacingWrite has an action fail no-potions between
+// kro resolving a kill and the attack writing its log: the attack's
+// objectives must be rebuilt on top of that write, not overwrite it.
+func TestObjectivesSurviveRacingWrite(t *testing.T) {
+	f := newFakeDungeonAPI(t, 0)
+	objectives := model.Objectives{
+		{ID: "kill-goblin", Kind: model.ObjectiveKill, Monster: "goblin", Target: 2, State: model.ObjectiveActive, XP: 30},
+		{ID: "no-potions", Kind: model.ObjectiveNoPotions, State: model.ObjectiveActive, XP: 75},
+	}
+	gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+	obj, err := f.client.Tracker().Get(gvr, "default", "lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := obj.(*unstructured.Unstructured)
+	unstructured.SetNestedSlice(d.Object, objectives.Unstructured(), "spec", "objectives")
+	unstructured.SetNestedSlice(d.Object, []interface{}{"goblin", "skeleton"}, "status", "game", "monsterTypes")
+	if err := f.client.Tracker().Update(gvr, d, "default"); err != nil {
+		t.Fatal(err)
+	}
+	racing := model.Objectives{objectives[0], objectives[1]}
+	racing.UsedConsumable()
+
+	stage := 0
+	f.interleave = func(patch, dungeon map[string]interface{}) bool {
+		spec, _ := patch["spec"].(map[string]interface{})
+		switch {
+		case stage == 0 && spec["attackSeq"] != nil: // kro kills the goblin
+			unstructured.SetNestedSlice(dungeon, []interface{}{int64(0), int64(30)}, "status", "game", "monsterHP")
+		case stage == 1 && spec["lastHeroAction"] != nil: // a potion lands first
+			unstructured.SetNestedSlice(dungeon, racing.Unstructured(), "spec", "objectives")
+		default:
+			return false
+		}
+		stage++
+		return true
+	}
+	if rec := postAttack(attackServer(t, f), "lair-monster-0", 0); rec.Code != http.StatusAccepted {
+		t.Fatalf("attack = %d %s", rec.Code, rec.Body.String())
+	}
+	if stage != 2 {
+		t.Fatalf("the log write was never raced (stage %d)", stage)
+	}
+
+	obj, _ = f.client.Tracker().Get(gvr, "default", "lair")
+	spec, _, _ := unstructured.NestedMap(obj.(*unstructured.Unstructured).Object, "spec")
+	post, err := model.FromUnstructured(obj.(*unstructured.Unstructured))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := post.Spec.Objectives
+	if len(got) != 2 || got[0].Progress != 1 || got[1].State != model.ObjectiveFailed {
+		t.Errorf("objectives = %+v, want the kill counted and no-potions failed", got)
+	}
+	if spec["xpEarned"] != int64(10) {
+		t.Errorf("xpEarned = %v, want 10", spec["xpEarned"])
+	}
+}
+
+// TestUseItemSurvivesRacingWrite lands the previous attack's log — a kill
+// and its XP — between a potion's read and its trigger patch: the retried
+// patch must fail no-potions on top of that write, not resend the totals it
+// first read.
+func TestUseItemSurvivesRacingWrite(t *testing.T) {
+	f := newFakeDungeonAPI(t, 0)
+	f.client.PrependReactor("patch", "actions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{}, nil
+	})
+	objectives := model.Objectives{
+		{ID: "kill-goblin", Kind: model.ObjectiveKill, Monster: "goblin", Target: 2, State: model.ObjectiveActive, XP: 30},
+		{ID: "no-potions", Kind: model.ObjectiveNoPotions, State: model.ObjectiveActive, XP: 75},
+	}
+	gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+	obj, err := f.client.Tracker().Get(gvr, "default", "lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := obj.(*unstructured.Unstructured)
+	unstructured.SetNestedSlice(d.Object, objectives.Unstructured(), "spec", "objectives")
+	unstructured.SetNestedField(d.Object, model.ParseInventory(`["hppotion-common"]`).Unstructured(), "status", "game", "inventory")
+	if err := f.client.Tracker().Update(gvr, d, "default"); err != nil {
+		t.Fatal(err)
+	}
+	killed := model.Objectives{objectives[0], objectives[1]}
+	killed[0].Progress = 1
+
+	raced := false
+	f.interleave = func(patch, dungeon map[string]interface{}) bool {
+		if spec, _ := patch["spec"].(map[string]interface{}); raced || spec["lastAction"] == nil {
+			return false
+		}
+		raced = true
+		unstructured.SetNestedSlice(dungeon, killed.Unstructured(), "spec", "objectives")
+		unstructured.SetNestedField(dungeon, int64(10), "spec", "xpEarned")
+		unstructured.SetNestedField(dungeon, int64(2), "spec", "consumablesUsed")
+		return true
+	}
+	if rec := postAttack(attackServer(t, f), "use-hppotion-common", 0); rec.Code != http.StatusAccepted {
+		t.Fatalf("use-hppotion-common = %d %s", rec.Code, rec.Body.String())
+	}
+	if !raced {
+		t.Fatal("the item's trigger patch was never raced")
+	}
+
+	obj, _ = f.client.Tracker().Get(gvr, "default", "lair")
+	spec, _, _ := unstructured.NestedMap(obj.(*unstructured.Unstructured).Object, "spec")
+	post, err := model.FromUnstructured(obj.(*unstructured.Unstructured))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := post.Spec.Objectives
+	if len(got) != 2 || got[0].Progress != 1 || got[1].State != model.ObjectiveFailed {
+		t.Errorf("objectives = %+v, want the kill kept and no-potions failed", got)
+	}
+	if spec["xpEarned"] != int64(10) || spec["consumablesUsed"] != int64(3) {
+		t.Errorf("xpEarned = %v, consumablesUsed = %v, want 10 and 3", spec["xpEarned"], spec["consumablesUsed"])
+	}
+}
+
+// TestObjectivesPaidOnce records a won run the way deleting it does, with
+// and without the victory having recorded it first: objectives XP is paid
+// the first time only.
+func TestObjectivesPaidOnce(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	objectives := model.Objectives{
+		{ID: "kill-goblin", Kind: model.ObjectiveKill, Monster: "goblin", Target: 1, Progress: 1, State: model.ObjectiveDone, XP: 15},
+	}
+	xp := map[string]int{}
+	for _, tt := range []struct {
+		name    string
+		profile string
+	}{
+		{"first record", `{"dungeonsPlayed":1,"xp":100}`},
+		{"already paid", `{"dungeonsPlayed":1,"xp":100,"objectivesPaid":["lair-uid"]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dungeon := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "game.k8s.example/v1alpha1", "kind": "Dungeon",
+				"metadata": map[string]interface{}{
+					"name": "lair", "namespace": "default", "uid": "lair-uid",
+					"labels": map[string]interface{}{"krombat.io/owner": "alice"},
+				},
+				"spec": map[string]interface{}{
+					"monsters": int64(1), "difficulty": "normal", "heroClass": "warrior", "rooms": int64(2),
+					"attackSeq": int64(10), "objectives": objectives.Unstructured(),
+				},
+				"status": map[string]interface{}{
+					"victory": true, "maxHeroHP": "200",
+					"game": map[string]interface{}{
+						"heroHP": int64(200), "bossHP": int64(0), "monsterHP": []interface{}{int64(0)},
+						"currentRoom": int64(2), "initProcessedSeq": int64(1),
+					},
+				},
+			}}
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), configMap("krombat-profiles", "alice", tt.profile), dungeon)
+			h := newHandler(t, &k8s.Client{Dynamic: client}, nil)
+			req := httptest.NewRequest("DELETE", "/api/v1/dungeons/default/lair", nil)
+			req.SetPathValue("namespace", "default")
+			req.SetPathValue("name", "lair")
+			req.Header.Set("X-Test-User", "alice")
+			rec := httptest.NewRecorder()
+			h.AuthMiddleware(http.HandlerFunc(h.DeleteDungeon)).ServeHTTP(rec, req)
+			if rec.Code >= 300 {
+				t.Fatalf("DELETE = %d %s", rec.Code, rec.Body.String())
+			}
+
+			var p struct {
+				DungeonsPlayed int
+				XP             int
+				ObjectivesPaid []string
+			}
+			cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+			for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+				obj, err := client.Tracker().Get(cmGVR, "rpg-system", "krombat-profiles")
+				if err != nil {
+					t.Fatal(err)
+				}
+				raw, _, _ := unstructured.NestedString(obj.(*unstructured.Unstructured).Object, "data", "alice")
+				if err := json.Unmarshal([]byte(raw), &p); err == nil && p.DungeonsPlayed == 2 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("profile was not updated: %s", raw)
+				}
+			}
+			if len(p.ObjectivesPaid) != 1 || p.ObjectivesPaid[0] != "lair-uid" {
+				t.Errorf("objectivesPaid = %v, want [lair-uid]", p.ObjectivesPaid)
+			}
+			xp[tt.name] = p.XP
+		})
+	}
+	if paid := xp["first record"] - xp["already paid"]; paid != 15 {
+		t.Errorf("objectives XP paid on the second record: XP %v, want the first 15 more", xp)
+	}
+}
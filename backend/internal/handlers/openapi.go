@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+)
+
+// openAPIDoc is the OpenAPI 3 description of every route registered in
+// cmd/main.go. It is the single source of truth for request body shapes:
+// ValidateRequest rejects bodies that do not match it before any handler runs.
+// TestOpenAPICoversRoutes fails when a route is added without a spec entry.
+//
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// maxValidatedBody caps what ValidateRequest will buffer. Handlers apply their
+// own, tighter MaxBytesReader limits afterwards.
+const maxValidatedBody = 64 * 1024
+
+// OpenAPIHandler serves the API description.
+// GET /api/v1/openapi.json
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(openAPIDoc)
+}
+
+// bodySchema validates the JSON request body of one operation.
+type bodySchema struct {
+	required  bool
+	validator *validate.SchemaValidator
+}
+
+// requestBodies routes "METHOD /path/{param}" patterns to their body schema.
+// A ServeMux is used for the lookup so path templates match exactly as they
+// do in cmd/main.go.
+var requestBodies, requestBodyMux = mustLoadRequestBodies(openAPIDoc)
+
+type openAPIOperation struct {
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema map[string]interface{} `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+func mustLoadRequestBodies(doc []byte) (map[string]*bodySchema, *http.ServeMux) {
+	var api struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(doc, &api); err != nil {
+		panic("openapi.json: " + err.Error())
+	}
+	bodies := map[string]*bodySchema{}
+	mux := http.NewServeMux()
+	for path, item := range api.Paths {
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op openAPIOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				panic(fmt.Sprintf("openapi.json: %s %s: %v", method, path, err))
+			}
+			if op.RequestBody == nil {
+				continue
+			}
+			media, ok := op.RequestBody.Content["application/json"]
+			if !ok {
+				continue
+			}
+			resolved, err := json.Marshal(inlineRefs(media.Schema, api.Components.Schemas))
+			if err != nil {
+				panic(fmt.Sprintf("openapi.json: %s %s: %v", method, path, err))
+			}
+			s := new(spec.Schema)
+			if err := s.UnmarshalJSON(resolved); err != nil {
+				panic(fmt.Sprintf("openapi.json: %s %s: %v", method, path, err))
+			}
+			pattern := strings.ToUpper(method) + " " + path
+			bodies[pattern] = &bodySchema{
+				required:  op.RequestBody.Required,
+				validator: validate.NewSchemaValidator(s, nil, "", strfmt.Default),
+			}
+			mux.Handle(pattern, http.NotFoundHandler())
+		}
+	}
+	return bodies, mux
+}
+
+// inlineRefs replaces every local "#/components/schemas/X" reference with a
+// copy of X, so each operation's schema validates on its own.
+func inlineRefs(v interface{}, schemas map[string]interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := t["$ref"].(string); ok {
+			name, found := strings.CutPrefix(ref, "#/components/schemas/")
+			target, known := schemas[name]
+			if !found || !known {
+				panic("openapi.json: unresolvable $ref " + ref)
+			}
+			return inlineRefs(target, schemas)
+		}
+		out := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			out[k] = inlineRefs(e, schemas)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = inlineRefs(e, schemas)
+		}
+		return out
+	}
+	return v
+}
+
+// ValidateRequest checks JSON request bodies against openapi.json. Requests
+// whose route declares no body pass straight through; a body that is not JSON
+// or breaks the schema is rejected with 400 and the validator's messages.
+func ValidateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := requestBodyMux.Handler(r)
+		schema := requestBodies[pattern]
+		if schema == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidatedBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			writeCodedError(w, "invalid request body", http.StatusBadRequest, CodeInvalidBody)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if len(bytes.TrimSpace(body)) == 0 {
+			if schema.required {
+				writeCodedError(w, "request body required", http.StatusBadRequest, CodeInvalidBody)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			writeCodedError(w, "invalid request body: not valid JSON", http.StatusBadRequest, CodeInvalidBody)
+			return
+		}
+		if res := schema.validator.Validate(doc); !res.IsValid() {
+			msgs := make([]string, 0, len(res.Errors))
+			for _, e := range res.Errors {
+				msgs = append(msgs, e.Error())
+			}
+			writeCodedError(w, "invalid request body: "+strings.Join(msgs, "; "), http.StatusBadRequest, CodeInvalidBody)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+)
+
+// registeredRoutes returns every "METHOD /path" pattern passed to
+// mux.HandleFunc in cmd/main.go.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "../../cmd/main.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse main.go: %v", err)
+	}
+	var routes []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "HandleFunc" {
+			return true
+		}
+		if recv, ok := sel.X.(*ast.Ident); !ok || recv.Name != "mux" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		routes = append(routes, pattern)
+		return true
+	})
+	if len(routes) == 0 {
+		t.Fatal("found no mux.HandleFunc routes in main.go")
+	}
+	return routes
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handlers.OpenAPIHandler(rec, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", doc.OpenAPI)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range registeredRoutes(t) {
+		registered[route] = true
+		method, path, _ := strings.Cut(route, " ")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("route %q is registered in main.go but missing from openapi.json", route)
+		}
+	}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if method == "parameters" {
+				continue
+			}
+			if route := strings.ToUpper(method) + " " + path; !registered[route] {
+				t.Errorf("openapi.json describes %q, which main.go does not register", route)
+			}
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantMsg  string
+	}{
+		{"valid create", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":3,"difficulty":"hard","heroClass":"rogue"}`, 200, ""},
+		{"extra fields allowed", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":3,"difficulty":"easy","weaponBonus":5}`, 200, ""},
+		{"monsters too high", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":11,"difficulty":"easy"}`, 400, "monsters"},
+		{"rooms in range", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":3,"difficulty":"easy","rooms":5}`, 200, ""},
+		{"rooms too high", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":3,"difficulty":"easy","rooms":6}`, 400, "rooms"},
+		{"bad difficulty", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":2,"difficulty":"insane"}`, 400, "difficulty"},
+		{"name not a DNS label", "POST", "/api/v1/dungeons", `{"name":"Lair!","monsters":2,"difficulty":"easy"}`, 400, "name"},
+		{"monsters as string", "POST", "/api/v1/dungeons", `{"name":"lair","monsters":"2","difficulty":"easy"}`, 400, "monsters"},
+		{"not JSON", "POST", "/api/v1/dungeons", `{"name":`, 400, "not valid JSON"},
+		{"empty required body", "POST", "/api/v1/dungeons", ``, 400, "required"},
+		{"valid attack", "POST", "/api/v1/dungeons/default/lair/attacks", `{"target":"lair-boss","damage":0,"seq":-1}`, 200, ""},
+		{"attack without target", "POST", "/api/v1/dungeons/default/lair/attacks", `{"damage":10}`, 400, "target"},
+		{"cel expr too long", "POST", "/api/v1/dungeons/default/lair/cel-eval", `{"expr":"` + strings.Repeat("1", 501) + `"}`, 400, "expr"},
+		{"cert", "POST", "/api/v1/profile/cert", `{"cert":"kro-expert","namespace":"default","name":"lair"}`, 200, ""},
+		{"cert without a dungeon", "POST", "/api/v1/profile/cert", `{"cert":"kro-expert"}`, 400, "namespace"},
+		{"route without a body", "GET", "/api/v1/dungeons", ``, 200, ""},
+		{"unknown route", "POST", "/api/v1/nope", `not json`, 200, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				seen = string(b)
+			})
+			rec := httptest.NewRecorder()
+			handlers.ValidateRequest(next).ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d (%s)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode == 200 && seen != tt.body {
+				t.Errorf("handler saw body %q, want %q", seen, tt.body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Errorf("body = %q, want it to mention %q", rec.Body.String(), tt.wantMsg)
+			}
+		})
+	}
+}
@@ -1,46 +1,243 @@
 package handlers
 
 import (
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// rateLimitPolicy is a token bucket: Burst requests may arrive back to back,
+// after which the bucket refills at Rate tokens per second.
+type rateLimitPolicy struct {
+	Name  string
+	Rate  float64 // tokens per second
+	Burst int
+	// PerDungeon adds the {namespace}/{name} path values to the key, so a
+	// player's two dungeons are limited independently.
+	PerDungeon bool
+}
+
+// window is how long an empty bucket takes to refill completely.
+func (p rateLimitPolicy) window() time.Duration {
+	return seconds(float64(p.Burst) / p.Rate)
+}
+
+// rateLimitPolicies are the per-route policies; routes opt in with
+// h.RateLimit(name, handler) in main.go.
+var rateLimitPolicies = map[string]rateLimitPolicy{
+	// Dungeon creation spins up a namespace and ~10 kro child resources.
+	"create": {Name: "create", Rate: 1.0 / 12, Burst: 5},
+	// One attack per ~300ms per dungeon; kro needs the time to reconcile.
+	"attack": {Name: "attack", Rate: 1 / 0.3, Burst: 2, PerDungeon: true},
+	// CEL playground evaluation is CPU-bound on the backend.
+	"cel-eval": {Name: "cel-eval", Rate: 2, Burst: 10},
+	// OAuth login/callback, keyed by client IP because nobody is signed in
+	// yet. A classroom behind one NAT signs in at once, and each sign-in is
+	// a login plus a callback, so the bucket fits a room full of players.
+	"auth": {Name: "auth", Rate: 1, Burst: 60},
+	// API token management, keyed by login.
+	"tokens": {Name: "tokens", Rate: 1.0 / 6, Burst: 10},
+	// Public certificate verify/SVG/PDF reads, keyed by client IP.
+	"certificates": {Name: "certificates", Rate: 2, Burst: 20},
+	// #419: frontend telemetry — guards CloudWatch log volume.
+	"telemetry": {Name: "telemetry", Rate: 0.5, Burst: 5},
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateDecision is the outcome of one take, with what the headers report.
+type rateDecision struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration // until the bucket is full again
+	retryAfter time.Duration // until the next token, when denied
+}
+
 type rateLimiter struct {
-	mu       sync.Mutex
-	last     map[string]time.Time
-	interval time.Duration
+	policy  rateLimitPolicy
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter returns a limiter for policy and starts its background
+// eviction loop, which runs until done is closed.
+func newRateLimiter(policy rateLimitPolicy, done <-chan struct{}) *rateLimiter {
+	rl := &rateLimiter{policy: policy, now: time.Now, buckets: make(map[string]*tokenBucket)}
+	go rl.evictLoop(done)
+	return rl
+}
+
+// take spends one token from key's bucket if one is available.
+func (rl *rateLimiter) take(key string) rateDecision {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	burst := float64(rl.policy.Burst)
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rl.policy.Rate)
+	b.last = now
+
+	d := rateDecision{}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = seconds((1 - b.tokens) / rl.policy.Rate)
+	}
+	d.remaining = int(b.tokens)
+	d.reset = seconds((burst - b.tokens) / rl.policy.Rate)
+	return d
 }
 
-func newRateLimiter(interval time.Duration) *rateLimiter {
-	return &rateLimiter{last: make(map[string]time.Time), interval: interval}
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
 }
 
-func (rl *rateLimiter) Allow(key string) bool {
+// evictLoop drops buckets that have been idle long enough to be full again —
+// they are indistinguishable from a fresh bucket, so forgetting them is free
+// and keeps unique keys (dungeon names, client IPs) from growing the map.
+func (rl *rateLimiter) evictLoop(done <-chan struct{}) {
+	interval := max(rl.policy.window(), time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			rl.evict()
+		}
+	}
+}
+
+func (rl *rateLimiter) evict() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
-	if t, ok := rl.last[key]; ok && time.Since(t) < rl.interval {
-		return false
-	}
-	rl.last[key] = time.Now()
-	// #420: TTL eviction — sweep entries older than 10x the interval to prevent
-	// unbounded map growth from unique dungeon names / remote addresses.
-	evictBefore := time.Now().Add(-rl.interval * 10)
-	for k, t := range rl.last {
-		if t.Before(evictBefore) {
-			delete(rl.last, k)
+	// #420: TTL eviction — anything untouched for a full refill window is full.
+	evictBefore := rl.now().Add(-rl.policy.window())
+	for k, b := range rl.buckets {
+		if b.last.Before(evictBefore) {
+			delete(rl.buckets, k)
 		}
 	}
-	return true
 }
 
-func (rl *rateLimiter) Wrap(next http.HandlerFunc, keyFn func(*http.Request) string) http.HandlerFunc {
+// allowRequest takes a token for r, writes the RateLimit-* headers and, when
+// the bucket is empty, a 429 with Retry-After. It returns false on 429.
+func (rl *rateLimiter) allowRequest(w http.ResponseWriter, r *http.Request) bool {
+	key := rateLimitKey(r)
+	if rl.policy.PerDungeon {
+		key += "|" + r.PathValue("namespace") + "/" + r.PathValue("name")
+	}
+	d := rl.take(key)
+	h := w.Header()
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rl.policy.Burst, ceilSeconds(rl.policy.window())))
+	h.Set("RateLimit-Limit", strconv.Itoa(rl.policy.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+	if d.allowed {
+		return true
+	}
+	h.Set("Retry-After", strconv.Itoa(ceilSeconds(d.retryAfter)))
+	rateLimited.WithLabelValues(rl.policy.Name).Inc()
+	if rl.policy.Name == "attack" {
+		attacksRateLimited.Inc()
+	}
+	writeError(w, "rate limit exceeded, try again shortly", http.StatusTooManyRequests)
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimit wraps next with the named policy from rateLimitPolicies.
+func (h *Handler) RateLimit(policy string, next http.HandlerFunc) http.HandlerFunc {
+	rl, ok := h.limits[policy]
+	if !ok {
+		panic("unknown rate limit policy: " + policy)
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !rl.Allow(keyFn(r)) {
-			attacksRateLimited.Inc()
-			http.Error(w, "rate limit exceeded, try again shortly", http.StatusTooManyRequests)
+		if !rl.allowRequest(w, r) {
 			return
 		}
 		next(w, r)
 	}
 }
+
+// rateLimitKey identifies the caller: the GitHub login when authenticated,
+// otherwise the client IP.
+func rateLimitKey(r *http.Request) string {
+	if sess := sessionFromCtx(r.Context()); sess != nil && sess.Login != "" {
+		return "user:" + sess.Login
+	}
+	return "ip:" + clientIP(r)
+}
+
+// trustedProxies are the networks whose X-Forwarded-For entries we believe.
+// The ALB connects from inside the VPC, so private ranges are trusted by
+// default; KROMBAT_TRUSTED_PROXIES (comma-separated CIDRs) overrides them.
+var trustedProxies = parseTrustedProxies(os.Getenv("KROMBAT_TRUSTED_PROXIES"))
+
+func parseTrustedProxies(v string) []*net.IPNet {
+	if strings.TrimSpace(v) == "" {
+		v = "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,127.0.0.0/8,::1/128,fc00::/7"
+	}
+	var nets []*net.IPNet
+	for _, c := range strings.Split(v, ",") {
+		if _, n, err := net.ParseCIDR(strings.TrimSpace(c)); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that reached our edge. When the
+// direct peer is a trusted proxy, X-Forwarded-For is walked right to left and
+// the first untrusted hop wins — entries further left are client-supplied and
+// could be spoofed.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !isTrustedProxy(peer) {
+		return host
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		host = ip.String()
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return host
+}
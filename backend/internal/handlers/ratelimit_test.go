@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	take := handlers.TokenBucket(0.5, 3, func() time.Time { return now })
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		key       string
+		allowed   bool
+		remaining int
+		reset     time.Duration
+		retry     time.Duration
+	}{
+		{"burst 1", 0, "a", true, 2, 2 * time.Second, 0},
+		{"burst 2", 0, "a", true, 1, 4 * time.Second, 0},
+		{"burst 3", 0, "a", true, 0, 6 * time.Second, 0},
+		{"empty", 0, "a", false, 0, 6 * time.Second, 2 * time.Second},
+		{"other keys have their own bucket", 0, "b", true, 2, 2 * time.Second, 0},
+		{"half a token", time.Second, "a", false, 0, 5 * time.Second, time.Second},
+		{"refilled one token", time.Second, "a", true, 0, 6 * time.Second, 0},
+		{"refill caps at burst", time.Hour, "a", true, 2, 2 * time.Second, 0},
+	}
+	for _, s := range steps {
+		now = now.Add(s.advance)
+		got := take(s.key)
+		want := handlers.RateDecision{Allowed: s.allowed, Remaining: s.remaining, Reset: s.reset, RetryAfter: s.retry}
+		if got != want {
+			t.Errorf("%s: take = %+v, want %+v", s.name, got, want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name, trusted, remote, xff, want string
+	}{
+		{"direct client ignores the header", "", "203.0.113.5:4000", "198.51.100.7", "203.0.113.5"},
+		{"proxy forwards the client", "", "10.0.0.1:4000", "198.51.100.7", "198.51.100.7"},
+		{"spoofed entries left of the client", "", "10.0.0.1:4000", "6.6.6.6, 198.51.100.7", "198.51.100.7"},
+		{"trusted hops are skipped", "", "10.0.0.1:4000", "198.51.100.7, 10.0.0.9, 192.168.1.1", "198.51.100.7"},
+		{"all hops trusted", "", "10.0.0.1:4000", "10.0.0.8, 10.0.0.9", "10.0.0.8"},
+		{"malformed hop stops the walk", "", "10.0.0.1:4000", "198.51.100.7, junk", "10.0.0.1"},
+		{"no header", "", "10.0.0.1:4000", "", "10.0.0.1"},
+		{"ipv6", "", "[::1]:4000", "2001:db8::1", "2001:db8::1"},
+		{"no port", "", "203.0.113.5", "198.51.100.7", "203.0.113.5"},
+		{"custom proxies", "203.0.113.0/24", "203.0.113.5:4000", "198.51.100.7", "198.51.100.7"},
+		{"custom proxies replace the defaults", "203.0.113.0/24", "10.0.0.1:4000", "198.51.100.7", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(handlers.SetTrustedProxies(tt.trusted))
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if got := handlers.ClientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAuthRateLimitBehindNAT signs a classroom in through one NAT address:
+// thirty players each make a login and a callback request.
+func TestAuthRateLimitBehindNAT(t *testing.T) {
+	h := newHandler(t, &k8s.Client{Dynamic: dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())}, nil)
+	srv := h.RateLimit("auth", func(w http.ResponseWriter, r *http.Request) {})
+	get := func(xff string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/v1/auth/login", nil)
+		r.RemoteAddr = "10.0.0.1:4000"
+		r.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		srv(rec, r)
+		return rec
+	}
+	for i := 0; i < 60; i++ {
+		if rec := get("198.51.100.7"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d from the NAT = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := get("198.51.100.7")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("request past the burst = %d %v, want 429 with Retry-After", rec.Code, rec.Header())
+	}
+	if rec := get("198.51.100.8"); rec.Code != http.StatusOK {
+		t.Errorf("another address = %d, want its own bucket", rec.Code)
+	}
+}
+
+// TestCloseStopsEvictLoops checks that the per-policy eviction goroutines
+// started by New end when the Handler is closed.
+func TestCloseStopsEvictLoops(t *testing.T) {
+	before := runtime.NumGoroutine()
+	h, err := handlers.New(&k8s.Client{Dynamic: dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme())}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := runtime.NumGoroutine(); n <= before {
+		t.Fatalf("goroutines after New = %d, want more than %d", n, before)
+	}
+	h.Close()
+	h.Close() // idempotent
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("goroutines after Close = %d, want %d", n, before)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+// Session signing keys — keyed HMAC with rotation support.
+//
+// Tokens carry the ID of the key that signed them:
+//
+//	<kid>.<hex-json>.<hex-sig>     sig = HMAC-SHA256(key[kid], "<kid>.<hex-json>")
+//
+// The signing key is always SESSION_SECRET, identified by SESSION_KEY_ID
+// (default "k1"). Keys that were retired from signing but must still verify
+// live cookies are listed in SESSION_PREVIOUS_KEYS as comma-separated
+// "<kid>=<secret>" pairs. Cookies minted before key IDs existed
+// ("<hex-json>.<hex-sig>") are checked against every key and reported under
+// the kid "legacy".
+//
+// Rotation procedure (no forced logout):
+//  1. Move the current SESSION_KEY_ID/SESSION_SECRET pair into
+//     SESSION_PREVIOUS_KEYS and set a new SESSION_KEY_ID + SESSION_SECRET.
+//  2. Roll the backend. Sessions signed with the old key keep working and are
+//     transparently re-signed with the new key on their next request.
+//  3. Once k8s_rpg_session_old_key_verifications_total stops increasing (or
+//     after sessionTTL has elapsed), drop the old pair from SESSION_PREVIOUS_KEYS.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultSessionKeyID = "k1"
+	legacySessionKeyID  = "legacy"
+)
+
+// validKeyID restricts key IDs to short label-safe strings. The kid is the
+// first dot-separated token segment, so it must never contain a dot.
+var validKeyID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// sessionOldKeyVerifications counts sessions verified with a key other than the
+// current signing key. Labelled by kid — cardinality is bounded by the number
+// of configured keys plus "legacy".
+var sessionOldKeyVerifications = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "k8s_rpg_session_old_key_verifications_total",
+	Help: "Session cookies verified with a retired signing key (re-signed with the current key)",
+}, []string{"kid"})
+
+// sessionKeyring holds the current signing key and every key accepted for
+// verification (the signing key included).
+type sessionKeyring struct {
+	signingKID string
+	keys       map[string][]byte
+}
+
+// loadSessionKeyring builds the keyring from SESSION_SECRET, SESSION_KEY_ID and
+// SESSION_PREVIOUS_KEYS.
+func loadSessionKeyring(secret, signingKID, previous string) (*sessionKeyring, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("SESSION_SECRET is not set")
+	}
+	if signingKID == "" {
+		signingKID = defaultSessionKeyID
+	}
+	if !validKeyID.MatchString(signingKID) || signingKID == legacySessionKeyID {
+		return nil, fmt.Errorf("SESSION_KEY_ID %q is invalid (1-32 chars of [A-Za-z0-9_-], not %q)", signingKID, legacySessionKeyID)
+	}
+	kr := &sessionKeyring{
+		signingKID: signingKID,
+		keys:       map[string][]byte{signingKID: []byte(secret)},
+	}
+	for i, pair := range strings.Split(previous, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		// Errors name the entry by position only: it may be a bare secret.
+		kid, key, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("SESSION_PREVIOUS_KEYS entry %d must be <kid>=<secret>", i+1)
+		}
+		if !validKeyID.MatchString(kid) || kid == legacySessionKeyID {
+			return nil, fmt.Errorf("SESSION_PREVIOUS_KEYS key id %q is invalid", kid)
+		}
+		if _, dup := kr.keys[kid]; dup {
+			return nil, fmt.Errorf("SESSION_PREVIOUS_KEYS key id %q is duplicated or equals SESSION_KEY_ID", kid)
+		}
+		kr.keys[kid] = []byte(key)
+	}
+	return kr, nil
+}
+
+// sessionKeyringFromEnv loads the keyring New installs on the Handler. Any
+// misconfiguration is returned so main exits at rollout rather than silently
+// logging users out.
+func sessionKeyringFromEnv() (*sessionKeyring, error) {
+	return loadSessionKeyring(os.Getenv("SESSION_SECRET"), os.Getenv("SESSION_KEY_ID"), os.Getenv("SESSION_PREVIOUS_KEYS"))
+}
+
+func hmacHex(key []byte, msg string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign returns "<kid>.<encoded>.<sig>" using the current signing key.
+func (kr *sessionKeyring) sign(encoded string) string {
+	signed := kr.signingKID + "." + encoded
+	return signed + "." + hmacHex(kr.keys[kr.signingKID], signed)
+}
+
+// verify checks the token signature and returns the encoded payload and the
+// kid that verified it. ok is false if no configured key matches.
+func (kr *sessionKeyring) verify(token string) (encoded, kid string, ok bool) {
+	parts := strings.Split(token, ".")
+	switch len(parts) {
+	case 3:
+		kid, encoded = parts[0], parts[1]
+		key, known := kr.keys[kid]
+		if !known {
+			return "", "", false
+		}
+		expected := hmacHex(key, kid+"."+encoded)
+		if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+			return "", "", false
+		}
+		return encoded, kid, true
+	case 2:
+		// Pre-rotation cookie: HMAC over the payload alone, no kid.
+		encoded = parts[0]
+		for _, key := range kr.keys {
+			if hmac.Equal([]byte(parts[1]), []byte(hmacHex(key, encoded))) {
+				return encoded, legacySessionKeyID, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/pnz1990/krombat/backend/internal/handlers"
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+func TestSessionKeyRotation(t *testing.T) {
+	old, err := handlers.LoadSessionKeyring("old-secret", "k1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := handlers.LoadSessionKeyring("new-secret", "k2", "k1=old-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped, err := handlers.LoadSessionKeyring("new-secret", "k2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := old.Sign("7b7d")
+	if !strings.HasPrefix(token, "k1.7b7d.") {
+		t.Errorf("Sign = %q, want k1.7b7d.<sig>", token)
+	}
+	if enc, kid, ok := rotated.Verify(token); !ok || kid != "k1" || enc != "7b7d" {
+		t.Errorf("rotated Verify(old token) = %q %q %v, want 7b7d k1 true", enc, kid, ok)
+	}
+	if _, kid, ok := rotated.Verify(rotated.Sign("7b7d")); !ok || kid != "k2" {
+		t.Errorf("rotated Verify(own token) = %q %v, want k2 true", kid, ok)
+	}
+	if _, _, ok := dropped.Verify(token); ok {
+		t.Error("Verify accepted a token signed with a dropped key")
+	}
+
+	// A k1 token re-labelled k2 must not verify under k2's key.
+	if _, _, ok := rotated.Verify("k2" + strings.TrimPrefix(token, "k1")); ok {
+		t.Error("Verify accepted a token with a swapped kid")
+	}
+	if _, _, ok := rotated.Verify(token[:len(token)-1] + "x"); ok {
+		t.Error("Verify accepted a tampered signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte("old-secret"))
+	mac.Write([]byte("7b7d"))
+	legacy := "7b7d." + hex.EncodeToString(mac.Sum(nil))
+	if _, kid, ok := rotated.Verify(legacy); !ok || kid != "legacy" {
+		t.Errorf("Verify(pre-rotation token) = %q %v, want legacy true", kid, ok)
+	}
+}
+
+func TestLoadSessionKeyringErrors(t *testing.T) {
+	tests := []struct {
+		name, secret, kid, previous string
+	}{
+		{"no secret", "", "", ""},
+		{"bad kid", "s", "k.1", ""},
+		{"reserved kid", "s", "legacy", ""},
+		{"no kid in entry", "s", "k2", "hunter2"},
+		{"empty key in entry", "s", "k2", "k1="},
+		{"bad previous kid", "s", "k2", "k.1=old"},
+		{"duplicate kid", "s", "k2", "k2=old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handlers.LoadSessionKeyring(tt.secret, tt.kid, tt.previous)
+			if err == nil {
+				t.Fatal("LoadSessionKeyring succeeded, want an error")
+			}
+			if strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("error %q leaks the secret", err)
+			}
+		})
+	}
+}
+
+// TestNewSessionConfigError checks that a bad session key configuration is
+// returned by New rather than panicking when the package loads.
+func TestNewSessionConfigError(t *testing.T) {
+	t.Setenv("SESSION_KEY_ID", "legacy")
+	if _, err := handlers.New(&k8s.Client{}, nil); err == nil {
+		t.Fatal("New succeeded with a reserved SESSION_KEY_ID, want an error")
+	}
+}
+
+// TestSessionCookie signs in through the test login and checks that a
+// pre-rotation cookie is accepted and re-signed with the current key.
+func TestSessionCookie(t *testing.T) {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	h := newHandler(t, &k8s.Client{Dynamic: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/auth/test-login", h.TestLoginHandler)
+	mux.HandleFunc("GET /api/v1/auth/me", handlers.MeHandler)
+	srv := h.AuthMiddleware(mux)
+
+	me := func(cookie string) (*httptest.ResponseRecorder, string) {
+		req := httptest.NewRequest("GET", "/api/v1/auth/me", nil)
+		req.AddCookie(&http.Cookie{Name: "krombat_session", Value: cookie})
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var body struct{ Login string }
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec, body.Login
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/auth/test-login?token=alice", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !strings.HasPrefix(cookies[0].Value, "k1.") {
+		t.Fatalf("test-login cookies = %v, want one k1 session", cookies)
+	}
+	if rec, login := me(cookies[0].Value); rec.Code != http.StatusOK || login != "alice" || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("me = %d %q, want alice with no re-signed cookie", rec.Code, login)
+	}
+	if rec, _ := me(cookies[0].Value + "0"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("me with a tampered cookie = %d, want 401", rec.Code)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"l": "bob", "e": time.Now().Add(time.Hour).Unix()})
+	encoded := hex.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(os.Getenv("SESSION_SECRET")))
+	mac.Write([]byte(encoded))
+	rec, login := me(encoded + "." + hex.EncodeToString(mac.Sum(nil)))
+	if login != "bob" {
+		t.Fatalf("me with a pre-rotation cookie = %d %s, want bob", rec.Code, rec.Body.String())
+	}
+	if c := rec.Result().Cookies(); len(c) != 1 || !strings.HasPrefix(c[0].Value, "k1.") {
+		t.Errorf("pre-rotation cookie re-signed as %v, want a k1 cookie", c)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/pnz1990/krombat/backend/internal/k8s"
+)
+
+// fakeDungeonAPI is a fake API server for one dungeon that, unlike the plain
+// dynamic fake, enforces resourceVersion preconditions on merge patches and
+// plays kro by marking every attack resolved as soon as it is written.
+type fakeDungeonAPI struct {
+	client *dynamicfake.FakeDynamicClient
+
+	mu sync.Mutex
+	rv int
+	// barrier holds the first held dungeon GETs until all of them have
+	// arrived, so concurrent requests are guaranteed to read the same state.
+	// It sits outside the fake, which runs reactors under a single lock.
+	barrier sync.WaitGroup
+	gets    int
+	held    int
+	// statusWrites is how many unrelated writes (kro status updates) to
+	// slip in ahead of the next conditional patch.
+	statusWrites int
+	// interleave, when set, runs against the stored dungeon ahead of each
+	// conditional patch, as another writer racing it would; returning true
+	// stores its edit under a new resourceVersion.
+	interleave func(patch, dungeon map[string]interface{}) bool
+	// attackSeqs records spec.seq of every <name>-latest-attack upsert.
+	attackSeqs []int64
+}
+
+func newFakeDungeonAPI(t *testing.T, concurrent int) *fakeDungeonAPI {
+	t.Helper()
+	dungeon := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1",
+		"kind":       "Dungeon",
+		"metadata": map[string]interface{}{
+			"name": "lair", "namespace": "default", "resourceVersion": "1",
+			"labels": map[string]interface{}{"krombat.io/owner": "alice"},
+		},
+		"spec": map[string]interface{}{"monsters": int64(2), "difficulty": "normal", "heroClass": "warrior", "attackSeq": int64(0), "actionSeq": int64(0)},
+		"status": map[string]interface{}{
+			"maxHeroHP": "200", "maxMonsterHP": "30", "maxBossHP": "200",
+			"game": map[string]interface{}{
+				"heroHP": int64(200), "bossHP": int64(200), "monsterHP": []interface{}{int64(30), int64(30)},
+				"initProcessedSeq": int64(1), "combatProcessedSeq": int64(0), "currentRoom": int64(1),
+			},
+		},
+	}}
+	f := &fakeDungeonAPI{client: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), dungeon), rv: 1, held: concurrent}
+	f.barrier.Add(concurrent)
+	gvr := schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}
+
+	f.client.PrependReactor("patch", "attacks", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		var attack struct {
+			Spec struct{ Seq int64 } `json:"spec"`
+		}
+		json.Unmarshal(a.(k8stesting.PatchAction).GetPatch(), &attack)
+		f.mu.Lock()
+		f.attackSeqs = append(f.attackSeqs, attack.Spec.Seq)
+		f.mu.Unlock()
+		return true, &unstructured.Unstructured{}, nil
+	})
+	f.client.PrependReactor("patch", "dungeons", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		obj, err := f.client.Tracker().Get(gvr, "default", "lair")
+		if err != nil {
+			return true, nil, err
+		}
+		cur := obj.(*unstructured.Unstructured).DeepCopy()
+		var patch map[string]interface{}
+		if err := json.Unmarshal(a.(k8stesting.PatchAction).GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		if meta, _ := patch["metadata"].(map[string]interface{}); meta["resourceVersion"] != nil {
+			if f.statusWrites > 0 {
+				f.statusWrites--
+				f.rv++
+				cur.SetResourceVersion(strconv.Itoa(f.rv))
+				if err := f.client.Tracker().Update(gvr, cur.DeepCopy(), "default"); err != nil {
+					return true, nil, err
+				}
+			}
+			if f.interleave != nil && f.interleave(patch, cur.Object) {
+				f.rv++
+				cur.SetResourceVersion(strconv.Itoa(f.rv))
+				if err := f.client.Tracker().Update(gvr, cur.DeepCopy(), "default"); err != nil {
+					return true, nil, err
+				}
+			}
+			if meta["resourceVersion"] != strconv.Itoa(f.rv) {
+				return true, nil, apierrors.NewConflict(gvr.GroupResource(), "lair", errors.New("the object has been modified"))
+			}
+		}
+		mergePatch(cur.Object, patch)
+		f.rv++
+		cur.SetResourceVersion(strconv.Itoa(f.rv))
+		seq, _, _ := unstructured.NestedInt64(cur.Object, "spec", "attackSeq")
+		unstructured.SetNestedField(cur.Object, seq, "status", "game", "combatProcessedSeq")
+		if err := f.client.Tracker().Update(gvr, cur, "default"); err != nil {
+			return true, nil, err
+		}
+		return true, cur, nil
+	})
+	return f
+}
+
+// mergePatch applies an RFC 7386 JSON merge patch to dst.
+func mergePatch(dst, patch map[string]interface{}) {
+	for k, v := range patch {
+		switch pv := v.(type) {
+		case nil:
+			delete(dst, k)
+		case map[string]interface{}:
+			sub, ok := dst[k].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				dst[k] = sub
+			}
+			mergePatch(sub, pv)
+		case float64:
+			dst[k] = int64(pv) // keep integers typed the way the tracker stores them
+		default:
+			dst[k] = v
+		}
+	}
+}
+
+// waitForReaders is the GET barrier; see fakeDungeonAPI.barrier.
+func (f *fakeDungeonAPI) waitForReaders() {
+	f.mu.Lock()
+	f.gets++
+	wait := f.gets <= f.held
+	f.mu.Unlock()
+	if wait {
+		f.barrier.Done()
+		f.barrier.Wait()
+	}
+}
+
+// barrierClient holds dungeon GET results in fakeDungeonAPI.waitForReaders.
+type barrierClient struct {
+	dynamic.Interface
+	f *fakeDungeonAPI
+}
+
+func (c barrierClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return barrierResource{c.Interface.Resource(gvr), c.f, gvr.Resource == "dungeons"}
+}
+
+type barrierResource struct {
+	dynamic.NamespaceableResourceInterface
+	f    *fakeDungeonAPI
+	hold bool
+}
+
+func (r barrierResource) Namespace(ns string) dynamic.ResourceInterface {
+	return barrierNamespace{r.NamespaceableResourceInterface.Namespace(ns), r.f, r.hold}
+}
+
+type barrierNamespace struct {
+	dynamic.ResourceInterface
+	f    *fakeDungeonAPI
+	hold bool
+}
+
+func (r barrierNamespace) Get(ctx context.Context, name string, opts metav1.GetOptions, sub ...string) (*unstructured.Unstructured, error) {
+	obj, err := r.ResourceInterface.Get(ctx, name, opts, sub...)
+	if r.hold {
+		r.f.waitForReaders()
+	}
+	return obj, err
+}
+
+func attackServer(t *testing.T, f *fakeDungeonAPI) http.Handler {
+	t.Setenv("KROMBAT_TEST_USER", "alice")
+	h := newHandler(t, &k8s.Client{Dynamic: barrierClient{f.client, f}}, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/dungeons/{namespace}/{name}/attacks", h.CreateAttack)
+	return h.AuthMiddleware(mux)
+}
+
+func postAttack(srv http.Handler, target string, seq int) *httptest.ResponseRecorder {
+	body := `{"target":"` + target + `","damage":0,"seq":` + strconv.Itoa(seq) + `}`
+	req := httptest.NewRequest("POST", "/api/v1/dungeons/default/lair/attacks", strings.NewReader(body))
+	req.Header.Set("X-Test-User", "alice")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConcurrentTurnsOnlyOneWins(t *testing.T) {
+	const players = 4
+	f := newFakeDungeonAPI(t, players)
+	srv := attackServer(t, f)
+
+	// Every request reads attackSeq 0 and passes the seq check before any of
+	// them writes; only one trigger write may land.
+	codes := make([]int, players)
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = postAttack(srv, "lair-monster-"+strconv.Itoa(i%2), 0).Code
+		}()
+	}
+	wg.Wait()
+
+	won, stale := 0, 0
+	for _, c := range codes {
+		switch c {
+		case http.StatusAccepted:
+			won++
+		case http.StatusConflict:
+			stale++
+		}
+	}
+	if won != 1 || stale != players-1 {
+		t.Errorf("codes = %v, want one 202 and %d 409s", codes, players-1)
+	}
+	obj, err := f.client.Tracker().Get(schema.GroupVersionResource{Group: "game.k8s.example", Version: "v1alpha1", Resource: "dungeons"}, "default", "lair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, _, _ := unstructured.NestedInt64(obj.(*unstructured.Unstructured).Object, "spec", "attackSeq"); seq != 1 {
+		t.Errorf("attackSeq = %d after one winning turn, want 1", seq)
+	}
+	// Losers must not overwrite the Attack CR with a turn that never ran.
+	if len(f.attackSeqs) != 1 || f.attackSeqs[0] != 1 {
+		t.Errorf("Attack CR upserts = %v, want only the winner's seq 1", f.attackSeqs)
+	}
+}
+
+func TestTurnSurvivesUnrelatedWrite(t *testing.T) {
+	f := newFakeDungeonAPI(t, 0)
+	srv := attackServer(t, f)
+
+	// kro writes status between the request's read and its trigger patch. No
+	// turn landed, so the patch is re-based rather than rejected.
+	f.statusWrites = 2
+	if rec := postAttack(srv, "lair-boss", 0); rec.Code != http.StatusAccepted {
+		t.Fatalf("code = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+	if rec := postAttack(srv, "lair-boss", 0); rec.Code != http.StatusConflict {
+		t.Errorf("replaying seq 0 after it advanced: code = %d, want 409", rec.Code)
+	}
+}
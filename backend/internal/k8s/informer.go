@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"log/slog"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DungeonInformer is a watch-backed, in-memory cache of every Dungeon CR.
+// Reads never touch the API server, so they are cheap enough to run on every
+// Prometheus scrape.
+type DungeonInformer struct {
+	informer cache.SharedIndexInformer
+}
+
+// StartDungeonInformer starts a cluster-wide Dungeon informer. The cache fills
+// asynchronously; List returns whatever has been observed so far.
+func StartDungeonInformer(client *Client) *DungeonInformer {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client.Dynamic, 10*time.Minute)
+	inf := factory.ForResource(DungeonGVR).Informer()
+	stop := make(chan struct{}) // runs for the life of the process
+	go inf.Run(stop)
+	go func() {
+		if cache.WaitForCacheSync(stop, inf.HasSynced) {
+			slog.Info("dungeon informer synced", "component", "k8s", "dungeons", len(inf.GetStore().List()))
+		}
+	}()
+	return &DungeonInformer{informer: inf}
+}
+
+// List returns the cached Dungeons. Callers must not mutate them.
+func (d *DungeonInformer) List() []*unstructured.Unstructured {
+	items := d.informer.GetStore().List()
+	out := make([]*unstructured.Unstructured, 0, len(items))
+	for _, it := range items {
+		if u, ok := it.(*unstructured.Unstructured); ok {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// HasSynced reports whether the initial List has completed.
+func (d *DungeonInformer) HasSynced() bool {
+	return d.informer.HasSynced()
+}
+
+// OnUpdate registers fn for every Dungeon modification seen by the informer.
+func (d *DungeonInformer) OnUpdate(fn func(old, cur *unstructured.Unstructured)) {
+	_, _ = d.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			o, ok1 := oldObj.(*unstructured.Unstructured)
+			n, ok2 := newObj.(*unstructured.Unstructured)
+			if ok1 && ok2 {
+				fn(o, n)
+			}
+		},
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/pnz1990/krombat/backend/internal/catalog"
 	"github.com/pnz1990/krombat/backend/internal/ws"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -92,12 +93,12 @@ var celAnnotations = map[string]celAnnotation{
 	},
 	// ── hero-graph outputs (ConfigMap data fields) ─────────────────────────
 	"configmap/data.maxhp": {
-		cel:     `schema.spec.heroClass == "warrior" ? "200" : schema.spec.heroClass == "mage" ? "120" : "150"`,
+		cel:     catalog.ClassChoiceCEL("schema.spec.heroClass", `"150"`, func(c catalog.Class) int64 { return c.MaxHP }, true),
 		rgd:     "hero-graph",
 		concept: "cel-basics",
 	},
 	"configmap/data.maxmana": {
-		cel:     `schema.spec.heroClass == "mage" ? "8" : "0"`,
+		cel:     catalog.ClassChoiceCEL("schema.spec.heroClass", `"0"`, func(c catalog.Class) int64 { return c.MaxMana }, true),
 		rgd:     "hero-graph",
 		concept: "cel-basics",
 	},
@@ -125,7 +126,7 @@ var celAnnotations = map[string]celAnnotation{
 	},
 	// ── Hero CR spec fields (driven by dungeon-graph dungeonInit state node) ───────────
 	"hero/spec.hp": {
-		cel:     `dungeonInit state node: heroClass == "warrior" ? 200 : heroClass == "mage" ? 120 : 150`,
+		cel:     "dungeonInit state node: " + catalog.ClassChoiceCEL("heroClass", "100", func(c catalog.Class) int64 { return c.MaxHP }, false),
 		rgd:     "dungeon-graph (dungeonInit)",
 		concept: "spec-patch",
 	},
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FromUnstructured decodes u into a Dungeon. Numbers may be int64 or float64
+// (the dynamic client returns either); a field of the wrong kind is an error
+// rather than a silent zero.
+func FromUnstructured(u *unstructured.Unstructured) (*Dungeon, error) {
+	d := &Dungeon{}
+	// Through JSON rather than the unstructured converter, which cannot skip
+	// the unexported raw field.
+	data, err := json.Marshal(u.Object)
+	if err == nil {
+		err = json.Unmarshal(data, d)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode dungeon %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+	}
+	d.raw = runtime.DeepCopyJSON(u.Object)
+	return d, nil
+}
+
+// ToUnstructured encodes d. Decoding and re-encoding an object unchanged
+// yields the same object: keys the model does not know are carried over from
+// the source, and zero-valued fields are only written if the source had them,
+// so an uninitialised status.game stays absent.
+func (d *Dungeon) ToUnstructured() (*unstructured.Unstructured, error) {
+	typed, err := runtime.DefaultUnstructuredConverter.ToUnstructured(d)
+	if err != nil {
+		return nil, fmt.Errorf("encode dungeon %s/%s: %w", d.Namespace, d.Name, err)
+	}
+	out := map[string]interface{}{}
+	if d.raw != nil {
+		out = runtime.DeepCopyJSON(d.raw)
+	}
+	// metadata and type meta are fully modelled by apimachinery.
+	for _, k := range []string{"apiVersion", "kind", "metadata"} {
+		if v, ok := typed[k]; ok {
+			out[k] = v
+		} else {
+			delete(out, k)
+		}
+	}
+	overlay(out, typed, "spec")
+	status := overlay(out, typed, "status")
+	if status != nil {
+		overlay(status, typed["status"].(map[string]interface{}), "game")
+	}
+	return &unstructured.Unstructured{Object: out}, nil
+}
+
+// overlay writes the typed fields of section src[key] into dst[key] and
+// returns the resulting section, or nil if it stayed empty and was not in dst.
+// Nested maps are left to the caller.
+func overlay(dst, src map[string]interface{}, key string) map[string]interface{} {
+	from, _ := src[key].(map[string]interface{})
+	to, existed := dst[key].(map[string]interface{})
+	if !existed {
+		to = map[string]interface{}{}
+	}
+	for k, v := range from {
+		if _, nested := v.(map[string]interface{}); nested {
+			continue
+		}
+		if _, had := to[k]; had || !isZero(v) {
+			to[k] = v
+		}
+	}
+	if !existed && len(to) == 0 && !hasNonZero(from) {
+		return nil
+	}
+	dst[key] = to
+	return to
+}
+
+func hasNonZero(m map[string]interface{}) bool {
+	for _, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if hasNonZero(sub) {
+				return true
+			}
+		} else if !isZero(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isZero(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
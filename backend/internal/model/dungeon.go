@@ -0,0 +1,290 @@
+// Package model is the typed view of the Dungeon CR defined by
+// manifests/rgds/dungeon-graph.yaml. Field names and JSON tags mirror the RGD
+// schema one-to-one; when the RGD gains a field, add it here too.
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Dungeon is a game.k8s.example/v1alpha1 Dungeon.
+type Dungeon struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DungeonSpec   `json:"spec"`
+	Status DungeonStatus `json:"status"`
+
+	// raw is the object this Dungeon was decoded from. ToUnstructured lays
+	// the typed fields over it so anything the model does not know about
+	// (kro conditions, fields added to the RGD later) survives a round trip.
+	raw map[string]interface{} `json:"-"`
+}
+
+// DungeonSpec is what the backend writes: the player's choices at creation
+// plus the trigger and display fields the state nodes read.
+type DungeonSpec struct {
+	// Immutable player choices.
+	Monsters   int64  `json:"monsters"`
+	Difficulty string `json:"difficulty"`
+	HeroClass  string `json:"heroClass"`
+	RunCount   int64  `json:"runCount"`
+	Rooms      int64  `json:"rooms"`
+
+	// Trigger fields — backend writes, state nodes read.
+	AttackSeq            int64  `json:"attackSeq"`
+	ActionSeq            int64  `json:"actionSeq"`
+	LastAttackTarget     string `json:"lastAttackTarget"`
+	LastAttackSeed       string `json:"lastAttackSeed"`
+	LastAttackIndex      int64  `json:"lastAttackIndex"`
+	LastAttackIsBoss     bool   `json:"lastAttackIsBoss"`
+	LastAttackIsBackstab bool   `json:"lastAttackIsBackstab"`
+	LastAbility          string `json:"lastAbility"`
+	LastAction           string `json:"lastAction"`
+
+	// Backend-written display/accumulator fields.
+	LastHeroAction  string     `json:"lastHeroAction"`
+	LastEnemyAction string     `json:"lastEnemyAction"`
+	LastCombatLog   string     `json:"lastCombatLog"`
+	XPEarned        int64      `json:"xpEarned"`
+	LastLootDrop    string     `json:"lastLootDrop"`
+	Inventory       Inventory  `json:"inventory"` // New Game+ carry-over
+	EnterRoom2      int64      `json:"enterRoom2"`
+	Objectives      Objectives `json:"objectives"`
+	// BossMaxPhaseReached is the highest phase (1–3) a living boss reached
+	// this run; the boss-phase certificate reads it.
+	BossMaxPhaseReached int64 `json:"bossMaxPhaseReached"`
+	// ConsumablesUsed counts the potions drunk this run, bumped with each
+	// use- trigger; the no-potions badge reads it.
+	ConsumablesUsed int64 `json:"consumablesUsed"`
+}
+
+// DungeonStatus holds kro's projections of the child CRs and gameConfig,
+// plus the state-node store in Game. The numeric projections that come from
+// ConfigMap data or orValue('…') defaults are strings in the CRD.
+type DungeonStatus struct {
+	LivingMonsters          int64  `json:"livingMonsters"`
+	BossState               string `json:"bossState"`
+	BossPhase               string `json:"bossPhase"`
+	BossDamageMultiplier    string `json:"bossDamageMultiplier"`
+	BossSpecialAttackChance string `json:"bossSpecialAttackChance"`
+	Victory                 bool   `json:"victory"`
+	Defeat                  bool   `json:"defeat"`
+	Loot                    string `json:"loot"`
+	TreasureState           string `json:"treasureState"`
+	Modifier                string `json:"modifier"`
+	ModifierType            string `json:"modifierType"`
+	MaxHeroHP               string `json:"maxHeroHP"`
+	MaxHeroMana             string `json:"maxHeroMana"`
+	MaxMonsterHP            string `json:"maxMonsterHP"`
+	MaxBossHP               string `json:"maxBossHP"`
+	DiceFormula             string `json:"diceFormula"`
+	MonsterCounter          string `json:"monsterCounter"`
+	BossCounter             string `json:"bossCounter"`
+
+	Game GameState `json:"game"`
+}
+
+// GameState is status.game, written only by the dungeon-graph state nodes
+// (dungeonInit, combatResolve, actionResolve, …).
+type GameState struct {
+	HeroHP   int64 `json:"heroHP"`
+	HeroMana int64 `json:"heroMana"`
+
+	MonsterHP     []int64  `json:"monsterHP"`
+	BossHP        int64    `json:"bossHP"`
+	Modifier      string   `json:"modifier"`
+	MonsterTypes  []string `json:"monsterTypes"`
+	RoomMonsterHP []int64  `json:"roomMonsterHP"` // starting HP in rooms after the first
+	RoomBossHP    int64    `json:"roomBossHP"`
+	CurrentRoom   int64    `json:"currentRoom"`
+
+	Inventory    Inventory `json:"inventory"`
+	LastLootDrop string    `json:"lastLootDrop"`
+	WeaponBonus  int64     `json:"weaponBonus"`
+	WeaponUses   int64     `json:"weaponUses"`
+	ArmorBonus   int64     `json:"armorBonus"`
+	ShieldBonus  int64     `json:"shieldBonus"`
+	HelmetBonus  int64     `json:"helmetBonus"`
+	PantsBonus   int64     `json:"pantsBonus"`
+	BootsBonus   int64     `json:"bootsBonus"`
+	RingBonus    int64     `json:"ringBonus"`
+	AmuletBonus  int64     `json:"amuletBonus"`
+
+	PoisonTurns      int64 `json:"poisonTurns"`
+	BurnTurns        int64 `json:"burnTurns"`
+	StunTurns        int64 `json:"stunTurns"`
+	TauntActive      int64 `json:"tauntActive"`
+	BackstabCooldown int64 `json:"backstabCooldown"`
+
+	TreasureOpened int64 `json:"treasureOpened"`
+	DoorUnlocked   int64 `json:"doorUnlocked"`
+
+	// Gold is earned on kills and sales and spent at the merchant, who opens
+	// in MerchantRoom once it is cleared. MerchantStock is a JSON array of
+	// item IDs, like Inventory.
+	Gold          int64  `json:"gold"`
+	MerchantStock string `json:"merchantStock"`
+	MerchantRoom  int64  `json:"merchantRoom"`
+
+	// Sentinels: each state node records the trigger seq it last consumed.
+	InitProcessedSeq     int64 `json:"initProcessedSeq"`
+	CombatProcessedSeq   int64 `json:"combatProcessedSeq"`
+	AbilityProcessedSeq  int64 `json:"abilityProcessedSeq"`
+	DotProcessedSeq      int64 `json:"dotProcessedSeq"`
+	TauntProcessedSeq    int64 `json:"tauntProcessedSeq"`
+	CooldownProcessedSeq int64 `json:"cooldownProcessedSeq"`
+	RingProcessedSeq     int64 `json:"ringProcessedSeq"`
+	ActionProcessedSeq   int64 `json:"actionProcessedSeq"`
+	ResolvedRoom         int64 `json:"resolvedRoom"` // last room enterRoomResolve spawned
+}
+
+// MaxInventory is the backpack size. Loot that does not fit is sold on the
+// spot; purchases that would not fit are refused.
+const MaxInventory = 8
+
+// EquipmentSlots are the item types that grant a <slot>Bonus in GameState.
+var EquipmentSlots = []string{"weapon", "armor", "shield", "helmet", "pants", "boots", "ring", "amulet"}
+
+// DefaultRooms is the RGD default for spec.rooms; MaxRooms its maximum.
+const (
+	DefaultRooms = 2
+	MaxRooms     = 5
+)
+
+// RoomCount is spec.rooms, or DefaultRooms for dungeons created before the
+// field existed.
+func (d *Dungeon) RoomCount() int64 {
+	if d.Spec.Rooms <= 0 {
+		return DefaultRooms
+	}
+	return d.Spec.Rooms
+}
+
+// InFinalRoom reports whether the hero has reached the last room, where
+// clearing the boss wins the dungeon.
+func (d *Dungeon) InFinalRoom() bool {
+	return d.Status.Game.CurrentRoom >= d.RoomCount()
+}
+
+// TotalTurns is every attack and action the player has submitted.
+func (d *Dungeon) TotalTurns() int64 {
+	return d.Spec.AttackSeq + d.Spec.ActionSeq
+}
+
+// HasStatus reports whether kro has written a status yet.
+func (d *Dungeon) HasStatus() bool {
+	_, ok := d.raw["status"].(map[string]interface{})
+	return ok
+}
+
+// HeroReady reports whether hero-graph has reconciled far enough for
+// status.maxHeroHP to be set (#399).
+func (s DungeonStatus) HeroReady() bool {
+	return s.MaxHeroHP != ""
+}
+
+// MaxHeroHPValue parses status.maxHeroHP, returning 0 until it is set.
+func (s DungeonStatus) MaxHeroHPValue() int64 {
+	n, _ := strconv.ParseInt(s.MaxHeroHP, 10, 64)
+	return n
+}
+
+// Initialized reports whether dungeonInit has populated the game state.
+func (g GameState) Initialized() bool {
+	return g.InitProcessedSeq > 0
+}
+
+// MonsterAlive reports whether monster i exists and has HP left.
+func (g GameState) MonsterAlive(i int) bool {
+	return i >= 0 && i < len(g.MonsterHP) && g.MonsterHP[i] > 0
+}
+
+// LivingMonsters counts monsters with HP left in the current room.
+func (g GameState) LivingMonsters() int {
+	n := 0
+	for _, hp := range g.MonsterHP {
+		if hp > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// RoomCleared reports whether every monster and the boss are dead.
+func (g GameState) RoomCleared() bool {
+	return g.BossHP <= 0 && g.LivingMonsters() == 0
+}
+
+// Items lists the backpack one ID per copy, in the order kro's CEL takes
+// them.
+func (g GameState) Items() []string { return g.Inventory.IDs() }
+
+// MerchantOpen reports whether the merchant is trading in the current room.
+func (g GameState) MerchantOpen() bool {
+	return g.MerchantRoom > 0 && g.MerchantRoom == g.CurrentRoom
+}
+
+// MerchantItems decodes MerchantStock; a missing or malformed stock is empty.
+func (g GameState) MerchantItems() []string {
+	var items []string
+	if g.MerchantStock != "" {
+		_ = json.Unmarshal([]byte(g.MerchantStock), &items)
+	}
+	return items
+}
+
+// SlotBonus returns the equipped bonus for one of EquipmentSlots.
+func (g GameState) SlotBonus(slot string) int64 {
+	switch slot {
+	case "weapon":
+		return g.WeaponBonus
+	case "armor":
+		return g.ArmorBonus
+	case "shield":
+		return g.ShieldBonus
+	case "helmet":
+		return g.HelmetBonus
+	case "pants":
+		return g.PantsBonus
+	case "boots":
+		return g.BootsBonus
+	case "ring":
+		return g.RingBonus
+	case "amulet":
+		return g.AmuletBonus
+	}
+	return 0
+}
+
+// Counter returns one of the turn counters by its status.game field name
+// (an ability's cooldownField); unknown names are 0.
+func (g GameState) Counter(field string) int64 {
+	switch field {
+	case "tauntActive":
+		return g.TauntActive
+	case "backstabCooldown":
+		return g.BackstabCooldown
+	case "stunTurns":
+		return g.StunTurns
+	case "poisonTurns":
+		return g.PoisonTurns
+	case "burnTurns":
+		return g.BurnTurns
+	}
+	return 0
+}
+
+// EquippedSlots counts the slots with a positive bonus.
+func (g GameState) EquippedSlots() int {
+	n := 0
+	for _, slot := range EquipmentSlots {
+		if g.SlotBonus(slot) > 0 {
+			n++
+		}
+	}
+	return n
+}
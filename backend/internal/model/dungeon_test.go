@@ -0,0 +1,154 @@
+package model_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// liveDungeon is shaped like a Dungeon read back from the API server: every
+// CRD-defaulted spec field, kro's own status keys, and a field the model does
+// not know about.
+func liveDungeon() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1",
+		"kind":       "Dungeon",
+		"metadata": map[string]interface{}{
+			"name":              "dragon-lair",
+			"namespace":         "default",
+			"uid":               "0c0d9c1e-1b64-4d2c-9a8e-6b3f0f0e6f11",
+			"resourceVersion":   "4242",
+			"generation":        int64(7),
+			"creationTimestamp": "2026-03-01T12:00:00Z",
+			"labels":            map[string]interface{}{"krombat.io/owner": "octocat"},
+		},
+		"spec": map[string]interface{}{
+			"monsters": int64(3), "difficulty": "hard", "heroClass": "rogue", "runCount": int64(0), "rooms": int64(3),
+			"attackSeq": int64(4), "actionSeq": int64(1),
+			"lastAttackTarget": "dragon-lair-monster-0", "lastAttackSeed": "dragon-lair-seq-4",
+			"lastAttackIndex": int64(0), "lastAttackIsBoss": false, "lastAttackIsBackstab": true,
+			"lastAbility": "", "lastAction": "", "lastHeroAction": "Hero (rogue) deals 24 damage",
+			"lastEnemyAction": "", "lastCombatLog": "", "xpEarned": int64(10),
+			"lastLootDrop": "", "inventory": []interface{}{}, "enterRoom2": int64(0), "objectives": []interface{}{},
+			"futureField": "kept",
+		},
+		"status": map[string]interface{}{
+			"state":          "ACTIVE",
+			"conditions":     []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			"livingMonsters": int64(2),
+			"bossState":      "pending",
+			"victory":        false,
+			"defeat":         false,
+			"maxHeroHP":      "150",
+			"game": map[string]interface{}{
+				"heroHP": int64(131), "heroMana": int64(0),
+				"monsterHP":    []interface{}{int64(0), int64(80), int64(80)},
+				"monsterTypes": []interface{}{"goblin", "skeleton", "archer"},
+				"bossHP":       int64(800), "currentRoom": int64(1), "modifier": "curse-fury",
+				"inventory":        []interface{}{map[string]interface{}{"id": "hppotion-common", "quantity": int64(1), "durability": int64(0)}},
+				"weaponBonus":      int64(0),
+				"backstabCooldown": int64(3), "initProcessedSeq": int64(1), "combatProcessedSeq": int64(4),
+				"gold": int64(35), "merchantStock": `["weapon-rare","helmet-common"]`, "merchantRoom": int64(0),
+			},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  func() map[string]interface{}
+	}{
+		{"live dungeon", liveDungeon},
+		{"before kro reconciles", func() map[string]interface{} {
+			o := liveDungeon()
+			delete(o, "status")
+			return o
+		}},
+		{"before dungeonInit", func() map[string]interface{} {
+			o := liveDungeon()
+			delete(o["status"].(map[string]interface{}), "game")
+			return o
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := model.FromUnstructured(&unstructured.Unstructured{Object: tt.obj()})
+			if err != nil {
+				t.Fatalf("FromUnstructured: %v", err)
+			}
+			got, err := d.ToUnstructured()
+			if err != nil {
+				t.Fatalf("ToUnstructured: %v", err)
+			}
+			if want := tt.obj(); !reflect.DeepEqual(got.Object, want) {
+				t.Errorf("round trip changed the object:\n got %#v\nwant %#v", got.Object, want)
+			}
+		})
+	}
+}
+
+func TestFromUnstructured(t *testing.T) {
+	obj := liveDungeon()
+	// JSON-decoded numbers arrive as float64.
+	obj["spec"].(map[string]interface{})["attackSeq"] = float64(4)
+	d, err := model.FromUnstructured(&unstructured.Unstructured{Object: obj})
+	if err != nil {
+		t.Fatalf("FromUnstructured: %v", err)
+	}
+	g := d.Status.Game
+	if d.Spec.AttackSeq != 4 || d.TotalTurns() != 5 || d.Labels["krombat.io/owner"] != "octocat" {
+		t.Errorf("spec/metadata = %+v", d.Spec)
+	}
+	if g.LivingMonsters() != 2 || g.MonsterAlive(0) || !g.MonsterAlive(1) || g.RoomCleared() {
+		t.Errorf("monsterHP helpers wrong for %v", g.MonsterHP)
+	}
+	if items := g.Items(); len(items) != 1 || items[0] != "hppotion-common" {
+		t.Errorf("Items() = %v", items)
+	}
+	if g.MerchantOpen() || len(g.MerchantItems()) != 2 || g.Gold != 35 {
+		t.Errorf("merchant = room %d, stock %v, gold %d", g.MerchantRoom, g.MerchantItems(), g.Gold)
+	}
+	if g.MerchantRoom = 1; !g.MerchantOpen() {
+		t.Error("merchant of the current room should be open")
+	}
+	if !d.Status.HeroReady() || d.Status.MaxHeroHPValue() != 150 || !d.HasStatus() {
+		t.Errorf("status = %+v", d.Status)
+	}
+
+	obj["spec"].(map[string]interface{})["monsters"] = "three"
+	if _, err := model.FromUnstructured(&unstructured.Unstructured{Object: obj}); err == nil {
+		t.Error("expected an error for a string in an integer field")
+	}
+}
+
+func TestToUnstructuredWritesChanges(t *testing.T) {
+	d, err := model.FromUnstructured(&unstructured.Unstructured{Object: liveDungeon()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Spec.AttackSeq = 5
+	d.Spec.LastAttackIsBackstab = false
+	d.Status.Game.MonsterHP[1] = 0
+	d.Status.Game.PoisonTurns = 2
+	u, err := d.ToUnstructured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := u.Object["spec"].(map[string]interface{})
+	game := u.Object["status"].(map[string]interface{})["game"].(map[string]interface{})
+	if spec["attackSeq"] != int64(5) || spec["lastAttackIsBackstab"] != false || spec["futureField"] != "kept" {
+		t.Errorf("spec = %v", spec)
+	}
+	if hp := game["monsterHP"].([]interface{}); hp[1] != int64(0) {
+		t.Errorf("monsterHP = %v", hp)
+	}
+	if game["poisonTurns"] != int64(2) {
+		t.Errorf("poisonTurns = %v", game["poisonTurns"])
+	}
+	if _, ok := game["burnTurns"]; ok {
+		t.Error("zero burnTurns absent from the source should stay absent")
+	}
+}
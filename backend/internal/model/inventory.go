@@ -0,0 +1,181 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ItemStack is one line of a structured inventory: Quantity copies of an item
+// that are interchangeable. Durability is the uses left on gear that wears
+// out (a weapon taken off mid-run); 0 means fresh or not wearing, and stacks
+// only merge items with the same Durability.
+type ItemStack struct {
+	ID         string `json:"id"`
+	Quantity   int    `json:"quantity"`
+	Durability int64  `json:"durability"`
+}
+
+// Rarity is the item's rarity: the suffix of its "<type>-<rarity>" ID, as in
+// the catalog. It is derived rather than stored because kro's CEL builds and
+// edits stacks from item IDs alone, and the Dungeon CRD's ItemStack type has
+// no rarity field for a stored copy to drift from.
+func (s ItemStack) Rarity() string {
+	if i := strings.LastIndex(s.ID, "-"); i >= 0 {
+		return s.ID[i+1:]
+	}
+	return ""
+}
+
+// Inventory is a backpack as stacks in first-seen order.
+//
+// Dungeons (spec.inventory, status.game.inventory) and profiles all store
+// this structured form, and kro's CEL edits it the way Add and Remove do.
+// UnmarshalJSON still accepts the older encoding, a JSON array of item IDs
+// wrapped in a string, so objects written before the change still read.
+type Inventory []ItemStack
+
+// ParseInventory decodes either encoding: ["id", ...] or [{"id": ...}, ...].
+// A missing or malformed inventory is empty.
+func ParseInventory(s string) Inventory {
+	var inv Inventory
+	inv.decode([]byte(s))
+	return inv
+}
+
+// Len is the number of items, counting every copy; MaxInventory caps it.
+func (inv Inventory) Len() int {
+	n := 0
+	for _, s := range inv {
+		n += s.Quantity
+	}
+	return n
+}
+
+// Count returns how many copies of id the inventory holds.
+func (inv Inventory) Count(id string) int {
+	n := 0
+	for _, s := range inv {
+		if s.ID == id {
+			n += s.Quantity
+		}
+	}
+	return n
+}
+
+// Contains reports whether the inventory holds at least one id.
+func (inv Inventory) Contains(id string) bool { return inv.Count(id) > 0 }
+
+// First returns the stack an action on id takes its copy from.
+func (inv Inventory) First(id string) (ItemStack, bool) {
+	for _, s := range inv {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return ItemStack{}, false
+}
+
+// Add puts s.Quantity copies of s into the inventory, stacking them with
+// matching items. It refuses, leaving the inventory unchanged, when they
+// would not fit in MaxInventory.
+func (inv *Inventory) Add(s ItemStack) bool {
+	if s.ID == "" || s.Quantity <= 0 || inv.Len()+s.Quantity > MaxInventory {
+		return false
+	}
+	inv.put(s)
+	return true
+}
+
+// Remove takes n copies of id out of the first stacks that hold it, as
+// actionResolve does. It refuses, leaving the inventory unchanged, when
+// there are fewer than n.
+func (inv *Inventory) Remove(id string, n int) bool {
+	if n <= 0 || inv.Count(id) < n {
+		return false
+	}
+	kept := (*inv)[:0]
+	for _, s := range *inv {
+		if s.ID == id && n > 0 {
+			take := min(s.Quantity, n)
+			s.Quantity -= take
+			n -= take
+		}
+		if s.Quantity > 0 {
+			kept = append(kept, s)
+		}
+	}
+	*inv = kept
+	return true
+}
+
+// IDs lists one item ID per copy, stack by stack.
+func (inv Inventory) IDs() []string {
+	ids := make([]string, 0, inv.Len())
+	for _, s := range inv {
+		for range s.Quantity {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
+// Unstructured is the inventory as an unstructured spec value, for writing
+// spec.inventory on a new Dungeon.
+func (inv Inventory) Unstructured() []interface{} {
+	out := make([]interface{}, 0, len(inv))
+	for _, s := range inv {
+		out = append(out, map[string]interface{}{"id": s.ID, "quantity": int64(s.Quantity), "durability": s.Durability})
+	}
+	return out
+}
+
+// MarshalJSON writes the structured form; an empty inventory is [].
+func (inv Inventory) MarshalJSON() ([]byte, error) {
+	if inv == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]ItemStack(inv))
+}
+
+// UnmarshalJSON reads the structured form, an ID array, or either one
+// wrapped in a JSON string as older dungeons and profiles stored it. Like
+// ParseInventory it never fails: a malformed inventory is empty.
+func (inv *Inventory) UnmarshalJSON(b []byte) error {
+	*inv = nil
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		b = []byte(s)
+	}
+	inv.decode(b)
+	return nil
+}
+
+func (inv *Inventory) decode(b []byte) {
+	var ids []string
+	if json.Unmarshal(b, &ids) == nil {
+		for _, id := range ids {
+			if id != "" {
+				inv.put(ItemStack{ID: id, Quantity: 1})
+			}
+		}
+		return
+	}
+	var stacks []ItemStack
+	_ = json.Unmarshal(b, &stacks)
+	for _, s := range stacks {
+		if s.ID != "" && s.Quantity > 0 {
+			inv.put(s)
+		}
+	}
+}
+
+// put stacks s onto a matching stack or appends it.
+func (inv *Inventory) put(s ItemStack) {
+	for i := range *inv {
+		if (*inv)[i].ID == s.ID && (*inv)[i].Durability == s.Durability {
+			(*inv)[i].Quantity += s.Quantity
+			return
+		}
+	}
+	*inv = append(*inv, s)
+}
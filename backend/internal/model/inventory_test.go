@@ -0,0 +1,105 @@
+package model_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+func TestParseInventory(t *testing.T) {
+	want := model.Inventory{
+		{ID: "hppotion-common", Quantity: 2},
+		{ID: "weapon-epic", Quantity: 1},
+	}
+	tests := []struct {
+		name string
+		in   string
+		want model.Inventory
+	}{
+		{"ids", `["hppotion-common","weapon-epic","hppotion-common"]`, want},
+		{"stacks", `[{"id":"hppotion-common","quantity":2},{"id":"weapon-epic","quantity":1}]`, want},
+		{"empty", "", nil},
+		{"malformed", `{"hppotion-common":2}`, nil},
+		{"blank ids and zero stacks", `[{"id":"","quantity":1},{"id":"ring-rare","quantity":0}]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := model.ParseInventory(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseInventory(%s) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemStackRarity(t *testing.T) {
+	for id, want := range map[string]string{"hppotion-common": "common", "weapon-epic": "epic", "key": ""} {
+		if got := (model.ItemStack{ID: id, Quantity: 1}).Rarity(); got != want {
+			t.Errorf("ItemStack{%s}.Rarity() = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestInventoryAddRemove(t *testing.T) {
+	inv := model.Inventory{{ID: "weapon-rare", Quantity: 1, Durability: 2}}
+	if !inv.Add(model.ItemStack{ID: "hppotion-common", Quantity: 1}) || !inv.Add(model.ItemStack{ID: "weapon-rare", Quantity: 1}) {
+		t.Fatal("Add refused")
+	}
+	if inv.Add(model.ItemStack{ID: "hppotion-rare", Quantity: 6}) {
+		t.Error("Add past MaxInventory accepted")
+	}
+	if got := inv.Count("weapon-rare"); got != 2 || len(inv) != 3 {
+		t.Errorf("Count(weapon-rare) = %d in %d stacks, want 2 in 3", got, len(inv))
+	}
+	if s, _ := inv.First("weapon-rare"); s.Durability != 2 {
+		t.Errorf("First(weapon-rare) = %+v, want the worn stack", s)
+	}
+	// Like actionResolve, Remove takes from the first stack: the worn one.
+	if !inv.Remove("weapon-rare", 1) || inv.Count("weapon-rare") != 1 || inv[1].Durability != 0 {
+		t.Errorf("Remove(weapon-rare) should take the first stack, left %+v", inv)
+	}
+	if inv.Remove("hppotion-common", 2) || !inv.Contains("hppotion-common") {
+		t.Errorf("Remove of more than held should refuse, left %+v", inv)
+	}
+	if got, want := inv.IDs(), []string{"hppotion-common", "weapon-rare"}; !reflect.DeepEqual(got, want) || inv.Len() != 2 {
+		t.Errorf("IDs() = %v (Len %d), want %v", got, inv.Len(), want)
+	}
+	want := []interface{}{
+		map[string]interface{}{"id": "hppotion-common", "quantity": int64(1), "durability": int64(0)},
+		map[string]interface{}{"id": "weapon-rare", "quantity": int64(1), "durability": int64(0)},
+	}
+	if got := inv.Unstructured(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unstructured() = %v, want %v", got, want)
+	}
+}
+
+// TestInventoryJSON covers the migration: older dungeons and profiles stored
+// an ID-array string and come back as structured stacks.
+func TestInventoryJSON(t *testing.T) {
+	var p struct {
+		Inventory model.Inventory `json:"inventory"`
+	}
+	for _, legacy := range []string{
+		`{"inventory":"[\"ring-epic\",\"ring-epic\"]"}`,
+		`{"inventory":["ring-epic","ring-epic"]}`,
+		`{"inventory":[{"id":"ring-epic","quantity":2}]}`,
+	} {
+		if err := json.Unmarshal([]byte(legacy), &p); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", legacy, err)
+		}
+		b, _ := json.Marshal(p)
+		if got, want := string(b), `{"inventory":[{"id":"ring-epic","quantity":2,"durability":0}]}`; got != want {
+			t.Errorf("%s round-trips to %s, want %s", legacy, got, want)
+		}
+	}
+	for _, empty := range []string{`{"inventory":""}`, `{"inventory":null}`, `{"inventory":"not json"}`} {
+		if err := json.Unmarshal([]byte(empty), &p); err != nil || p.Inventory.Len() != 0 {
+			t.Errorf("Unmarshal(%s) = %+v, %v; want empty", empty, p.Inventory, err)
+		}
+	}
+	b, _ := json.Marshal(p)
+	if string(b) != `{"inventory":[]}` {
+		t.Errorf("empty inventory marshals to %s, want []", b)
+	}
+}
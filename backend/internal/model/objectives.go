@@ -0,0 +1,223 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+)
+
+// Objective kinds.
+const (
+	ObjectiveKill      = "kill"        // slay Target monsters of type Monster
+	ObjectiveNoPotions = "no-potions"  // win without using a consumable
+	ObjectiveBossRush  = "boss-phase3" // kill a boss within Target turns of its phase 3
+)
+
+// Objective states.
+const (
+	ObjectiveActive = "active"
+	ObjectiveDone   = "done"
+	ObjectiveFailed = "failed"
+)
+
+// Objective is one goal a dungeon rolls at creation. The backend tracks it
+// in spec.objectives and the profile pays XP for it once it is done.
+type Objective struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Monster     string `json:"monster"`
+	Target      int64  `json:"target"`
+	Progress    int64  `json:"progress"`
+	State       string `json:"state"`
+	XP          int64  `json:"xp"`
+	// PhaseTurn is the attack turn the current boss reached phase 3 on, for
+	// boss-phase3; 0 until then.
+	PhaseTurn int64 `json:"phaseTurn"`
+}
+
+// Objectives is spec.objectives, a list of the RGD's Objective type. Its
+// tracking methods update the entries in place and report whether anything
+// changed.
+type Objectives []Objective
+
+// Unstructured is the objectives as an unstructured spec value, for writing
+// spec.objectives on a new Dungeon or in a patch.
+func (o Objectives) Unstructured() []interface{} {
+	out := make([]interface{}, 0, len(o))
+	for _, ob := range o {
+		out = append(out, map[string]interface{}{
+			"id": ob.ID, "kind": ob.Kind, "description": ob.Description, "monster": ob.Monster,
+			"target": ob.Target, "progress": ob.Progress, "state": ob.State, "xp": ob.XP, "phaseTurn": ob.PhaseTurn,
+		})
+	}
+	return out
+}
+
+// MarshalJSON writes the list; no objectives is [].
+func (o Objectives) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Objective(o))
+}
+
+// RoomMonsterType is the type of monster i in a room, as dungeonInit and
+// enterRoomResolve assign them.
+func RoomMonsterType(room int64, i int) string {
+	switch {
+	case room > 1 && i%2 == 0:
+		return "troll"
+	case room > 1:
+		return "ghoul"
+	case i == 0:
+		return "goblin"
+	case i == 1:
+		return "skeleton"
+	case i%2 == 0:
+		return "archer"
+	}
+	return "shaman"
+}
+
+// RollObjectives picks two or three objectives for a dungeon, seeded by seed
+// so the same dungeon always rolls the same ones. At most two are kill
+// objectives, each for a monster type the dungeon will spawn.
+func RollObjectives(seed string, monsters, rooms int64) Objectives {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	rng := rand.New(rand.NewPCG(h.Sum64(), uint64(monsters)<<8|uint64(rooms)))
+
+	spawned := map[string]int64{}
+	var types []string
+	for room := int64(1); room <= max(rooms, 1); room++ {
+		for i := range int(monsters) {
+			t := RoomMonsterType(room, i)
+			if spawned[t] == 0 {
+				types = append(types, t)
+			}
+			spawned[t]++
+		}
+	}
+	rng.Shuffle(len(types), func(i, j int) { types[i], types[j] = types[j], types[i] })
+
+	var pool Objectives
+	for _, t := range types[:min(len(types), 2)] {
+		n := spawned[t]/2 + 1 + rng.Int64N((spawned[t]+1)/2)
+		n = min(n, spawned[t])
+		pool = append(pool, Objective{
+			ID: "kill-" + t, Kind: ObjectiveKill, Monster: t, Target: n, XP: 15 * n,
+			Description: fmt.Sprintf("Slay %d %s", n, plural(t, n)),
+		})
+	}
+	pool = append(pool, Objective{
+		ID: ObjectiveNoPotions, Kind: ObjectiveNoPotions, XP: 75,
+		Description: "Win without using a potion",
+	})
+	k := 3 + rng.Int64N(3)
+	pool = append(pool, Objective{
+		ID: ObjectiveBossRush, Kind: ObjectiveBossRush, Target: k, XP: 60,
+		Description: fmt.Sprintf("Finish a boss within %d turns of it reaching phase 3", k),
+	})
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	out := pool[:2+rng.IntN(2)]
+	for i := range out {
+		out[i].State = ObjectiveActive
+	}
+	return out
+}
+
+func plural(monster string, n int64) string {
+	if n == 1 {
+		return monster
+	}
+	return monster + "s"
+}
+
+// TrackCombat advances kill and boss objectives over one attack turn, from
+// the game state before and after kro resolved it. bossPhase is the boss's
+// phase after the turn and turn is its attack sequence number.
+func (o Objectives) TrackCombat(pre, post GameState, bossPhase string, turn int64) bool {
+	changed := false
+	for i := range o {
+		ob := &o[i]
+		if ob.State != ObjectiveActive {
+			continue
+		}
+		switch ob.Kind {
+		case ObjectiveKill:
+			for m := range post.MonsterHP {
+				if pre.MonsterAlive(m) && !post.MonsterAlive(m) && m < len(pre.MonsterTypes) && pre.MonsterTypes[m] == ob.Monster {
+					ob.Progress++
+					changed = true
+				}
+			}
+			if ob.Progress >= ob.Target {
+				ob.State = ObjectiveDone
+			}
+		case ObjectiveBossRush:
+			if pre.BossHP <= 0 {
+				continue
+			}
+			// A boss killed before phase 3 was seen went through it this turn.
+			if ob.PhaseTurn == 0 && (bossPhase == "phase3" || post.BossHP <= 0) {
+				ob.PhaseTurn = turn
+				changed = true
+			}
+			if post.BossHP > 0 {
+				continue
+			}
+			ob.Progress = turn - ob.PhaseTurn
+			if ob.Progress <= ob.Target {
+				ob.State = ObjectiveDone
+			}
+			// The next room's boss starts the clock over.
+			ob.PhaseTurn = 0
+			changed = true
+		}
+	}
+	return changed
+}
+
+// UsedConsumable fails the no-potions objective.
+func (o Objectives) UsedConsumable() bool {
+	changed := false
+	for i := range o {
+		if o[i].Kind == ObjectiveNoPotions && o[i].State == ObjectiveActive {
+			o[i].State = ObjectiveFailed
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Finish settles the objectives when the run ends: a win completes
+// no-potions, and whatever is still active then has failed.
+func (o Objectives) Finish(won bool) bool {
+	changed := false
+	for i := range o {
+		ob := &o[i]
+		if ob.State != ObjectiveActive {
+			continue
+		}
+		ob.State = ObjectiveFailed
+		if won && ob.Kind == ObjectiveNoPotions {
+			ob.State = ObjectiveDone
+		}
+		changed = true
+	}
+	return changed
+}
+
+// XP is the reward for the objectives done so far.
+func (o Objectives) XP() int64 {
+	var xp int64
+	for _, ob := range o {
+		if ob.State == ObjectiveDone {
+			xp += ob.XP
+		}
+	}
+	return xp
+}
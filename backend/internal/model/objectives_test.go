@@ -0,0 +1,119 @@
+package model_test
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+func TestRollObjectives(t *testing.T) {
+	for _, tt := range []struct {
+		seed            string
+		monsters, rooms int64
+	}{
+		{"lair-0", 3, 2}, {"lair-1", 3, 2}, {"cave-0", 1, 2}, {"deep-0", 10, 5}, {"x-3", 2, 3},
+	} {
+		o := model.RollObjectives(tt.seed, tt.monsters, tt.rooms)
+		if again := model.RollObjectives(tt.seed, tt.monsters, tt.rooms); !reflect.DeepEqual(o, again) {
+			t.Errorf("%s: rolls differ: %v vs %v", tt.seed, o, again)
+		}
+		if len(o) < 2 || len(o) > 3 {
+			t.Errorf("%s: rolled %d objectives, want 2 or 3", tt.seed, len(o))
+		}
+		spawned := map[string]int64{}
+		for room := int64(1); room <= tt.rooms; room++ {
+			for i := range int(tt.monsters) {
+				spawned[model.RoomMonsterType(room, i)]++
+			}
+		}
+		for _, ob := range o {
+			if ob.State != model.ObjectiveActive || ob.XP <= 0 || ob.Description == "" {
+				t.Errorf("%s: %+v is not a fresh objective", tt.seed, ob)
+			}
+			if ob.Kind == model.ObjectiveKill && (ob.Target < 1 || ob.Target > spawned[ob.Monster]) {
+				t.Errorf("%s: %s needs %d of %d spawned", tt.seed, ob.ID, ob.Target, spawned[ob.Monster])
+			}
+		}
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"objectives": o.Unstructured()},
+		}}
+		if d, err := model.FromUnstructured(u); err != nil {
+			t.Errorf("%s: decode: %v", tt.seed, err)
+		} else if !reflect.DeepEqual(d.Spec.Objectives, o) {
+			t.Errorf("%s: round trip = %v, want %v", tt.seed, d.Spec.Objectives, o)
+		}
+	}
+}
+
+// TestMalformedObjectives checks that objectives in any other shape fail the
+// dungeon's decode instead of reading as none.
+func TestMalformedObjectives(t *testing.T) {
+	for _, v := range []interface{}{
+		`[{"id":"no-potions","kind":"no-potions","state":"active","xp":75}]`,
+		[]interface{}{map[string]interface{}{"id": "kill-goblin", "target": "two"}},
+	} {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"objectives": v},
+		}}
+		if _, err := model.FromUnstructured(u); err == nil {
+			t.Errorf("objectives %v decoded without error", v)
+		}
+	}
+}
+
+func TestTrackObjectives(t *testing.T) {
+	o := model.Objectives{
+		{ID: "kill-archer", Kind: model.ObjectiveKill, Monster: "archer", Target: 2, State: model.ObjectiveActive, XP: 30},
+		{ID: "boss-phase3", Kind: model.ObjectiveBossRush, Target: 2, State: model.ObjectiveActive, XP: 60},
+		{ID: "no-potions", Kind: model.ObjectiveNoPotions, State: model.ObjectiveActive, XP: 75},
+	}
+	types := []string{"goblin", "skeleton", "archer", "shaman", "archer"}
+	game := func(boss int64, monsters ...int64) model.GameState {
+		return model.GameState{MonsterHP: monsters, MonsterTypes: types, BossHP: boss}
+	}
+
+	// An archer and a goblin die; the boss is hit into phase 3 on turn 4.
+	if !o.TrackCombat(game(100, 5, 0, 5, 0, 5), game(20, 0, 0, 0, 0, 5), "phase3", 4) {
+		t.Fatal("TrackCombat reported no change")
+	}
+	if o[0].Progress != 1 || o[1].PhaseTurn != 4 {
+		t.Errorf("after turn 4: %+v", o[:2])
+	}
+	// The second archer falls; the boss dies three turns after phase 3, one too many.
+	o.TrackCombat(game(20, 0, 0, 0, 0, 5), game(0, 0, 0, 0, 0, 0), "phase3", 7)
+	if o[0].State != model.ObjectiveDone || o[1].State != model.ObjectiveActive || o[1].Progress != 3 || o[1].PhaseTurn != 0 {
+		t.Errorf("after turn 7: %+v", o[:2])
+	}
+	// The next room's boss is one-shot from phase 1.
+	o.TrackCombat(game(300, 0), game(0, 0), "phase1", 9)
+	if o[1].State != model.ObjectiveDone || o[1].Progress != 0 {
+		t.Errorf("one-shot boss: %+v", o[1])
+	}
+	if got := o.XP(); got != 90 {
+		t.Errorf("XP() = %d, want 90", got)
+	}
+
+	if !o.UsedConsumable() || o[2].State != model.ObjectiveFailed || o.UsedConsumable() {
+		t.Errorf("UsedConsumable should fail no-potions once: %+v", o[2])
+	}
+	if o.Finish(true) {
+		t.Error("Finish changed settled objectives")
+	}
+
+	won := model.Objectives{
+		{Kind: model.ObjectiveNoPotions, State: model.ObjectiveActive},
+		{Kind: model.ObjectiveKill, Monster: "troll", Target: 3, State: model.ObjectiveActive},
+	}
+	won.Finish(true)
+	if won[0].State != model.ObjectiveDone || won[1].State != model.ObjectiveFailed {
+		t.Errorf("Finish(true) = %+v", won)
+	}
+	lost := model.Objectives{{Kind: model.ObjectiveNoPotions, State: model.ObjectiveActive}}
+	lost.Finish(false)
+	if lost[0].State != model.ObjectiveFailed {
+		t.Errorf("Finish(false) = %+v", lost)
+	}
+}
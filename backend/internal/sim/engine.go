@@ -0,0 +1,229 @@
+// Package sim plays simulated dungeons against the real dungeon-graph RGD.
+//
+// The engine loads manifests/rgds/dungeon-graph.yaml, compiles every state
+// node (dungeonInit, combatResolve, actionResolve, ...) with the same CEL
+// environment kro uses, and reconciles them in-process: each node whose
+// includeWhen holds is evaluated against the current schema and its fields are
+// merged into status.game, repeating until no node fires. The backend's side of
+// a turn (writing attackSeq/lastAttackSeed/lastAction trigger fields) is
+// mirrored in game.go, so a simulated turn resolves exactly like a click on a
+// live cluster — just without the API server in the loop.
+package sim
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	krocel "github.com/kubernetes-sigs/kro/pkg/cel"
+	"sigs.k8s.io/yaml"
+)
+
+// maxPasses bounds one reconcile. Every state node is gated on a sentinel it
+// advances itself, so a healthy RGD settles in two or three passes.
+const maxPasses = 16
+
+// rgdDoc is the slice of a ResourceGraphDefinition the engine reads.
+type rgdDoc struct {
+	Spec struct {
+		Resources []struct {
+			ID    string `json:"id"`
+			State *struct {
+				StoreName string            `json:"storeName"`
+				Fields    map[string]string `json:"fields"`
+			} `json:"state"`
+			IncludeWhen []string `json:"includeWhen"`
+		} `json:"resources"`
+	} `json:"spec"`
+}
+
+type compiledField struct {
+	name string
+	prg  cel.Program
+}
+
+type stateNode struct {
+	id      string
+	fields  []compiledField
+	include []cel.Program
+}
+
+// Engine holds the compiled state nodes of one RGD. It is immutable after
+// construction and safe for concurrent use.
+type Engine struct {
+	nodes    []stateNode
+	modifier cel.Program // dungeonInit's modifier field, used to pick dungeon names
+}
+
+// LoadEngine reads and compiles the RGD at path.
+func LoadEngine(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngine(data)
+}
+
+// NewEngine compiles the "game" state nodes of an RGD document.
+func NewEngine(rgdYAML []byte) (*Engine, error) {
+	var doc rgdDoc
+	if err := yaml.Unmarshal(rgdYAML, &doc); err != nil {
+		return nil, fmt.Errorf("parse RGD: %w", err)
+	}
+	opts := append(krocel.BaseDeclarations(),
+		cel.Variable("schema", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	env, err := cel.NewEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("build kro CEL env: %w", err)
+	}
+
+	e := &Engine{}
+	for _, res := range doc.Spec.Resources {
+		if res.State == nil || res.State.StoreName != "game" {
+			continue
+		}
+		node := stateNode{id: res.ID}
+		names := make([]string, 0, len(res.State.Fields))
+		for name := range res.State.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prg, err := compile(env, res.State.Fields[name])
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", res.ID, name, err)
+			}
+			node.fields = append(node.fields, compiledField{name: name, prg: prg})
+			if res.ID == "dungeonInit" && name == "modifier" {
+				e.modifier = prg
+			}
+		}
+		for i, cond := range res.IncludeWhen {
+			prg, err := compile(env, cond)
+			if err != nil {
+				return nil, fmt.Errorf("%s.includeWhen[%d]: %w", res.ID, i, err)
+			}
+			node.include = append(node.include, prg)
+		}
+		e.nodes = append(e.nodes, node)
+	}
+	if len(e.nodes) == 0 {
+		return nil, fmt.Errorf("RGD has no game state nodes")
+	}
+	if e.modifier == nil {
+		return nil, fmt.Errorf("RGD has no dungeonInit.modifier field")
+	}
+	return e, nil
+}
+
+// compile strips the ${...} wrapper from an RGD expression and compiles it.
+func compile(env *cel.Env, raw string) (cel.Program, error) {
+	expr := strings.TrimSpace(raw)
+	if !strings.HasPrefix(expr, "${") || !strings.HasSuffix(expr, "}") {
+		return nil, fmt.Errorf("not a ${...} expression")
+	}
+	ast, iss := env.Compile(expr[2 : len(expr)-1])
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	return env.Program(ast)
+}
+
+// Modifier returns the modifier dungeonInit would roll for a dungeon name.
+func (e *Engine) Modifier(name string) (string, error) {
+	out, _, err := e.modifier.Eval(map[string]interface{}{"schema": map[string]interface{}{
+		"spec":     map[string]interface{}{},
+		"status":   map[string]interface{}{"game": map[string]interface{}{}},
+		"metadata": map[string]interface{}{"name": name},
+	}})
+	if err != nil {
+		return "", err
+	}
+	s, _ := out.Value().(string)
+	return s, nil
+}
+
+// reconcile runs state nodes in RGD order until none is included. Fields of a
+// node are all evaluated against the state as it was when the node started,
+// then merged — the same atomic write kro makes to status.game.
+func (e *Engine) reconcile(d *dungeon) error {
+	for pass := 0; pass < maxPasses; pass++ {
+		fired := false
+		for _, n := range e.nodes {
+			act := d.activation()
+			ok, err := n.included(act)
+			if err != nil {
+				return fmt.Errorf("%s includeWhen: %w", n.id, err)
+			}
+			if !ok {
+				continue
+			}
+			updates := make(map[string]interface{}, len(n.fields))
+			for _, f := range n.fields {
+				out, _, err := f.prg.Eval(act)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %w", n.id, f.name, err)
+				}
+				updates[f.name] = toNative(out)
+			}
+			for k, v := range updates {
+				d.game[k] = v
+			}
+			fired = true
+		}
+		if !fired {
+			return nil
+		}
+	}
+	return fmt.Errorf("state nodes did not settle after %d passes", maxPasses)
+}
+
+func (n stateNode) included(act map[string]interface{}) (bool, error) {
+	for _, prg := range n.include {
+		out, _, err := prg.Eval(act)
+		if err != nil {
+			return false, err
+		}
+		if b, ok := out.Value().(bool); !ok || !b {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// toNative converts a CEL result into the types status.game holds after a
+// JSON round-trip through the API server (int64, string, bool, slices, maps).
+func toNative(v ref.Val) interface{} {
+	switch t := v.(type) {
+	case types.Int:
+		return int64(t)
+	case types.Uint:
+		return int64(t)
+	case types.Double:
+		return float64(t)
+	case types.String:
+		return string(t)
+	case types.Bool:
+		return bool(t)
+	case traits.Mapper:
+		out := map[string]interface{}{}
+		for it := t.Iterator(); it.HasNext() == types.True; {
+			k := it.Next()
+			out[fmt.Sprint(k.Value())] = toNative(t.Get(k))
+		}
+		return out
+	case traits.Lister:
+		out := []interface{}{}
+		for it := t.Iterator(); it.HasNext() == types.True; {
+			out = append(out, toNative(it.Next()))
+		}
+		return out
+	}
+	return v.Value()
+}
@@ -0,0 +1,256 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/pnz1990/krombat/backend/internal/autoplay"
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+// Setup is one point of the balance matrix.
+type Setup struct {
+	HeroClass  string `json:"heroClass"`
+	Difficulty string `json:"difficulty"`
+	Modifier   string `json:"modifier"` // "any" leaves the modifier to the dungeon name roll
+	RunCount   int    `json:"runCount"`
+	// Backpack is the inventory carried over from a won run, as the backend
+	// writes it to spec.inventory; nil starts empty.
+	Backpack model.Inventory `json:"backpack,omitempty"`
+}
+
+func (s Setup) String() string {
+	return fmt.Sprintf("%s/%s/%s/ng+%d", s.HeroClass, s.Difficulty, s.Modifier, s.RunCount)
+}
+
+// key identifies a Setup, backpack included, for matching report rows.
+func (s Setup) key() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// Outcome of one simulated game.
+const (
+	OutcomeVictory = "victory"
+	OutcomeDefeat  = "defeat"
+	OutcomeStalled = "stalled" // turn cap hit or strategy had no legal move
+)
+
+// GameResult is what one simulated game reports back to the aggregator.
+type GameResult struct {
+	Outcome     string
+	Turns       int64    // attackSeq + actionSeq at the end, as the leaderboard counts them
+	DamageDealt []int64  // HP removed from the target on each hero attack
+	DamageTaken []int64  // hero HP lost on each combat turn
+	Kills       int      // monsters and bosses killed
+	Loot        []string // items dropped (lastLootDrop), e.g. "weapon-rare"
+	Gold        int64    // gold earned over the game, spent or not
+}
+
+// dungeon is the in-memory stand-in for a Dungeon CR: the spec the backend
+// writes and the status.game kro computes. They stay untyped maps because
+// they are the CEL activation; everything else reads them through decode.
+type dungeon struct {
+	name      string
+	spec      map[string]interface{}
+	game      map[string]interface{}
+	maxHeroHP int64 // status.maxHeroHP, which hero-graph would project
+}
+
+func newDungeon(name string, s Setup, monsters, rooms int) *dungeon {
+	return &dungeon{
+		name: name,
+		// Schema defaults from dungeon-graph.yaml plus the creation-time choices.
+		spec: map[string]interface{}{
+			"monsters":             int64(monsters),
+			"difficulty":           s.Difficulty,
+			"heroClass":            s.HeroClass,
+			"runCount":             int64(s.RunCount),
+			"inventory":            s.Backpack.Unstructured(),
+			"rooms":                int64(rooms),
+			"attackSeq":            int64(0),
+			"actionSeq":            int64(0),
+			"lastAttackTarget":     "",
+			"lastAttackSeed":       "",
+			"lastAttackIndex":      int64(-1),
+			"lastAttackIsBoss":     false,
+			"lastAttackIsBackstab": false,
+			"lastAbility":          "",
+			"lastAction":           "",
+		},
+		game: map[string]interface{}{},
+	}
+}
+
+func (d *dungeon) activation() map[string]interface{} {
+	return map[string]interface{}{"schema": map[string]interface{}{
+		"spec":     d.spec,
+		"status":   map[string]interface{}{"game": d.game},
+		"metadata": map[string]interface{}{"name": d.name, "namespace": d.name},
+	}}
+}
+
+// decode reads the dungeon through the typed model, as the backend reads a
+// Dungeon CR.
+func (d *dungeon) decode() (*model.Dungeon, error) {
+	m := &model.Dungeon{}
+	m.Name = d.name
+	conv := runtime.DefaultUnstructuredConverter
+	if err := conv.FromUnstructured(d.spec, &m.Spec); err != nil {
+		return nil, fmt.Errorf("decode spec: %w", err)
+	}
+	if err := conv.FromUnstructured(d.game, &m.Status.Game); err != nil {
+		return nil, fmt.Errorf("decode status.game: %w", err)
+	}
+	if d.maxHeroHP > 0 {
+		m.Status.MaxHeroHP = strconv.FormatInt(d.maxHeroHP, 10)
+	}
+	return m, nil
+}
+
+// apply writes the trigger fields processCombat/processAction would write for
+// move on m, the decoded d, then reconciles. Moves use the UI vocabulary (see
+// autoplay).
+func (e *Engine) apply(d *dungeon, m *model.Dungeon, move string) error {
+	attackSeq, actionSeq, game := m.Spec.AttackSeq, m.Spec.ActionSeq, m.Status.Game
+	if isAction(move) {
+		d.set(map[string]interface{}{"lastAction": move, "actionSeq": actionSeq + 1, "lastAttackTarget": "", "lastAbility": ""})
+		return e.reconcile(d)
+	}
+	target, ability := move, catalog.Ability{}
+	if action, enemy, ok := catalog.AbilityForTarget(move); ok {
+		class := m.Spec.HeroClass
+		a, ok, _ := catalog.LookupAbility(class, action)
+		switch {
+		case !ok:
+			return fmt.Errorf("%s cannot %s", class, action)
+		case game.HeroMana < a.ManaCost:
+			return fmt.Errorf("%s with %d mana", action, game.HeroMana)
+		case a.CooldownField != "" && game.Counter(a.CooldownField) > 0:
+			return fmt.Errorf("%s on cooldown", action)
+		}
+		if a.Target == catalog.TargetSelf {
+			d.set(map[string]interface{}{"lastAbility": "", "attackSeq": attackSeq + 1, "lastAttackTarget": "", "lastAction": ""})
+			d.set(map[string]interface{}{a.Trigger.Field: a.Trigger.Value})
+			return e.reconcile(d)
+		}
+		target, ability = enemy, a
+	}
+	isBoss := strings.HasSuffix(target, "-boss")
+	idx := int64(-1)
+	if !isBoss {
+		i, err := strconv.ParseInt(target[strings.LastIndex(target, "-")+1:], 10, 64)
+		if err != nil || i < 0 || int(i) >= len(game.MonsterHP) {
+			return fmt.Errorf("invalid target %q", move)
+		}
+		idx = i
+	}
+	seq := attackSeq + 1
+	d.set(map[string]interface{}{
+		"attackSeq":            seq,
+		"lastAttackTarget":     target,
+		"lastAttackSeed":       d.name + "-seq-" + strconv.FormatInt(seq, 10),
+		"lastAttackIndex":      idx,
+		"lastAttackIsBoss":     isBoss,
+		"lastAttackIsBackstab": false,
+		"lastAbility":          "",
+		"lastAction":           "",
+	})
+	if ability.ID != "" {
+		d.set(map[string]interface{}{ability.Trigger.Field: ability.Trigger.Value})
+	}
+	return e.reconcile(d)
+}
+
+func (d *dungeon) set(fields map[string]interface{}) {
+	for k, v := range fields {
+		d.spec[k] = v
+	}
+}
+
+// isSelfAbility reports whether move is an ability that targets the hero.
+func isSelfAbility(move string) bool {
+	_, enemy, ok := catalog.AbilityForTarget(move)
+	return ok && enemy == ""
+}
+
+func isAction(move string) bool {
+	return strings.HasPrefix(move, "use-") || strings.HasPrefix(move, "equip-") ||
+		strings.HasPrefix(move, "buy-") || strings.HasPrefix(move, "sell-") ||
+		strings.HasPrefix(move, "craft-") || strings.HasPrefix(move, "drop-") ||
+		strings.HasPrefix(move, "unequip-") || strings.HasPrefix(move, "swap-") ||
+		strings.HasPrefix(move, "enter-room-") ||
+		move == "open-treasure" || move == "unlock-door"
+}
+
+// Play runs one game through rooms rooms (0 = the RGD default of 2) to
+// victory, defeat or maxTurns, asking pick for every move.
+func (e *Engine) Play(name string, s Setup, monsters, rooms int, pick func(autoplay.View) string, maxTurns int) (GameResult, error) {
+	if rooms <= 0 {
+		rooms = 2
+	}
+	d := newDungeon(name, s, monsters, rooms)
+	if err := e.reconcile(d); err != nil {
+		return GameResult{}, fmt.Errorf("init: %w", err)
+	}
+	d.maxHeroHP, _ = d.game["heroHP"].(int64)
+	m, err := d.decode()
+	if err != nil {
+		return GameResult{}, fmt.Errorf("init: %w", err)
+	}
+	var res GameResult
+	for {
+		game := m.Status.Game
+		switch {
+		case game.HeroHP <= 0:
+			res.Outcome = OutcomeDefeat
+		case game.CurrentRoom == int64(rooms) && game.ResolvedRoom == int64(rooms) && game.RoomCleared():
+			res.Outcome = OutcomeVictory
+		case m.TotalTurns() >= int64(maxTurns):
+			res.Outcome = OutcomeStalled
+		}
+		if res.Outcome != "" {
+			res.Turns = m.TotalTurns()
+			return res, nil
+		}
+
+		move := pick(autoplay.NewView(m))
+		if move == "" {
+			res.Outcome, res.Turns = OutcomeStalled, m.TotalTurns()
+			return res, nil
+		}
+		if err := e.apply(d, m, move); err != nil {
+			return res, fmt.Errorf("turn %d %q: %w", m.TotalTurns(), move, err)
+		}
+		next, err := d.decode()
+		if err != nil {
+			return res, fmt.Errorf("turn %d %q: %w", m.TotalTurns(), move, err)
+		}
+		after := next.Status.Game
+		if !strings.HasPrefix(move, "buy-") {
+			res.Gold += max(after.Gold-game.Gold, 0)
+		}
+		if !isAction(move) && !isSelfAbility(move) {
+			var hpBefore, hpAfter int64
+			if idx := next.Spec.LastAttackIndex; idx >= 0 {
+				hpBefore, hpAfter = game.MonsterHP[idx], after.MonsterHP[idx]
+			} else {
+				hpBefore, hpAfter = game.BossHP, after.BossHP
+			}
+			res.DamageDealt = append(res.DamageDealt, max(hpBefore-hpAfter, 0))
+			if hpBefore > 0 && hpAfter <= 0 {
+				res.Kills++
+			}
+			res.DamageTaken = append(res.DamageTaken, max(game.HeroHP-after.HeroHP, 0))
+			if after.LastLootDrop != "" {
+				res.Loot = append(res.Loot, after.LastLootDrop)
+			}
+		}
+		m = next
+	}
+}
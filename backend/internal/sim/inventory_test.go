@@ -0,0 +1,49 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+// TestWornWeaponDurability follows a carried-over worn weapon through
+// actionResolve and combatResolve: equipping the first stack starts
+// weaponUses from its durability, and unequipping puts the uses left back
+// on a stack of their own.
+func TestWornWeaponDurability(t *testing.T) {
+	e, err := LoadEngine("../../../manifests/rgds/dungeon-graph.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backpack := model.Inventory{{ID: "weapon-rare", Quantity: 1, Durability: 2}, {ID: "weapon-rare", Quantity: 1}}
+	d := newDungeon("sim-worn", Setup{HeroClass: "warrior", Difficulty: "easy", Backpack: backpack}, 2, 2)
+	if err := e.reconcile(d); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		move     string
+		uses     int64
+		backpack model.Inventory
+	}{
+		{"equip-weapon-rare", 2, model.Inventory{{ID: "weapon-rare", Quantity: 1}}},
+		{"sim-worn-monster-0", 1, model.Inventory{{ID: "weapon-rare", Quantity: 1}}},
+		{"unequip-weapon", 0, model.Inventory{{ID: "weapon-rare", Quantity: 1}, {ID: "weapon-rare", Quantity: 1, Durability: 1}}},
+		{"equip-weapon-rare", 3, model.Inventory{{ID: "weapon-rare", Quantity: 1, Durability: 1}}},
+	}
+	for _, st := range steps {
+		m, err := d.decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.apply(d, m, st.move); err != nil {
+			t.Fatalf("%s: %v", st.move, err)
+		}
+		if m, err = d.decode(); err != nil {
+			t.Fatal(err)
+		}
+		if g := m.Status.Game; g.WeaponUses != st.uses || !reflect.DeepEqual(g.Inventory, st.backpack) {
+			t.Errorf("after %s: weaponUses %d, backpack %+v; want %d, %+v", st.move, g.WeaponUses, g.Inventory, st.uses, st.backpack)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pnz1990/krombat/backend/internal/autoplay"
+)
+
+// Matrix describes a balance run: every combination of the four axes is
+// played Games times with one strategy.
+type Matrix struct {
+	HeroClasses  []string
+	Difficulties []string
+	Modifiers    []string // "any" or a modifier name (none, curse-fury, blessing-strength, ...)
+	RunCounts    []int
+	Monsters     int
+	Rooms        int // 0 = the RGD default of 2
+	Games        int
+	Strategy     string // an autoplay strategy, played at the default heal threshold
+	MaxTurns     int
+	Workers      int // 0 = GOMAXPROCS
+}
+
+// Stats summarises an integer sample.
+type Stats struct {
+	N    int     `json:"n"`
+	Mean float64 `json:"mean"`
+	Min  int64   `json:"min"`
+	P10  int64   `json:"p10"`
+	P50  int64   `json:"p50"`
+	P90  int64   `json:"p90"`
+	Max  int64   `json:"max"`
+}
+
+// Result aggregates every game played for one Setup.
+type Result struct {
+	Setup
+	Games          int                `json:"games"`
+	Wins           int                `json:"wins"`
+	Defeats        int                `json:"defeats"`
+	Stalls         int                `json:"stalls"`
+	WinRate        float64            `json:"winRate"`
+	TurnsToVictory Stats              `json:"turnsToVictory"`
+	DamageDealt    Stats              `json:"damageDealt"`
+	DamageTaken    Stats              `json:"damageTaken"`
+	LootPerGame    float64            `json:"lootPerGame"`
+	LootPerKill    float64            `json:"lootPerKill"`
+	LootRarity     map[string]float64 `json:"lootRarity"` // share of drops by rarity
+	GoldPerGame    float64            `json:"goldPerGame"`
+}
+
+// Report is the tool's JSON output and the format of regression baselines.
+type Report struct {
+	Strategy string   `json:"strategy"`
+	Monsters int      `json:"monsters"`
+	Rooms    int      `json:"rooms,omitempty"`
+	Games    int      `json:"games"`
+	MaxTurns int      `json:"maxTurns"`
+	Results  []Result `json:"results"`
+}
+
+// Run plays the whole matrix. Games are deterministic: game i of a Setup is
+// played in the i-th dungeon name (in order) whose seeded modifier roll
+// matches, so the same matrix always yields the same Report.
+func (e *Engine) Run(m Matrix) (Report, error) {
+	strategy, ok := autoplay.Strategies[m.Strategy]
+	if !ok {
+		return Report{}, fmt.Errorf("unknown strategy %q (want one of %s)", m.Strategy, strings.Join(autoplay.Names(), ", "))
+	}
+	pick := func(v autoplay.View) string {
+		return strategy(v, autoplay.Options{HealThreshold: autoplay.DefaultHealThreshold})
+	}
+	workers := m.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	rep := Report{Strategy: m.Strategy, Monsters: m.Monsters, Rooms: m.Rooms, Games: m.Games, MaxTurns: m.MaxTurns}
+	for _, class := range m.HeroClasses {
+		for _, diff := range m.Difficulties {
+			for _, mod := range m.Modifiers {
+				for _, rc := range m.RunCounts {
+					s := Setup{HeroClass: class, Difficulty: diff, Modifier: mod, RunCount: rc}
+					games, err := e.playSetup(s, m, pick, workers)
+					if err != nil {
+						return Report{}, fmt.Errorf("%s: %w", s, err)
+					}
+					rep.Results = append(rep.Results, aggregate(s, games))
+				}
+			}
+		}
+	}
+	return rep, nil
+}
+
+func (e *Engine) playSetup(s Setup, m Matrix, pick func(autoplay.View) string, workers int) ([]GameResult, error) {
+	names, err := e.dungeonNames(s, m.Games)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]GameResult, len(names))
+	errs := make([]error, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = e.Play(names[i], s, m.Monsters, m.Rooms, pick, m.MaxTurns)
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", names[i], err)
+		}
+	}
+	return results, nil
+}
+
+// dungeonNames picks n dungeon names whose modifier roll matches s.Modifier.
+func (e *Engine) dungeonNames(s Setup, n int) ([]string, error) {
+	names := make([]string, 0, n)
+	for k := 0; len(names) < n; k++ {
+		if k > n*100+1000 {
+			return nil, fmt.Errorf("no dungeon name rolls modifier %q", s.Modifier)
+		}
+		name := fmt.Sprintf("sim-%s-%s-%d", s.HeroClass, s.Difficulty, k)
+		if s.Modifier != "any" {
+			mod, err := e.Modifier(name)
+			if err != nil {
+				return nil, err
+			}
+			if mod != s.Modifier {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func aggregate(s Setup, games []GameResult) Result {
+	r := Result{Setup: s, Games: len(games), LootRarity: map[string]float64{}}
+	var turns, dealt, taken []int64
+	loot, kills := 0, 0
+	var gold int64
+	for _, g := range games {
+		switch g.Outcome {
+		case OutcomeVictory:
+			r.Wins++
+			turns = append(turns, g.Turns)
+		case OutcomeDefeat:
+			r.Defeats++
+		default:
+			r.Stalls++
+		}
+		dealt = append(dealt, g.DamageDealt...)
+		taken = append(taken, g.DamageTaken...)
+		kills += g.Kills
+		gold += g.Gold
+		for _, item := range g.Loot {
+			loot++
+			r.LootRarity[item[strings.LastIndex(item, "-")+1:]]++
+		}
+	}
+	if r.Games > 0 {
+		r.WinRate = round(float64(r.Wins) / float64(r.Games))
+		r.LootPerGame = round(float64(loot) / float64(r.Games))
+		r.GoldPerGame = round(float64(gold) / float64(r.Games))
+	}
+	if kills > 0 {
+		r.LootPerKill = round(float64(loot) / float64(kills))
+	}
+	for k, v := range r.LootRarity {
+		r.LootRarity[k] = round(v / float64(loot))
+	}
+	r.TurnsToVictory = summarize(turns)
+	r.DamageDealt = summarize(dealt)
+	r.DamageTaken = summarize(taken)
+	return r
+}
+
+func summarize(xs []int64) Stats {
+	if len(xs) == 0 {
+		return Stats{}
+	}
+	sorted := append([]int64(nil), xs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum int64
+	for _, x := range sorted {
+		sum += x
+	}
+	pct := func(p int) int64 { return sorted[(len(sorted)-1)*p/100] }
+	return Stats{
+		N:    len(sorted),
+		Mean: round(float64(sum) / float64(len(sorted))),
+		Min:  sorted[0],
+		P10:  pct(10),
+		P50:  pct(50),
+		P90:  pct(90),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// round keeps JSON baselines stable and diffable.
+func round(f float64) float64 { return math.Round(f*1000) / 1000 }
+
+// WriteTable prints one row per Setup.
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLASS\tDIFFICULTY\tMODIFIER\tNG+\tGAMES\tWIN%\tDEFEAT\tSTALL\tTURNS p50/p90\tDMG DEALT p10/p50/p90\tDMG TAKEN p50/p90\tLOOT/GAME\tLOOT/KILL\tC/R/E\tGOLD/GAME")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.1f\t%d\t%d\t%d/%d\t%d/%d/%d\t%d/%d\t%.2f\t%.2f\t%.0f/%.0f/%.0f%%\t%.0f\n",
+			res.HeroClass, res.Difficulty, res.Modifier, res.RunCount, res.Games,
+			res.WinRate*100, res.Defeats, res.Stalls,
+			res.TurnsToVictory.P50, res.TurnsToVictory.P90,
+			res.DamageDealt.P10, res.DamageDealt.P50, res.DamageDealt.P90,
+			res.DamageTaken.P50, res.DamageTaken.P90,
+			res.LootPerGame, res.LootPerKill,
+			res.LootRarity["common"]*100, res.LootRarity["rare"]*100, res.LootRarity["epic"]*100,
+			res.GoldPerGame)
+	}
+	return tw.Flush()
+}
+
+// Tolerance bounds how far a Report may drift from a baseline.
+type Tolerance struct {
+	WinRate float64 // absolute, e.g. 0.05 = five percentage points
+	Turns   float64 // relative change of mean turns-to-victory, e.g. 0.15
+	Loot    float64 // absolute change of loot per game
+}
+
+// Compare returns one line per Setup that drifted past tol or is missing
+// from cur. An empty result means cur matches the baseline.
+func Compare(baseline, cur Report, tol Tolerance) []string {
+	byKey := make(map[string]Result, len(cur.Results))
+	for _, r := range cur.Results {
+		byKey[r.Setup.key()] = r
+	}
+	var drift []string
+	for _, b := range baseline.Results {
+		c, ok := byKey[b.Setup.key()]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: missing from current run", b.Setup))
+			continue
+		}
+		if d := math.Abs(c.WinRate - b.WinRate); d > tol.WinRate {
+			drift = append(drift, fmt.Sprintf("%s: win rate %.3f → %.3f", b.Setup, b.WinRate, c.WinRate))
+		}
+		if b.TurnsToVictory.Mean > 0 {
+			if d := math.Abs(c.TurnsToVictory.Mean-b.TurnsToVictory.Mean) / b.TurnsToVictory.Mean; d > tol.Turns {
+				drift = append(drift, fmt.Sprintf("%s: mean turns to victory %.1f → %.1f", b.Setup, b.TurnsToVictory.Mean, c.TurnsToVictory.Mean))
+			}
+		}
+		if d := math.Abs(c.LootPerGame - b.LootPerGame); d > tol.Loot {
+			drift = append(drift, fmt.Sprintf("%s: loot per game %.2f → %.2f", b.Setup, b.LootPerGame, c.LootPerGame))
+		}
+	}
+	return drift
+}
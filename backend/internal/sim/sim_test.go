@@ -0,0 +1,342 @@
+package sim_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pnz1990/krombat/backend/internal/autoplay"
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"github.com/pnz1990/krombat/backend/internal/sim"
+)
+
+const rgdPath = "../../../manifests/rgds/dungeon-graph.yaml"
+
+func loadEngine(t *testing.T) *sim.Engine {
+	t.Helper()
+	e, err := sim.LoadEngine(rgdPath)
+	if err != nil {
+		t.Fatalf("LoadEngine: %v", err)
+	}
+	return e
+}
+
+// TestBaseline replays the matrix recorded in testdata/baseline.json and fails
+// when dungeon-graph.yaml changes move the balance past the CLI's default
+// tolerances. After an intentional balance change, regenerate it from backend/:
+//
+//	go run ./cmd/balance -games 40 -difficulties easy,normal -json internal/sim/testdata/baseline.json
+func TestBaseline(t *testing.T) {
+	data, err := os.ReadFile("testdata/baseline.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var base sim.Report
+	if err := json.Unmarshal(data, &base); err != nil {
+		t.Fatal(err)
+	}
+	m := sim.Matrix{Monsters: base.Monsters, Rooms: base.Rooms, Games: base.Games, Strategy: base.Strategy, MaxTurns: base.MaxTurns}
+	for _, r := range base.Results {
+		m.HeroClasses = appendUnique(m.HeroClasses, r.HeroClass)
+		m.Difficulties = appendUnique(m.Difficulties, r.Difficulty)
+		m.Modifiers = appendUnique(m.Modifiers, r.Modifier)
+		if !slices.Contains(m.RunCounts, r.RunCount) {
+			m.RunCounts = append(m.RunCounts, r.RunCount)
+		}
+	}
+
+	got, err := loadEngine(t).Run(m)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, d := range sim.Compare(base, got, sim.Tolerance{WinRate: 0.05, Turns: 0.15, Loot: 0.5}) {
+		t.Error(d)
+	}
+}
+
+func TestRunDeterministic(t *testing.T) {
+	e := loadEngine(t)
+	m := sim.Matrix{
+		HeroClasses:  []string{"rogue"},
+		Difficulties: []string{"easy"},
+		Modifiers:    []string{"blessing-strength"},
+		RunCounts:    []int{0, 2},
+		Monsters:     2,
+		Games:        5,
+		Strategy:     "backstab-on-cooldown",
+		MaxTurns:     200,
+	}
+	a, err := e.Run(m)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	m.Workers = 1
+	b, err := e.Run(m)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same matrix produced different reports:\n%+v\n%+v", a, b)
+	}
+	for _, r := range a.Results {
+		if r.Wins+r.Defeats+r.Stalls != r.Games {
+			t.Errorf("%s: outcomes %d+%d+%d != %d games", r.Setup, r.Wins, r.Defeats, r.Stalls, r.Games)
+		}
+		if r.DamageDealt.N == 0 {
+			t.Errorf("%s: no attacks recorded", r.Setup)
+		}
+	}
+}
+
+func TestModifier(t *testing.T) {
+	e := loadEngine(t)
+	want := map[string]bool{
+		"none": true, "curse-fortitude": true, "curse-fury": true, "curse-darkness": true,
+		"blessing-strength": true, "blessing-resilience": true, "blessing-fortune": true,
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		mod, err := e.Modifier(fmt.Sprintf("sim-modifier-%d", i))
+		if err != nil {
+			t.Fatalf("Modifier: %v", err)
+		}
+		if !want[mod] {
+			t.Fatalf("unexpected modifier %q", mod)
+		}
+		seen[mod] = true
+	}
+	if len(seen) != len(want) {
+		t.Errorf("200 names rolled %d of %d modifiers", len(seen), len(want))
+	}
+}
+
+func TestRunUnknownStrategy(t *testing.T) {
+	if _, err := loadEngine(t).Run(sim.Matrix{Strategy: "berserk"}); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	setup := sim.Setup{HeroClass: "mage", Difficulty: "easy", Modifier: "any"}
+	base := sim.Report{Results: []sim.Result{{Setup: setup, WinRate: 0.5, LootPerGame: 4, TurnsToVictory: sim.Stats{Mean: 40}}}}
+	tol := sim.Tolerance{WinRate: 0.05, Turns: 0.15, Loot: 0.5}
+
+	tests := []struct {
+		name  string
+		cur   []sim.Result
+		drift int
+	}{
+		{"identical", base.Results, 0},
+		{"within tolerance", []sim.Result{{Setup: setup, WinRate: 0.54, LootPerGame: 4.4, TurnsToVictory: sim.Stats{Mean: 45}}}, 0},
+		{"win rate drift", []sim.Result{{Setup: setup, WinRate: 0.6, LootPerGame: 4, TurnsToVictory: sim.Stats{Mean: 40}}}, 1},
+		{"turns and loot drift", []sim.Result{{Setup: setup, WinRate: 0.5, LootPerGame: 3, TurnsToVictory: sim.Stats{Mean: 50}}}, 2},
+		{"missing setup", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sim.Compare(base, sim.Report{Results: tt.cur}, tol)
+			if len(got) != tt.drift {
+				t.Errorf("Compare() = %q, want %d drift lines", got, tt.drift)
+			}
+		})
+	}
+}
+
+func appendUnique(xs []string, x string) []string {
+	if slices.Contains(xs, x) {
+		return xs
+	}
+	return append(xs, x)
+}
+
+func TestRunRooms(t *testing.T) {
+	e := loadEngine(t)
+	m := sim.Matrix{
+		HeroClasses:  []string{"warrior"},
+		Difficulties: []string{"easy"},
+		Modifiers:    []string{"none"},
+		RunCounts:    []int{0},
+		Monsters:     2,
+		Games:        10,
+		Strategy:     "heal-at-threshold",
+		MaxTurns:     400,
+	}
+	turns := map[int]float64{}
+	for _, rooms := range []int{2, 4} {
+		m.Rooms = rooms
+		r, err := e.Run(m)
+		if err != nil {
+			t.Fatalf("Run(%d rooms): %v", rooms, err)
+		}
+		res := r.Results[0]
+		if res.Wins == 0 {
+			t.Fatalf("%d rooms: no victories in %d games (%d defeats, %d stalls)", rooms, res.Games, res.Defeats, res.Stalls)
+		}
+		turns[rooms] = res.TurnsToVictory.Mean
+	}
+	if turns[4] <= turns[2] {
+		t.Errorf("4-room victories took %.1f turns, 2-room %.1f; deeper dungeons should take longer", turns[4], turns[2])
+	}
+}
+
+// TestMerchant buys an item at the first merchant it can afford, sells it
+// straight back, and checks kro moved the gold, stock and backpack.
+func TestMerchant(t *testing.T) {
+	e := loadEngine(t)
+	for i := 0; i < 20; i++ {
+		var views []autoplay.View
+		var moves []string
+		strategy := func(v autoplay.View) string {
+			mv := healAtThreshold(v)
+			switch last := len(moves) - 1; {
+			case last >= 0 && strings.HasPrefix(moves[last], "buy-"):
+				mv = "sell-" + strings.TrimPrefix(moves[last], "buy-")
+			case v.MerchantOpen && !slices.ContainsFunc(moves, func(m string) bool { return strings.HasPrefix(m, "buy-") }):
+				for _, id := range v.MerchantStock {
+					if it, _ := catalog.Default().Lookup(id); it.Price <= v.Gold && len(v.Inventory) < 8 {
+						mv = "buy-" + id
+						break
+					}
+				}
+			}
+			views, moves = append(views, v), append(moves, mv)
+			return mv
+		}
+		name := fmt.Sprintf("sim-merchant-%d", i)
+		if _, err := e.Play(name, sim.Setup{HeroClass: "warrior", Difficulty: "easy"}, 2, 3, strategy, 300); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		k := slices.IndexFunc(moves, func(m string) bool { return strings.HasPrefix(m, "buy-") })
+		if k < 0 || k+2 >= len(views) {
+			continue // never afforded anything; try another dungeon
+		}
+		item := strings.TrimPrefix(moves[k], "buy-")
+		it, _ := catalog.Default().Lookup(item)
+		before, bought, sold := views[k], views[k+1], views[k+2]
+		if bought.Gold != before.Gold-it.Price || count(bought.Inventory, item) != count(before.Inventory, item)+1 || slices.Contains(bought.MerchantStock, item) {
+			t.Errorf("%s: buy %s: gold %d → %d, stock %v, backpack %v", name, item, before.Gold, bought.Gold, bought.MerchantStock, bought.Inventory)
+		}
+		if sold.Gold != bought.Gold+it.SellPrice() || count(sold.Inventory, item) != count(before.Inventory, item) {
+			t.Errorf("%s: sell %s: gold %d → %d, backpack %v", name, item, bought.Gold, sold.Gold, sold.Inventory)
+		}
+		return
+	}
+	t.Fatal("no simulated dungeon could afford anything at the merchant")
+}
+
+func healAtThreshold(v autoplay.View) string {
+	return autoplay.Strategies["heal-at-threshold"](v, autoplay.Options{HealThreshold: autoplay.DefaultHealThreshold})
+}
+
+func count(items []string, item string) int {
+	n := 0
+	for _, it := range items {
+		if it == item {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCraft(t *testing.T) {
+	e := loadEngine(t)
+	for i := 0; i < 20; i++ {
+		var views []autoplay.View
+		var moves []string
+		strategy := func(v autoplay.View) string {
+			mv := autoplay.Strategies["crafter"](v, autoplay.Options{HealThreshold: autoplay.DefaultHealThreshold})
+			views, moves = append(views, v), append(moves, mv)
+			return mv
+		}
+		name := fmt.Sprintf("sim-craft-%d", i)
+		if _, err := e.Play(name, sim.Setup{HeroClass: "warrior", Difficulty: "easy"}, 6, 5, strategy, 400); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		k := slices.IndexFunc(moves, func(m string) bool { return strings.HasPrefix(m, "craft-") })
+		if k < 0 || k+1 >= len(views) {
+			continue // never held a recipe's inputs; try another dungeon
+		}
+		r, _ := catalog.LookupRecipe(strings.TrimPrefix(moves[k], "craft-"))
+		before, after := views[k], views[k+1]
+		if count(after.Inventory, r.Output) != count(before.Inventory, r.Output)+1 ||
+			len(after.Inventory) != len(before.Inventory)-len(r.Inputs)+1 {
+			t.Errorf("%s: craft %s: backpack %v → %v", name, r.ID, before.Inventory, after.Inventory)
+		}
+		for _, in := range r.Inputs {
+			if count(after.Inventory, in) != count(before.Inventory, in)-count(r.Inputs, in) {
+				t.Errorf("%s: craft %s did not consume %s: backpack %v → %v", name, r.ID, in, before.Inventory, after.Inventory)
+			}
+		}
+		return
+	}
+	t.Fatal("no simulated dungeon collected a recipe's inputs")
+}
+
+func TestEquipmentActions(t *testing.T) {
+	e := loadEngine(t)
+	script := []string{"equip-weapon-common", "swap-weapon", "unequip-weapon", "drop-hppotion-common", "swap-ring"}
+	var views []autoplay.View
+	strategy := func(v autoplay.View) string {
+		views = append(views, v)
+		if len(views) > len(script) {
+			return ""
+		}
+		return script[len(views)-1]
+	}
+	setup := sim.Setup{HeroClass: "warrior", Difficulty: "easy", Backpack: model.ParseInventory(`["weapon-common","hppotion-common","weapon-rare","ring-rare","hppotion-common"]`)}
+	if _, err := e.Play("sim-equipment", setup, 2, 2, strategy, 50); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		weapon, ring int64
+		backpack     string
+	}{
+		{0, 0, "weapon-common hppotion-common hppotion-common weapon-rare ring-rare"}, // carried over, potions stacked
+		{5, 0, "hppotion-common hppotion-common weapon-rare ring-rare"},
+		{10, 0, "hppotion-common hppotion-common ring-rare weapon-common"},
+		{0, 0, "hppotion-common hppotion-common ring-rare weapon-common weapon-rare"},
+		{0, 0, "hppotion-common ring-rare weapon-common weapon-rare"}, // drop takes one copy
+		{0, 8, "hppotion-common weapon-common weapon-rare"},           // swap into an empty slot equips
+	}
+	if len(views) != len(want) {
+		t.Fatalf("played %d turns, want %d", len(views), len(want))
+	}
+	for i, w := range want {
+		v := views[i]
+		if got := strings.Join(v.Inventory, " "); v.SlotBonus["weapon"] != w.weapon || v.SlotBonus["ring"] != w.ring || got != w.backpack {
+			t.Errorf("after %d moves: weapon %d, ring %d, backpack %q; want %d, %d, %q", i, v.SlotBonus["weapon"], v.SlotBonus["ring"], got, w.weapon, w.ring, w.backpack)
+		}
+	}
+}
+
+// TestMonsterTypes checks model.RoomMonsterType, which objectives roll
+// kill targets from, against the types dungeonInit and enterRoomResolve
+// spawn.
+func TestMonsterTypes(t *testing.T) {
+	e := loadEngine(t)
+	rooms := map[int64]bool{}
+	strategy := func(v autoplay.View) string {
+		rooms[v.CurrentRoom] = true
+		for i, typ := range v.MonsterTypes {
+			if want := model.RoomMonsterType(v.CurrentRoom, i); typ != want {
+				t.Fatalf("room %d monster %d is a %s, RoomMonsterType says %s", v.CurrentRoom, i, typ, want)
+			}
+		}
+		if len(v.MonsterTypes) != 5 {
+			t.Fatalf("room %d has %d monster types, want 5", v.CurrentRoom, len(v.MonsterTypes))
+		}
+		return healAtThreshold(v)
+	}
+	setup := sim.Setup{HeroClass: "warrior", Difficulty: "easy"}
+	if _, err := e.Play("sim-types", setup, 5, 3, strategy, 600); err != nil {
+		t.Fatal(err)
+	}
+	if !rooms[2] {
+		t.Errorf("never reached room 2 (saw rooms %v)", rooms)
+	}
+}
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/pnz1990/krombat/backend/internal/model"
+)
+
+// CreateDungeonRequest is the body of POST /api/v1/dungeons.
+type CreateDungeonRequest struct {
+	Name       string `json:"name"`
+	Monsters   int64  `json:"monsters"`
+	Difficulty string `json:"difficulty"`
+	HeroClass  string `json:"heroClass,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Rooms      int64  `json:"rooms,omitempty"` // 2-5; 0 = 2
+	RunCount   int64  `json:"runCount,omitempty"`
+}
+
+// DungeonSummary is one entry of ListDungeons. The status fields are nil
+// until kro has reconciled the dungeon.
+type DungeonSummary struct {
+	Name           string  `json:"name"`
+	Namespace      string  `json:"namespace"`
+	Difficulty     string  `json:"difficulty"`
+	LivingMonsters *int64  `json:"livingMonsters"`
+	BossState      *string `json:"bossState"`
+	Victory        *bool   `json:"victory"` // true once the final room is cleared
+	Modifier       string  `json:"modifier"`
+	RunCount       int64   `json:"runCount"`
+	Rooms          int64   `json:"rooms"`
+	CurrentRoom    int64   `json:"currentRoom"`
+}
+
+// LeaderboardEntry is one finished run.
+type LeaderboardEntry struct {
+	DungeonName string `json:"dungeonName"`
+	GitHubLogin string `json:"githubLogin,omitempty"`
+	HeroClass   string `json:"heroClass"`
+	Difficulty  string `json:"difficulty"`
+	Outcome     string `json:"outcome"`
+	TotalTurns  int64  `json:"totalTurns"`
+	CurrentRoom int64  `json:"currentRoom"` // deepest room reached
+	Rooms       int64  `json:"rooms,omitempty"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// Profile is the signed-in player's lifetime stats and carried-over gear.
+type Profile struct {
+	DungeonsPlayed      int             `json:"dungeonsPlayed"`
+	DungeonsWon         int             `json:"dungeonsWon"`
+	DungeonsLost        int             `json:"dungeonsLost"`
+	DungeonsAbandoned   int             `json:"dungeonsAbandoned"`
+	TotalTurns          int             `json:"totalTurns"`
+	TotalKills          int             `json:"totalKills"`
+	TotalBossKills      int             `json:"totalBossKills"`
+	FavouriteClass      string          `json:"favouriteClass"`
+	FavouriteDifficulty string          `json:"favouriteDifficulty"`
+	Inventory           model.Inventory `json:"inventory"`
+	WeaponBonus         int64           `json:"weaponBonus"`
+	WeaponUses          int64           `json:"weaponUses"`
+	ArmorBonus          int64           `json:"armorBonus"`
+	ShieldBonus         int64           `json:"shieldBonus"`
+	HelmetBonus         int64           `json:"helmetBonus"`
+	PantsBonus          int64           `json:"pantsBonus"`
+	BootsBonus          int64           `json:"bootsBonus"`
+	RingBonus           int64           `json:"ringBonus"`
+	AmuletBonus         int64           `json:"amuletBonus"`
+	HeroHP              int64           `json:"heroHP"`
+	HeroMana            int64           `json:"heroMana"`
+	EarnedBadges        []string        `json:"earnedBadges"`
+	BadgeCounts         map[string]int  `json:"badgeCounts"`
+	XP                  int             `json:"xp"`
+	Level               int             `json:"level"`
+	KroCertificates     []string        `json:"kroCertificates"`
+	FirstPlayed         string          `json:"firstPlayed"`
+	LastPlayed          string          `json:"lastPlayed"`
+}
+
+// CreateDungeon creates a dungeon and returns it as first written; poll
+// GetDungeon or Subscribe to see kro fill in status.
+func (c *Client) CreateDungeon(ctx context.Context, req CreateDungeonRequest) (*Dungeon, error) {
+	return c.doDungeon(ctx, http.MethodPost, "/dungeons", nil, req)
+}
+
+// ListDungeons returns the caller's dungeons.
+func (c *Client) ListDungeons(ctx context.Context) ([]DungeonSummary, error) {
+	var out []DungeonSummary
+	return out, c.do(ctx, http.MethodGet, "/dungeons", nil, &out)
+}
+
+// GetDungeon returns one dungeon.
+func (c *Client) GetDungeon(ctx context.Context, namespace, name string) (*Dungeon, error) {
+	return c.doDungeon(ctx, http.MethodGet, dungeonPath(namespace, name), nil, nil)
+}
+
+// DeleteDungeon deletes a dungeon, recording it on the leaderboard and the
+// caller's profile.
+func (c *Client) DeleteDungeon(ctx context.Context, namespace, name string) error {
+	return c.do(ctx, http.MethodDelete, dungeonPath(namespace, name), nil, nil)
+}
+
+// Attack strikes target ("<dungeon>-monster-<i>", "<dungeon>-boss", a
+// "-backstab" variant) or uses a combat ability by its older target name
+// ("hero" for a heal, "activate-taunt") and returns the dungeon once kro has
+// resolved the turn. UseAbility is the direct way to use an ability.
+func (c *Client) Attack(ctx context.Context, namespace, name, target string) (*Dungeon, error) {
+	body := map[string]interface{}{"target": target, "damage": 0}
+	return c.submit(ctx, namespace, name, "/attacks", body, func(d *Dungeon) int64 { return d.Spec.AttackSeq })
+}
+
+// UseAbility uses one of the hero's class abilities by action ("heal",
+// "taunt", "backstab"). target is the enemy for abilities aimed at one and
+// ignored otherwise.
+func (c *Client) UseAbility(ctx context.Context, namespace, name, ability, target string) (*Dungeon, error) {
+	body := map[string]interface{}{"ability": ability, "target": target}
+	return c.submit(ctx, namespace, name, "/abilities", body, func(d *Dungeon) int64 { return d.Spec.AttackSeq })
+}
+
+// AbilityStatus is one ability and whether the hero can use it right now;
+// Code and Reason say why not.
+type AbilityStatus struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Action      string `json:"action"`
+	Class       string `json:"class"`
+	ManaCost    int64  `json:"manaCost"`
+	Cooldown    int64  `json:"cooldown"`
+	Target      string `json:"target"`
+	Description string `json:"description"`
+	Usable      bool   `json:"usable"`
+	Code        string `json:"code"`
+	Reason      string `json:"reason"`
+}
+
+// Abilities returns every class ability and whether the dungeon's hero can
+// use it now.
+func (c *Client) Abilities(ctx context.Context, namespace, name string) ([]AbilityStatus, error) {
+	var out struct {
+		Abilities []AbilityStatus `json:"abilities"`
+	}
+	return out.Abilities, c.do(ctx, http.MethodGet, dungeonPath(namespace, name)+"/abilities", nil, &out)
+}
+
+// Objectives returns the dungeon's run objectives and their progress.
+func (c *Client) Objectives(ctx context.Context, namespace, name string) ([]model.Objective, error) {
+	var out struct {
+		Objectives []model.Objective `json:"objectives"`
+	}
+	return out.Objectives, c.do(ctx, http.MethodGet, dungeonPath(namespace, name)+"/objectives", nil, &out)
+}
+
+// Recipe is a crafting recipe: Action "craft-<ID>" swaps Inputs (one copy
+// per listing) for Output.
+type Recipe struct {
+	ID     string   `json:"id"`
+	Inputs []string `json:"inputs"`
+	Output string   `json:"output"`
+}
+
+// Recipes returns every crafting recipe.
+func (c *Client) Recipes(ctx context.Context) ([]Recipe, error) {
+	var out struct {
+		Recipes []Recipe `json:"recipes"`
+	}
+	return out.Recipes, c.do(ctx, http.MethodGet, "/recipes", nil, &out)
+}
+
+// Classes returns the hero classes, in display order.
+func (c *Client) Classes(ctx context.Context) ([]Class, error) {
+	var out struct {
+		Classes []Class `json:"classes"`
+	}
+	return out.Classes, c.do(ctx, http.MethodGet, "/classes", nil, &out)
+}
+
+// Action performs a non-combat move ("use-<item>", "equip-<item>",
+// "unequip-<slot>", "swap-<slot>", "drop-<item>", "craft-<recipe>",
+// "open-treasure", "unlock-door", "enter-room-<n>", or
+// "buy-<item>" and "sell-<item>" while the merchant is open) and returns the
+// dungeon once kro has resolved it.
+func (c *Client) Action(ctx context.Context, namespace, name, action string) (*Dungeon, error) {
+	body := map[string]interface{}{"target": action, "damage": 0}
+	return c.submit(ctx, namespace, name, "/attacks", body, func(d *Dungeon) int64 { return d.Spec.ActionSeq })
+}
+
+// submit posts body to a turn endpoint with the sequence number the server
+// expects. A 409 means another turn landed in between (another tab, an
+// auto-battle); the dungeon is re-read and the move resubmitted. A transport
+// error is retried the same way.
+//
+// One Idempotency-Key covers the whole turn. A rejected attempt releases its
+// claim, so the key may be resent with a fresh seq; an attempt whose response
+// was lost but which did run is then answered with a 422 for the changed body,
+// and the turn is reported as done.
+func (c *Client) submit(ctx context.Context, namespace, name, endpoint string, body map[string]interface{}, seqOf func(*Dungeon) int64) (*Dungeon, error) {
+	path := dungeonPath(namespace, name) + endpoint
+	hdr := http.Header{"Idempotency-Key": {uuid.NewString()}}
+	lost := false
+	for attempt := 0; ; attempt++ {
+		cur, err := c.GetDungeon(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		body["seq"] = seqOf(cur)
+		d, err := c.doDungeon(ctx, http.MethodPost, path, hdr, body)
+		var apiErr *APIError
+		switch {
+		case err == nil:
+			return d, nil
+		case lost && IsStatus(err, http.StatusUnprocessableEntity):
+			return c.GetDungeon(ctx, namespace, name)
+		case attempt >= c.staleRetries || ctx.Err() != nil:
+			return nil, err
+		case !errors.As(err, &apiErr):
+			lost = true
+		case apiErr.StatusCode != http.StatusConflict:
+			return nil, err
+		}
+	}
+}
+
+// User is the signed-in account.
+type User struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatarUrl"`
+	Admin     bool   `json:"admin"`
+}
+
+// Me returns the account the client's credentials belong to, or a 401
+// APIError when they are missing or invalid.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var out User
+	if err := c.do(ctx, http.MethodGet, "/auth/me", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Leaderboard returns recent finished runs.
+func (c *Client) Leaderboard(ctx context.Context) ([]LeaderboardEntry, error) {
+	var out []LeaderboardEntry
+	return out, c.do(ctx, http.MethodGet, "/leaderboard", nil, &out)
+}
+
+// Profile returns the caller's profile.
+func (c *Client) Profile(ctx context.Context) (*Profile, error) {
+	var out Profile
+	if err := c.do(ctx, http.MethodGet, "/profile", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CELError is a CEL expression that failed to compile or evaluate.
+type CELError struct{ Message string }
+
+func (e *CELError) Error() string { return "cel: " + e.Message }
+
+// EvalCEL evaluates expr against the dungeon (schema.spec, schema.status,
+// schema.metadata) and returns the result formatted as the kro inspector
+// shows it. An invalid expression returns a *CELError.
+func (c *Client) EvalCEL(ctx context.Context, namespace, name, expr string) (string, error) {
+	var out struct {
+		Result string `json:"result"`
+		Error  string `json:"error"`
+	}
+	if err := c.do(ctx, http.MethodPost, dungeonPath(namespace, name)+"/cel-eval", map[string]string{"expr": expr}, &out); err != nil {
+		return "", err
+	}
+	if out.Error != "" {
+		return "", &CELError{Message: out.Error}
+	}
+	return out.Result, nil
+}
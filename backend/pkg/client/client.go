@@ -0,0 +1,174 @@
+// Package client is a Go client for the Krombat backend API (/api/v1) and its
+// WebSocket event stream. Bots and test harnesses should use it rather than
+// hand-rolling requests against /api/v1/dungeons/.../attacks.
+//
+//	c := client.New("https://learn-kro.eks.aws.dev", client.WithToken(os.Getenv("KROMBAT_TOKEN")))
+//	d, err := c.CreateDungeon(ctx, client.CreateDungeonRequest{Name: "lair", Monsters: 3, Difficulty: "easy"})
+//	d, err = c.Attack(ctx, d.Namespace, d.Name, d.Name+"-monster-0")
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pnz1990/krombat/backend/internal/catalog"
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"github.com/pnz1990/krombat/backend/internal/ws"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Dungeon is the typed Dungeon CR returned by the API.
+type Dungeon = model.Dungeon
+
+// Class is a hero class from GET /api/v1/classes.
+type Class = catalog.Class
+
+// Event is one message from the /api/v1/events stream.
+type Event = ws.Event
+
+// sessionCookieName matches the cookie the backend sets after GitHub sign-in.
+const sessionCookieName = "krombat_session"
+
+// DefaultStaleRetries is how many times Attack and Action re-read the dungeon
+// and resubmit after a 409 stale-sequence conflict or a transport error.
+const DefaultStaleRetries = 3
+
+// Client talks to one Krombat backend. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	token        string
+	session      string
+	staleRetries int
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken authenticates with a personal API token (Authorization: Bearer).
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithSessionCookie authenticates with a browser session cookie value.
+func WithSessionCookie(value string) Option {
+	return func(c *Client) { c.session = value }
+}
+
+// WithHTTPClient replaces the default http.Client (30 s timeout).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithStaleRetries sets how many 409 conflicts and transport errors Attack
+// and Action absorb before returning the error. 0 disables retrying.
+func WithStaleRetries(n int) Option {
+	return func(c *Client) { c.staleRetries = n }
+}
+
+// New returns a client for the backend at baseURL (scheme and host, no /api/v1).
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		staleRetries: DefaultStaleRetries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a non-2xx response. Message is the backend's text/plain body.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("krombat: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsStatus reports whether err is an APIError with the given status code.
+func IsStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// authorize adds the configured credentials to a request's headers.
+func (c *Client) authorize(h http.Header) {
+	if c.token != "" {
+		h.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.session != "" {
+		h.Add("Cookie", (&http.Cookie{Name: sessionCookieName, Value: c.session}).String())
+	}
+}
+
+// do sends a request to /api/v1+path and decodes a JSON response into out
+// (which may be nil).
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.send(ctx, method, path, nil, body, out)
+}
+
+// send is do with extra request headers.
+func (c *Client) send(ctx context.Context, method, path string, hdr http.Header, body, out interface{}) error {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, rd)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	c.authorize(req.Header)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("krombat: decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// doDungeon is do for endpoints that return a Dungeon CR.
+func (c *Client) doDungeon(ctx context.Context, method, path string, hdr http.Header, body interface{}) (*Dungeon, error) {
+	var obj map[string]interface{}
+	if err := c.send(ctx, method, path, hdr, body, &obj); err != nil {
+		return nil, err
+	}
+	return model.FromUnstructured(&unstructured.Unstructured{Object: obj})
+}
+
+func dungeonPath(namespace, name string) string {
+	return "/dungeons/" + url.PathEscape(namespace) + "/" + url.PathEscape(name)
+}
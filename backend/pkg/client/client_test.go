@@ -0,0 +1,186 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/pnz1990/krombat/backend/pkg/client"
+)
+
+func dungeonJSON(attackSeq int64, heroHP int64) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "game.k8s.example/v1alpha1",
+		"kind":       "Dungeon",
+		"metadata":   map[string]interface{}{"name": "lair", "namespace": "default"},
+		"spec":       map[string]interface{}{"monsters": 2, "difficulty": "easy", "attackSeq": attackSeq},
+		"status":     map[string]interface{}{"game": map[string]interface{}{"heroHP": heroHP}},
+	}
+}
+
+func TestAttackRetriesStaleSeq(t *testing.T) {
+	// Another player's turn lands between our read and our submit once.
+	serverSeq, posts := int64(4), 0
+	keys := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer kpat_test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/dungeons/default/lair":
+			json.NewEncoder(w).Encode(dungeonJSON(serverSeq, 100))
+		case r.Method == "POST" && r.URL.Path == "/api/v1/dungeons/default/lair/attacks":
+			posts++
+			if r.Header.Get("Idempotency-Key") == "" {
+				t.Error("attack sent without an Idempotency-Key")
+			}
+			keys[r.Header.Get("Idempotency-Key")] = true
+			var req struct {
+				Target string `json:"target"`
+				Seq    int64  `json:"seq"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if posts == 1 {
+				serverSeq++
+			}
+			if req.Seq != serverSeq {
+				http.Error(w, "stale request — dungeon state has changed, please retry", http.StatusConflict)
+				return
+			}
+			serverSeq++
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(dungeonJSON(serverSeq, 90))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL, client.WithToken("kpat_test"))
+	d, err := c.Attack(context.Background(), "default", "lair", "lair-monster-0")
+	if err != nil {
+		t.Fatalf("Attack: %v", err)
+	}
+	if posts != 2 || d.Spec.AttackSeq != 6 || d.Status.Game.HeroHP != 90 {
+		t.Errorf("posts = %d, attackSeq = %d, heroHP = %d", posts, d.Spec.AttackSeq, d.Status.Game.HeroHP)
+	}
+	if len(keys) != 1 {
+		t.Errorf("the retried turn used %d Idempotency-Keys, want 1", len(keys))
+	}
+
+	// With retries off the conflict surfaces as an APIError.
+	posts = 0
+	c = client.New(srv.URL, client.WithToken("kpat_test"), client.WithStaleRetries(0))
+	if _, err := c.Attack(context.Background(), "default", "lair", "lair-monster-0"); !client.IsStatus(err, http.StatusConflict) {
+		t.Errorf("err = %v, want a 409 APIError", err)
+	}
+}
+
+func TestAttackLostResponse(t *testing.T) {
+	// The first attack runs but its response never arrives. The resend carries
+	// the same key with the new seq, which the server rejects as a reused key.
+	serverSeq, posts := int64(4), 0
+	var firstKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			json.NewEncoder(w).Encode(dungeonJSON(serverSeq, 100-10*(serverSeq-4)))
+		case r.Method == "POST":
+			posts++
+			key := r.Header.Get("Idempotency-Key")
+			if posts == 1 {
+				firstKey = key
+				serverSeq++
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			if key != firstKey {
+				t.Errorf("resend key = %q, want %q", key, firstKey)
+			}
+			http.Error(w, "Idempotency-Key was already used with a different request body", http.StatusUnprocessableEntity)
+		}
+	}))
+	defer srv.Close()
+
+	// Fresh connections keep net/http from resending the POST on its own.
+	hc := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	c := client.New(srv.URL, client.WithToken("kpat_test"), client.WithHTTPClient(hc))
+	d, err := c.Attack(context.Background(), "default", "lair", "lair-monster-0")
+	if err != nil {
+		t.Fatalf("Attack: %v", err)
+	}
+	if posts != 2 || d.Spec.AttackSeq != 5 || d.Status.Game.HeroHP != 90 {
+		t.Errorf("posts = %d, attackSeq = %d, heroHP = %d, want the turn reported once", posts, d.Spec.AttackSeq, d.Status.Game.HeroHP)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("krombat_session"); err != nil || c.Value != "sess" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("name") != "lair" {
+			t.Errorf("query = %q", r.URL.RawQuery)
+		}
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteJSON(client.Event{Type: client.EventDungeonUpdate, Action: "MODIFIED", Name: "lair", Namespace: "default", Payload: dungeonJSON(3, 42)})
+		conn.ReadMessage() // hold the connection until the client closes it
+	}))
+	defer srv.Close()
+
+	if _, err := client.New(srv.URL).Subscribe(context.Background(), "default", "lair"); !client.IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("unauthenticated Subscribe err = %v, want 401", err)
+	}
+
+	sub, err := client.New(srv.URL, client.WithSessionCookie("sess")).Subscribe(context.Background(), "default", "lair")
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+	ev, err := sub.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	d, err := client.DungeonFromEvent(ev)
+	if err != nil {
+		t.Fatalf("DungeonFromEvent: %v", err)
+	}
+	if ev.Action != "MODIFIED" || d.Name != "lair" || d.Status.Game.HeroHP != 42 {
+		t.Errorf("event = %+v, dungeon = %+v", ev, d)
+	}
+	if _, err := client.DungeonFromEvent(client.Event{Type: client.EventReconcileDiff}); err != client.ErrNoDungeon {
+		t.Errorf("DungeonFromEvent(RECONCILE_DIFF) err = %v", err)
+	}
+}
+
+func TestEvalCEL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Expr string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Expr == "bad(" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "syntax error"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"result": "true"})
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL)
+	if got, err := c.EvalCEL(context.Background(), "default", "lair", "1 < 2"); err != nil || got != "true" {
+		t.Errorf("EvalCEL = %q, %v", got, err)
+	}
+	var celErr *client.CELError
+	if _, err := c.EvalCEL(context.Background(), "default", "lair", "bad("); !errors.As(err, &celErr) {
+		t.Errorf("EvalCEL(bad) err = %v, want *CELError", err)
+	}
+}
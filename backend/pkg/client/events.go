@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/pnz1990/krombat/backend/internal/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Event types sent on the stream.
+const (
+	EventDungeonUpdate = "DUNGEON_UPDATE"
+	EventAttack        = "ATTACK_EVENT"
+	EventReconcileDiff = "RECONCILE_DIFF"
+)
+
+// ErrNoDungeon is returned by DungeonFromEvent for events that do not carry a
+// Dungeon object.
+var ErrNoDungeon = errors.New("krombat: event does not carry a dungeon")
+
+// Subscription is an open /api/v1/events stream.
+type Subscription struct {
+	conn      *websocket.Conn
+	closeOnce sync.Once
+	stop      chan struct{}
+}
+
+// Subscribe opens the event stream, filtered to one dungeon when namespace
+// and name are set (either may be empty to widen the filter). The stream is
+// closed when ctx is done or Close is called.
+func (c *Client) Subscribe(ctx context.Context, namespace, name string) (*Subscription, error) {
+	u, err := url.Parse(c.baseURL + "/api/v1/events")
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	q := url.Values{}
+	if namespace != "" {
+		q.Set("namespace", namespace)
+	}
+	if name != "" {
+		q.Set("name", name)
+	}
+	u.RawQuery = q.Encode()
+
+	header := http.Header{}
+	c.authorize(header)
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: err.Error()}
+		}
+		return nil, err
+	}
+	s := &Subscription{conn: conn, stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.stop:
+		}
+	}()
+	return s, nil
+}
+
+// Next blocks until the next event arrives. It returns an error once the
+// stream is closed.
+func (s *Subscription) Next() (Event, error) {
+	var ev Event
+	err := s.conn.ReadJSON(&ev)
+	return ev, err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// DungeonFromEvent decodes the Dungeon carried by a DUNGEON_UPDATE event.
+func DungeonFromEvent(ev Event) (*Dungeon, error) {
+	obj, ok := ev.Payload.(map[string]interface{})
+	if ev.Type != EventDungeonUpdate || !ok {
+		return nil, ErrNoDungeon
+	}
+	return model.FromUnstructured(&unstructured.Unstructured{Object: obj})
+}
@@ -1,6 +1,6 @@
-import { useState, useEffect, useCallback, useRef, type MutableRefObject, type ReactNode } from 'react'
+import { Fragment, useState, useEffect, useCallback, useRef, type MutableRefObject, type ReactNode } from 'react'
 import { useParams, useNavigate } from 'react-router-dom'
-import { DungeonSummary, DungeonCR, listDungeons, getDungeon, createDungeon, createNewGamePlus, submitAttack, deleteDungeon, ApiError, LeaderboardEntry, getLeaderboard, UserProfile, getProfile, awardCert, reportError, trackEvent, getMe, logout, AuthUser } from './api'
+import { DungeonSummary, DungeonCR, listDungeons, getDungeon, createDungeon, createNewGamePlus, submitAttack, deleteDungeon, ApiError, LeaderboardEntry, getLeaderboard, UserProfile, getProfile, awardCert, reportError, trackEvent, getMe, logout, AuthUser, startAutoBattle, stopAutoBattle, Recipe, listRecipes, HeroClass, listClasses, Achievement, listAchievements, ItemStack } from './api'
 import { useWebSocket, WSEvent } from './useWebSocket'
 
 import { Sprite, getMonsterSprite, getMonsterName, SpriteAction, ItemSprite } from './Sprite'
@@ -70,18 +70,26 @@ function computeAchievements(spec: any, game: any, maxHeroHP: number) {
     { id: 'warrior-win', name: 'War Chief', icon: 'sword', earned: heroClass === 'warrior', desc: 'Won as Warrior' },
     { id: 'mage-win', name: 'Archmage', icon: 'mana', earned: heroClass === 'mage', desc: 'Won as Mage' },
     { id: 'rogue-win', name: 'Shadow', icon: 'dagger', earned: heroClass === 'rogue', desc: 'Won as Rogue' },
+    { id: 'paladin-win', name: 'Crusader', icon: 'shield', earned: heroClass === 'paladin', desc: 'Won as Paladin' },
+    { id: 'ranger-win', name: 'Deadeye', icon: 'lightning', earned: heroClass === 'ranger', desc: 'Won as Ranger' },
     { id: 'hard-win', name: 'Nightmare', icon: 'skull', earned: difficulty === 'hard', desc: 'Won on Hard difficulty' },
     { id: 'collector', name: 'Hoarder', icon: 'chest', earned: equippedCount >= 5, desc: `Won with ${equippedCount}/5 items equipped` },
    ]
 }
 
-// parseInventory parses a JSON array inventory string.
-// Returns an empty array for null/empty/invalid input.
-function parseInventory(inv: string | undefined | null): string[] {
+// parseInventory lists one item ID per copy of a stacked inventory. It also
+// takes the JSON array string of IDs that merchantStock and dungeons not yet
+// migrated still use. Returns an empty array for null/empty/invalid input.
+function parseInventory(inv: ItemStack[] | string | undefined | null): string[] {
   if (!inv) return []
+  if (Array.isArray(inv)) return inv.flatMap(s => Array<string>(s.quantity).fill(s.id))
   try { return JSON.parse(inv) as string[] } catch { return [] }
 }
 
+// Merchant prices by rarity — mirrors the price table in dungeon-graph's
+// combatResolve/actionResolve. Selling fetches half.
+const ITEM_PRICE: Record<string, number> = { common: 20, rare: 50, epic: 120 }
+
 function AchievementBadges({ achievements }: { achievements: ReturnType<typeof computeAchievements> }) {
   const earned = achievements.filter(a => a.earned)
   if (earned.length === 0) return null
@@ -209,16 +217,17 @@ export default function App() {
   // Tier 2 certificate trigger (#361) — called from UI interaction callbacks
   const handleCertTrigger = useCallback(async (certId: string) => {
     if (profile?.kroCertificates?.includes(certId)) return // already earned (persisted)
+    if (!detail) return // the backend checks the claim against the open dungeon
     if (certTriggeredThisSessionRef.current.has(certId)) return // already triggered this session
     certTriggeredThisSessionRef.current.add(certId)
-    const updated = await awardCert(certId)
+    const updated = await awardCert(certId, detail.metadata.namespace, detail.metadata.name)
     if (!updated) return
     setProfile(prev => prev ? { ...prev, kroCertificates: updated } : prev)
     // Show toast
     if (certToastTimerRef.current) clearTimeout(certToastTimerRef.current)
     setCertToast(certId)
     certToastTimerRef.current = setTimeout(() => setCertToast(null), 4000)
-  }, [profile])
+  }, [profile, detail])
 
   // Auto-surface CEL Playground once the player is engaged (10+ concepts unlocked)
   const playgroundFiredRef = useRef(false)
@@ -376,13 +385,13 @@ export default function App() {
     }
   }, [selected])
 
-  const handleCreate = async (name: string, monsters: number, difficulty: string, heroClass: string, onSuccess: () => void) => {
+  const handleCreate = async (name: string, monsters: number, difficulty: string, heroClass: string, rooms: number, onSuccess: () => void) => {
     setError('')
     try {
-      await createDungeon(name, monsters, difficulty, heroClass, 'default')
+      await createDungeon(name, monsters, difficulty, heroClass, 'default', rooms)
       trackEvent('dungeon_created', { monsters, difficulty, heroClass })
       addK8s(`kubectl apply -f dungeon.yaml`, 'dungeon.game.k8s.example created',
-        `apiVersion: game.k8s.example/v1alpha1\nkind: Dungeon\nmetadata:\n  name: ${name}\nspec:\n  monsters: ${monsters}\n  difficulty: ${difficulty}\n  heroClass: ${heroClass}`)
+        `apiVersion: game.k8s.example/v1alpha1\nkind: Dungeon\nmetadata:\n  name: ${name}\nspec:\n  monsters: ${monsters}\n  difficulty: ${difficulty}\n  heroClass: ${heroClass}\n  rooms: ${rooms}`)
       triggerInsight('dungeon-created')
       triggerInsight('spec-schema')
       // forEach is always in play when creating a dungeon with multiple monsters
@@ -418,7 +427,8 @@ export default function App() {
     }
     setError('')
     const isAbility = target === 'hero' || target === 'activate-taunt'
-    const isItem = target.startsWith('use-') || target.startsWith('equip-') || target === 'open-treasure' || target === 'unlock-door' || target === 'enter-room-2'
+    const enterRoom = target.startsWith('enter-room-') ? parseInt(target.slice('enter-room-'.length)) : 0
+    const isItem = target.startsWith('use-') || target.startsWith('equip-') || target.startsWith('buy-') || target.startsWith('sell-') || target.startsWith('craft-') || target.startsWith('drop-') || target.startsWith('unequip-') || target.startsWith('swap-') || target === 'open-treasure' || target === 'unlock-door' || enterRoom > 0
     const shortTarget = (isAbility || isItem) ? target : target.replace(/-backstab$/, '').split('-').slice(-2).join('-')
     try {
       setAttackTarget(target.replace(/-backstab$/, ''))
@@ -457,17 +467,17 @@ export default function App() {
         // (status.game.actionProcessedSeq reaches the new actionSeq).
         // State nodes write results to status.game — spec trigger fields are NOT
         // cleared, so we compare ProcessedSeq values instead.
-        // For enter-room-2: also wait for room2ProcessedSeq to advance (enterRoom2Resolve fires
-        // on the next reconcile after actionResolve sets currentRoom=2).
+        // For enter-room-<n>: also wait for resolvedRoom to reach n (enterRoomResolve fires
+        // on the next reconcile after actionResolve sets currentRoom=n).
         const prevSeq = detail?.spec.actionSeq ?? 0
-        if (target === 'enter-room-2') setRoomLoading(true)
+        if (enterRoom) setRoomLoading(true)
         for (let attempt = 0; attempt < 40; attempt++) {
           await new Promise(r => setTimeout(r, 1500))
           const current = await getDungeon(selected.ns, selected.name)
           const currentGame = getGame(current)
           const seqAdvanced = (current.spec.actionSeq || 0) > prevSeq && (currentGame.actionProcessedSeq || 0) >= (current.spec.actionSeq || 0)
-          const room2Ready = target !== 'enter-room-2' || (currentGame.room2ProcessedSeq || 0) >= (current.spec.actionSeq || 0)
-          if (seqAdvanced && room2Ready) {
+          const roomReady = !enterRoom || (currentGame.resolvedRoom || 0) >= enterRoom
+          if (seqAdvanced && roomReady) {
             updated = current
             break
           }
@@ -480,16 +490,16 @@ export default function App() {
         setAttackTarget(null)
         attackingRef.current = false
         // Teach specific item/room events
-        if (target === 'enter-room-2') {
-          triggerInsight('enter-room-2')
-          // #444: K8s log entry for room transition — enterRoom2Resolve state node fired
+        if (enterRoom) {
+          triggerInsight('enter-room')
+          // #444: K8s log entry for room transition — enterRoomResolve state node fired
           const updatedGame = getGame(updated)
-          const newMonHP = updatedGame.room2MonsterHP?.join(',') ?? '...'
-          const newBossHP = updatedGame.room2BossHP ?? '...'
+          const newMonHP = updatedGame.roomMonsterHP?.join(',') ?? '...'
+          const newBossHP = updatedGame.roomBossHP ?? '...'
           addK8s(
-            `kubectl patch dungeon ${selected.name} --type=merge -p '{"spec":{"enterRoom2":1}}'`,
-            `enterRoom2Resolve state node fired — status.game.monsterHP: [${newMonHP}], status.game.bossHP: ${newBossHP}`,
-            `# dungeon-graph.yaml — enterRoom2Resolve state node\ntype: stateNode\npatch:\n  currentRoom: 2\n  monsterHP: "<scaled ×1.5 via CEL>"\n  bossHP: "<scaled ×1.3 via CEL>"`
+            `kubectl patch dungeon ${selected.name} --type=merge -p '{"spec":{"lastAction":"${target}"}}'`,
+            `enterRoomResolve state node fired — status.game.monsterHP: [${newMonHP}], status.game.bossHP: ${newBossHP}`,
+            `# dungeon-graph.yaml — enterRoomResolve state node\ntype: stateNode\npatch:\n  currentRoom: ${enterRoom}\n  monsterHP: "<scaled ×${(enterRoom + 1) / 2} via CEL>"\n  bossHP: "<scaled ×${(7 + 3 * enterRoom) / 10} via CEL>"`
           )
         }
         if (target === 'open-treasure') triggerInsight('treasure-opened')
@@ -814,6 +824,7 @@ export default function App() {
     try {
       await createNewGamePlus(newName, spec.monsters ?? 3, spec.difficulty ?? 'normal', spec.heroClass ?? 'warrior', {
         runCount,
+        rooms: spec.rooms ?? 2,
         // *Bonus fields intentionally omitted — gear starts in backpack (inventory),
         // player re-equips each run (#555). Backend also ignores these fields now.
       }, ns)
@@ -893,7 +904,7 @@ export default function App() {
               )}
             </div>
           </div>
-          <CreateForm onCreate={(n, m, d, c, onSuccess) => handleCreate(n, m, d, c, onSuccess)} />
+          <CreateForm onCreate={(n, m, d, c, r, onSuccess) => handleCreate(n, m, d, c, r, onSuccess)} />
           {resumePrompt && (
             <div className="card" style={{ borderColor: '#f5c518', display: 'flex', justifyContent: 'space-between', alignItems: 'center', gap: 8, padding: '8px 12px' }}>
               <span style={{ fontSize: '8px', color: '#f5c518' }}>Resume last dungeon: <strong>{resumePrompt.name}</strong>?</span>
@@ -1192,11 +1203,21 @@ function FaqModal({ onClose }: { onClose: () => void }) {
   )
 }
 
-function CreateForm({ onCreate }: { onCreate: (n: string, m: number, d: string, c: string, onSuccess: () => void) => void }) {
+// The hero class registry from GET /classes, fetched once per page load.
+let heroClassesPromise: Promise<HeroClass[]> | null = null
+function useHeroClasses(): HeroClass[] {
+  const [classes, setClasses] = useState<HeroClass[]>([])
+  useEffect(() => { (heroClassesPromise ??= listClasses()).then(setClasses) }, [])
+  return classes
+}
+
+function CreateForm({ onCreate }: { onCreate: (n: string, m: number, d: string, c: string, r: number, onSuccess: () => void) => void }) {
   const [name, setName] = useState('')
   const [monsters, setMonsters] = useState(3)
   const [difficulty, setDifficulty] = useState('normal')
   const [heroClass, setHeroClass] = useState('warrior')
+  const heroClasses = useHeroClasses()
+  const [rooms, setRooms] = useState(2)
   const dnsLabelRegex = /^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$/
   const nameValid = name === '' || dnsLabelRegex.test(name)
   const monstersValid = monsters >= 1 && monsters <= 10
@@ -1226,10 +1247,16 @@ function CreateForm({ onCreate }: { onCreate: (n: string, m: number, d: string,
       </div>
       <div><label>Hero Class</label>
         <select value={heroClass} onChange={e => setHeroClass(e.target.value)}>
-          <option value="warrior">Warrior</option><option value="mage">Mage</option><option value="rogue">Rogue</option>
+          {heroClasses.length === 0 && <option value="warrior">Warrior</option>}
+          {heroClasses.map(c => <option key={c.id} value={c.id}>{c.name}</option>)}
+        </select>
+      </div>
+      <div><label>Rooms</label>
+        <select value={rooms} onChange={e => setRooms(+e.target.value)}>
+          {[2, 3, 4, 5].map(n => <option key={n} value={n}>{n}</option>)}
         </select>
       </div>
-      <button className="btn btn-gold" disabled={!canCreate} onClick={() => { if (canCreate) { onCreate(name, monsters, difficulty, heroClass, () => setName('')) } }}>
+      <button className="btn btn-gold" disabled={!canCreate} onClick={() => { if (canCreate) { onCreate(name, monsters, difficulty, heroClass, rooms, () => setName('')) } }}>
         Create Dungeon
       </button>
     </div>
@@ -1284,7 +1311,7 @@ function DungeonList({ dungeons, onSelect, onDelete, deleting, lastDungeon }: {
   )
 }
 
-const CLASS_ICON: Record<string, string> = { warrior: 'sword', mage: 'mana', rogue: 'dagger' }
+const CLASS_ICON: Record<string, string> = { warrior: 'sword', mage: 'mana', rogue: 'dagger', paladin: 'shield', ranger: 'lightning' }
 
 function LeaderboardPanel({ entries, loading, onClose }: {
   entries: LeaderboardEntry[]; loading: boolean; onClose: () => void
@@ -1325,6 +1352,7 @@ function LeaderboardPanel({ entries, loading, onClose }: {
                 <th>Player</th>
                 <th>Class</th>
                 <th>Difficulty</th>
+                <th>Rooms</th>
                 <th>Turns</th>
               </tr>
             </thead>
@@ -1344,6 +1372,7 @@ function LeaderboardPanel({ entries, loading, onClose }: {
                   </td>
                   <td><PixelIcon name={CLASS_ICON[e.heroClass] ?? 'sword'} size={10} /></td>
                   <td><span className={`tag tag-${e.difficulty}`}>{e.difficulty}</span></td>
+                  <td className="lb-rooms">{e.currentRoom || e.rooms || 2}</td>
                   <td className="lb-turns">{e.totalTurns}</td>
                 </tr>
               ))}
@@ -1361,18 +1390,20 @@ function LeaderboardPanel({ entries, loading, onClose }: {
 const BADGE_LABELS: Record<string, string> = {
   speedrun: 'Speedrunner', deathless: 'Untouchable', pacifist: 'Potionist',
   'warrior-win': 'War Chief', 'mage-win': 'Archmage', 'rogue-win': 'Shadow',
+  'paladin-win': 'Crusader', 'ranger-win': 'Deadeye',
   'hard-win': 'Nightmare', collector: 'Hoarder',
   'room2-winner': 'Dungeon Diver', 'no-damage': 'Flawless', 'multi-class': 'Versatile',
   reaper: 'Reaper', legend: 'Legend', 'new-game-plus': 'Ascendant',
-  'no-potions': 'Iron Will', 'full-kit': 'Fully Loaded',
+  'no-potions': 'Iron Will', 'full-kit': 'Fully Loaded', 'deep-delver': 'Deep Delver',
 }
 const BADGE_ICONS: Record<string, string> = {
   speedrun: 'lightning', deathless: 'shield', pacifist: 'potion',
   'warrior-win': 'sword', 'mage-win': 'mana', 'rogue-win': 'dagger',
+  'paladin-win': 'shield', 'ranger-win': 'lightning',
   'hard-win': 'skull', collector: 'chest',
   'room2-winner': 'chest', 'no-damage': 'shield', 'multi-class': 'crown',
   reaper: 'skull', legend: 'crown', 'new-game-plus': 'lightning',
-  'no-potions': 'heart', 'full-kit': 'armor',
+  'no-potions': 'heart', 'full-kit': 'armor', 'deep-delver': 'key',
 }
 const ALL_BADGES = Object.keys(BADGE_LABELS)
 
@@ -1386,7 +1417,7 @@ const CERT_REGISTRY: CertDef[] = [
   // Tier 1 — Observer (awarded automatically by backend on run completion)
   { id: 'first-dungeon',      name: 'Dungeon Architect',       tier: 1, icon: 'helm',    hint: 'Create your first dungeon (a Kubernetes CR!)' },
   { id: 'cel-state',          name: 'CEL State Machine',       tier: 1, icon: 'mana',    hint: 'Win a dungeon (kro CEL computed victory=true)' },
-  { id: 'two-rooms',          name: 'Graph Traverser',         tier: 1, icon: 'door',    hint: 'Clear both rooms (traverse the full resource graph)' },
+  { id: 'two-rooms',          name: 'Graph Traverser',         tier: 1, icon: 'door',    hint: 'Clear every room (traverse the full resource graph)' },
   { id: 'loot-system',        name: 'Resource Graph Explorer', tier: 1, icon: 'chest',   hint: 'Equip 3+ different item types in one run' },
   // Tier 2 — Practitioner (awarded by POST /api/v1/profile/cert from frontend interactions)
   { id: 'log-explorer',       name: 'Log Explorer',            tier: 2, icon: 'scroll',  hint: 'Open the K8s Log Tab for the first time' },
@@ -1400,7 +1431,7 @@ const CERT_REGISTRY: CertDef[] = [
   { id: 'modifier-master',    name: 'Modifier Master',         tier: 3, icon: 'skull',   hint: 'Win on Hard with a Curse modifier active' },
   { id: 'new-game-plus-cert', name: 'Ascendant Architect',     tier: 3, icon: 'crown',   hint: 'Win a New Game+ run' },
   { id: 'cel-scholar',        name: 'CEL Scholar',             tier: 3, icon: 'mana',    hint: 'Reach Level 5 (XP system)' },
-  { id: 'dungeon-master',     name: 'Dungeon Master',          tier: 3, icon: 'trophy',  hint: 'Win 5 dungeons total across different classes' },
+  { id: 'dungeon-master',     name: 'Dungeon Master',          tier: 3, icon: 'trophy',  hint: 'Win 5 dungeons total, with at least 2 different classes' },
 ]
 const TIER_LABELS: Record<number, string> = { 1: 'Tier 1 — Observer', 2: 'Tier 2 — Practitioner', 3: 'Tier 3 — Architect' }
 
@@ -1410,7 +1441,28 @@ function ProfilePanel({ profile, loading, authUser, onClose }: {
   const login = authUser?.login ?? 'anonymous'
   const avatarUrl = authUser?.avatarUrl ?? ''
   const earnedSet = new Set(profile?.earnedBadges ?? [])
-  const inventoryItems = parseInventory(profile?.inventory)
+  // Definitions come from GET /achievements so ConfigMap-added or retitled
+  // ones show as served; the built-in tables are the fallback and supply icons.
+  const [achievements, setAchievements] = useState<Achievement[]>([])
+  useEffect(() => { listAchievements().then(setAchievements) }, [])
+  const badgeLabels: Record<string, string> = { ...BADGE_LABELS }
+  const badgeIcons: Record<string, string> = { ...BADGE_ICONS }
+  for (const a of achievements) {
+    if (a.kind === 'certificate') continue
+    badgeLabels[a.id] = a.title || BADGE_LABELS[a.id]
+    badgeIcons[a.id] = BADGE_ICONS[a.id] ?? a.icon ?? 'star'
+  }
+  const allBadges = achievements.length
+    ? achievements.filter(a => a.kind !== 'certificate').map(a => a.id)
+    : ALL_BADGES
+  const certRegistry: CertDef[] = achievements.length
+    ? achievements.filter(a => a.kind === 'certificate').map(a => {
+        const builtIn = CERT_REGISTRY.find(c => c.id === a.id)
+        return { id: a.id, name: a.title || builtIn?.name || a.id, tier: a.tier ?? builtIn?.tier ?? 1,
+          hint: a.description || builtIn?.hint || '', icon: builtIn?.icon ?? a.icon ?? 'star' }
+      })
+    : CERT_REGISTRY
+  const backpack = profile?.inventory ?? []
 
   return (
     <div className="leaderboard-overlay" role="dialog" aria-label="Player Profile">
@@ -1478,14 +1530,14 @@ function ProfilePanel({ profile, loading, authUser, onClose }: {
                 </div>
 
                 {/* Persistent backpack */}
-                {inventoryItems.length > 0 && (
+                {backpack.length > 0 && (
                   <div style={{ marginBottom: 10 }}>
                     <div style={{ fontSize: '7px', color: 'var(--text-dim)', marginBottom: 4, letterSpacing: '0.05em' }}>PERSISTENT BACKPACK</div>
                     <div style={{ display: 'flex', flexWrap: 'wrap', gap: 4 }}>
-                      {inventoryItems.map((item, i) => (
-                        <div key={i} title={item} style={{ display: 'flex', flexDirection: 'column', alignItems: 'center', gap: 2, background: 'var(--panel-bg)', border: '1px solid var(--border)', padding: '4px 6px', borderRadius: 2 }}>
-                          <ItemSprite id={item} size={20} />
-                          <span style={{ fontSize: '6px', color: 'var(--text-dim)' }}>{item.replace('-', ' ')}</span>
+                      {backpack.map((stack, i) => (
+                        <div key={i} title={stack.durability ? `${stack.id} (${stack.durability} uses left)` : stack.id} style={{ display: 'flex', flexDirection: 'column', alignItems: 'center', gap: 2, background: 'var(--panel-bg)', border: '1px solid var(--border)', padding: '4px 6px', borderRadius: 2 }}>
+                          <ItemSprite id={stack.id} size={20} />
+                          <span style={{ fontSize: '6px', color: 'var(--text-dim)' }}>{stack.id.replace('-', ' ')}{stack.quantity > 1 ? ` ×${stack.quantity}` : ''}</span>
                         </div>
                       ))}
                     </div>
@@ -1495,26 +1547,26 @@ function ProfilePanel({ profile, loading, authUser, onClose }: {
                 {/* Badges */}
                 <div style={{ marginBottom: 6 }}>
                   <div style={{ fontSize: '7px', color: 'var(--text-dim)', marginBottom: 4, letterSpacing: '0.05em' }}>
-                    BADGES — {earnedSet.size} / {ALL_BADGES.length}
+                    BADGES — {earnedSet.size} / {allBadges.length}
                   </div>
                   <div style={{ display: 'flex', flexWrap: 'wrap', gap: 4 }}>
-                    {ALL_BADGES.map(id => {
+                    {allBadges.map(id => {
                       const earned = earnedSet.has(id)
                       const count = profile.badgeCounts?.[id] ?? 0
                       return (
                         <div key={id}
-                          title={BADGE_LABELS[id] + (earned && count > 1 ? ` ×${count}` : '') + (earned ? '' : ' — not yet earned')}
+                          title={badgeLabels[id] + (earned && count > 1 ? ` ×${count}` : '') + (earned ? '' : ' — not yet earned')}
                           style={{
                             display: 'flex', flexDirection: 'column', alignItems: 'center', gap: 2,
                             background: 'var(--panel-bg)', border: `1px solid ${earned ? 'var(--gold)' : 'var(--border)'}`,
                             padding: '4px 6px', borderRadius: 2, opacity: earned ? 1 : 0.35,
                             minWidth: 44,
                           }}
-                          aria-label={`badge: ${BADGE_LABELS[id]}${earned ? ' earned' : ''}`}
+                          aria-label={`badge: ${badgeLabels[id]}${earned ? ' earned' : ''}`}
                         >
-                          <PixelIcon name={BADGE_ICONS[id] ?? 'star'} size={12} />
+                          <PixelIcon name={badgeIcons[id] ?? 'star'} size={12} />
                           <span style={{ fontSize: '6px', color: earned ? 'var(--text-bright)' : 'var(--text-dim)', textAlign: 'center' }}>
-                            {BADGE_LABELS[id]}{earned && count > 1 ? ` ×${count}` : ''}
+                            {badgeLabels[id]}{earned && count > 1 ? ` ×${count}` : ''}
                           </span>
                         </div>
                       )
@@ -1525,7 +1577,7 @@ function ProfilePanel({ profile, loading, authUser, onClose }: {
                 {/* kro Certificates — grouped by tier (#361) */}
                 <div>
                   {[1, 2, 3].map(tier => {
-                    const tierCerts = CERT_REGISTRY.filter(c => c.tier === tier)
+                    const tierCerts = certRegistry.filter(c => c.tier === tier)
                     const earnedCerts = new Set(profile.kroCertificates ?? [])
                     const earnedCount = tierCerts.filter(c => earnedCerts.has(c.id)).length
                     return (
@@ -1536,6 +1588,7 @@ function ProfilePanel({ profile, loading, authUser, onClose }: {
                         <div style={{ display: 'flex', flexWrap: 'wrap', gap: 4 }}>
                           {tierCerts.map(cert => {
                             const earned = earnedCerts.has(cert.id)
+                            const docId = profile.certificateIds?.[cert.id]
                             return (
                               <div key={cert.id}
                                 title={earned ? cert.name : cert.hint}
@@ -1552,6 +1605,12 @@ function ProfilePanel({ profile, loading, authUser, onClose }: {
                                 <span style={{ fontSize: '6px', color: earned ? '#00d4ff' : 'var(--text-dim)', textAlign: 'center', maxWidth: 60 }}>
                                   {cert.name}
                                 </span>
+                                {earned && docId && (
+                                  <span style={{ fontSize: '5px', display: 'flex', gap: 4 }}>
+                                    <a href={`/api/v1/certificates/${docId}/svg`} target="_blank" rel="noreferrer" style={{ color: 'var(--text-dim)' }}>SVG</a>
+                                    <a href={`/api/v1/certificates/${docId}/pdf`} target="_blank" rel="noreferrer" style={{ color: 'var(--text-dim)' }}>PDF</a>
+                                  </span>
+                                )}
                               </div>
                             )
                           })}
@@ -1854,33 +1913,29 @@ function EventLogTabs({ events, k8sLog, reconcileStream, kroUnlocked, onViewKroC
 }
 
 // ── DungeonMiniMap ────────────────────────────────────────────────────────────
-// Shows a compact 2-room progress strip: Room 1 → Room 2
+// Shows a compact room progress strip: Room 1 → Room 2 → … → Room spec.rooms
 // Room states: 'current' (gold) | 'cleared' (green) | 'locked' (gray) | 'active-boss' (red pulse)
+type RoomState = 'locked' | 'current' | 'cleared' | 'boss-active'
+
 function DungeonMiniMap({ spec, game }: { spec: any; game: any }) {
+  const rooms = spec.rooms || 2
   const currentRoom = game.currentRoom || 1
   const bossHP = game.bossHP ?? 1
-  const room2BossHP = game.room2BossHP ?? 1
   const monsterHP: number[] = game.monsterHP || []
-  const room2MonsterHP: number[] = game.room2MonsterHP || []
   const treasureOpened = game.treasureOpened ?? 0
   const doorUnlocked = game.doorUnlocked ?? 0
-  const allDead1 = monsterHP.length > 0 && monsterHP.every((h: number) => h <= 0)
-  const allDead2 = room2MonsterHP.length > 0 && room2MonsterHP.every((h: number) => h <= 0)
+  const allDead = monsterHP.length > 0 && monsterHP.every((h: number) => h <= 0)
   const heroHP = game.heroHP ?? 1
 
-  // Room 1 state
-  let r1State: 'current' | 'cleared' | 'boss-active' = 'current'
-  if (currentRoom === 1 && allDead1 && bossHP <= 0 && heroHP > 0) r1State = 'cleared'
-  else if (currentRoom === 1 && allDead1 && bossHP > 0) r1State = 'boss-active'
-
-  // Room 2 state
-  let r2State: 'locked' | 'current' | 'cleared' | 'boss-active' = 'locked'
-  if (currentRoom === 2) {
-    if (allDead2 && room2BossHP <= 0 && heroHP > 0) r2State = 'cleared'
-    else if (allDead2 && room2BossHP > 0) r2State = 'boss-active'
-    else r2State = 'current'
-  } else if (doorUnlocked > 0) {
-    r2State = 'current'
+  const roomState = (n: number): RoomState => {
+    if (n < currentRoom) return 'cleared'
+    if (n === currentRoom) {
+      if (allDead && bossHP <= 0 && heroHP > 0) return 'cleared'
+      if (allDead && bossHP > 0) return 'boss-active'
+      return 'current'
+    }
+    // The next room opens once its door is unlocked
+    return n === currentRoom + 1 && doorUnlocked > 0 ? 'current' : 'locked'
   }
 
   const stateColor = (s: string) => {
@@ -1898,18 +1953,24 @@ function DungeonMiniMap({ spec, game }: { spec: any; game: any }) {
 
   return (
     <div className="dungeon-minimap" aria-label="Dungeon progress map">
-      <div className="minimap-room" style={{ borderColor: stateColor(r1State), color: stateColor(r1State) }}>
-        {stateLabel(r1State, 1)}
-        {r1State === 'cleared' && treasureOpened === 0 && (
-          <span className="minimap-icon" title="Treasure available"><PixelIcon name="chest" size={10} /></span>
-        )}
-      </div>
-      <div className="minimap-connector" style={{ background: r2State !== 'locked' ? '#f5c518' : '#333' }}>
-        {r2State !== 'locked' ? '→' : '⋯'}
-      </div>
-      <div className="minimap-room" style={{ borderColor: stateColor(r2State), color: stateColor(r2State) }}>
-        {stateLabel(r2State, 2)}
-      </div>
+      {Array.from({ length: rooms }, (_, i) => i + 1).map(n => {
+        const state = roomState(n)
+        return (
+          <Fragment key={n}>
+            {n > 1 && (
+              <div className="minimap-connector" style={{ background: state !== 'locked' ? '#f5c518' : '#333' }}>
+                {state !== 'locked' ? '→' : '⋯'}
+              </div>
+            )}
+            <div className="minimap-room" style={{ borderColor: stateColor(state), color: stateColor(state) }}>
+              {stateLabel(state, n)}
+              {n === currentRoom && state === 'cleared' && treasureOpened === 0 && (
+                <span className="minimap-icon" title="Treasure available"><PixelIcon name="chest" size={10} /></span>
+              )}
+            </div>
+          </Fragment>
+        )
+      })}
     </div>
   )
 }
@@ -2018,14 +2079,14 @@ function HelpModal({ onClose }: { onClose: () => void }) {
           </tbody>
         </table>
         <p>Status effect chances are fixed regardless of boss phase (computed by the <code>combatResolve</code> state node in dungeon-graph).</p>
-        <p><b>Room 2:</b> After defeating the Room 1 boss, treasure opens and the door unlocks automatically. Click the door to enter Room 2 with trolls, ghouls, and a Bat-boss (stronger than Room 1). Mage mana is fully restored on entry. Defeating the Room 2 boss wins the dungeon.</p>
+        <p><b>Rooms:</b> A dungeon has 2 to 5 rooms (chosen at creation). After defeating a room's boss, treasure opens and the door unlocks automatically. Click the door to enter the next room with trolls, ghouls, and a Bat-boss — each room tougher than the last. Mage mana is fully restored on entry. Defeating the boss of the last room wins the dungeon.</p>
       </>
     )},
     { title: 'Tips & Strategy', content: (
       <>
         <p><b>General:</b> Kill monsters first to reduce counter-attack damage before engaging the boss.</p>
         <p><b>Warrior:</b> Best for beginners. High HP lets you survive many hits. Use Taunt before big boss attacks.</p>
-        <p><b>Mage:</b> Glass cannon. Rush the boss with 1.3x damage. Heal when low. Mana regens on monster kills and is restored when entering Room 2.</p>
+        <p><b>Mage:</b> Glass cannon. Rush the boss with 1.3x damage. Heal when low. Mana regens on monster kills and is restored when entering a new room.</p>
         <p><b>Rogue:</b> High risk/reward. Dodge procs can save you. Save Backstab (3x) for the boss.</p>
         <p><b>Items:</b> Equip weapons before attacking the boss. Use potions freely — they don't cost a turn. Boots resist status effects; pants stack dodge with Rogue's passive.</p>
         <p><b>Modifiers:</b> Blessing of Fortune (20% crit) is the strongest. Curse of Fury makes boss fights brutal — especially in BERSERK phase.</p>
@@ -2038,12 +2099,11 @@ function HelpModal({ onClose }: { onClose: () => void }) {
           <thead><tr><th>Event</th><th>XP</th></tr></thead>
           <tbody>
             <tr><td>Monster kill</td><td>+10</td></tr>
-            <tr><td>Room 1 boss kill</td><td>+50</td></tr>
-            <tr><td>Room 1 clear bonus</td><td>+25</td></tr>
-            <tr><td>Enter Room 2</td><td>+10</td></tr>
-            <tr><td>Room 2 boss kill</td><td>+100</td></tr>
-            <tr><td>Room 2 clear bonus</td><td>+25</td></tr>
+            <tr><td>Boss kill in room <i>n</i></td><td>+50 × <i>n</i></td></tr>
+            <tr><td>Room clear bonus</td><td>+25</td></tr>
+            <tr><td>Enter a new room</td><td>+10</td></tr>
             <tr><td>Victory bonus</td><td>+150</td></tr>
+            <tr><td>Each room beyond 2</td><td>+50</td></tr>
             <tr><td>Hard difficulty</td><td>+50</td></tr>
             <tr><td>Flawless (full HP)</td><td>+25</td></tr>
             <tr><td>Speedrun (≤30 turns)</td><td>+25</td></tr>
@@ -2141,6 +2201,38 @@ function getModifierArenaStyle(modifier: string | undefined): React.CSSPropertie
   }
 }
 
+const AUTO_BATTLE_STRATEGIES = ['aggressive', 'heal-at-threshold', 'backstab-on-cooldown', 'loot-first', 'shopper', 'crafter']
+
+// AutoBattleToggle lets the backend play the dungeon. State comes from the
+// krombat.io/auto-battle annotation, so it follows the CR through WebSocket updates.
+function AutoBattleToggle({ cr }: { cr: DungeonCR }) {
+  const ann = cr.metadata.annotations ?? {}
+  const running = ann['krombat.io/auto-battle']
+  const [strategy, setStrategy] = useState(running?.split(':')[0] || 'heal-at-threshold')
+  const [busy, setBusy] = useState(false)
+  const toggle = async () => {
+    setBusy(true)
+    try {
+      if (running) await stopAutoBattle(cr.metadata.namespace, cr.metadata.name)
+      else await startAutoBattle(cr.metadata.namespace, cr.metadata.name, strategy)
+    } catch { /* state re-syncs from the next CR update */ }
+    setBusy(false)
+  }
+  const stopped = ann['krombat.io/auto-battle-stopped']
+  return (
+    <div className="auto-battle">
+      <span className="label">Auto-battle:</span>
+      <select value={strategy} disabled={!!running || busy} onChange={e => setStrategy(e.target.value)} aria-label="Auto-battle strategy">
+        {AUTO_BATTLE_STRATEGIES.map(s => <option key={s} value={s}>{s}</option>)}
+      </select>
+      <button className={`btn ${running ? 'btn-gold' : 'btn-primary'}`} disabled={busy} onClick={toggle}>
+        {running ? 'Stop' : 'Start'}
+      </button>
+      {!running && stopped && <span className="value" title="Why the last auto-battle run ended">({stopped})</span>}
+    </div>
+  )
+}
+
 function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAttack, events, k8sLog, reconcileStream, showLoot, onOpenLoot, onCloseLoot, attackPhase, roomLoading, animPhase, attackTarget, showHelp, onToggleHelp, floatingDmg, bossPhaseFlash, combatModal, onDismissCombat, lootDrop, onDismissLoot, wsConnected, apiError, kroUnlocked, onViewKroConcept, reconciling, onOpenLeaderboard, onCertTrigger, glossaryOpenCountRef, celTraceSeenRef }: {
   cr: DungeonCR; prevCr?: DungeonCR | null; onBack: () => void; onGameOverBack?: () => void; onNewGamePlus?: () => void; onAttack: (t: string, d: number) => void; events: WSEvent[]; k8sLog: { ts: string; cmd: string; res: string; yaml?: string }[]; reconcileStream: ReconcileDiffEvent[]
   showLoot: boolean; onOpenLoot: () => void; onCloseLoot: () => void
@@ -2170,8 +2262,13 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
   const maxMonsterHP = Number(status?.maxMonsterHP) || Math.max(...(game.monsterHP || [1]))
   const maxBossHP = Number(status?.maxBossHP) || game.bossHP
   const heroHP = game.heroHP ?? 100
-  const classMaxHP = spec.heroClass === 'warrior' ? 200 : spec.heroClass === 'mage' ? 120 : 150
-  const maxHeroHP = Number(status?.maxHeroHP) || classMaxHP
+  const heroClasses = useHeroClasses()
+  const heroClassInfo = heroClasses.find(c => c.id === (spec.heroClass || 'warrior'))
+  const classMaxHP = heroClassInfo?.maxHP
+  const usesMana = (heroClassInfo?.maxMana ?? 0) > 0
+  const manaClassNames = heroClasses.filter(c => (c.maxMana ?? 0) > 0).map(c => c.name).join(' and ')
+  const hasAbility = (action: string) => heroClassInfo?.abilities?.includes(action) ?? false
+  const maxHeroHP = Number(status?.maxHeroHP) || classMaxHP || heroHP
   const isDefeated = status?.defeated || heroHP <= 0
   const allMonstersDead = (game.monsterHP || []).every((hp: number) => hp <= 0)
   const bossState = game.bossHP <= 0 ? 'defeated' : allMonstersDead ? 'ready' : 'pending'
@@ -2186,29 +2283,33 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
     return 'phase1'
   })()
   const gameOver = isDefeated || (game.bossHP <= 0 && allMonstersDead)
+  // Runs created before objectives existed have none.
+  const objectives = spec.objectives ?? []
   const currentRoom = game.currentRoom || 1
-  // room2BossHP > 0 guards against the brief kro reconciliation window where currentRoom=2
-  // but enterRoom2Resolve hasn't fired yet (monsterHP/bossHP still show Room 1 cleared state)
-  const isVictory = gameOver && !isDefeated && (game.currentRoom || 1) === 2 && (game.room2BossHP || 0) > 0
+  const finalRoom = spec.rooms || 2
+  // resolvedRoom guards against the brief kro reconciliation window where currentRoom has
+  // advanced but enterRoomResolve hasn't fired yet (monsterHP/bossHP still show the cleared room)
+  const isVictory = gameOver && !isDefeated && currentRoom >= finalRoom && (game.resolvedRoom || 0) >= currentRoom
 
   // XP earned breakdown for the victory/defeat screen (#360)
   const xpRunBreakdown = (() => {
     const earned = spec.xpEarned ?? 0
     const kills = (game.monsterHP || []).filter((hp: number) => hp <= 0).length
-    const bossR1Dead = (game.currentRoom || 1) >= 2 || game.bossHP <= 0
-    const bossR2Dead = (game.currentRoom || 1) === 2 && game.bossHP <= 0
     const rows: { label: string; xp: number }[] = []
     if (kills > 0) rows.push({ label: `Monster kills (${kills})`, xp: kills * 10 })
-    if (bossR1Dead && (game.currentRoom || 1) === 1) rows.push({ label: 'Boss kill (Room 1)', xp: 50 })
-    if ((game.currentRoom || 1) >= 2) rows.push({ label: 'Room 1 boss kill', xp: 50 })
-    if ((game.currentRoom || 1) >= 2) rows.push({ label: 'Room 1 clear bonus', xp: 25 })
-    if ((game.currentRoom || 1) >= 2) rows.push({ label: 'Enter Room 2', xp: 10 })
-    if (bossR2Dead) rows.push({ label: 'Room 2 boss kill', xp: 100 })
-    if (bossR2Dead) rows.push({ label: 'Room 2 clear bonus', xp: 25 })
+    // Boss kill XP grows with depth: 50 × room number
+    for (let room = 1; room <= currentRoom; room++) {
+      if (room >= 2) rows.push({ label: `Enter Room ${room}`, xp: 10 })
+      if (room < currentRoom || game.bossHP <= 0) {
+        rows.push({ label: `Room ${room} boss kill`, xp: 50 * room })
+        rows.push({ label: `Room ${room} clear bonus`, xp: 25 })
+      }
+    }
     if (isVictory) {
       rows.push({ label: 'Victory bonus', xp: 150 })
+      if (finalRoom > 2) rows.push({ label: `Depth (${finalRoom} rooms)`, xp: 50 * (finalRoom - 2) })
       if (spec.difficulty === 'hard') rows.push({ label: 'Hard difficulty', xp: 50 })
-      if (game.heroHP >= classMaxHP) rows.push({ label: 'Flawless (full HP)', xp: 25 })
+      if (classMaxHP !== undefined && game.heroHP >= classMaxHP) rows.push({ label: 'Flawless (full HP)', xp: 25 })
       if ((spec.attackSeq ?? 0) + (spec.actionSeq ?? 0) <= 30) rows.push({ label: 'Speedrun (≤30 turns)', xp: 25 })
       if ((spec.runCount ?? 0) >= 1) rows.push({ label: 'New Game+', xp: 50 })
     }
@@ -2223,7 +2324,8 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
   const [narrativeText, setNarrativeText] = useState('')       // #460
   const [narrativeLoading, setNarrativeLoading] = useState(false)  // #460
   const [narrativeCopied, setNarrativeCopied] = useState(false)    // #460
-  // Auto-show certificate once on room-2 victory
+  const [recipes, setRecipes] = useState<Recipe[]>([])   // crafting recipes, GET /recipes
+  // Auto-show certificate once on final-room victory
   const certShownRef = useRef(false)
   useEffect(() => {
     if (isVictory && !certShownRef.current) {
@@ -2232,6 +2334,8 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
     }
   }, [isVictory])
 
+  useEffect(() => { listRecipes().then(setRecipes) }, [])
+
   // Tier 2: cel-trace cert — fire once when CelTrace is rendered in combat results (#361)
   useEffect(() => {
     if (combatModal && combatModal.phase !== 'rolling' && combatModal.heroAction) {
@@ -2242,17 +2346,17 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
     }
   }, [combatModal, celTraceSeenRef, onCertTrigger])
 
-  // Room 1 cleared celebration — show for 3s when boss defeated in room 1
+  // Room cleared celebration — show for 3s when a boss short of the final room is defeated
   const [showRoom1Cleared, setShowRoom1Cleared] = useState(false)
-  const room1ClearedRef = useRef(false)
-  const room1IsCleared = (game.currentRoom || 1) === 1 && game.bossHP <= 0 && allMonstersDead && !isDefeated
+  const roomClearedRef = useRef(0)
+  const roomIsCleared = currentRoom < finalRoom && game.bossHP <= 0 && allMonstersDead && !isDefeated
   useEffect(() => {
-    if (room1IsCleared && !room1ClearedRef.current) {
-      room1ClearedRef.current = true
+    if (roomIsCleared && roomClearedRef.current < currentRoom) {
+      roomClearedRef.current = currentRoom
       setShowRoom1Cleared(true)
       setTimeout(() => setShowRoom1Cleared(false), 3000)
     }
-  }, [room1IsCleared])
+  }, [roomIsCleared, currentRoom])
   const [showDoorModal, setShowDoorModal] = useState(false)
   const [doorPassword, setDoorPassword] = useState('')
   const autoTriggeredRef = useRef('')
@@ -2301,20 +2405,22 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
     if (!engineWarning) setDismissedEngineWarning('')
   }, [engineWarning])
 
-  // Auto-open treasure and unlock door after boss kill (room 1 only)
+  // Auto-battle runs server-side; while active the backend drives every move.
+  const autoBattleActive = !!cr.metadata.annotations?.['krombat.io/auto-battle']
+
+  // Auto-open treasure and unlock door after boss kill (every room but the last)
   useEffect(() => {
-    const currentRoom = game.currentRoom || 1
-    if (currentRoom !== 1 || !allMonstersDead || game.bossHP > 0 || isDefeated || attackPhase) return
+    if (currentRoom >= finalRoom || !allMonstersDead || game.bossHP > 0 || isDefeated || attackPhase || autoBattleActive) return
     const treasureOpened = (game.treasureOpened ?? 0) === 1
     const doorUnlocked = (game.doorUnlocked ?? 0) === 1
-    if (!treasureOpened && autoTriggeredRef.current !== 'open-treasure') {
-      autoTriggeredRef.current = 'open-treasure'
+    if (!treasureOpened && autoTriggeredRef.current !== `open-treasure@${currentRoom}`) {
+      autoTriggeredRef.current = `open-treasure@${currentRoom}`
       onAttack('open-treasure', 0)
-    } else if (treasureOpened && !doorUnlocked && autoTriggeredRef.current !== 'unlock-door') {
-      autoTriggeredRef.current = 'unlock-door'
+    } else if (treasureOpened && !doorUnlocked && autoTriggeredRef.current !== `unlock-door@${currentRoom}`) {
+      autoTriggeredRef.current = `unlock-door@${currentRoom}`
       onAttack('unlock-door', 0)
     }
-  }, [game.bossHP, allMonstersDead, game.treasureOpened, game.doorUnlocked, attackPhase])
+  }, [currentRoom, game.bossHP, allMonstersDead, game.treasureOpened, game.doorUnlocked, attackPhase, autoBattleActive])
 
   // Build turn order for display
   const turnOrder: { id: string; label: string; alive: boolean }[] = [{ id: 'hero', label: 'Hero', alive: !isDefeated }]
@@ -2434,7 +2540,8 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
             <span>Turns: <span style={{ color: 'var(--gold)' }}>{spec.attackSeq ?? 0}</span></span>
             <span>Hero: <span style={{ color: 'var(--gold)' }}>{spec.heroClass ?? 'warrior'}</span></span>
             <span>Difficulty: <span style={{ color: 'var(--gold)' }}>{spec.difficulty}</span></span>
-            <span>Room: <span style={{ color: 'var(--gold)' }}>{game.currentRoom ?? 1}</span></span>
+            <span>Room: <span style={{ color: 'var(--gold)' }}>{game.currentRoom ?? 1}/{finalRoom}</span></span>
+            <span>Gold: <span style={{ color: 'var(--gold)' }}>{game.gold ?? 0}</span></span>
             {game.weaponBonus ? <span><PixelIcon name="sword" size={8} /> Weapon +{game.weaponBonus}</span> : null}
             {game.armorBonus ? <span><PixelIcon name="shield" size={8} /> Armor {game.armorBonus}%</span> : null}
             {game.ringBonus ? <span><PixelIcon name="ring" size={8} /> Ring +{game.ringBonus}/turn</span> : null}
@@ -2457,7 +2564,7 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
         </div>
       )}
 
-      {gameOver && !isDefeated && (game.currentRoom || 1) === 2 && (game.room2BossHP || 0) > 0 && (
+      {isVictory && (
         <div className="victory-banner">
           <h2><PixelIcon name="crown" size={18} /> VICTORY! <PixelIcon name="crown" size={18} /></h2>
           <p className="loot">The dungeon has been conquered!</p>
@@ -2659,19 +2766,37 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
           <div><span className="label">Difficulty:</span><span className="value">{spec.difficulty}</span></div>
         </Tooltip>
         <Tooltip text={KRO_STATUS_TIPS.room}>
-          <div><span className="label">Room:</span><span className="value">{game.currentRoom || 1}</span></div>
+          <div><span className="label">Room:</span><span className="value">{currentRoom}/{finalRoom}</span></div>
+        </Tooltip>
+        <Tooltip text={KRO_STATUS_TIPS.gold}>
+          <div><span className="label">Gold:</span><span className="value">{game.gold ?? 0}</span></div>
         </Tooltip>
         <Tooltip text={KRO_STATUS_TIPS.turn}>
           <div><span className="label">Turn:</span><span className="value">{(spec.attackSeq ?? 0) + 1}</span></div>
         </Tooltip>
+        {!gameOver && <AutoBattleToggle cr={cr} />}
       </div>
 
+      {objectives.length > 0 && (
+        <div className="objectives-bar" aria-label="Objectives">
+          {objectives.map(o => (
+            <Tooltip key={o.id} text={`${o.state === 'done' ? 'Complete' : o.state === 'failed' ? 'Failed' : 'In progress'} — worth ${o.xp} XP when the run is recorded`}>
+              <div className={`objective ${o.state}`}>
+                {o.state === 'done' ? '✓' : o.state === 'failed' ? '✗' : '•'} {o.description}
+                {o.kind === 'kill' && o.state === 'active' && <span className="objective-progress"> {o.progress}/{o.target}</span>}
+                <span className="objective-xp"> +{o.xp} XP</span>
+              </div>
+            </Tooltip>
+          ))}
+        </div>
+      )}
+
       <div className="game-layout">
         {/* LEFT PANEL — Dungeon Arena */}
         <div className="left-panel">
           <div className={`dungeon-arena${game.modifier === 'blessing-fortune' ? ' arena-blessing-fortune' : ''}`} style={getModifierArenaStyle(game.modifier)}>
             {/* Stone floor texture layers */}
-            <div className="arena-floor" style={{ backgroundImage: `url('/sprites/dungeon/floor-${currentRoom === 2 ? 2 : 1}.png')`, ...(currentRoom === 2 ? { backgroundSize: '80px' } : {}) }} />
+            <div className="arena-floor" style={{ backgroundImage: `url('/sprites/dungeon/floor-${currentRoom >= 2 ? 2 : 1}.png')`, ...(currentRoom >= 2 ? { backgroundSize: '80px' } : {}) }} />
             <div className="arena-glow" />
 
             {/* Dungeon props — scattered decorations */}
@@ -2703,15 +2828,15 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
                 style={{ left: `${p.x}%`, top: `${p.y}%`, width: p.size, transform: `translate(-50%,-50%) rotate(${p.rot}deg)` }} />
             ))}
 
-            {/* Door at top of arena — room 1 only */}
-             {(game.currentRoom || 1) === 1 && (
+            {/* Door at top of arena — every room but the last */}
+             {currentRoom < finalRoom && (
              <div className="arena-entity door-entity" style={{ top: '8%', left: '50%', cursor: (game.doorUnlocked ?? 0) === 1 ? 'pointer' : 'default' }}
                role={(game.doorUnlocked ?? 0) === 1 ? 'button' : undefined}
                tabIndex={(game.doorUnlocked ?? 0) === 1 ? 0 : undefined}
-               aria-label={(game.doorUnlocked ?? 0) === 1 ? 'Enter Room 2' : undefined}
+               aria-label={(game.doorUnlocked ?? 0) === 1 ? `Enter Room ${currentRoom + 1}` : undefined}
                 onClick={() => {
                   if (attackPhase) return
-                  if ((game.doorUnlocked ?? 0) === 1) onAttack('enter-room-2', 0)
+                  if ((game.doorUnlocked ?? 0) === 1) onAttack(`enter-room-${currentRoom + 1}`, 0)
                 }}>
                {(() => {
                  const doorUnlocked = (game.doorUnlocked ?? 0) === 1
@@ -2726,8 +2851,8 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
              </div>
              )}
 
-            {/* Treasure chest — appears after boss defeated in room 1, auto-opens */}
-            {(game.currentRoom || 1) === 1 && (game.bossHP <= 0 && allMonstersDead) && (
+            {/* Treasure chest — appears after boss defeated in every room but the last, auto-opens */}
+            {currentRoom < finalRoom && (game.bossHP <= 0 && allMonstersDead) && (
               <div className="arena-entity chest-entity" style={{ top: '55%', left: '30%' }}>
                 <img src={`/sprites/dungeon/chest-${(game.treasureOpened ?? 0) === 1 ? 'opened' : 'closed'}.png`}
                   alt="chest" style={{ width: 56, height: 56, imageRendering: 'pixelated' as any, filter: (game.treasureOpened ?? 0) === 0 ? 'drop-shadow(0 0 4px gold)' : 'none' }} />
@@ -2766,7 +2891,7 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
                       {bossPhase === 'phase2' ? 'ENRAGED' : 'BERSERK'}
                     </div>
                   )}
-                   <Sprite spriteType={(game.currentRoom || 1) === 2 ? 'bat-boss' : 'dragon'} action={bAction} size={144} />
+                   <Sprite spriteType={currentRoom >= 2 ? 'bat-boss' : 'dragon'} action={bAction} size={144} />
                    <div className="arena-shadow" style={{ width: 120 }} />
                    <div className="arena-hover-ui">
                     <div className="arena-hp-bar"><div className={`arena-hp-fill ${game.bossHP > 0 ? 'high' : 'low'}`} style={{ width: `${Math.min((game.bossHP / maxBossHP) * 100, 100)}%` }} /></div>
@@ -2774,7 +2899,7 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
                     {bossState === 'ready' && !gameOver && !attackPhase && (
                       <div className="arena-actions">
                         <button className="btn btn-primary arena-atk-btn" onClick={() => onAttack(bossName, 0)}><PixelIcon name="dice" size={8} /> {status?.diceFormula || '2d12+6'}</button>
-                        {spec.heroClass === 'rogue' && (game.backstabCooldown ?? 0) === 0 && (
+                        {hasAbility('backstab') && (game.backstabCooldown ?? 0) === 0 && (
                           <button className="btn btn-ability arena-atk-btn" onClick={() => onAttack(bossName + '-backstab', 0)}>Backstab</button>
                         )}
                       </div>
@@ -2821,7 +2946,7 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
                        {!gameOver && !attackPhase && (
                          <div className="arena-actions">
                            <button className="btn btn-primary arena-atk-btn" onClick={() => onAttack(mName, 0)}><PixelIcon name="dice" size={8} /> {status?.diceFormula || '2d12+6'}</button>
-                           {spec.heroClass === 'rogue' && (game.backstabCooldown ?? 0) === 0 && (
+                           {hasAbility('backstab') && (game.backstabCooldown ?? 0) === 0 && (
                              <button className="btn btn-ability arena-atk-btn" onClick={() => onAttack(mName + '-backstab', 0)}>Backstab</button>
                            )}
                          </div>
@@ -2844,12 +2969,12 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
             {/* Room transition loading */}
             {roomLoading && (
               <div style={{ position: 'absolute', inset: 0, background: 'rgba(0,0,0,0.7)', display: 'flex', alignItems: 'center', justifyContent: 'center', zIndex: 20, borderRadius: 12 }}>
-                 <div style={{ textAlign: 'center', color: 'var(--gold)', fontSize: 12 }}>[~] Entering Room 2...</div>
+                 <div style={{ textAlign: 'center', color: 'var(--gold)', fontSize: 12 }}>[~] Entering Room {currentRoom + 1}...</div>
               </div>
             )}
 
-            {/* Flying bats — Room 2 only (bat-boss lives here) */}
-            {(game.currentRoom || 1) === 2 && <DungeonBats />}
+            {/* Flying bats — Room 2 and beyond (bat-boss lives there) */}
+            {currentRoom >= 2 && <DungeonBats />}
 
             {/* Room 1 cleared — 3s celebration overlay */}
             {showRoom1Cleared && (
@@ -2874,26 +2999,26 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
                 style={{ width: `${Math.min((heroHP / maxHeroHP) * 100, 100)}%` }} />
             </div>
             <div className="hero-hp-text">HP: {heroHP} / {maxHeroHP}</div>
-            {spec.heroClass === 'mage' && <div className="mana-text"><PixelIcon name="mana" size={10} /> Mana: {game.heroMana ?? 0}</div>}
+            {usesMana && <div className="mana-text"><PixelIcon name="mana" size={10} /> Mana: {game.heroMana ?? 0}</div>}
             {floatingDmg?.target === 'hero' && <div className="floating-dmg" style={{ color: floatingDmg.color }}>{floatingDmg.amount}</div>}
           </div>
 
           {!gameOver && !attackPhase && (
             <div className="ability-bar">
-              {spec.heroClass === 'mage' && (
+              {hasAbility('heal') && (
                 <button className="btn btn-ability" disabled={(game.heroMana ?? 0) < 2 || heroHP >= maxHeroHP}
                   onClick={() => onAttack('hero', 0)}>
                   <PixelIcon name="heal" size={12} /> Heal
                 </button>
               )}
-              {spec.heroClass === 'warrior' && (
+              {hasAbility('taunt') && (
                 <button className={`btn btn-ability${(game.tauntActive ?? 0) > 0 ? ' active' : ''}`}
                   disabled={(game.tauntActive ?? 0) > 0}
                   onClick={() => onAttack('activate-taunt', 0)}>
                   <PixelIcon name="shield" size={12} /> Taunt
                 </button>
               )}
-              {spec.heroClass === 'rogue' && (
+              {hasAbility('backstab') && (
                 <span className="cooldown-text">
                   <PixelIcon name="dagger" size={12} /> Backstab: {(game.backstabCooldown ?? 0) > 0 ? `${game.backstabCooldown} CD` : 'Ready'}
                 </span>
@@ -2918,55 +3043,65 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
             const stun = game.stunTurns || 0
             const taunt = game.tauntActive || 0
             const RARITY_COLOR: Record<string, string> = { common: '#aaa', rare: '#5dade2', epic: '#9b59b6' }
+            const gold = game.gold ?? 0
+            // merchantResolve opens shop once a room short of the last is cleared
+            const merchantOpen = (game.merchantRoom ?? 0) > 0 && game.merchantRoom === currentRoom
+            const merchantStock = parseInventory(game.merchantStock)
+            // unequip-<slot> puts the worn item back, so it needs a free backpack slot
+            const canUnequip = !gameOver && !attackPhase && items.length < 8
+            const worn: Record<string, number> = { weapon: wb, armor: ab, shield: sb, helmet: hb, pants: pb, boots: bb, ring: rb, amulet: amb }
+            // Recipes whose inputs are all in the backpack (actionResolve crafts them)
+            const craftable = recipes.filter(r => r.inputs.every(x =>
+              items.filter(y => y === x).length >= r.inputs.filter(y => y === x).length))
             return (
               <div className="equip-panel">
                 <div className="equip-grid">
                   <div className="equip-row">
-                    <Tooltip text={hb > 0 ? `Helmet equipped: ${hb}% chance to land critical hits` : 'Helmet — none equipped'}>
-                      <div className={`equip-slot${hb > 0 ? ' filled' : ' empty'}`}>
+                    <Tooltip text={hb > 0 ? `Helmet equipped: ${hb}% chance to land critical hits — click to unequip` : 'Helmet — none equipped'}>
+                      <div className={`equip-slot${hb > 0 ? ' filled' : ' empty'}`} onClick={hb > 0 && canUnequip ? () => onAttack('unequip-helmet', 0) : undefined}>
                         {hb > 0 ? <><ItemSprite id={hb >= 15 ? 'helmet-epic' : hb >= 10 ? 'helmet-rare' : 'helmet-common'} size={22} /><span className="slot-stat">{hb}%</span></> : <PixelIcon name="helmet" size={14} color="#333" />}
                       </div>
                     </Tooltip>
                   </div>
                   <div className="equip-row">
-                    <Tooltip text={sb > 0 ? `Shield equipped: ${sb}% chance to block counter-attacks` : 'Shield — none equipped'}>
-                      <div className={`equip-slot${sb > 0 ? ' filled' : ' empty'}`}>
+                    <Tooltip text={sb > 0 ? `Shield equipped: ${sb}% chance to block counter-attacks — click to unequip` : 'Shield — none equipped'}>
+                      <div className={`equip-slot${sb > 0 ? ' filled' : ' empty'}`} onClick={sb > 0 && canUnequip ? () => onAttack('unequip-shield', 0) : undefined}>
                         {sb > 0 ? <><ItemSprite id={sb >= 25 ? 'shield-epic' : sb >= 15 ? 'shield-rare' : 'shield-common'} size={22} /><span className="slot-stat">{sb}%</span></> : <PixelIcon name="shield" size={14} color="#333" />}
                       </div>
                     </Tooltip>
-                    <Tooltip text={ab > 0 ? `Armor equipped: +${ab}% damage reduction` : 'Armor — none equipped'}>
-                      <div className={`equip-slot${ab > 0 ? ' filled' : ' empty'}`}>
+                    <Tooltip text={ab > 0 ? `Armor equipped: +${ab}% damage reduction — click to unequip` : 'Armor — none equipped'}>
+                      <div className={`equip-slot${ab > 0 ? ' filled' : ' empty'}`} onClick={ab > 0 && canUnequip ? () => onAttack('unequip-armor', 0) : undefined}>
                         {ab > 0 ? <><ItemSprite id={ab >= 30 ? 'armor-epic' : ab >= 20 ? 'armor-rare' : 'armor-common'} size={22} /><span className="slot-stat">+{ab}%</span></> : <PixelIcon name="shield" size={14} color="#333" />}
                       </div>
                     </Tooltip>
-                    <Tooltip text={wb > 0 ? `Weapon equipped: +${wb} damage (${wu} uses left)` : 'Weapon — none equipped'}>
-                      <div className={`equip-slot${wb > 0 ? ' filled' : ' empty'}`}>
+                    <Tooltip text={wb > 0 ? `Weapon equipped: +${wb} damage (${wu} uses left) — click to unequip` : 'Weapon — none equipped'}>
+                      <div className={`equip-slot${wb > 0 ? ' filled' : ' empty'}`} onClick={wb > 0 && canUnequip ? () => onAttack('unequip-weapon', 0) : undefined}>
                         {wb > 0 ? <><ItemSprite id={wb >= 20 ? 'weapon-epic' : wb >= 10 ? 'weapon-rare' : 'weapon-common'} size={22} /><span className="slot-stat">+{wb}<br/>{wu}u</span></> : <PixelIcon name="weapon-slot" size={14} color="#333" />}
                       </div>
                     </Tooltip>
                   </div>
                   <div className="equip-row">
-                    <Tooltip text={pb > 0 ? `Pants equipped: ${pb}% chance to dodge counter-attacks` : 'Pants — none equipped'}>
-                      <div className={`equip-slot${pb > 0 ? ' filled' : ' empty'}`}>
+                    <Tooltip text={pb > 0 ? `Pants equipped: ${pb}% chance to dodge counter-attacks — click to unequip` : 'Pants — none equipped'}>
+                      <div className={`equip-slot${pb > 0 ? ' filled' : ' empty'}`} onClick={pb > 0 && canUnequip ? () => onAttack('unequip-pants', 0) : undefined}>
                         {pb > 0 ? <><ItemSprite id={pb >= 15 ? 'pants-epic' : pb >= 10 ? 'pants-rare' : 'pants-common'} size={22} /><span className="slot-stat">{pb}%</span></> : <PixelIcon name="pants" size={14} color="#333" />}
                       </div>
                     </Tooltip>
                   </div>
                    <div className="equip-row">
-                     <Tooltip text={bb > 0 ? `Boots equipped: ${bb}% chance to resist status effects` : 'Boots — none equipped'}>
-                       <div className={`equip-slot${bb > 0 ? ' filled' : ' empty'}`}>
+                     <Tooltip text={bb > 0 ? `Boots equipped: ${bb}% chance to resist status effects — click to unequip` : 'Boots — none equipped'}>
+                       <div className={`equip-slot${bb > 0 ? ' filled' : ' empty'}`} onClick={bb > 0 && canUnequip ? () => onAttack('unequip-boots', 0) : undefined}>
                          {bb > 0 ? <><ItemSprite id={bb >= 60 ? 'boots-epic' : bb >= 40 ? 'boots-rare' : 'boots-common'} size={22} /><span className="slot-stat">{bb}%</span></> : <PixelIcon name="boots" size={14} color="#333" />}
                        </div>
                      </Tooltip>
                    </div>
                     <div className="equip-row">
-                      <Tooltip text={rb > 0 ? `Ring equipped: +${rb} HP regen at start of each round` : 'Ring — none equipped'}>
-                        <div className={`equip-slot${rb > 0 ? ' filled' : ' empty'}`}>
+                      <Tooltip text={rb > 0 ? `Ring equipped: +${rb} HP regen at start of each round — click to unequip` : 'Ring — none equipped'}>
+                        <div className={`equip-slot${rb > 0 ? ' filled' : ' empty'}`} onClick={rb > 0 && canUnequip ? () => onAttack('unequip-ring', 0) : undefined}>
                           {rb > 0 ? <><ItemSprite id={rb >= 12 ? 'ring-epic' : rb >= 8 ? 'ring-rare' : 'ring-common'} size={22} /><span className="slot-stat">+{rb}/t</span></> : <PixelIcon name="ring" size={14} color="#333" />}
                          </div>
                        </Tooltip>
-                       <Tooltip text={amb > 0 ? `Amulet equipped: +${amb}% to all damage dealt` : 'Amulet — none equipped'}>
-                         <div className={`equip-slot${amb > 0 ? ' filled' : ' empty'}`}>
+                       <Tooltip text={amb > 0 ? `Amulet equipped: +${amb}% to all damage dealt — click to unequip` : 'Amulet — none equipped'}>
+                         <div className={`equip-slot${amb > 0 ? ' filled' : ' empty'}`} onClick={amb > 0 && canUnequip ? () => onAttack('unequip-amulet', 0) : undefined}>
                            {amb > 0 ? <><ItemSprite id={amb >= 30 ? 'amulet-epic' : amb >= 20 ? 'amulet-rare' : 'amulet-common'} size={22} /><span className="slot-stat">+{amb}%</span></> : <PixelIcon name="amulet" size={14} color="#333" />}
                         </div>
                       </Tooltip>
@@ -2993,27 +3128,89 @@ function DungeonView({ cr, prevCr, onBack, onGameOverBack, onNewGamePlus, onAtta
                       {items.map((item, i) => {
                         const rarity = item.split('-').pop()!
                         const isPotion = item.includes('potion')
+                        // swap-<slot> trades the worn item for the first backpack item of the slot
+                        const slot = item.split('-')[0]
+                        const swaps = !isPotion && (worn[slot] ?? 0) > 0 && items.findIndex(x => x.startsWith(slot + '-')) === i
                         const isManaPotion = item.includes('manapotion')
                         const desc =                           item.includes('weapon') ? `Weapon (${rarity}) — click to equip, +damage for 3 attacks` :
                           item.includes('armor') ? `Armor (${rarity}) — click to equip, +defense for dungeon` :
                           item.includes('hppotion') ? `HP Potion (${rarity}) — click to restore HP` :
-                          isManaPotion ? (spec.heroClass === 'mage' ? `Mana Potion (${rarity}) — click to restore mana` : `Mana Potion (${rarity}) — Mage only`) :
+                          isManaPotion ? (usesMana ? `Mana Potion (${rarity}) — click to restore mana` : `Mana Potion (${rarity}) — ${manaClassNames} only`) :
                           item.includes('helmet') ? `Helmet (${rarity}) — click to equip, +crit chance` :
                           item.includes('pants') ? `Pants (${rarity}) — click to equip, +dodge chance` :
                           item.includes('boots') ? `Boots (${rarity}) — click to equip, +status resist` :
                           item.includes('ring') ? `Ring (${rarity}) — click to equip, +HP regen per round` :
                           item.includes('amulet') ? `Amulet (${rarity}) — click to equip, +% damage boost` : item
                         return (
-                          <Tooltip key={i} text={desc}>
-                            <button className="backpack-slot" disabled={gameOver || !!attackPhase || (isManaPotion && spec.heroClass !== 'mage')}
+                          <Tooltip key={i} text={`${swaps ? desc.replace('click to equip', 'click to swap with the worn one') : desc} · right-click to drop`}>
+                            <button className="backpack-slot" disabled={gameOver || !!attackPhase || (isManaPotion && !usesMana)}
+                              style={{ borderColor: RARITY_COLOR[rarity] || '#555' }}
+                              onClick={() => onAttack(isPotion ? `use-${item}` : swaps ? `swap-${slot}` : `equip-${item}`, 0)}
+                              onContextMenu={e => { e.preventDefault(); onAttack(`drop-${item}`, 0) }}>
+                              <ItemSprite id={item} size={22} />
+                            </button>
+                          </Tooltip>
+                        )
+                      })}
+                    </div>
+                    {craftable.length > 0 && (
+                      <div className="backpack-grid craft-row" aria-label="Craft">
+                        {craftable.map(r => (
+                          <Tooltip key={r.id} text={`Craft ${r.output} from ${r.inputs.join(' + ')}`}>
+                            <button className="backpack-slot craft-slot" disabled={gameOver || !!attackPhase}
+                              style={{ borderColor: RARITY_COLOR[r.output.split('-').pop()!] || '#555' }}
+                              onClick={() => onAttack(`craft-${r.id}`, 0)}>
+                              <ItemSprite id={r.output} size={16} />
+                              <span className="craft-label">CRAFT</span>
+                            </button>
+                          </Tooltip>
+                        ))}
+                      </div>
+                    )}
+                  </div>
+                )}
+
+                {merchantOpen && (
+                  <div className="merchant" aria-label="Merchant">
+                    <div className="backpack-label">
+                      Merchant <span className="merchant-gold">{gold} gold</span>
+                    </div>
+                    <div className="backpack-grid">
+                      {merchantStock.length === 0 && <span className="merchant-empty">Sold out</span>}
+                      {merchantStock.map(item => {
+                        const rarity = item.split('-').pop()!
+                        const price = ITEM_PRICE[rarity] ?? 0
+                        const classLocked = item.includes('manapotion') && !usesMana
+                        return (
+                          <Tooltip key={item} text={classLocked ? `${item} — Mage and Paladin only` : `Buy ${item} for ${price} gold`}>
+                            <button className="backpack-slot merchant-slot"
+                              disabled={isDefeated || !!attackPhase || price > gold || items.length >= 8 || classLocked}
                               style={{ borderColor: RARITY_COLOR[rarity] || '#555' }}
-                              onClick={() => onAttack(isPotion ? `use-${item}` : `equip-${item}`, 0)}>
+                              onClick={() => onAttack(`buy-${item}`, 0)}>
                               <ItemSprite id={item} size={22} />
+                              <span className="merchant-price">{price}</span>
                             </button>
                           </Tooltip>
                         )
                       })}
                     </div>
+                    {items.length > 0 && (
+                      <div className="backpack-grid merchant-sell">
+                        {items.map((item, i) => {
+                          const rarity = item.split('-').pop()!
+                          const price = Math.floor((ITEM_PRICE[rarity] ?? 0) / 2)
+                          return (
+                            <Tooltip key={i} text={`Sell ${item} for ${price} gold`}>
+                              <button className="backpack-slot merchant-slot" disabled={isDefeated || !!attackPhase}
+                                onClick={() => onAttack(`sell-${item}`, 0)}>
+                                <ItemSprite id={item} size={16} />
+                                <span className="merchant-price">+{price}</span>
+                              </button>
+                            </Tooltip>
+                          )
+                        })}
+                      </div>
+                    )}
                   </div>
                 )}
               </div>
@@ -351,7 +351,7 @@ export function buildGraph(cr: DungeonCR, reconciling: boolean): { nodes: GraphN
   }
 
   // State nodes — virtual nodes in dungeon-graph that write computed game state to status.game
-  // (combat, action, DoT, taunt, cooldown, ring, room2 transitions)
+  // (combat, action, DoT, taunt, cooldown, ring, room transitions)
   nodes.push({
     id: 'combat-cm',
     label: 'combatResolve',
@@ -114,7 +114,7 @@ spec:
       heroClass:
         type: string
         default: "warrior"
-        enum: [warrior, mage, rogue]`,
+        enum: [warrior, mage, rogue, paladin, ranger]`,
     learnMore: 'manifests/rgds/dungeon-graph.yaml — spec.schema section',
   },
 
@@ -136,7 +136,7 @@ validation:
             enum: [easy, normal, hard]
             type: string
           heroClass:
-            enum: [warrior, mage, rogue]
+            enum: [warrior, mage, rogue, paladin, ranger]
             type: string`,
     learnMore: 'kubectl get crd dungeons.kro.run -o yaml — look for openAPIV3Schema',
   },
@@ -581,19 +581,19 @@ metadata:
     id: 'spec-mutation',
     title: 'Trigger Fields Drive Full Reconcile',
     tagline: 'One patch to spec trigger fields → kro reconciles the entire resource graph.',
-    body: `When you enter Room 2, the Go backend patches the Dungeon CR spec with \`lastAction: 'enter-room-2'\` and increments \`actionSeq\`. kro watches the Dungeon CR and immediately re-evaluates all CEL expressions in dungeon-graph.
-kro's \`enterRoom2Resolve\` state node detects the action and computes the new \`monsterHP\`, \`bossHP\`, \`room2MonsterHP\`, \`room2BossHP\` values via CEL — writing them to \`status.game\`. New Monster CRs and an updated Boss CR are then reconciled from those state values. Kubernetes becomes the state machine.`,
+    body: `When you enter the next room, the Go backend patches the Dungeon CR spec with \`lastAction: 'enter-room-<n>'\` and increments \`actionSeq\`. kro watches the Dungeon CR and immediately re-evaluates all CEL expressions in dungeon-graph.
+kro's \`enterRoomResolve\` state node sees \`currentRoom\` move past \`resolvedRoom\` and computes the new \`monsterHP\`, \`bossHP\`, \`roomMonsterHP\`, \`roomBossHP\` values via CEL, scaled by the room number — writing them to \`status.game\`. New Monster CRs and an updated Boss CR are then reconciled from those state values. Kubernetes becomes the state machine.`,
     snippet: `# Backend writes only the trigger — kro does the rest
 patch := map[string]interface{}{
   "spec": map[string]interface{}{
-    "lastAction": "enter-room-2",
+    "lastAction": "enter-room-3",
     "actionSeq":  newSeq,
-    // kro's enterRoom2Resolve state node computes new HP values via CEL
+    // kro's enterRoomResolve state node computes new HP values via CEL
     // and writes them to status.game
   },
 }
 // manifests/rgds/dungeon-graph.yaml reacts automatically`,
-    learnMore: 'manifests/rgds/dungeon-graph.yaml — enterRoom2Resolve state node',
+    learnMore: 'manifests/rgds/dungeon-graph.yaml — enterRoomResolve state node',
   },
 
   'cel-playground': {
@@ -607,7 +607,7 @@ Under the hood: \`POST /api/v1/dungeons/{ns}/{name}/cel-eval\` with \`{"expr":".
 This means every kro extension is available in the Playground:
 - \`cel.bind(x, schema.status.game.heroHP, x * 2)\` — bind macro (same as dungeon-graph.yaml)
 - \`random.seededInt(0, 20, "seed")\` — deterministic random (same RNG kro uses)
-- \`json.marshal(json.unmarshal(schema.status.game.inventory == '' ? '[]' : schema.status.game.inventory) + ["sword"])\` — add item to JSON inventory
+- \`schema.status.game.inventory + [{"id": "sword", "quantity": 1, "durability": 0}]\` — add a stack to the inventory
 - \`lists.setAtIndex([1, 2, 3], 0, 99)\` — list mutation
 - \`json.unmarshal('{"name":"goblin","hp":30}').name\` — parse JSON string into a map
 - \`json.marshal({"class": schema.spec.heroClass, "hp": schema.status.game.heroHP})\` — serialize a map to JSON
@@ -719,7 +719,7 @@ stringData:
     tagline: 'kro writes computed values into status.game on the Dungeon CR',
     body: `State nodes (\`type: stateNode\`) are RGD resource entries that evaluate CEL expressions and write results to \`status.game.*\` on the Dungeon CR. This enables stateful game logic (combat, cooldowns, DoT, room transitions) with no backend code.
 
-9 of dungeon-graph's resource entries are state nodes: \`dungeonInit\`, \`abilityResolve\`, \`tickDoT\`, \`advanceTaunt\`, \`tickCooldown\`, \`regenRing\`, \`combatResolve\`, \`actionResolve\`, \`enterRoom2Resolve\`. Together they implement the entire game engine via CEL — the Go backend only patches trigger fields (\`attackSeq\`, \`lastAbility\`, etc.) and reads the results from \`status.game\`.`,
+9 of dungeon-graph's resource entries are state nodes: \`dungeonInit\`, \`abilityResolve\`, \`tickDoT\`, \`advanceTaunt\`, \`tickCooldown\`, \`regenRing\`, \`combatResolve\`, \`actionResolve\`, \`enterRoomResolve\`. Together they implement the entire game engine via CEL — the Go backend only patches trigger fields (\`attackSeq\`, \`lastAbility\`, etc.) and reads the results from \`status.game\`.`,
     snippet: `# dungeon-graph.yaml — tickDoT state node
 # Fires each attack turn when DoT is active.
 # Reads status.game.poisonTurns/burnTurns → writes status.game.heroHP, decrements counters.
@@ -823,7 +823,7 @@ export function getInsightForEvent(event: string): InsightTrigger | null {
   if (event === 'boss-killed') return { conceptId: 'cel-filter', headline: 'kro ran .filter() on all Monster CRs to re-aggregate livingMonsters to 0' }
   if (event === 'all-monsters-dead') return { conceptId: 'status-aggregation', headline: 'All monsters dead — kro aggregated victory state from Hero + Boss + Monster CRs' }
   if (event === 'treasure-opened') return { conceptId: 'secret-output', headline: 'Opening treasure created a Kubernetes Secret via treasure-graph' }
-  if (event === 'enter-room-2') return { conceptId: 'spec-mutation', headline: 'One spec patch triggered a full kro reconcile of the resource graph' }
+  if (event === 'enter-room') return { conceptId: 'spec-mutation', headline: 'One spec patch triggered a full kro reconcile of the resource graph' }
   if (event === 'modifier-present') return { conceptId: 'readyWhen', headline: 'dungeon-graph waited for modifier-graph via readyWhen before proceeding' }
   if (event === 'forEach') return { conceptId: 'forEach', headline: 'kro created one Monster CR per entry in monsterHP[] via forEach' }
   if (event === 'loot-drop') return { conceptId: 'seeded-random', headline: 'Loot type and rarity rolled via random.seededString() in monster-graph' }
@@ -1132,10 +1132,17 @@ Validated by dungeon-graph spec.schema:
 kro rejects invalid values at admission time.`,
 
   room: `kro field: status.game.currentRoom
-Written by enterRoom2Resolve state node when you enter Room 2.
+Written by actionResolve when you enter the next room;
+enterRoomResolve then spawns that room's enemies.
 Triggers full dungeon-graph reconciliation:
   new Monster CRs, new Boss CR, updated ConfigMaps.`,
 
+  gold: `kro field: status.game.gold
+Written by combatResolve on every kill (+10 monster,
++50 × room boss) and when loot is sold from a full backpack.
+actionResolve spends it on buy-<item>, adds half back on sell-<item>.
+merchantResolve stocks the shop once a room is cleared.`,
+
   turn: `kro field: spec.attackSeq
 Monotonically incrementing counter.
 The backend uses this for optimistic concurrency control:
@@ -8,6 +8,9 @@ const FRAME_COUNT: Record<string, number> = {
   archer: 6, shaman: 6,
 }
 
+// Classes without their own art borrow the closest existing sprite sheet.
+const SPRITE_ALIAS: Record<string, string> = { paladin: 'warrior', ranger: 'rogue' }
+
 export type SpriteAction = 'idle' | 'attack' | 'hurt' | 'dead' | 'victory' | 'itemUse'
 
 // Map actions to frame numbers (1-indexed file names)
@@ -46,7 +49,8 @@ interface SpriteProps {
   flip?: boolean
 }
 
-export function Sprite({ spriteType, action, size = 64, flip = false }: SpriteProps) {
+export function Sprite({ spriteType: requested, action, size = 64, flip = false }: SpriteProps) {
+  const spriteType = SPRITE_ALIAS[requested] ?? requested
   const [frameIdx, setFrameIdx] = useState(0)
   const intervalRef = useRef<ReturnType<typeof setInterval>>(undefined)
 
@@ -104,7 +108,7 @@ export function getMonsterSprite(index: number, room: number = 1, monsterTypes?:
       default: break
     }
   }
-  if (room === 2) return index % 2 === 0 ? 'troll' : 'ghoul'
+  if (room >= 2) return index % 2 === 0 ? 'troll' : 'ghoul'
   return index % 2 === 0 ? 'goblin' : 'skeleton'
 }
 
@@ -121,7 +125,7 @@ export function getMonsterName(index: number, room: number = 1, monsterTypes?: s
       case 'ghoul': return 'Ghoul'
     }
   }
-  if (room === 2) return index % 2 === 0 ? 'troll' : 'ghoul'
+  if (room >= 2) return index % 2 === 0 ? 'troll' : 'ghoul'
   return index % 2 === 0 ? 'goblin' : 'skeleton'
 }
 
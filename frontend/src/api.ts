@@ -7,6 +7,7 @@ export interface DungeonSummary {
   name: string; namespace: string; difficulty: string
   livingMonsters: number | null; bossState: string | null; victory: boolean | null
   modifier?: string | null; runCount?: number | null
+  rooms?: number; currentRoom?: number
 }
 
 // GetDungeon now returns the raw Dungeon CR — game state in status.game, trigger fields in spec
@@ -19,10 +20,10 @@ export interface KroCondition {
 }
 
 export interface DungeonCR {
-  metadata: { name: string; namespace: string; creationTimestamp?: string; labels?: Record<string, string> }
+  metadata: { name: string; namespace: string; creationTimestamp?: string; labels?: Record<string, string>; annotations?: Record<string, string> }
   spec: {
     // Immutable creation-time config (written once by backend on dungeon creation)
-    monsters: number; difficulty: string; heroClass?: string; runCount?: number
+    monsters: number; difficulty: string; heroClass?: string; runCount?: number; rooms?: number
     // Trigger fields — written by backend to drive kro state-node reconciliation
     attackSeq?: number; actionSeq?: number
     lastAttackTarget?: string; lastAttackSeed?: number; lastAttackIndex?: number
@@ -30,9 +31,10 @@ export interface DungeonCR {
     lastAction?: string; lastAbility?: string
     lastHeroAction?: string; lastEnemyAction?: string; lastCombatLog?: string
     lastLootDrop?: string
-    inventory?: string
+    inventory?: ItemStack[] | string  // string of item IDs until migrated
     enterRoom2?: number
     xpEarned?: number
+    objectives?: Objective[]  // rolled at creation and tracked by the backend
   }
   status?: {
     livingMonsters: number; bossState: string; victory: boolean; defeated: boolean
@@ -44,18 +46,19 @@ export interface DungeonCR {
     game?: {
       heroHP?: number; heroMana?: number; bossHP?: number
       monsterHP?: number[]; monsterTypes?: string[]
-      modifier?: string; inventory?: string
+      modifier?: string; inventory?: ItemStack[] | string
       weaponBonus?: number; weaponUses?: number; armorBonus?: number; shieldBonus?: number
       helmetBonus?: number; pantsBonus?: number; bootsBonus?: number
       ringBonus?: number; amuletBonus?: number
       poisonTurns?: number; burnTurns?: number; stunTurns?: number
       tauntActive?: number; backstabCooldown?: number
       treasureOpened?: number; doorUnlocked?: number; currentRoom?: number
-      room2MonsterHP?: number[]; room2BossHP?: number
+      gold?: number; merchantStock?: string; merchantRoom?: number
+      roomMonsterHP?: number[]; roomBossHP?: number; resolvedRoom?: number
       lastLootDrop?: string
       initProcessedSeq?: number; combatProcessedSeq?: number
       abilityProcessedSeq?: number; actionProcessedSeq?: number
-      room2ProcessedSeq?: number; dotProcessedSeq?: number
+      dotProcessedSeq?: number
       tauntProcessedSeq?: number; cooldownProcessedSeq?: number
       ringProcessedSeq?: number
     }
@@ -95,10 +98,10 @@ export async function getDungeon(ns: string, name: string): Promise<DungeonCR> {
   return r.json()
 }
 
-export async function createDungeon(name: string, monsters: number, difficulty: string, heroClass: string = 'warrior', namespace: string = 'default') {
+export async function createDungeon(name: string, monsters: number, difficulty: string, heroClass: string = 'warrior', namespace: string = 'default', rooms: number = 2) {
   const r = await fetch(`${BASE}/dungeons`, {
     ...CREDS, method: 'POST', headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ name, monsters, difficulty, heroClass, namespace }),
+    body: JSON.stringify({ name, monsters, difficulty, heroClass, namespace, rooms }),
   })
   if (!r.ok) throw new Error(await r.text())
   return r.json()
@@ -106,6 +109,7 @@ export async function createDungeon(name: string, monsters: number, difficulty:
 
 export interface NewGamePlusOptions {
   runCount: number
+  rooms?: number
   // *Bonus fields removed — gear is carried in inventory only (#555)
 }
 
@@ -125,14 +129,31 @@ export async function deleteDungeon(ns: string, name: string) {
   if (!r.ok && r.status !== 204) throw new Error(await r.text())
 }
 
+// Auto-battle: the backend plays the dungeon with a named strategy until
+// victory, defeat, or the player makes a move themselves.
+export async function startAutoBattle(ns: string, name: string, strategy: string, healThreshold?: number) {
+  const r = await fetch(`${BASE}/dungeons/${ns}/${name}/auto-battle`, {
+    ...CREDS, method: 'POST', headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ strategy, healThreshold }),
+  })
+  if (!r.ok) throw new Error(await r.text())
+  return r.json()
+}
+
+export async function stopAutoBattle(ns: string, name: string) {
+  const r = await fetch(`${BASE}/dungeons/${ns}/${name}/auto-battle`, { ...CREDS, method: 'DELETE' })
+  if (!r.ok && r.status !== 204) throw new Error(await r.text())
+}
+
 export interface LeaderboardEntry {
   dungeonName: string
   githubLogin?: string
   heroClass: string
   difficulty: string
-  outcome: string  // 'victory' | 'defeat' | 'room1-cleared' | 'in-progress'
+  outcome: string  // 'victory' | 'defeat' | 'room-cleared' | 'in-progress'
   totalTurns: number
-  currentRoom: number
+  currentRoom: number  // deepest room reached
+  rooms?: number
   timestamp: string
 }
 
@@ -142,6 +163,90 @@ export async function getLeaderboard(): Promise<LeaderboardEntry[]> {
   return r.json()
 }
 
+// Crafting recipe from GET /recipes; inputs repeat an item once per copy consumed.
+export interface Recipe {
+  id: string
+  inputs: string[]
+  output: string
+}
+
+export async function listRecipes(): Promise<Recipe[]> {
+  try {
+    const r = await fetch(`${BASE}/recipes`, CREDS)
+    if (!r.ok) return []
+    return (await r.json()).recipes ?? []
+  } catch {
+    return []
+  }
+}
+
+// Hero class from GET /classes. abilities lists the ability actions the class
+// may use ("heal", "taunt", "backstab").
+export interface HeroClass {
+  id: string
+  name: string
+  icon: string
+  maxHP: number
+  maxMana?: number
+  abilities?: string[]
+  passives?: string[]
+  description: string
+}
+
+export async function listClasses(): Promise<HeroClass[]> {
+  try {
+    const r = await fetch(`${BASE}/classes`, CREDS)
+    if (!r.ok) return []
+    return (await r.json()).classes ?? []
+  } catch {
+    return []
+  }
+}
+
+// A badge or kro certificate from GET /achievements. rule is the kro CEL the
+// backend evaluates when a run ends; certificates without one are awarded
+// through awardCert.
+export interface Achievement {
+  id: string
+  kind: 'badge' | 'career' | 'certificate'
+  tier?: 1 | 2 | 3
+  title: string
+  icon?: string
+  description: string
+  rule?: string
+  evidence?: string  // certificates without a rule: what the claimed dungeon's run must show
+}
+
+export async function listAchievements(): Promise<Achievement[]> {
+  try {
+    const r = await fetch(`${BASE}/achievements`, CREDS)
+    if (!r.ok) return []
+    return (await r.json()).achievements ?? []
+  } catch {
+    return []
+  }
+}
+
+// A run objective from spec.objectives or GET .../objectives.
+export interface Objective {
+  id: string
+  kind: 'kill' | 'no-potions' | 'boss-phase3'
+  description: string
+  monster: string  // kill objectives only; "" otherwise
+  target: number
+  progress: number
+  state: 'active' | 'done' | 'failed'
+  xp: number
+  phaseTurn: number  // boss-phase3: the turn the boss reached phase 3; 0 until then
+}
+
+// One line of a profile's backpack; durability is the uses left on worn gear.
+export interface ItemStack {
+  id: string
+  quantity: number
+  durability: number  // uses left on worn-down gear; 0 when fresh
+}
+
 export interface UserProfile {
   dungeonsPlayed: number
   dungeonsWon: number
@@ -152,7 +257,7 @@ export interface UserProfile {
   totalBossKills: number
   favouriteClass: string
   favouriteDifficulty: string
-  inventory: string          // JSON array string e.g. '["weapon-epic","hppotion-rare"]'
+  inventory: ItemStack[]     // carried into the next run's backpack
   weaponBonus: number; weaponUses: number; armorBonus: number; shieldBonus: number
   helmetBonus: number; pantsBonus: number; bootsBonus: number; ringBonus: number; amuletBonus: number
   heroHP: number
@@ -164,6 +269,7 @@ export interface UserProfile {
   kroCertificates: string[]
   firstPlayed: string
   lastPlayed: string
+  certificateIds?: Record<string, string> // signed document ID per certificate; see /api/v1/certificates/{id}/verify
 }
 
 export async function getProfile(): Promise<UserProfile | null> {
@@ -177,11 +283,12 @@ export async function getProfile(): Promise<UserProfile | null> {
 }
 
 // Award a Tier 2 certificate (frontend-triggered on K8s log tab interactions, #361).
-export async function awardCert(cert: string): Promise<string[] | null> {
+// awardCert claims a Tier 2 certificate earned in one of the player's dungeons.
+export async function awardCert(cert: string, namespace: string, name: string): Promise<string[] | null> {
   try {
     const r = await fetch(`${BASE}/profile/cert`, {
       ...CREDS, method: 'POST', headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ cert }),
+      body: JSON.stringify({ cert, namespace, name }),
     })
     if (!r.ok) return null
     return r.json()
@@ -220,12 +327,32 @@ export async function getDungeonResource(ns: string, name: string, kind: Resourc
 }
 
 export async function submitAttack(ns: string, dungeon: string, target: string, damage: number, seq?: number) {
-  const r = await fetch(`${BASE}/dungeons/${ns}/${dungeon}/attacks`, {
-    ...CREDS, method: 'POST', headers: { 'Content-Type': 'application/json' },
+  // One key per turn, reused on every retry: if a response is lost to a network
+  // blip, the resend replays the first response instead of running the turn twice.
+  const key = crypto.randomUUID()
+  const send = () => fetch(`${BASE}/dungeons/${ns}/${dungeon}/attacks`, {
+    ...CREDS, method: 'POST',
+    headers: { 'Content-Type': 'application/json', 'Idempotency-Key': key },
     // seq: send the last-known sequence so the backend can detect concurrent
     // writes. Omit (send -1) when seq is unknown to stay backward-compatible.
     body: JSON.stringify({ target, damage, seq: seq ?? -1 }),
   })
+  const pause = (ms: number) => new Promise(res => setTimeout(res, ms))
+  let r: Response | undefined
+  for (let attempt = 0; !r; attempt++) {
+    try {
+      r = await send()
+    } catch (e) {
+      if (attempt >= 2) throw e
+      await pause(500 * (attempt + 1))
+      continue
+    }
+    // A resend can find the first attempt still running: wait, then replay it.
+    if (r.status === 409 && attempt > 0 && attempt < 6 && (await r.clone().text()).includes('still in progress')) {
+      r = undefined
+      await pause(500)
+    }
+  }
   if (!r.ok) throw new ApiError(r.status, await r.text())
   return r.json()
 }
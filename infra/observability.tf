@@ -562,7 +562,7 @@ resource "aws_cloudwatch_log_metric_filter" "rate_limited" {
   }
 }
 
-# #475: Active dungeon count gauge — emitted every 30s by pollGameMetrics.
+# #475: Active dungeon count gauge — emitted every 30s by logActiveDungeons (informer cache).
 # Uses $.count from the structured log so the metric reflects the actual live CR count.
 resource "aws_cloudwatch_log_metric_filter" "active_dungeons" {
   name           = "${var.cluster_name}-active-dungeons"
@@ -1136,12 +1136,12 @@ resource "aws_cloudwatch_log_metric_filter" "dungeon_abandoned" {
   }
 }
 
-# Counts dungeons deleted after clearing Room 1 (outcome = "room1-cleared").
+# Counts dungeons deleted after clearing a room short of the final one (outcome = "room-cleared").
 # These are also "abandoned" runs — combined with DungeonAbandoned they form the full abandoned funnel bucket.
 resource "aws_cloudwatch_log_metric_filter" "dungeon_room1_cleared_exit" {
   name           = "${var.cluster_name}-dungeon-room1-cleared-exit"
   log_group_name = aws_cloudwatch_log_group.rpg_system.name
-  pattern        = "{ $.msg = \"dungeon_ended\" && $.outcome = \"room1-cleared\" }"
+  pattern        = "{ $.msg = \"dungeon_ended\" && $.outcome = \"room-cleared\" }"
 
   metric_transformation {
     name          = "DungeonRoom1ClearedExit"
@@ -1197,7 +1197,7 @@ resource "aws_cloudwatch_log_metric_filter" "room1_clears" {
 resource "aws_cloudwatch_log_metric_filter" "room2_entries" {
   name           = "${var.cluster_name}-room2-entries"
   log_group_name = aws_cloudwatch_log_group.rpg_system.name
-  pattern        = "room2_entered"
+  pattern        = "{ $.msg = \"room_entered\" && $.room = 2 }"
 
   metric_transformation {
     name          = "Room2Entries"
@@ -1369,7 +1369,7 @@ resource "aws_cloudwatch_dashboard" "krombat_business" {
             ["Krombat/Business", "DungeonVictory", { label = "Victory", color = "#2ca02c" }],
             ["Krombat/Business", "DungeonDefeat", { label = "Defeat", color = "#d62728" }],
             ["Krombat/Business", "DungeonAbandoned", { label = "Abandoned (in-progress)", color = "#7f7f7f" }],
-            ["Krombat/Business", "DungeonRoom1ClearedExit", { label = "Abandoned (room1-cleared)", color = "#bcbd22" }]
+            ["Krombat/Business", "DungeonRoom1ClearedExit", { label = "Abandoned (room cleared)", color = "#bcbd22" }]
           ]
         }
       },
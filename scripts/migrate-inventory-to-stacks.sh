@@ -0,0 +1,204 @@
+#!/usr/bin/env bash
+# migrate-inventory-to-stacks.sh
+#
+# One-time migration: rewrites spec.inventory and status.game.inventory of
+# existing dungeons from the old string form (a JSON array of item IDs
+# wrapped in a string, e.g. '["hppotion-common","hppotion-common"]') to the
+# structured list of {id, quantity, durability} stacks the Dungeon RGD now
+# declares, e.g. [{"id":"hppotion-common","quantity":2,"durability":0}].
+#
+# Run it right after applying the new dungeon-graph RGD. Until a dungeon is
+# migrated the backend still reads its string inventory, but kro's CEL only
+# understands the structured form.
+#
+# Usage:
+#   ./scripts/migrate-inventory-to-stacks.sh              # dry-run (default)
+#   ./scripts/migrate-inventory-to-stacks.sh --apply       # actually patch
+#   ./scripts/migrate-inventory-to-stacks.sh --apply --verbose
+#   ./scripts/migrate-inventory-to-stacks.sh --apply --rounds 10 --wait 60
+#
+# Safety:
+#   - Covers dungeons in every namespace
+#   - Idempotent: skips dungeons whose inventories are already lists
+#   - Defers dungeons with active combat (Attack/Action CR < 90s old) and
+#     retries them for up to --rounds passes, --wait seconds apart
+#   - Exits non-zero, listing them, if any dungeon is still unmigrated
+#   - Uses --context per AGENTS.md rules
+#   - Dry-run by default
+#
+set -euo pipefail
+
+CONTEXT="arn:aws:eks:us-west-2:<AWS_ACCOUNT_ID>:cluster/krombat"
+DRY_RUN=true
+VERBOSE=false
+ROUNDS=5
+WAIT=30
+
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --apply)  DRY_RUN=false ;;
+    --verbose) VERBOSE=true ;;
+    --rounds) ROUNDS="$2"; shift ;;
+    --wait)   WAIT="$2"; shift ;;
+    --help|-h)
+      echo "Usage: $0 [--apply] [--verbose] [--rounds N] [--wait SECONDS]"
+      echo "  --apply   Actually patch dungeons (default is dry-run)"
+      echo "  --verbose Print old and new inventories"
+      echo "  --rounds  Passes over dungeons deferred for active combat (default 5)"
+      echo "  --wait    Seconds between passes (default 30)"
+      exit 0
+      ;;
+    *) echo "Unknown arg: $1"; exit 1 ;;
+  esac
+  shift
+done
+
+kctl() { kubectl --context "$CONTEXT" "$@"; }
+
+# stacks turns an old string inventory into stacks: copies of an item merge
+# into one stack in first-seen order, as the backend's model.Inventory does.
+# A malformed string becomes an empty backpack, which is how both kro and the
+# backend already read it.
+STACKS='def stacks:
+  ((try fromjson catch []) // [])
+  | if type == "array" then . else [] end
+  | map(select(type == "string" and . != ""))
+  | reduce .[] as $id ([];
+      (map(.id) | index($id)) as $i
+      | if $i == null then . + [{id: $id, quantity: 1, durability: 0}]
+        else .[$i].quantity += 1 end);'
+
+if $DRY_RUN; then
+  echo "=== DRY RUN (pass --apply to execute) ==="
+else
+  echo "=== LIVE RUN — will patch spec.inventory and status.game.inventory ==="
+fi
+echo ""
+
+# active_dungeons prints "<namespace>/<name>" for every dungeon with an
+# Attack or Action CR created in the last 90s.
+active_dungeons() {
+  local now kind
+  now=$(date +%s)
+  for kind in attacks actions; do
+    kctl get "$kind" -A -o json 2>/dev/null | jq -r --argjson now "$now" '
+      .items[]
+      | select((.metadata.creationTimestamp | fromdateiso8601) > ($now - 90))
+      | .metadata.namespace + "/" + (.metadata.labels["krombat.io/dungeon"] // .spec.dungeonName // "")
+    ' || true
+  done
+}
+
+MIGRATED=0
+SKIPPED_ALREADY=0
+ERRORS=0
+FAILED=""
+DEFERRED=""
+
+TMPFILE=$(mktemp)
+trap 'rm -f "$TMPFILE"' EXIT
+
+for ROUND in $(seq 1 "$ROUNDS"); do
+  # --- List dungeons in every namespace; later rounds only revisit deferred ones ---
+  DUNGEONS=$(kctl get dungeons -A -o json)
+  if [ "$ROUND" -eq 1 ]; then
+    echo "Found $(echo "$DUNGEONS" | jq '.items | length') dungeons across all namespaces"
+    echo ""
+    echo "$DUNGEONS" | jq -c '.items[]' > "$TMPFILE"
+  else
+    echo ""
+    echo "--- Round $ROUND: retrying $(echo "$DEFERRED" | wc -w) deferred dungeon(s) ---"
+    echo "$DUNGEONS" | jq -c --arg keys "$DEFERRED" '
+      ($keys | split(" ") | map(select(. != ""))) as $k
+      | .items[] | select((.metadata.namespace + "/" + .metadata.name) as $n | $k | index($n))
+    ' > "$TMPFILE"
+  fi
+  ACTIVE=$(active_dungeons)
+  DEFERRED=""
+
+  while read -r DUNGEON; do
+    NS=$(echo "$DUNGEON" | jq -r '.metadata.namespace')
+    NAME=$(echo "$DUNGEON" | jq -r '.metadata.name')
+    KEY="$NS/$NAME"
+    OWNER=$(echo "$DUNGEON" | jq -r '.metadata.labels["krombat.io/owner"] // "unknown"')
+
+    # --- Build the patches; a field that is absent or already a list is left alone ---
+    SPEC_PATCH=$(echo "$DUNGEON" | jq -c "$STACKS"'
+      if (.spec.inventory | type) == "string"
+      then {spec: {inventory: (.spec.inventory | stacks)}} else empty end')
+    STATUS_PATCH=$(echo "$DUNGEON" | jq -c "$STACKS"'
+      if (.status.game.inventory | type) == "string"
+      then {status: {game: {inventory: (.status.game.inventory | stacks)}}} else empty end')
+
+    if [ -z "$SPEC_PATCH" ] && [ -z "$STATUS_PATCH" ]; then
+      SKIPPED_ALREADY=$((SKIPPED_ALREADY + 1))
+      $VERBOSE && echo "SKIP (already migrated): $KEY (owner=$OWNER)"
+      continue
+    fi
+
+    # --- Defer if active combat ---
+    if echo "$ACTIVE" | grep -qxF "$KEY"; then
+      DEFERRED="$DEFERRED $KEY"
+      echo "DEFER (active combat): $KEY (owner=$OWNER)"
+      continue
+    fi
+
+    if $VERBOSE; then
+      echo "  spec.inventory:        $(echo "$DUNGEON" | jq -c '.spec.inventory // "n/a"') -> $(echo "$SPEC_PATCH" | jq -c '.spec.inventory // "unchanged"' 2>/dev/null || echo unchanged)"
+      echo "  status.game.inventory: $(echo "$DUNGEON" | jq -c '.status.game.inventory // "n/a"') -> $(echo "$STATUS_PATCH" | jq -c '.status.game.inventory // "unchanged"' 2>/dev/null || echo unchanged)"
+    fi
+
+    if $DRY_RUN; then
+      echo "WOULD MIGRATE: $KEY (owner=$OWNER)"
+      MIGRATED=$((MIGRATED + 1))
+      continue
+    fi
+
+    echo "MIGRATING: $KEY (owner=$OWNER)..."
+    OK=true
+    if [ -n "$SPEC_PATCH" ] && ! kctl patch dungeon "$NAME" -n "$NS" \
+        --type=merge -p "$SPEC_PATCH" >/dev/null 2>&1; then
+      OK=false
+    fi
+    if $OK && [ -n "$STATUS_PATCH" ] && ! kctl patch dungeon "$NAME" -n "$NS" \
+        --subresource=status --type=merge -p "$STATUS_PATCH" >/dev/null 2>&1; then
+      OK=false
+    fi
+    if $OK; then
+      MIGRATED=$((MIGRATED + 1))
+      echo "  OK"
+    else
+      ERRORS=$((ERRORS + 1))
+      FAILED="$FAILED $KEY"
+      echo "  FAILED"
+    fi
+  done < "$TMPFILE"
+
+  # A dry run patches nothing, so waiting would not change the answer.
+  if [ -z "$DEFERRED" ] || $DRY_RUN || [ "$ROUND" -eq "$ROUNDS" ]; then
+    break
+  fi
+  echo "Waiting ${WAIT}s for $(echo "$DEFERRED" | wc -w) active dungeon(s) to go idle..."
+  sleep "$WAIT"
+done
+
+echo ""
+echo "=== Summary ==="
+echo "Migrated:               $MIGRATED"
+echo "Skipped (already done): $SKIPPED_ALREADY"
+echo "Still active:           $(echo "$DEFERRED" | wc -w)"
+echo "Errors:                 $ERRORS"
+
+if $DRY_RUN; then
+  echo ""
+  echo "This was a dry run. Pass --apply to execute."
+fi
+
+if [ -n "$DEFERRED" ] || [ -n "$FAILED" ]; then
+  echo ""
+  echo "NOT MIGRATED — re-run the script to finish these:"
+  for KEY in $DEFERRED $FAILED; do
+    echo "  $KEY"
+  done
+  $DRY_RUN || exit 1
+fi
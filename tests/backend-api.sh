@@ -402,7 +402,7 @@ curl -s -X POST "$BASE/api/v1/dungeons" \
   -d "{\"name\":\"$MANA_DUNGEON\",\"monsters\":1,\"difficulty\":\"easy\",\"heroClass\":\"warrior\"}" -o /dev/null
 sleep 15
 # Inject a manapotion-common into the warrior's inventory via status subresource
-kctl patch dungeon "$MANA_DUNGEON" --subresource=status --type merge -p '{"status":{"game":{"inventory":"manapotion-common"}}}' 2>/dev/null || true
+kctl patch dungeon "$MANA_DUNGEON" --subresource=status --type merge -p '{"status":{"game":{"inventory":[{"id":"manapotion-common","quantity":1,"durability":0}]}}}' 2>/dev/null || true
 sleep 3
 MP_CODE=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE/api/v1/dungeons/default/$MANA_DUNGEON/attacks" \
   -H "Content-Type: application/json" "${AUTH_H[@]}" \
@@ -373,7 +373,7 @@ kctl delete loot "${DROP_GUARD_NAME}-monster-0-loot" -n "$DROP_GUARD_NS" --ignor
 echo "=== Combat/Action separation"
 # After #110: attack-graph and action-graph are no-op stubs (resources: []).
 # Guard: Go handler routes item actions separately from combat actions.
-ITEM_ROUTING=$(grep -c 'isItem\|open-treasure\|unlock-door\|enter-room-2\|equip-\|use-' backend/internal/handlers/handlers.go 2>/dev/null || echo 0)
+ITEM_ROUTING=$(grep -c 'isItem\|open-treasure\|unlock-door\|enter-room-\|equip-\|use-' backend/internal/handlers/handlers.go 2>/dev/null || echo 0)
 [ "$ITEM_ROUTING" -ge 3 ] && pass "Go handler routes item/equip/door actions separately ($ITEM_ROUTING refs)" || fail "Go handler missing item action routing"
 # RGDs must remain no-op stubs
 ATTACK_RGD_RESOURCES=$(grep 'resources:' manifests/rgds/attack-graph.yaml 2>/dev/null | grep -v '^\s*#' | head -1)
@@ -479,7 +479,7 @@ grep -q "boss-typ\|boss.*loot\|bossLoot\|boss-rar" manifests/rgds/dungeon-graph.
 # Verify Go backend does NOT contain loot computation functions (clean separation)
 ! grep -q "computeBossLoot\|computeMonsterLoot\|kroSeededRoll\|seededRoll" backend/internal/handlers/handlers.go && pass "Go backend has no loot/RNG math (kro is authoritative)" || fail "Go backend still contains loot/RNG math functions — not fully cleaned up"
 # Hero class validation must cover all 3 valid hero classes — ensure no fallthrough for known classes
-grep -q '"warrior"' backend/internal/handlers/handlers.go && grep -q '"mage"' backend/internal/handlers/handlers.go && grep -q '"rogue"' backend/internal/handlers/handlers.go && pass "Hero class handling covers warrior/mage/rogue" || fail "Hero class handling missing a branch"
+grep -q 'id: warrior' backend/internal/catalog/classes.yaml && grep -q 'id: mage' backend/internal/catalog/classes.yaml && grep -q 'id: rogue' backend/internal/catalog/classes.yaml && pass "Hero class registry covers warrior/mage/rogue" || fail "Hero class registry missing a class"
 # #399: classMaxHP/classMaxMana fallback functions must NOT exist — kro hero-graph is authoritative; backend must reject if maxHeroHP is absent
 ! grep -q "func classMaxHP\|func classMaxMana" backend/internal/handlers/handlers.go && pass "#399: classMaxHP/classMaxMana fallback functions removed (kro is authoritative)" || fail "#399: classMaxHP/classMaxMana fallback functions still exist — remove them"
 # #402: leaderboard/profile must not fall back to raw-HP derivation when kro status is nil
@@ -491,7 +491,8 @@ grep -q '"warrior"' backend/internal/handlers/handlers.go && grep -q '"mage"' ba
 
 # --- Mana potion class guard ---
 echo "=== Mana potion class guard"
-grep -zq 'manapotion.*mage\|heroClass.*mage.*mana\|mana.*heroClass.*mage\|"manapotion-[a-z]*":[[:space:]]*$' backend/internal/handlers/handlers.go && grep -q 'heroClass != "mage"' backend/internal/handlers/handlers.go && pass "Backend rejects mana potions for non-Mage heroes" || fail "Backend missing mana potion class guard"
+# Mana potions are restricted by usableBy in the item catalog; the handler must enforce it.
+grep -q 'id: manapotion-.*usableBy: \[mage' backend/internal/catalog/items.yaml && grep -q 'UsableByClass(heroClass)' backend/internal/handlers/handlers.go && pass "Backend rejects mana potions for classes without mana" || fail "Backend missing mana potion class guard"
 
 # --- Leaderboard guardrails ---
 echo "=== Leaderboard guardrails"
@@ -587,6 +588,7 @@ grep -q "Strict-Transport-Security" frontend/nginx.conf && pass "#417: HSTS head
 # #418: SESSION_SECRET must be required (no optional:true on github-oauth secret, fail-fast in main.go)
 grep -A3 "name: krombat-github-oauth" manifests/system/backend.yaml | grep -q "optional: true" && fail "#418: krombat-github-oauth still optional:true — SESSION_SECRET can be absent" || pass "#418: krombat-github-oauth not optional (SESSION_SECRET is required)"
 grep -q "SESSION_SECRET.*not set\|SESSION_SECRET is not set" backend/cmd/main.go && pass "#418: main.go exits if SESSION_SECRET absent (fail-fast)" || fail "#418: main.go missing SESSION_SECRET fail-fast check"
+grep -q "argocd.argoproj.io/hook: PreSync" manifests/system/certificate-signing-key.yaml && pass "#418: CERTIFICATE_SIGNING_KEY added to the Secret before rollout" || fail "#418: no PreSync hook adds CERTIFICATE_SIGNING_KEY — existing Secrets crashloop the backend"
 
 # #419: telemetry handlers must have body size limits and event allowlist
 grep -q "validGameEvents\|allowlist" backend/internal/handlers/handlers.go && pass "#419: game event allowlist present in EventsTrackHandler" || fail "#419: game event allowlist missing from EventsTrackHandler"
@@ -765,14 +767,16 @@ grep -q 'POST /api/v1/profile/cert' backend/cmd/main.go \
   && pass "#361: POST /api/v1/profile/cert route registered in main.go" \
   || fail "#361: POST /api/v1/profile/cert route missing from main.go"
 
-# tier2Certs allow-list must include expected cert IDs
-grep -q '"log-explorer"' backend/internal/handlers/handlers.go \
-  && pass "#361: tier2Certs allow-list contains log-explorer" \
-  || fail "#361: tier2Certs allow-list missing log-explorer"
+# Tier 2 certs must stay client-awarded: certificates with no rule in achievements.yaml
+grep -q 'id: log-explorer, kind: certificate, tier: 2,.*}$' backend/internal/catalog/achievements.yaml \
+  && ! grep 'id: log-explorer,' backend/internal/catalog/achievements.yaml | grep -q 'rule:' \
+  && pass "#361: log-explorer is a client-awarded Tier 2 certificate" \
+  || fail "#361: log-explorer missing from achievements.yaml or no longer client-awarded"
 
-grep -q '"kro-reconcile"' backend/internal/handlers/handlers.go \
-  && pass "#361: tier2Certs allow-list contains kro-reconcile" \
-  || fail "#361: tier2Certs allow-list missing kro-reconcile"
+grep -q 'id: kro-reconcile, kind: certificate, tier: 2,.*}$' backend/internal/catalog/achievements.yaml \
+  && ! grep 'id: kro-reconcile,' backend/internal/catalog/achievements.yaml | grep -q 'rule:' \
+  && pass "#361: kro-reconcile is a client-awarded Tier 2 certificate" \
+  || fail "#361: kro-reconcile missing from achievements.yaml or no longer client-awarded"
 
 # computeCertificates helper must exist
 grep -q 'func computeCertificates' backend/internal/handlers/handlers.go \
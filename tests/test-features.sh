@@ -53,7 +53,7 @@ FX_STUN=$(kctl get dungeon "test-fx-$TS" -o jsonpath='{.status.game.stunTurns}')
 # --- Loot: patch inventory, then use item ---
 log "Loot test"
 # Game state now lives in status.game — patch via status subresource
-kctl patch dungeon "test-loot-$TS" --subresource=status --type=merge -p '{"status":{"game":{"inventory":"[\"hppotion-rare\"]","heroHP":50}}}' &>/dev/null
+kctl patch dungeon "test-loot-$TS" --subresource=status --type=merge -p '{"status":{"game":{"inventory":[{"id":"hppotion-rare","quantity":1,"durability":0}],"heroHP":50}}}' &>/dev/null
 sleep 2
 submit_action "test-loot-$TS" "use-hppotion-rare"
 INV=$(kctl get dungeon "test-loot-$TS" -o jsonpath='{.status.game.inventory}')